@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ActionDocumentApprove       = "document.approve"
+	ActionDocumentReject        = "document.reject"
+	ActionDocumentDeleteRequest = "document.delete_request"
+	ActionDocumentDelete        = "document.delete"
+	ActionDocumentRollback      = "document.rollback"
+	ActionDocumentApproveStep   = "document.approve_step"
+	ActionDocumentResubmit      = "document.resubmit"
+	ActionDocumentUpdateDates   = "document.update_dates"
+	ActionDocumentExpire        = "document.expire"
+	ActionDocumentPublish       = "document.publish"
+	ActionDocumentMetadata      = "document.update_metadata"
+	ActionDocumentRestore       = "document.restore"
+	ActionDocumentPurge         = "document.purge"
+	ActionDocumentLegalHold     = "document.legal_hold"
+	ActionWorkflowCreate        = "workflow.create"
+	ActionWorkflowUpdate        = "workflow.update"
+	ActionWorkflowDelete        = "workflow.delete"
+	ActionMetadataFieldCreate   = "metadata_field.create"
+	ActionMetadataFieldUpdate   = "metadata_field.update"
+	ActionMetadataFieldDelete   = "metadata_field.delete"
+	ActionCrawlSourceCreate     = "crawl_source.create"
+	ActionCrawlSourceUpdate     = "crawl_source.update"
+	ActionCrawlSourceDelete     = "crawl_source.delete"
+	ActionShareLinkCreate       = "share_link.create"
+	ActionShareLinkRevoke       = "share_link.revoke"
+	ActionURLFormatCreate       = "url_format.create"
+	ActionURLFormatUpdate       = "url_format.update"
+	ActionURLFormatDelete       = "url_format.delete"
+	ActionRoleCreate            = "role.create"
+	ActionRoleUpdate            = "role.update"
+	ActionRoleDelete            = "role.delete"
+	ActionHelpdeskSwitch        = "helpdesk.switch"
+	ActionAnswerValidate        = "chat.validate_answer"
+)
+
+const (
+	EntityDocumentDetail = "document_detail"
+	EntityDocument       = "document"
+	EntityRole           = "role"
+	EntitySwitchHelpdesk = "switch_helpdesk"
+	EntityChatAnswer     = "chat_answer"
+	EntityWorkflow       = "approval_workflow"
+	EntityMetadataField  = "metadata_field"
+	EntityCrawlSource    = "crawl_source"
+	EntityShareLink      = "document_share_link"
+	EntityURLFormat      = "url_format"
+)
+
+type AuditEvent struct {
+	ID          int64            `db:"id" json:"id"`
+	ActorUserID *int64           `db:"actor_user_id" json:"actor_user_id"`
+	ActorName   *string          `db:"actor_name" json:"actor_name"`
+	Action      string           `db:"action" json:"action"`
+	EntityType  string           `db:"entity_type" json:"entity_type"`
+	EntityID    *string          `db:"entity_id" json:"entity_id"`
+	Before      *json.RawMessage `db:"before_data" json:"before"`
+	After       *json.RawMessage `db:"after_data" json:"after"`
+	IPAddress   *string          `db:"ip_address" json:"ip_address"`
+	CreatedAt   time.Time        `db:"created_at" json:"created_at"`
+}
+
+type AuditFilter struct {
+	ActorUserID *int64
+	Action      string
+	EntityType  string
+	EntityID    string
+	StartDate   *time.Time
+	EndDate     *time.Time
+	Limit       int
+	Offset      int
+}
+
+// Actor identifies who triggered an audited action. Handlers build it from the
+// request and pass it down to the service layer.
+type Actor struct {
+	UserID *int64
+	IP     string
+}
+
+func ActorFromContext(ctx *gin.Context) Actor {
+	actor := Actor{IP: ctx.ClientIP()}
+	if userID, exists := ctx.Get("user_id"); exists {
+		if id, ok := userID.(int64); ok {
+			actor.UserID = &id
+		}
+	}
+	return actor
+}
@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"dokuprime-be/util"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateOnlyLayout = "2006-01-02"
+
+type AuditHandler struct {
+	service *AuditService
+}
+
+func NewAuditHandler(service *AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) GetAll(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = offset
+
+	events, total, err := h.service.GetAll(filter)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Audit events retrieved successfully", gin.H{
+		"data":   events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *AuditHandler) ExportCSV(ctx *gin.Context) {
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := h.service.Export(filter)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("audit_events_%s.csv", time.Now().Format("20060102_150405"))
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	ctx.Status(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write([]string{"id", "created_at", "actor_user_id", "actor_name", "action", "entity_type", "entity_id", "ip_address", "before", "after"})
+
+	for _, e := range events {
+		writer.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Format(time.RFC3339),
+			formatInt64(e.ActorUserID),
+			formatString(e.ActorName),
+			e.Action,
+			e.EntityType,
+			formatString(e.EntityID),
+			formatString(e.IPAddress),
+			formatJSON(e.Before),
+			formatJSON(e.After),
+		})
+	}
+	writer.Flush()
+}
+
+func parseFilter(ctx *gin.Context) (AuditFilter, error) {
+	filter := AuditFilter{
+		Action:     ctx.Query("action"),
+		EntityType: ctx.Query("entity_type"),
+		EntityID:   ctx.Query("entity_id"),
+	}
+
+	if actor := ctx.Query("actor_user_id"); actor != "" {
+		id, err := strconv.ParseInt(actor, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid actor_user_id")
+		}
+		filter.ActorUserID = &id
+	}
+
+	if sd := ctx.Query("start_date"); sd != "" {
+		t, _, err := parseDate(sd)
+		if err != nil {
+			return filter, err
+		}
+		filter.StartDate = &t
+	}
+	if ed := ctx.Query("end_date"); ed != "" {
+		t, dateOnly, err := parseDate(ed)
+		if err != nil {
+			return filter, err
+		}
+		// A plain date includes the whole day.
+		if dateOnly {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		filter.EndDate = &t
+	}
+
+	return filter, nil
+}
+
+func parseDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(dateOnlyLayout, s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date format: %s", s)
+}
+
+func formatInt64(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func formatString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func formatJSON(v *json.RawMessage) string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(event *AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (actor_user_id, action, entity_type, entity_id, before_data, after_data, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		event.ActorUserID,
+		event.Action,
+		event.EntityType,
+		event.EntityID,
+		jsonArg(event.Before),
+		jsonArg(event.After),
+		event.IPAddress,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// jsonArg passes JSON as text; lib/pq would send raw bytes as bytea.
+func jsonArg(raw *json.RawMessage) interface{} {
+	if raw == nil {
+		return nil
+	}
+	return string(*raw)
+}
+
+func (r *AuditRepository) buildWhere(filter AuditFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	argIdx := 1
+
+	if filter.ActorUserID != nil {
+		conditions = append(conditions, "ae.actor_user_id = $"+fmt.Sprint(argIdx))
+		args = append(args, *filter.ActorUserID)
+		argIdx++
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "ae.action = $"+fmt.Sprint(argIdx))
+		args = append(args, filter.Action)
+		argIdx++
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "ae.entity_type = $"+fmt.Sprint(argIdx))
+		args = append(args, filter.EntityType)
+		argIdx++
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "ae.entity_id = $"+fmt.Sprint(argIdx))
+		args = append(args, filter.EntityID)
+		argIdx++
+	}
+	if filter.StartDate != nil {
+		conditions = append(conditions, "ae.created_at >= $"+fmt.Sprint(argIdx))
+		args = append(args, *filter.StartDate)
+		argIdx++
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, "ae.created_at <= $"+fmt.Sprint(argIdx))
+		args = append(args, *filter.EndDate)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	return where, args
+}
+
+func (r *AuditRepository) GetAll(filter AuditFilter) ([]AuditEvent, int, error) {
+	events := []AuditEvent{}
+	where, args := r.buildWhere(filter)
+
+	var total int
+	if err := r.db.Get(&total, "SELECT COUNT(*) FROM audit_events ae "+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	limitPlaceholder := "$" + fmt.Sprint(len(args)+1)
+	offsetPlaceholder := "$" + fmt.Sprint(len(args)+2)
+
+	query := `
+		SELECT ae.id, ae.actor_user_id, u.name AS actor_name, ae.action, ae.entity_type, ae.entity_id,
+			ae.before_data, ae.after_data, ae.ip_address, ae.created_at
+		FROM audit_events ae
+		LEFT JOIN users u ON u.id = ae.actor_user_id
+		` + where + `
+		ORDER BY ae.created_at DESC, ae.id DESC
+		LIMIT ` + limitPlaceholder + ` OFFSET ` + offsetPlaceholder
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	if err := r.db.Select(&events, query, args...); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"dokuprime-be/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+const permAuditRead = "audit-log:read"
+
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
+	repo := NewAuditRepository(db)
+	service := NewAuditService(repo)
+	handler := NewAuditHandler(service)
+
+	auditGroup := r.Group("/api/audit")
+	auditGroup.Use(middleware.AuthMiddleware())
+	{
+		auditGroup.GET("", middleware.RequirePermission(permAuditRead), handler.GetAll)
+		auditGroup.GET("/export", middleware.RequirePermission(permAuditRead), handler.ExportCSV)
+	}
+}
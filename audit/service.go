@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+)
+
+// Upper bound on rows written by a single CSV export.
+const maxExportRows = 50000
+
+type AuditService struct {
+	repo *AuditRepository
+}
+
+func NewAuditService(repo *AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores an audit event. Failures are logged and never block the
+// audited action itself.
+func (s *AuditService) Record(actor Actor, action, entityType string, entityID interface{}, before, after interface{}) {
+	if s == nil {
+		return
+	}
+
+	event := &AuditEvent{
+		ActorUserID: actor.UserID,
+		Action:      action,
+		EntityType:  entityType,
+		Before:      toJSON(before),
+		After:       toJSON(after),
+	}
+	if entityID != nil {
+		id := fmt.Sprint(entityID)
+		event.EntityID = &id
+	}
+	if actor.IP != "" {
+		event.IPAddress = &actor.IP
+	}
+
+	if err := s.repo.Create(event); err != nil {
+		log.Printf("Warning: Failed to record audit event %s for %s %v: %v", action, entityType, entityID, err)
+	}
+}
+
+func (s *AuditService) GetAll(filter AuditFilter) ([]AuditEvent, int, error) {
+	return s.repo.GetAll(filter)
+}
+
+func (s *AuditService) Export(filter AuditFilter) ([]AuditEvent, error) {
+	filter.Limit = maxExportRows
+	filter.Offset = 0
+	events, _, err := s.repo.GetAll(filter)
+	return events, err
+}
+
+func toJSON(v interface{}) *json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Warning: Failed to marshal audit payload: %v", err)
+		return nil
+	}
+	raw := json.RawMessage(data)
+	return &raw
+}
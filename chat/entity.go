@@ -2,6 +2,7 @@ package chat
 
 import (
 	"database/sql/driver"
+	"dokuprime-be/document"
 	"dokuprime-be/external"
 	"encoding/json"
 	"time"
@@ -56,6 +57,7 @@ type ResponseAsk struct {
 	QuestionCategory []string                       `json:"question_category"`
 	Answer           string                         `json:"answer"`
 	Citations        external.FlexibleCitationArray `json:"citations"`
+	Sources          []document.Citation            `json:"sources"`
 	IsHelpdesk       bool                           `json:"is_helpdesk"`
 	IsAnswered       *bool                          `json:"is_answered"`
 	Platform         string                         `json:"platform"`
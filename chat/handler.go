@@ -1,8 +1,11 @@
 package chat
 
 import (
+	"context"
 	"database/sql"
+	"dokuprime-be/audit"
 	"dokuprime-be/config"
+	"dokuprime-be/document"
 	"dokuprime-be/external"
 	"dokuprime-be/helpdesk"
 	"dokuprime-be/messaging"
@@ -26,6 +29,7 @@ const (
 	invalidSessionID      = "Invalid session ID"
 	invalidConversationID = "Invalid conversation ID"
 	isNotAuthenticated    = "User not authenticated"
+	assistantUnavailable  = "Assistant is temporarily unavailable, please try again later"
 )
 
 type ChatHandler struct {
@@ -34,15 +38,17 @@ type ChatHandler struct {
 	wsClient        *config.WebSocketClient
 	helpdeskService helpdesk.HelpdeskService
 	messageService  messaging.MessageService
+	citations       *document.CitationResolver
 }
 
-func NewChatHandler(service *ChatService, externalClient *external.Client, wsURL, wsToken string, helpdeskService helpdesk.HelpdeskService, messageService messaging.MessageService) *ChatHandler {
+func NewChatHandler(service *ChatService, externalClient *external.Client, wsURL, wsToken string, helpdeskService helpdesk.HelpdeskService, messageService messaging.MessageService, citations *document.CitationResolver) *ChatHandler {
 	handler := &ChatHandler{
 		service:         service,
 		externalClient:  externalClient,
 		wsClient:        config.NewWebSocketClient(wsURL, wsToken),
 		helpdeskService: helpdeskService,
 		messageService:  messageService,
+		citations:       citations,
 	}
 
 	if err := handler.wsClient.Connect(); err != nil {
@@ -393,14 +399,16 @@ func (h *ChatHandler) DeleteConversation(ctx *gin.Context) {
 	util.SuccessResponse(ctx, "Conversation deleted successfully", nil)
 }
 
+type askRequest struct {
+	PlatformUniqueID string `json:"platform_unique_id" binding:"required"`
+	Query            string `json:"query" binding:"required"`
+	ConversationID   string `json:"conversation_id"`
+	Platform         string `json:"platform" binding:"required"`
+	StartTimestamp   string `json:"start_timestamp,omitempty"`
+}
+
 func (h *ChatHandler) Ask(ctx *gin.Context) {
-	var req struct {
-		PlatformUniqueID string `json:"platform_unique_id" binding:"required"`
-		Query            string `json:"query" binding:"required"`
-		ConversationID   string `json:"conversation_id"`
-		Platform         string `json:"platform" binding:"required"`
-		StartTimestamp   string `json:"start_timestamp,omitempty"`
-	}
+	var req askRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Println("Line 293", err)
@@ -429,9 +437,13 @@ func (h *ChatHandler) Ask(ctx *gin.Context) {
 		StartTimestamp:   req.StartTimestamp,
 	}
 
-	resp, err := h.externalClient.SendChatMessage(chatReq)
+	resp, err := h.externalClient.SendChatMessage(ctx.Request.Context(), chatReq)
 	if err != nil {
 		log.Println("Line 307", err)
+		if external.IsUnavailable(err) {
+			util.ErrorResponse(ctx, http.StatusServiceUnavailable, assistantUnavailable)
+			return
+		}
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -444,10 +456,123 @@ func (h *ChatHandler) Ask(ctx *gin.Context) {
 	}
 
 	responseAsk := h.processAskResponseData(finalConversation, resp)
+	responseAsk.Sources = h.resolveCitations(ctx, responseAsk.Citations)
 	util.SuccessResponse(ctx, "Message sent successfully", responseAsk)
 	h.broadcastAskResponse(ctx, finalConversation, responseAsk)
 }
 
+// AskStream is the streaming variant of Ask. Token deltas from the RAG service
+// are written to the client as Server-Sent Events and, for web conversations,
+// published on the conversation's WebSocket channel. The final answer is then
+// handled exactly like Ask.
+func (h *ChatHandler) AskStream(ctx *gin.Context) {
+	var req askRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, invalidRequestBody)
+		return
+	}
+
+	h.ensureWebSocketConnection()
+
+	conversation, err := h.resolveAskConversation(ctx, req.ConversationID)
+	if err != nil {
+		return
+	}
+
+	// Helpdesk conversations are answered by an agent, so there is nothing to stream.
+	if conversation != nil && conversation.IsHelpdesk {
+		if handled := h.handleExistingHelpdesk(ctx, conversation, req.Query, req.StartTimestamp); handled {
+			return
+		}
+	}
+
+	chatReq := external.ChatRequest{
+		PlatformUniqueID: req.PlatformUniqueID,
+		Query:            req.Query,
+		ConversationID:   req.ConversationID,
+		Platform:         req.Platform,
+		StartTimestamp:   req.StartTimestamp,
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	clientGone := ctx.Request.Context().Done()
+	writeEvent := func(name string, data interface{}) {
+		select {
+		case <-clientGone:
+			return
+		default:
+		}
+		ctx.SSEvent(name, data)
+		ctx.Writer.Flush()
+	}
+
+	sequence := 0
+	onDelta := func(delta external.ChatDelta) {
+		sequence++
+		conversationID := delta.ConversationID
+		if conversationID == "" {
+			conversationID = req.ConversationID
+		}
+
+		writeEvent("delta", gin.H{
+			"conversation_id": conversationID,
+			"delta":           delta.Text,
+			"sequence":        sequence,
+		})
+		h.publishAskDelta(req.Platform, conversationID, delta.Text, sequence)
+	}
+
+	// The upstream request is not tied to the client connection so the answer
+	// is still persisted and broadcast if the browser goes away mid-stream.
+	resp, err := h.externalClient.SendChatMessageStream(context.Background(), chatReq, onDelta)
+	if err != nil {
+		log.Printf("Error streaming chat answer: %v", err)
+		if external.IsUnavailable(err) {
+			writeEvent("error", gin.H{"message": assistantUnavailable, "unavailable": true})
+			return
+		}
+		writeEvent("error", gin.H{"message": err.Error()})
+		return
+	}
+
+	finalConversation, err := h.ensureConversationFromResponse(req.Platform, req.PlatformUniqueID, resp)
+	if err != nil {
+		log.Printf("Error creating conversation: %v", err)
+		writeEvent("error", gin.H{"message": "Error creating conversation"})
+		return
+	}
+
+	responseAsk := h.processAskResponseData(finalConversation, resp)
+	responseAsk.Sources = h.resolveCitations(ctx, responseAsk.Citations)
+	writeEvent("done", responseAsk)
+	h.broadcastAskResponse(ctx, finalConversation, responseAsk)
+}
+
+func (h *ChatHandler) publishAskDelta(platform, conversationID, text string, sequence int) {
+	if platform != "web" || conversationID == "" || !h.wsClient.IsConnected() {
+		return
+	}
+
+	publishData := map[string]interface{}{
+		"type":            "delta",
+		"conversation_id": conversationID,
+		"delta":           text,
+		"sequence":        sequence,
+		"timestamp":       time.Now().Unix(),
+	}
+
+	if err := h.wsClient.Publish(conversationID, publishData); err != nil {
+		log.Printf("Failed to publish delta to channel %s: %v", conversationID, err)
+	}
+}
+
 func (h *ChatHandler) ensureWebSocketConnection() {
 	if !h.wsClient.IsConnected() {
 		log.Println("WebSocket not connected, attempting to reconnect...")
@@ -611,6 +736,12 @@ func (h *ChatHandler) processAskResponseData(conversation *Conversation, resp *e
 	}
 }
 
+// resolveCitations links the cited documents. Internal users get view URLs
+// of their own; multichannel users only get public source URLs.
+func (h *ChatHandler) resolveCitations(ctx *gin.Context, citations external.FlexibleCitationArray) []document.Citation {
+	return h.citations.Resolve(citations, document.CitationViewer(ctx), util.PublicBaseURL())
+}
+
 func (h *ChatHandler) broadcastAskResponse(ctx *gin.Context, conversation *Conversation, responseAsk ResponseAsk) {
 	if conversation.Platform == "web" {
 		if h.wsClient.IsConnected() {
@@ -624,6 +755,7 @@ func (h *ChatHandler) broadcastAskResponse(ctx *gin.Context, conversation *Conve
 				"question_category":  responseAsk.QuestionCategory,
 				"answer":             responseAsk.Answer,
 				"citations":          responseAsk.Citations,
+				"sources":            responseAsk.Sources,
 				"is_helpdesk":        responseAsk.IsHelpdesk,
 				"is_answered":        responseAsk.IsAnswered,
 				"platform":           conversation.Platform,
@@ -640,7 +772,9 @@ func (h *ChatHandler) broadcastAskResponse(ctx *gin.Context, conversation *Conve
 			}
 		}
 	} else {
-		err := h.externalClient.SendMessageToAPI(responseAsk)
+		// The answer must still reach the channel when the caller has hung up,
+		// which is likely after a long stream; the client's own timeout applies.
+		err := h.externalClient.SendMessageToAPI(context.WithoutCancel(ctx.Request.Context()), responseAsk)
 		if err != nil {
 			log.Printf("Error sending to Multi Channel API: %v", err)
 
@@ -767,13 +901,12 @@ func (h *ChatHandler) ValidateAnswer(ctx *gin.Context) {
 		req.Revision = req.Answer
 	}
 
-	userID, exists := ctx.Get("user_id")
-	if !exists {
+	if _, exists := ctx.Get("user_id"); !exists {
 		util.ErrorResponse(ctx, http.StatusUnauthorized, isNotAuthenticated)
 		return
 	}
 
-	if err := h.service.UpdateIsAnsweredStatus(req.QuestionID, req.AnswerID, req.Revision, req.Validate, userID); err != nil {
+	if err := h.service.UpdateIsAnsweredStatus(req.QuestionID, req.AnswerID, req.Revision, req.Validate, audit.ActorFromContext(ctx)); err != nil {
 		log.Println("Error updating is_answered status:", err)
 		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update validation status")
 		return
@@ -797,7 +930,7 @@ func (h *ChatHandler) ValidateAnswer(ctx *gin.Context) {
 			FilePath: tempFilePath,
 		}
 
-		if err := h.externalClient.ExtractDocument(extractReq); err != nil {
+		if err := h.externalClient.ExtractDocument(ctx.Request.Context(), extractReq); err != nil {
 			log.Println("Error extracting document:", err)
 			os.Remove(tempFilePath)
 			util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to upload document to external API")
@@ -1,7 +1,9 @@
 package chat
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/config"
+	"dokuprime-be/document"
 	"dokuprime-be/external"
 	"dokuprime-be/helpdesk"
 	"dokuprime-be/messaging"
@@ -10,20 +12,24 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 )
 const(
 	urlHistoryID = "/history/:id"
 	urConversationID = "/conversations/:id"
+	permPublicRead = "public-service:read"
+	permValidationRead = "validation-history:read"
 ) 
 
-func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
 	repo := NewChatRepository(db)
-	service := NewChatService(repo)
+	auditService := audit.NewAuditService(audit.NewAuditRepository(db))
+	service := NewChatService(repo, auditService)
 
 	externalAPIConfig := config.LoadExternalAPIConfig()
 	externalClient := external.NewClient(externalAPIConfig)
 
-	helpdeskService := helpdesk.NewHelpdeskService(helpdesk.NewHelpdeskRepository(db))
+	helpdeskService := helpdesk.NewHelpdeskService(helpdesk.NewHelpdeskRepository(db), auditService)
 
 	wsURL := os.Getenv("WEBSOCKET_URL")
 	if wsURL == "" {
@@ -37,32 +43,35 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
 
 	messageService := messaging.NewMessageService(db, wsURL, wsToken, externalClient)
 
-	handler := NewChatHandler(service, externalClient, wsURL, wsToken, *helpdeskService, *messageService)
+	citations := document.NewCitationResolver(db, redisClient)
+
+	handler := NewChatHandler(service, externalClient, wsURL, wsToken, *helpdeskService, *messageService, citations)
 
 	chatRoutes := r.Group("/api/chat")
 	chatRoutes.Use(middleware.AuthMiddleware())
 	{
-		chatRoutes.POST("/history", handler.CreateChatHistory)
-		chatRoutes.GET("/history", handler.GetChatHistories)
-		chatRoutes.GET(urlHistoryID, handler.GetChatHistoryByID)
-		chatRoutes.GET("/history/session/:session_id", handler.GetChatHistoryBySessionID)
-		chatRoutes.PUT(urlHistoryID, handler.UpdateChatHistory)
-		chatRoutes.DELETE(urlHistoryID, handler.DeleteChatHistory)
-
-		chatRoutes.GET("/pairs/session/:session_id", handler.GetChatPairsBySessionID)
-		chatRoutes.GET("/pairs/all", handler.GetChatPairsBySessionID)
-		chatRoutes.GET("/debug/session/:session_id", handler.DebugChatHistory)
-
-		chatRoutes.POST("/conversations", handler.CreateConversation)
-		chatRoutes.GET("/conversations", handler.GetConversations)
-		chatRoutes.GET(urConversationID, handler.GetConversationByID)
-		chatRoutes.PUT(urConversationID, handler.UpdateConversation)
-		chatRoutes.DELETE(urConversationID, handler.DeleteConversation)
-
-		chatRoutes.POST("/ask", handler.Ask)
-		chatRoutes.POST("/validate", handler.ValidateAnswer)
-
-		chatRoutes.POST("/feedback", handler.Feedback)
+		chatRoutes.POST("/history", middleware.RequirePermission(permPublicRead), handler.CreateChatHistory)
+		chatRoutes.GET("/history", middleware.RequirePermission(permPublicRead, permValidationRead), handler.GetChatHistories)
+		chatRoutes.GET(urlHistoryID, middleware.RequirePermission(permPublicRead, permValidationRead), handler.GetChatHistoryByID)
+		chatRoutes.GET("/history/session/:session_id", middleware.RequirePermission(permPublicRead, permValidationRead), handler.GetChatHistoryBySessionID)
+		chatRoutes.PUT(urlHistoryID, middleware.RequirePermission("validation-history:update"), handler.UpdateChatHistory)
+		chatRoutes.DELETE(urlHistoryID, middleware.RequirePermission("validation-history:delete"), handler.DeleteChatHistory)
+
+		chatRoutes.GET("/pairs/session/:session_id", middleware.RequirePermission(permValidationRead), handler.GetChatPairsBySessionID)
+		chatRoutes.GET("/pairs/all", middleware.RequirePermission(permValidationRead), handler.GetChatPairsBySessionID)
+		chatRoutes.GET("/debug/session/:session_id", middleware.RequirePermission("validation-history:master"), handler.DebugChatHistory)
+
+		chatRoutes.POST("/conversations", middleware.RequirePermission(permPublicRead), handler.CreateConversation)
+		chatRoutes.GET("/conversations", middleware.RequirePermission(permPublicRead, permValidationRead), handler.GetConversations)
+		chatRoutes.GET(urConversationID, middleware.RequirePermission(permPublicRead, permValidationRead), handler.GetConversationByID)
+		chatRoutes.PUT(urConversationID, middleware.RequirePermission(permPublicRead), handler.UpdateConversation)
+		chatRoutes.DELETE(urConversationID, middleware.RequirePermission("public-service:manager"), handler.DeleteConversation)
+
+		chatRoutes.POST("/ask", middleware.RequirePermission(permPublicRead), handler.Ask)
+		chatRoutes.POST("/ask/stream", middleware.RequirePermission(permPublicRead), handler.AskStream)
+		chatRoutes.POST("/validate", middleware.RequirePermission("validation-history:update"), handler.ValidateAnswer)
+
+		chatRoutes.POST("/feedback", middleware.RequirePermission(permPublicRead), handler.Feedback)
 	}
 
 	apiKeyRoutes := r.Group("/api/chat/multichannel")
@@ -1,17 +1,19 @@
 package chat
 
 import (
+	"dokuprime-be/audit"
 	"math"
 
 	"github.com/google/uuid"
 )
 
 type ChatService struct {
-	repo *ChatRepository
+	repo  *ChatRepository
+	audit *audit.AuditService
 }
 
-func NewChatService(repo *ChatRepository) *ChatService {
-	return &ChatService{repo: repo}
+func NewChatService(repo *ChatRepository, auditService *audit.AuditService) *ChatService {
+	return &ChatService{repo: repo, audit: auditService}
 }
 
 func (s *ChatService) CreateChatHistory(history *ChatHistory) error {
@@ -159,8 +161,16 @@ func (s *ChatService) GetChatPairsBySessionID(sessionID *uuid.UUID, filter ChatH
 	}, nil
 }
 
-func (s *ChatService) UpdateIsAnsweredStatus(questionID, answerID int, revision string, isValidated bool, userID any) error {
-	return s.repo.UpdateIsAnsweredStatus(questionID, answerID, revision, isValidated, userID)
+func (s *ChatService) UpdateIsAnsweredStatus(questionID, answerID int, revision string, isValidated bool, actor audit.Actor) error {
+	before, _ := s.repo.GetChatHistoryByID(answerID)
+
+	if err := s.repo.UpdateIsAnsweredStatus(questionID, answerID, revision, isValidated, actor.UserID); err != nil {
+		return err
+	}
+
+	after, _ := s.repo.GetChatHistoryByID(answerID)
+	s.audit.Record(actor, audit.ActionAnswerValidate, audit.EntityChatAnswer, answerID, before, after)
+	return nil
 }
 
 func (s *ChatService) Feedback(answerID int, sessionID uuid.UUID, feedback bool) error {
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func InitDB() *sqlx.DB {
@@ -45,3 +46,18 @@ func InitDB() *sqlx.DB {
 
 	return db
 }
+
+// GetDBSchema returns the schema configured in DB_SCHEMA, or "" when the
+// connection's default search_path is used.
+func GetDBSchema() string {
+	return strings.TrimSpace(os.Getenv("DB_SCHEMA"))
+}
+
+// QualifiedTable prefixes a table name with DB_SCHEMA when one is configured.
+func QualifiedTable(name string) string {
+	schema := GetDBSchema()
+	if schema == "" {
+		return name
+	}
+	return pq.QuoteIdentifier(schema) + "." + name
+}
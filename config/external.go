@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type ExternalAPIConfig struct {
@@ -9,6 +11,18 @@ type ExternalAPIConfig struct {
 	APIKey         string
 	MessagesAPIURL string
 	MessagesAPIKey string
+
+	ChatTimeout       time.Duration
+	ChatStreamTimeout time.Duration
+	ExtractTimeout    time.Duration
+	DeleteTimeout     time.Duration
+	MessagesTimeout   time.Duration
+
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
 }
 
 func LoadExternalAPIConfig() *ExternalAPIConfig {
@@ -37,5 +51,28 @@ func LoadExternalAPIConfig() *ExternalAPIConfig {
 		APIKey:         apiKey,
 		MessagesAPIURL: messagesAPIURL,
 		MessagesAPIKey: messagesAPIKey,
+
+		ChatTimeout:       envSeconds("EXTERNAL_CHAT_TIMEOUT_SECONDS", 60),
+		ChatStreamTimeout: envSeconds("EXTERNAL_CHAT_STREAM_TIMEOUT_SECONDS", 300),
+		ExtractTimeout:    envSeconds("EXTERNAL_EXTRACT_TIMEOUT_SECONDS", 300),
+		DeleteTimeout:     envSeconds("EXTERNAL_DELETE_TIMEOUT_SECONDS", 30),
+		MessagesTimeout:   envSeconds("MESSAGES_API_TIMEOUT_SECONDS", 30),
+
+		MaxRetries:     envInt("EXTERNAL_MAX_RETRIES", 3),
+		RetryBaseDelay: time.Duration(envInt("EXTERNAL_RETRY_BASE_MS", 500)) * time.Millisecond,
+
+		BreakerThreshold: envInt("EXTERNAL_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  envSeconds("EXTERNAL_BREAKER_COOLDOWN_SECONDS", 30),
 	}
 }
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return fallback
+}
+
+func envSeconds(key string, fallback int) time.Duration {
+	return time.Duration(envInt(key, fallback)) * time.Second
+}
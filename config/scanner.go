@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+type ScannerConfig struct {
+	Backend      string
+	ClamdAddress string
+	Timeout      time.Duration
+	FailOpen     bool
+}
+
+// LoadScannerConfig reads the malware scanner settings. SCANNER_BACKEND is
+// clamav, mock or empty to disable scanning.
+func LoadScannerConfig() *ScannerConfig {
+	address := os.Getenv("CLAMAV_ADDRESS")
+	if address == "" {
+		address = "127.0.0.1:3310"
+	}
+
+	return &ScannerConfig{
+		Backend:      strings.ToLower(os.Getenv("SCANNER_BACKEND")),
+		ClamdAddress: address,
+		Timeout:      envSeconds("CLAMAV_TIMEOUT_SECONDS", 60),
+		FailOpen:     os.Getenv("SCANNER_FAIL_OPEN") == "true",
+	}
+}
@@ -21,3 +21,30 @@ func GetUploadPath() string {
 func GetDocumentPath(filename string) string {
 	return filepath.Join(GetUploadPath(), filename)
 }
+
+type S3Config struct {
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	Prefix       string
+	UsePathStyle bool
+}
+
+func LoadS3Config() *S3Config {
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Config{
+		Endpoint:     os.Getenv("S3_ENDPOINT"),
+		Region:       region,
+		Bucket:       os.Getenv("S3_BUCKET"),
+		AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		Prefix:       os.Getenv("S3_PREFIX"),
+		UsePathStyle: os.Getenv("S3_USE_PATH_STYLE") != "false",
+	}
+}
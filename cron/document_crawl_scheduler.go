@@ -0,0 +1,36 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+type DocumentCrawlScheduler struct {
+	crawler *document.DocumentService
+}
+
+func NewDocumentCrawlScheduler(db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend, asyncProcessor *document.AsyncProcessor) *DocumentCrawlScheduler {
+	return &DocumentCrawlScheduler{
+		crawler: document.NewSchedulerService(db, redisClient, fileStorage, asyncProcessor),
+	}
+}
+
+// RegisterJobs checks for due crawl sources; each source has its own
+// schedule.
+func (d *DocumentCrawlScheduler) RegisterJobs(scheduler *Scheduler) error {
+	spec := os.Getenv("DOCUMENT_CRAWL_CRON")
+	if spec == "" {
+		spec = "0 * * * * *"
+	}
+	if err := scheduler.AddJob(spec, d.crawler.RunDueCrawlSources); err != nil {
+		return err
+	}
+
+	log.Println("Document crawl scheduler jobs registered successfully")
+	return nil
+}
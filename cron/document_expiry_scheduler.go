@@ -0,0 +1,52 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+type DocumentExpiryScheduler struct {
+	expiry    *document.ExpiryService
+	documents *document.DocumentService
+}
+
+func NewDocumentExpiryScheduler(db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend, asyncProcessor *document.AsyncProcessor) *DocumentExpiryScheduler {
+	return &DocumentExpiryScheduler{
+		expiry:    document.NewExpiryService(db),
+		documents: document.NewSchedulerService(db, redisClient, fileStorage, asyncProcessor),
+	}
+}
+
+func (d *DocumentExpiryScheduler) RegisterJobs(scheduler *Scheduler) error {
+	expireSpec := os.Getenv("DOCUMENT_EXPIRY_CRON")
+	if expireSpec == "" {
+		expireSpec = "0 */10 * * * *"
+	}
+	if err := scheduler.AddJob(expireSpec, d.expiry.ExpireDocuments); err != nil {
+		return err
+	}
+
+	publishSpec := os.Getenv("DOCUMENT_PUBLISH_CRON")
+	if publishSpec == "" {
+		publishSpec = "0 */5 * * * *"
+	}
+	if err := scheduler.AddJob(publishSpec, d.documents.PublishScheduledDocuments); err != nil {
+		return err
+	}
+
+	reviewSpec := os.Getenv("DOCUMENT_REVIEW_NOTICE_CRON")
+	if reviewSpec == "" {
+		reviewSpec = "0 0 * * * *"
+	}
+	if err := scheduler.AddJob(reviewSpec, d.expiry.NotifyReviewsDue); err != nil {
+		return err
+	}
+
+	log.Println("Document expiry scheduler jobs registered successfully")
+	return nil
+}
@@ -0,0 +1,33 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DocumentHashScheduler struct {
+	hashes *document.ContentHashService
+}
+
+func NewDocumentHashScheduler(db *sqlx.DB, fileStorage storage.Backend) *DocumentHashScheduler {
+	return &DocumentHashScheduler{
+		hashes: document.NewContentHashService(db, fileStorage),
+	}
+}
+
+func (d *DocumentHashScheduler) RegisterJobs(scheduler *Scheduler) error {
+	spec := os.Getenv("DOCUMENT_HASH_BACKFILL_CRON")
+	if spec == "" {
+		spec = "15 */10 * * * *"
+	}
+	if err := scheduler.AddJob(spec, d.hashes.BackfillContentHashes); err != nil {
+		return err
+	}
+
+	log.Println("Document hash backfill scheduler jobs registered successfully")
+	return nil
+}
@@ -0,0 +1,33 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DocumentTextScheduler struct {
+	indexer *document.TextIndexService
+}
+
+func NewDocumentTextScheduler(db *sqlx.DB, fileStorage storage.Backend) *DocumentTextScheduler {
+	return &DocumentTextScheduler{
+		indexer: document.NewTextIndexService(db, fileStorage),
+	}
+}
+
+func (d *DocumentTextScheduler) RegisterJobs(scheduler *Scheduler) error {
+	spec := os.Getenv("DOCUMENT_TEXT_INDEX_CRON")
+	if spec == "" {
+		spec = "30 * * * * *"
+	}
+	if err := scheduler.AddJob(spec, d.indexer.IndexPendingTexts); err != nil {
+		return err
+	}
+
+	log.Println("Document text index scheduler jobs registered successfully")
+	return nil
+}
@@ -0,0 +1,34 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+type DocumentTrashScheduler struct {
+	documents *document.DocumentService
+}
+
+func NewDocumentTrashScheduler(db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend, asyncProcessor *document.AsyncProcessor) *DocumentTrashScheduler {
+	return &DocumentTrashScheduler{
+		documents: document.NewSchedulerService(db, redisClient, fileStorage, asyncProcessor),
+	}
+}
+
+func (d *DocumentTrashScheduler) RegisterJobs(scheduler *Scheduler) error {
+	spec := os.Getenv("DOCUMENT_TRASH_PURGE_CRON")
+	if spec == "" {
+		spec = "0 30 * * * *"
+	}
+	if err := scheduler.AddJob(spec, d.documents.PurgeExpiredTrash); err != nil {
+		return err
+	}
+
+	log.Println("Document trash scheduler jobs registered successfully")
+	return nil
+}
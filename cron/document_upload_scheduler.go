@@ -0,0 +1,44 @@
+package cron
+
+import (
+	"dokuprime-be/document"
+	"dokuprime-be/storage"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DocumentUploadScheduler struct {
+	cleanup *document.UploadCleanupService
+}
+
+func NewDocumentUploadScheduler(db *sqlx.DB, fileStorage storage.Backend) *DocumentUploadScheduler {
+	return &DocumentUploadScheduler{
+		cleanup: document.NewUploadCleanupService(db, fileStorage),
+	}
+}
+
+func (d *DocumentUploadScheduler) RegisterJobs(scheduler *Scheduler) error {
+	spec := os.Getenv("TUS_CLEANUP_CRON")
+	if spec == "" {
+		spec = "0 15 * * * *"
+	}
+	if err := scheduler.AddJob(spec, d.cleanup.CleanupExpiredUploads); err != nil {
+		return err
+	}
+
+	batchSpec := os.Getenv("DOCUMENT_BATCH_INTERRUPT_CRON")
+	if batchSpec == "" {
+		batchSpec = "0 */5 * * * *"
+	}
+	if err := scheduler.AddJob(batchSpec, d.cleanup.InterruptOrphanedBatches); err != nil {
+		return err
+	}
+	// Batches orphaned by the restart that started this process are caught
+	// right away, or by the next run once they become stale.
+	go d.cleanup.InterruptOrphanedBatches()
+
+	log.Println("Document upload scheduler jobs registered successfully")
+	return nil
+}
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"dokuprime-be/config"
 	"log"
 	"os"
 	"strconv"
@@ -29,7 +30,7 @@ func (h *HelpdeskScheduler) UpdateQueuedToPending() {
 
 	threshold := time.Now().UTC().Add(-time.Duration(period) * time.Minute)
 	query := `
-		UPDATE bkpm.helpdesk
+		UPDATE ` + config.QualifiedTable("helpdesk") + `
 		SET status = 'pending'
 		WHERE (status = 'Queue' OR status = 'queue') -- Tambahkan kurung di sini
 		AND created_at <= $1
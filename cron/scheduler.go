@@ -13,7 +13,8 @@ type Scheduler struct {
 
 
 func NewScheduler() *Scheduler {
-	c := cron.New(cron.WithSeconds())
+	// Recover keeps a panicking job from taking the whole backend down.
+	c := cron.New(cron.WithSeconds(), cron.WithChain(cron.Recover(cron.PrintfLogger(log.Default()))))
 	return &Scheduler{
 		cron: c,
 	}
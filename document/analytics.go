@@ -0,0 +1,156 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	accessChannelView     = "view"
+	accessChannelDownload = "download"
+
+	accessSourceDirect    = "direct"
+	accessSourceCitation  = "citation"
+	accessSourceShareLink = "share_link"
+
+	viewAccessKey = "view_access:"
+
+	defaultReportDays  = 30
+	defaultUncitedDays = 90
+	maxReportDays      = 3650
+)
+
+var errInvalidReport = errors.New("invalid report parameters")
+
+// usageSortColumns are the DocumentUsage columns a usage report can be
+// ranked by.
+var usageSortColumns = map[string]bool{
+	"views":          true,
+	"downloads":      true,
+	"unique_viewers": true,
+	"citations":      true,
+}
+
+// recordAccess stores an access to the document version stored as filename.
+// Files that are not document versions are not recorded.
+func (s *DocumentService) recordAccess(filename string, event DocumentAccessEvent) {
+	detail, err := s.repo.GetDocumentDetailByFilename(filename)
+	if err != nil {
+		return
+	}
+
+	event.DocumentID = detail.DocumentID
+	event.DetailID = &detail.ID
+	if err := s.repo.RecordAccessEvent(&event); err != nil {
+		log.Printf("Warning: Failed to record %s of document ID %d: %v", event.Channel, detail.DocumentID, err)
+	}
+}
+
+func (s *DocumentService) RecordDownload(filename string, event DocumentAccessEvent) {
+	event.Channel = accessChannelDownload
+	s.recordAccess(filename, event)
+}
+
+// trackViewToken keeps the viewer of a view token until the file is first
+// opened with it; see RecordViewAccess.
+func trackViewToken(redisClient *redis.Client, token string, event DocumentAccessEvent, ttl time.Duration) {
+	event.Channel = accessChannelView
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Warning: Failed to encode view access: %v", err)
+		return
+	}
+	if err := redisClient.Set(context.Background(), viewAccessKey+token, payload, ttl).Err(); err != nil {
+		log.Printf("Warning: Failed to store view access: %v", err)
+	}
+}
+
+// RecordViewAccess records the view behind token the first time the file is
+// fetched with it. The viewer fetches the file again for every byte range it
+// needs, and those requests are not counted.
+func (s *DocumentService) RecordViewAccess(token, filename string) {
+	ctx := context.Background()
+	key := viewAccessKey + token
+
+	// GET and DEL in one transaction so concurrent range requests cannot
+	// both take the access.
+	var get *redis.StringCmd
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Warning: Failed to read view access: %v", err)
+		}
+		return
+	}
+
+	var event DocumentAccessEvent
+	if err := json.Unmarshal([]byte(get.Val()), &event); err != nil {
+		log.Printf("Warning: Failed to decode view access: %v", err)
+		return
+	}
+	s.recordAccess(filename, event)
+}
+
+func (s *DocumentService) GetDocumentAccessEvents(documentID int, from, to time.Time, limit, offset int) ([]DocumentAccessEvent, int, error) {
+	if _, err := s.repo.GetDocumentByID(documentID); err != nil {
+		return nil, 0, err
+	}
+	if !from.Before(to) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", errInvalidReport)
+	}
+
+	events, total, err := s.repo.GetDocumentAccessEvents(documentID, from, to, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get access events: %w", err)
+	}
+	return events, total, nil
+}
+
+// GetDocumentUsage ranks documents by their views, downloads and RAG
+// citations between from and to.
+func (s *DocumentService) GetDocumentUsage(from, to time.Time, sortBy string, limit, offset int) ([]DocumentUsage, int, error) {
+	if sortBy == "" {
+		sortBy = "views"
+	}
+	if !usageSortColumns[sortBy] {
+		return nil, 0, fmt.Errorf("%w: sort must be one of views, downloads, unique_viewers or citations", errInvalidReport)
+	}
+	if !from.Before(to) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", errInvalidReport)
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		return nil, 0, fmt.Errorf("%w: period cannot be longer than %d days", errInvalidReport, maxReportDays)
+	}
+
+	usage, total, err := s.repo.GetDocumentUsage(from, to, sortBy, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get document usage: %w", err)
+	}
+	return usage, total, nil
+}
+
+// GetUncitedDocuments lists the published documents the RAG has not cited in
+// the last days days.
+func (s *DocumentService) GetUncitedDocuments(days, limit, offset int) ([]UncitedDocument, int, error) {
+	if days <= 0 || days > maxReportDays {
+		return nil, 0, fmt.Errorf("%w: days must be between 1 and %d", errInvalidReport, maxReportDays)
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	documents, total, err := s.repo.GetUncitedDocuments(since, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get uncited documents: %w", err)
+	}
+	return documents, total, nil
+}
@@ -3,9 +3,26 @@ package document
 import (
 	"context"
 	"dokuprime-be/external"
+	"dokuprime-be/storage"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	jobStatusFailed = "failed"
+	jobStatusDead   = "dead"
+
+	ingestStatusProcessing = "processing"
+	ingestStatusSuccess    = "success"
+	ingestStatusFailed     = "failed"
 )
 
 type ExtractionJob struct {
@@ -13,33 +30,52 @@ type ExtractionJob struct {
 	Request  external.ExtractRequest
 }
 
+type extractionQueueConfig struct {
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	lease        time.Duration
+	pollInterval time.Duration
+}
+
 type AsyncProcessor struct {
+	repo           *DocumentRepository
 	externalClient *external.Client
-	jobQueue       chan ExtractionJob
+	storage        storage.Backend
+	workerID       string
+	config         extractionQueueConfig
 	wg             sync.WaitGroup
 	workerCount    int
-	ctx            context.Context
-	cancel         context.CancelFunc
+	wake           chan struct{}
+	stop           chan struct{}
 	mu             sync.RWMutex
 	isShuttingDown bool
 }
 
-func NewAsyncProcessor(externalClient *external.Client, workerCount int) *AsyncProcessor {
+func NewAsyncProcessor(repo *DocumentRepository, externalClient *external.Client, fileStorage storage.Backend, workerCount int) *AsyncProcessor {
 	if workerCount <= 0 {
 		workerCount = 3
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
 
 	processor := &AsyncProcessor{
+		repo:           repo,
 		externalClient: externalClient,
-		jobQueue:       make(chan ExtractionJob, 100),
+		storage:        fileStorage,
+		workerID:       fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		config:         loadExtractionQueueConfig(),
 		workerCount:    workerCount,
-		ctx:            ctx,
-		cancel:         cancel,
+		wake:           make(chan struct{}, workerCount),
+		stop:           make(chan struct{}),
 		isShuttingDown: false,
 	}
 
+	if stats, err := repo.GetExtractionQueueStats(); err == nil {
+		log.Printf("Resuming extraction queue: %d queued, %d running, %d awaiting retry",
+			stats.Queued, stats.Running, stats.Failed)
+	}
+
 	for i := 0; i < workerCount; i++ {
 		processor.wg.Add(1)
 		go processor.worker(i)
@@ -48,33 +84,182 @@ func NewAsyncProcessor(externalClient *external.Client, workerCount int) *AsyncP
 	return processor
 }
 
+func loadExtractionQueueConfig() extractionQueueConfig {
+	return extractionQueueConfig{
+		maxAttempts:  getEnvInt("EXTRACTION_MAX_ATTEMPTS", 5),
+		backoffBase:  time.Duration(getEnvInt("EXTRACTION_BACKOFF_BASE_SECONDS", 30)) * time.Second,
+		backoffMax:   time.Duration(getEnvInt("EXTRACTION_BACKOFF_MAX_SECONDS", 3600)) * time.Second,
+		lease:        time.Duration(getEnvInt("EXTRACTION_LEASE_SECONDS", 300)) * time.Second,
+		pollInterval: time.Duration(getEnvInt("EXTRACTION_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+	}
+}
+
+func getEnvInt(key string, fallback int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || val <= 0 {
+		return fallback
+	}
+	return val
+}
+
 func (p *AsyncProcessor) worker(id int) {
 	defer p.wg.Done()
-	log.Printf("Extraction worker %d started", id)
+	log.Printf("Extraction worker %d started (%s)", id, p.workerID)
+
+	ticker := time.NewTicker(p.config.pollInterval)
+	defer ticker.Stop()
 
 	for {
+		for p.processNext(id) {
+			if p.shuttingDown() {
+				break
+			}
+		}
+
 		select {
-		case <-p.ctx.Done():
+		case <-p.stop:
 			log.Printf("Extraction worker %d received shutdown signal", id)
 			return
-		case job, ok := <-p.jobQueue:
-			if !ok {
-				log.Printf("Extraction worker %d: job queue closed", id)
-				return
-			}
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and runs a single job. It returns false when the queue
+// had nothing due, so the worker goes back to waiting.
+func (p *AsyncProcessor) processNext(id int) bool {
+	job, err := p.repo.ClaimExtractionJob(p.workerID, p.config.lease)
+	if err != nil {
+		log.Printf("Worker %d: Failed to claim extraction job: %v", id, err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
 
-			log.Printf("Worker %d: Processing extraction job for detail ID %d", id, job.DetailID)
+	log.Printf("Worker %d: Processing extraction job %d for detail ID %d (attempt %d/%d)",
+		id, job.ID, job.DetailID, job.Attempts, job.MaxAttempts)
+	p.recordIngestStatus(job.DetailID, ingestStatusProcessing, job.Attempts, nil)
 
-			err := p.externalClient.ExtractDocument(job.Request)
-			if err != nil {
-				log.Printf("Worker %d: Failed to extract document (detail ID: %d): %v", id, job.DetailID, err)
-			} else {
-				log.Printf("Worker %d: Successfully extracted document (detail ID: %d)", id, job.DetailID)
-			}
+	stopHeartbeat := p.startHeartbeat(job.ID)
+	err = p.extract(job)
+	stopHeartbeat()
+
+	if err == nil {
+		if err := p.repo.CompleteExtractionJob(job.ID, p.workerID); err != nil {
+			log.Printf("Worker %d: Failed to mark job %d as succeeded: %v", id, job.ID, err)
 		}
+		p.recordIngestStatus(job.DetailID, ingestStatusSuccess, job.Attempts, nil)
+		log.Printf("Worker %d: Successfully extracted document (detail ID: %d)", id, job.DetailID)
+		return true
+	}
+
+	status := jobStatusFailed
+	nextRunAt := time.Now().Add(p.backoff(job.Attempts))
+	if job.Attempts >= job.MaxAttempts {
+		status = jobStatusDead
+	}
+
+	if err := p.repo.FailExtractionJob(job.ID, p.workerID, status, err.Error(), nextRunAt); err != nil {
+		log.Printf("Worker %d: Failed to record failure for job %d: %v", id, job.ID, err)
+	}
+	// A scheduled retry is still processing; the version only fails once the
+	// job is dead. The error of the last attempt is kept either way.
+	ingestStatus := ingestStatusProcessing
+	if status == jobStatusDead {
+		ingestStatus = ingestStatusFailed
+	}
+	p.recordIngestStatus(job.DetailID, ingestStatus, job.Attempts, err)
+
+	if status == jobStatusDead {
+		log.Printf("Worker %d: Extraction job %d (detail ID: %d) is dead after %d attempts: %v",
+			id, job.ID, job.DetailID, job.Attempts, err)
+	} else {
+		log.Printf("Worker %d: Failed to extract document (detail ID: %d), retrying at %s: %v",
+			id, job.DetailID, nextRunAt.Format(time.RFC3339), err)
+	}
+
+	return true
+}
+
+// extract fetches the stored file to a local path for the extractor. Jobs
+// queued before the storage backend kept an absolute path, hence the Base.
+func (p *AsyncProcessor) extract(job *ExtractionJobRecord) error {
+	filePath, cleanup, err := storage.LocalPath(context.Background(), p.storage, filepath.Base(job.FilePath))
+	if err != nil {
+		return fmt.Errorf("failed to fetch stored file: %w", err)
+	}
+	defer cleanup()
+
+	req := external.ExtractRequest{
+		ID:       job.ExternalID,
+		Category: job.Category,
+		Filename: job.Filename,
+		FilePath: filePath,
+	}
+
+	// Metadata is read at extraction time so retries pick up later edits.
+	if document, err := p.repo.GetDocumentByDetailID(job.DetailID); err == nil {
+		req.Metadata = document.Metadata
+		req.Tags = document.Tags
+	} else {
+		log.Printf("Warning: Failed to load metadata for detail ID %d: %v", job.DetailID, err)
+	}
+
+	return p.externalClient.ExtractDocument(context.Background(), req)
+}
+
+func (p *AsyncProcessor) recordIngestStatus(detailID int, status string, attempts int, cause error) {
+	recordIngestStatus(p.repo, detailID, status, attempts, cause)
+}
+
+// recordIngestStatus mirrors an extraction outcome onto document_details so the
+// list filters and RequestDelete see the real state of the ingestion.
+func recordIngestStatus(repo *DocumentRepository, detailID int, status string, attempts int, cause error) {
+	var ingestError *string
+	if cause != nil {
+		msg := cause.Error()
+		ingestError = &msg
+	}
+
+	if err := repo.UpdateDocumentDetailIngestResult(detailID, status, attempts, ingestError); err != nil {
+		log.Printf("Warning: Failed to update ingest_status for detail ID %d: %v", detailID, err)
 	}
 }
 
+func (p *AsyncProcessor) backoff(attempts int) time.Duration {
+	delay := float64(p.config.backoffBase) * math.Pow(2, float64(attempts-1))
+	if delay > float64(p.config.backoffMax) {
+		return p.config.backoffMax
+	}
+	return time.Duration(delay)
+}
+
+// startHeartbeat keeps extending the lease while a long extraction is running,
+// so other replicas do not reclaim a job that is still being worked on.
+func (p *AsyncProcessor) startHeartbeat(jobID int) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(p.config.lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.repo.ExtendExtractionJobLease(jobID, p.workerID, p.config.lease); err != nil {
+					log.Printf("Warning: Failed to extend lease for extraction job %d: %v", jobID, err)
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
 func (p *AsyncProcessor) SubmitJob(job ExtractionJob) error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -83,15 +268,65 @@ func (p *AsyncProcessor) SubmitJob(job ExtractionJob) error {
 		return fmt.Errorf("processor is shutting down, cannot accept new jobs")
 	}
 
+	record := &ExtractionJobRecord{
+		DetailID:    job.DetailID,
+		ExternalID:  job.Request.ID,
+		Category:    job.Request.Category,
+		Filename:    job.Request.Filename,
+		FilePath:    job.Request.FilePath,
+		MaxAttempts: p.config.maxAttempts,
+	}
+
+	if err := p.repo.EnqueueExtractionJob(record); err != nil {
+		return fmt.Errorf("failed to enqueue extraction job: %w", err)
+	}
+	p.recordIngestStatus(job.DetailID, ingestStatusProcessing, 0, nil)
+	p.notify()
+
+	log.Printf("Extraction job %d submitted for detail ID %d", record.ID, job.DetailID)
+	return nil
+}
+
+// RetryJobs puts the failed or dead jobs of a detail back on the queue. It
+// returns false when the detail has no such job, e.g. it was extracted inline.
+func (p *AsyncProcessor) RetryJobs(detailID int) (bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.isShuttingDown {
+		return false, fmt.Errorf("processor is shutting down, cannot accept new jobs")
+	}
+
+	requeued, err := p.repo.RequeueExtractionJobs(detailID)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue extraction jobs: %w", err)
+	}
+	if requeued == 0 {
+		return false, nil
+	}
+
+	p.recordIngestStatus(detailID, ingestStatusProcessing, 0, nil)
+	p.notify()
+
+	log.Printf("Requeued %d extraction jobs for detail ID %d", requeued, detailID)
+	return true, nil
+}
+
+func (p *AsyncProcessor) notify() {
 	select {
-	case p.jobQueue <- job:
-		log.Printf("Extraction job submitted for detail ID %d (queue size: %d)", job.DetailID, len(p.jobQueue))
-		return nil
+	case p.wake <- struct{}{}:
 	default:
-		return fmt.Errorf("job queue is full (%d jobs), cannot submit new job", cap(p.jobQueue))
 	}
 }
 
+func (p *AsyncProcessor) shuttingDown() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.isShuttingDown
+}
+
+// Shutdown stops claiming new jobs and waits for in-flight extractions to
+// finish. Jobs still queued stay in the table for the next startup.
 func (p *AsyncProcessor) Shutdown() {
 	p.mu.Lock()
 	p.isShuttingDown = true
@@ -99,15 +334,28 @@ func (p *AsyncProcessor) Shutdown() {
 
 	log.Println("Shutting down async processor...")
 
-	p.cancel()
-
-	close(p.jobQueue)
+	close(p.stop)
 
 	p.wg.Wait()
 
+	if released, err := p.repo.ReleaseExtractionJobs(p.workerID); err != nil {
+		log.Printf("Warning: Failed to release extraction jobs: %v", err)
+	} else if released > 0 {
+		log.Printf("Released %d extraction jobs back to the queue", released)
+	}
+
 	log.Println("Async processor shut down complete")
 }
 
 func (p *AsyncProcessor) GetQueueSize() int {
-	return len(p.jobQueue)
+	stats, err := p.repo.GetExtractionQueueStats()
+	if err != nil {
+		log.Printf("Failed to get extraction queue stats: %v", err)
+		return 0
+	}
+	return stats.Queued + stats.Failed + stats.Running
+}
+
+func (p *AsyncProcessor) GetQueueStats() (*ExtractionQueueStats, error) {
+	return p.repo.GetExtractionQueueStats()
 }
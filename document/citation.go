@@ -0,0 +1,164 @@
+package document
+
+import (
+	"dokuprime-be/audit"
+	"dokuprime-be/external"
+	"dokuprime-be/middleware"
+	"dokuprime-be/urlformat"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultCitationLinkMinutes = 60
+
+// CitationResolver turns the [id, filename] pairs the RAG cites into document
+// records with links to open them.
+type CitationResolver struct {
+	repo    *DocumentRepository
+	redis   *redis.Client
+	formats *urlformat.URLFormatService
+}
+
+func NewCitationResolver(db *sqlx.DB, redisClient *redis.Client) *CitationResolver {
+	auditService := audit.NewAuditService(audit.NewAuditRepository(db))
+	return &CitationResolver{
+		repo:    NewDocumentRepository(db),
+		redis:   redisClient,
+		formats: urlformat.NewURLFormatService(urlformat.NewURLFormatRepository(db), auditService),
+	}
+}
+
+func citationLinkTTL() time.Duration {
+	return time.Duration(getEnvInt("DOCUMENT_CITATION_LINK_MINUTES", defaultCitationLinkMinutes)) * time.Minute
+}
+
+// Resolve resolves each cited document once, in citation order. The RAG
+// cites documents by the ID they were ingested with, so the filename is only
+// used when the ID does not match a live document.
+//
+// Every resolved citation gets the public source URL of its category's
+// url_format template, if any. viewer is set for internal users only: they
+// also get a view-file URL with a token of their own, valid for
+// DOCUMENT_CITATION_LINK_MINUTES, under baseURL.
+func (r *CitationResolver) Resolve(citations external.FlexibleCitationArray, viewer *DocumentAccessEvent, baseURL string) []Citation {
+	resolved := make([]Citation, 0, len(citations))
+	seen := make(map[[2]string]bool, len(citations))
+	var ids []int64
+	var names []string
+	for _, citation := range citations {
+		if seen[citation] {
+			continue
+		}
+		seen[citation] = true
+		resolved = append(resolved, Citation{Reference: citation[0], Filename: citation[1]})
+
+		if id, err := strconv.ParseInt(strings.TrimSpace(citation[0]), 10, 32); err == nil {
+			ids = append(ids, id)
+		}
+		if citation[1] != "" {
+			names = append(names, citation[1])
+		}
+	}
+	if len(resolved) == 0 {
+		return resolved
+	}
+
+	documents, err := r.repo.GetCitedDocuments(ids, names)
+	if err != nil {
+		log.Printf("Warning: Failed to resolve citations: %v", err)
+		return resolved
+	}
+	byID := make(map[string]Citation, len(documents))
+	byName := make(map[string]Citation, len(documents))
+	for _, document := range documents {
+		byID[strconv.Itoa(*document.DocumentID)] = document
+		byName[*document.DocumentName] = document
+	}
+
+	templates, err := r.formats.Templates()
+	if err != nil {
+		log.Printf("Warning: Failed to get url formats: %v", err)
+	}
+
+	results := make([]Citation, 0, len(resolved))
+	included := make(map[int]bool, len(documents))
+	for _, citation := range resolved {
+		document, ok := byID[strings.TrimSpace(citation.Reference)]
+		if !ok {
+			document, ok = byName[citation.Filename]
+		}
+		if !ok {
+			results = append(results, citation)
+			continue
+		}
+		if included[*document.DocumentID] {
+			continue
+		}
+		included[*document.DocumentID] = true
+
+		document.Reference = citation.Reference
+		document.Filename = citation.Filename
+		document.SourceURL = sourceURL(templates, document)
+		if viewer != nil {
+			document.ViewURL = r.viewURL(document, *viewer, baseURL)
+		}
+		results = append(results, document)
+	}
+	return results
+}
+
+func sourceURL(templates map[string]string, citation Citation) string {
+	template, ok := templates[*citation.Category]
+	if !ok {
+		template, ok = templates[urlformat.DefaultKode]
+	}
+	if !ok {
+		return ""
+	}
+
+	return urlformat.Render(template, urlformat.Values{
+		"document_id":   strconv.Itoa(*citation.DocumentID),
+		"detail_id":     strconv.Itoa(*citation.DetailID),
+		"document_name": *citation.DocumentName,
+		"filename":      citation.StoredFile,
+		"category":      *citation.Category,
+		"version":       strconv.Itoa(*citation.Version),
+	})
+}
+
+func (r *CitationResolver) viewURL(citation Citation, viewer DocumentAccessEvent, baseURL string) string {
+	viewer.Source = accessSourceCitation
+	token, err := issueViewToken(r.redis, citation.StoredFile, viewer, citationLinkTTL())
+	if err != nil {
+		log.Printf("Warning: Failed to issue view token for document ID %d: %v", *citation.DocumentID, err)
+		return ""
+	}
+	return fmt.Sprintf("%s/api/documents/view-file?token=%s", baseURL, token)
+}
+
+// CitationViewer is the logged-in user of ctx as a citation viewer, or nil
+// for requests authenticated by API key, such as the multichannel ones, and
+// for users who may not read documents, so they get no view URL.
+func CitationViewer(ctx *gin.Context) *DocumentAccessEvent {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		return nil
+	}
+	allowed, err := middleware.UserHasPermission(userID.(int64), permDocumentRead)
+	if err != nil {
+		log.Printf("Warning: Failed to check document permissions of user %d: %v", userID.(int64), err)
+		return nil
+	}
+	if !allowed {
+		return nil
+	}
+	viewer := accessEvent(ctx, accessSourceCitation)
+	return &viewer
+}
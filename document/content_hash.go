@@ -0,0 +1,70 @@
+package document
+
+import (
+	"context"
+	"crypto/sha256"
+	"dokuprime-be/storage"
+	"encoding/hex"
+	"log"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultHashBackfillBatch = 50
+
+// ContentHashService fills in the content hash of versions stored before
+// hashes were recorded, so duplicate checks also find their content.
+type ContentHashService struct {
+	repo    *DocumentRepository
+	storage storage.Backend
+	running sync.Mutex
+	cursor  int
+}
+
+func NewContentHashService(db *sqlx.DB, fileStorage storage.Backend) *ContentHashService {
+	return &ContentHashService{
+		repo:    NewDocumentRepository(db),
+		storage: fileStorage,
+	}
+}
+
+// BackfillContentHashes hashes the stored files of a batch of versions
+// without a content hash. Versions whose file cannot be read are skipped
+// until the batches wrap around to the start again.
+func (s *ContentHashService) BackfillContentHashes() {
+	if !s.running.TryLock() {
+		return
+	}
+	defer s.running.Unlock()
+
+	limit := getEnvInt("DOCUMENT_HASH_BACKFILL_BATCH", defaultHashBackfillBatch)
+	details, err := s.repo.GetDetailsMissingContentHash(s.cursor, limit)
+	if err != nil {
+		log.Printf("Error getting documents without content hash: %v", err)
+		return
+	}
+	if len(details) < limit {
+		s.cursor = 0
+	} else {
+		s.cursor = details[len(details)-1].ID
+	}
+
+	hashed := 0
+	for _, detail := range details {
+		hasher := sha256.New()
+		if _, err := s.storage.Stream(context.Background(), detail.Filename, hasher); err != nil {
+			log.Printf("Warning: Failed to read stored file of detail ID %d: %v", detail.ID, err)
+			continue
+		}
+		if err := s.repo.UpdateContentHash(detail.ID, hex.EncodeToString(hasher.Sum(nil))); err != nil {
+			log.Printf("Warning: Failed to save content hash of detail ID %d: %v", detail.ID, err)
+			continue
+		}
+		hashed++
+	}
+
+	if hashed > 0 {
+		log.Printf("Backfilled content hash of %d document version(s)", hashed)
+	}
+}
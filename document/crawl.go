@@ -0,0 +1,778 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/scanner"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	crawlSourceURL     = "url"
+	crawlSourceSitemap = "sitemap"
+	crawlSourceListing = "listing"
+
+	crawlTriggerSchedule = "schedule"
+	crawlTriggerManual   = "manual"
+
+	crawlRunStatusRunning   = "running"
+	crawlRunStatusCompleted = "completed"
+	crawlRunStatusFailed    = "failed"
+
+	crawlItemCreated     = "created"
+	crawlItemUpdated     = "updated"
+	crawlItemReplaced    = "replaced"
+	crawlItemNotModified = "not_modified"
+	crawlItemUnchanged   = "unchanged"
+	crawlItemSkipped     = "skipped"
+	crawlItemQuarantined = "quarantined"
+	crawlItemFailed      = "failed"
+
+	defaultCrawlTimeoutSeconds = 60
+	defaultCrawlMaxLinks       = 200
+	defaultCrawlRunHistory     = 50
+	defaultCrawlUserAgent      = "DokuPrime-Crawler/1.0"
+
+	// maxCrawlPageSize limits sitemaps and listing pages, which are read
+	// into memory to find links.
+	maxCrawlPageSize = 10 * 1024 * 1024
+	maxSitemapDepth  = 2
+
+	maxCrawlRedirects = 5
+)
+
+var (
+	errInvalidCrawlSource  = errors.New("invalid crawl source")
+	errCrawlRunning        = errors.New("source is already being crawled")
+	errCrawlAddressBlocked = errors.New("address is not public")
+)
+
+// crawlScheduleParser reads schedules in the same six-field format, with
+// seconds, as the rest of the cron jobs.
+var crawlScheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*["']([^"']+)["']`)
+
+// crawlContentTypes names the file type of downloads whose URL has no
+// extension.
+var crawlContentTypes = map[string]string{
+	"application/pdf":    "pdf",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       "xlsx",
+	"text/plain":    "txt",
+	"text/markdown": "md",
+	"text/csv":      "csv",
+	"text/html":     "html",
+}
+
+type sitemapDocument struct {
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc string `xml:"loc"`
+}
+
+// newCrawlClient only connects to public addresses, so a source cannot point
+// the crawler at the backend's own network. The check runs on every dial,
+// which covers redirects and DNS names that resolve to internal addresses.
+// Proxies are not used, since they would dial on the crawler's behalf.
+func newCrawlClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: crawlDialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   time.Duration(getEnvInt("CRAWLER_TIMEOUT_SECONDS", defaultCrawlTimeoutSeconds)) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxCrawlRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxCrawlRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+func crawlDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blockedCrawlIP(ip) {
+		return fmt.Errorf("%w: %s", errCrawlAddressBlocked, host)
+	}
+	return nil
+}
+
+// blockedCrawlIP reports whether ip is loopback, private (including IPv6
+// unique local), link-local, shared, multicast or unspecified.
+func blockedCrawlIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		// 100.64.0.0/10, carrier-grade NAT, is not covered by IsPrivate.
+		if ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+			return true
+		}
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified()
+}
+
+func crawlUserAgent() string {
+	if agent := os.Getenv("CRAWLER_USER_AGENT"); agent != "" {
+		return agent
+	}
+	return defaultCrawlUserAgent
+}
+
+func (s *DocumentService) GetCrawlSources() ([]CrawlSource, error) {
+	return s.repo.GetCrawlSources()
+}
+
+func (s *DocumentService) GetCrawlSource(id int) (*CrawlSource, error) {
+	return s.repo.GetCrawlSource(id)
+}
+
+func (s *DocumentService) CreateCrawlSource(input CrawlSourceInput, email string, actor audit.Actor) (*CrawlSource, error) {
+	source, err := buildCrawlSource(input)
+	if err != nil {
+		return nil, err
+	}
+	source.CreatedBy = email
+
+	if err := s.repo.CreateCrawlSource(source); err != nil {
+		return nil, fmt.Errorf("failed to create crawl source: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionCrawlSourceCreate, audit.EntityCrawlSource, source.ID, nil, source)
+	return source, nil
+}
+
+func (s *DocumentService) UpdateCrawlSource(id int, input CrawlSourceInput, actor audit.Actor) (*CrawlSource, error) {
+	existing, err := s.repo.GetCrawlSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := buildCrawlSource(input)
+	if err != nil {
+		return nil, err
+	}
+	source.ID = id
+	source.CreatedBy = existing.CreatedBy
+	source.CreatedAt = existing.CreatedAt
+	source.LastRunAt = existing.LastRunAt
+
+	if err := s.repo.UpdateCrawlSource(source); err != nil {
+		return nil, fmt.Errorf("failed to update crawl source: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionCrawlSourceUpdate, audit.EntityCrawlSource, id, existing, source)
+	return source, nil
+}
+
+// DeleteCrawlSource removes a source and its run history. Documents it
+// created stay.
+func (s *DocumentService) DeleteCrawlSource(id int, actor audit.Actor) error {
+	existing, err := s.repo.GetCrawlSource(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteCrawlSource(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionCrawlSourceDelete, audit.EntityCrawlSource, id, existing, nil)
+	return nil
+}
+
+func buildCrawlSource(input CrawlSourceInput) (*CrawlSource, error) {
+	source := &CrawlSource{
+		Name:       strings.TrimSpace(input.Name),
+		SourceType: strings.TrimSpace(input.SourceType),
+		URL:        strings.TrimSpace(input.URL),
+		Category:   strings.TrimSpace(input.Category),
+		IsActive:   input.IsActive == nil || *input.IsActive,
+	}
+
+	if source.Name == "" || len(source.Name) > 255 {
+		return nil, fmt.Errorf("%w: name is required and must be at most 255 characters", errInvalidCrawlSource)
+	}
+	if source.Category == "" || len(source.Category) > 100 {
+		return nil, fmt.Errorf("%w: category is required and must be at most 100 characters", errInvalidCrawlSource)
+	}
+
+	switch source.SourceType {
+	case crawlSourceURL, crawlSourceSitemap, crawlSourceListing:
+	default:
+		return nil, fmt.Errorf("%w: source_type must be one of url, sitemap or listing", errInvalidCrawlSource)
+	}
+
+	if _, ok := normalizeCrawlLink(source.URL); !ok {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", errInvalidCrawlSource)
+	}
+
+	if pattern := strings.TrimSpace(input.LinkPattern); pattern != "" {
+		if source.SourceType == crawlSourceURL {
+			return nil, fmt.Errorf("%w: link_pattern only applies to sitemap and listing sources", errInvalidCrawlSource)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("%w: link_pattern is not a valid regular expression: %v", errInvalidCrawlSource, err)
+		}
+		source.LinkPattern = &pattern
+	}
+
+	if schedule := strings.TrimSpace(input.Schedule); schedule != "" {
+		source.Schedule = &schedule
+	}
+	nextRunAt, err := nextCrawlRun(source.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schedule is not a valid cron expression: %v", errInvalidCrawlSource, err)
+	}
+	source.NextRunAt = nextRunAt
+	return source, nil
+}
+
+// nextCrawlRun returns when a schedule fires next, or nil for sources that
+// only run by hand.
+func nextCrawlRun(schedule *string) (*time.Time, error) {
+	if schedule == nil {
+		return nil, nil
+	}
+	parsed, err := crawlScheduleParser.Parse(*schedule)
+	if err != nil {
+		return nil, err
+	}
+	next := parsed.Next(time.Now())
+	return &next, nil
+}
+
+func (s *DocumentService) GetCrawlRuns(sourceID, limit, offset int) ([]CrawlRun, int, error) {
+	if _, err := s.repo.GetCrawlSource(sourceID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetCrawlRuns(sourceID, limit, offset)
+}
+
+func (s *DocumentService) GetCrawlRun(id int64) (*CrawlRun, error) {
+	return s.repo.GetCrawlRun(id)
+}
+
+// StartCrawl runs a source now, in the background, whether or not it is
+// active.
+func (s *DocumentService) StartCrawl(sourceID int) (*CrawlRun, error) {
+	source, err := s.repo.GetCrawlSource(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := s.repo.StartCrawlRun(source.ID, crawlTriggerManual)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runCrawl(source, run)
+	return run, nil
+}
+
+// RunDueCrawlSources starts every active source whose schedule has come up.
+func (s *DocumentService) RunDueCrawlSources() {
+	sources, err := s.repo.GetDueCrawlSources()
+	if err != nil {
+		log.Printf("Error getting due crawl sources: %v", err)
+		return
+	}
+
+	for i := range sources {
+		source := &sources[i]
+
+		nextRunAt, err := nextCrawlRun(source.Schedule)
+		if err != nil {
+			log.Printf("Warning: Crawl source %d has an invalid schedule: %v", source.ID, err)
+		}
+		claimed, err := s.repo.ClaimCrawlSource(source, nextRunAt)
+		if err != nil {
+			log.Printf("Warning: Failed to claim crawl source %d: %v", source.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		run, err := s.repo.StartCrawlRun(source.ID, crawlTriggerSchedule)
+		if errors.Is(err, errCrawlRunning) {
+			log.Printf("Crawl source %d is still running, skipping this run", source.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to start crawl of source %d: %v", source.ID, err)
+			continue
+		}
+
+		go s.runCrawl(source, run)
+	}
+}
+
+func (s *DocumentService) runCrawl(source *CrawlSource, run *CrawlRun) {
+	ctx := context.Background()
+	client := newCrawlClient()
+
+	links, err := discoverCrawlLinks(ctx, client, source)
+	if err != nil {
+		log.Printf("Crawl source %d failed: %v", source.ID, err)
+		msg := err.Error()
+		s.finishCrawlRun(source, run, crawlRunStatusFailed, &msg)
+		return
+	}
+
+	for _, link := range links {
+		item := s.crawlURL(ctx, client, source, link)
+		item.RunID = run.ID
+		item.URL = link
+		if err := s.repo.AddCrawlRunItem(&item); err != nil {
+			log.Printf("Warning: Failed to record crawl of %s: %v", link, err)
+		}
+	}
+
+	log.Printf("Crawl source %d finished: %d link(s) checked", source.ID, len(links))
+	s.finishCrawlRun(source, run, crawlRunStatusCompleted, nil)
+}
+
+func (s *DocumentService) finishCrawlRun(source *CrawlSource, run *CrawlRun, status string, runErr *string) {
+	if err := s.repo.FinishCrawlRun(run.ID, status, runErr); err != nil {
+		log.Printf("Warning: Failed to finish crawl run %d: %v", run.ID, err)
+	}
+	if err := s.repo.PruneCrawlRuns(source.ID, getEnvInt("CRAWL_RUN_HISTORY", defaultCrawlRunHistory)); err != nil {
+		log.Printf("Warning: Failed to prune crawl runs of source %d: %v", source.ID, err)
+	}
+}
+
+// discoverCrawlLinks lists the document URLs of a source. Without a link
+// pattern only links to accepted file types are followed.
+func discoverCrawlLinks(ctx context.Context, client *http.Client, source *CrawlSource) ([]string, error) {
+	var links []string
+	var err error
+	switch source.SourceType {
+	case crawlSourceURL:
+		return []string{source.URL}, nil
+	case crawlSourceSitemap:
+		links, err = fetchSitemapLinks(ctx, client, source.URL, maxSitemapDepth)
+	case crawlSourceListing:
+		links, err = fetchListingLinks(ctx, client, source.URL)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", source.SourceType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pattern *regexp.Regexp
+	if source.LinkPattern != nil {
+		pattern, err = regexp.Compile(*source.LinkPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link pattern: %w", err)
+		}
+	}
+
+	maxLinks := getEnvInt("CRAWLER_MAX_LINKS", defaultCrawlMaxLinks)
+	validTypes := acceptedFileTypes()
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, link := range links {
+		normalized, ok := normalizeCrawlLink(link)
+		if !ok || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+
+		if pattern != nil {
+			if !pattern.MatchString(normalized) {
+				continue
+			}
+		} else {
+			parsed, _ := url.Parse(normalized)
+			if !validTypes[strings.TrimPrefix(strings.ToLower(path.Ext(parsed.Path)), ".")] {
+				continue
+			}
+		}
+
+		result = append(result, normalized)
+		if len(result) >= maxLinks {
+			log.Printf("Crawl source %d: stopping at %d links", source.ID, maxLinks)
+			break
+		}
+	}
+	return result, nil
+}
+
+// normalizeCrawlLink accepts absolute http and https URLs and drops their
+// fragment.
+func normalizeCrawlLink(link string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", false
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String(), true
+}
+
+func fetchCrawlPage(ctx context.Context, client *http.Client, link string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", crawlUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch %s: unexpected status %s", link, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlPageSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", link, err)
+	}
+	return body, resp.Request.URL, nil
+}
+
+// fetchSitemapLinks reads a sitemap, following sitemap indexes up to depth
+// levels.
+func fetchSitemapLinks(ctx context.Context, client *http.Client, sitemapURL string, depth int) ([]string, error) {
+	body, _, err := fetchCrawlPage(ctx, client, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sitemap sitemapDocument
+	if err := xml.Unmarshal(body, &sitemap); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+
+	links := make([]string, 0, len(sitemap.URLs))
+	for _, entry := range sitemap.URLs {
+		links = append(links, strings.TrimSpace(entry.Loc))
+	}
+
+	if depth > 1 {
+		for _, entry := range sitemap.Sitemaps {
+			nested, err := fetchSitemapLinks(ctx, client, strings.TrimSpace(entry.Loc), depth-1)
+			if err != nil {
+				log.Printf("Warning: Skipping sitemap: %v", err)
+				continue
+			}
+			links = append(links, nested...)
+		}
+	}
+	return links, nil
+}
+
+func fetchListingLinks(ctx context.Context, client *http.Client, pageURL string) ([]string, error) {
+	body, base, err := fetchCrawlPage(ctx, client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	links := []string{}
+	for _, match := range hrefPattern.FindAllSubmatch(body, -1) {
+		ref, err := url.Parse(html.UnescapeString(strings.TrimSpace(string(match[1]))))
+		if err != nil {
+			continue
+		}
+		links = append(links, base.ResolveReference(ref).String())
+	}
+	return links, nil
+}
+
+// crawlURL fetches one link, asking the server to skip the body when it has
+// not changed since the last fetch, and stores what it got.
+func (s *DocumentService) crawlURL(ctx context.Context, client *http.Client, source *CrawlSource, link string) CrawlRunItem {
+	crawled, err := s.repo.GetCrawledURL(source.ID, link)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return failedCrawlItem(err.Error())
+	}
+	// Validators are only useful while the document they describe exists.
+	if crawled != nil && crawled.DocumentID == nil {
+		crawled = nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return failedCrawlItem(err.Error())
+	}
+	req.Header.Set("User-Agent", crawlUserAgent())
+	if crawled != nil {
+		if crawled.ETag != nil {
+			req.Header.Set("If-None-Match", *crawled.ETag)
+		}
+		if crawled.LastModified != nil {
+			req.Header.Set("If-Modified-Since", *crawled.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return failedCrawlItem(err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && crawled != nil {
+		if err := s.repo.TouchCrawledURL(source.ID, link, crawled.DetailID); err != nil {
+			log.Printf("Warning: Failed to record fetch of %s: %v", link, err)
+		}
+		return CrawlRunItem{Status: crawlItemNotModified, DocumentID: crawled.DocumentID, DetailID: crawled.DetailID}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return failedCrawlItem("unexpected status " + resp.Status)
+	}
+
+	name := crawlFilename(resp)
+	dataType := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	if !acceptedFileTypes()[dataType] {
+		return skippedCrawlItem(invalidFileTypeMessage())
+	}
+
+	maxSize := maxUploadSize()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return failedCrawlItem("failed to read content: " + err.Error())
+	}
+	if int64(len(content)) > maxSize {
+		return failedCrawlItem(fmt.Sprintf("File size exceeds maximum limit of %d MB", maxSize/(1024*1024)))
+	}
+
+	contentHash := hashContent(content)
+	fetched := &CrawledURL{
+		SourceID:     source.ID,
+		URL:          link,
+		ETag:         optionalString(resp.Header.Get("ETag")),
+		LastModified: optionalString(resp.Header.Get("Last-Modified")),
+		ContentHash:  &contentHash,
+	}
+
+	var item CrawlRunItem
+	if crawled != nil && crawled.ContentHash != nil && *crawled.ContentHash == contentHash {
+		// The server sent the body again but nothing changed.
+		item = CrawlRunItem{Status: crawlItemUnchanged, DocumentID: crawled.DocumentID, DetailID: crawled.DetailID}
+	} else {
+		item = s.storeCrawledDocument(source, link, name, dataType, content, contentHash, crawled)
+	}
+
+	if item.DocumentID != nil {
+		fetched.DocumentID = item.DocumentID
+		fetched.DetailID = item.DetailID
+		if err := s.repo.SaveCrawledURL(fetched); err != nil {
+			log.Printf("Warning: Failed to record fetch of %s: %v", link, err)
+		}
+		if item.Status == crawlItemUnchanged {
+			if err := s.repo.TouchCrawledURL(source.ID, link, item.DetailID); err != nil {
+				log.Printf("Warning: Failed to record fetch of %s: %v", link, err)
+			}
+		}
+	}
+	return item
+}
+
+// storeCrawledDocument turns changed content into a document. New URLs
+// create a document, a pending or rejected one is replaced the way the
+// crawler upload replaces it, and a live one gets a new version for review.
+func (s *DocumentService) storeCrawledDocument(source *CrawlSource, link, name, dataType string, content []byte, contentHash string, crawled *CrawledURL) CrawlRunItem {
+	screened, err := s.screenContent(content, name, dataType, uploadSourceCrawler, "")
+	if err != nil {
+		var infectedErr *InfectedFileError
+		switch {
+		case errors.As(err, &infectedErr):
+			return CrawlRunItem{Status: crawlItemQuarantined, Reason: optionalString(err.Error())}
+		case errors.Is(err, scanner.ErrContentMismatch):
+			return skippedCrawlItem(err.Error())
+		default:
+			return failedCrawlItem(err.Error())
+		}
+	}
+
+	existing, err := s.crawlTarget(link, name, crawled)
+	if err != nil {
+		return skippedCrawlItem(err.Error())
+	}
+
+	duplicate, err := s.repo.FindDetailByContentHash(contentHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return failedCrawlItem("Failed to check for duplicate content: " + err.Error())
+	}
+	if err == nil {
+		if existing != nil && duplicate.DocumentID == existing.DocumentID {
+			return CrawlRunItem{Status: crawlItemUnchanged, DocumentID: &duplicate.DocumentID, DetailID: &duplicate.ID}
+		}
+		return skippedCrawlItem("Identical content already exists as " + duplicate.DocumentName)
+	}
+
+	status := crawlItemCreated
+	if existing != nil {
+		status = crawlItemUpdated
+		if s.shouldReplaceDocument(existing) {
+			status = crawlItemReplaced
+		} else if reason := s.crawlUpdateBlocker(existing); reason != "" {
+			return skippedCrawlItem(reason)
+		}
+	}
+
+	uniqueFilename, err := s.saveCrawledFile(content, name)
+	if err != nil {
+		return failedCrawlItem(err.Error())
+	}
+
+	fetchedAt := time.Now()
+	detail := newCrawledDetail(name, uniqueFilename, contentHash, screened)
+	detail.SourceURL = &link
+	detail.FetchedAt = &fetchedAt
+
+	switch status {
+	case crawlItemReplaced:
+		if err := s.deleteOldDocument(existing); err != nil {
+			s.RemoveFile(uniqueFilename)
+			return failedCrawlItem("Failed to delete old rejected/pending document")
+		}
+		err = s.CreateDocument(&Document{Category: source.Category}, detail)
+	case crawlItemUpdated:
+		err = s.UpdateDocument(existing.DocumentID, detail)
+	default:
+		err = s.CreateDocument(&Document{Category: source.Category}, detail)
+	}
+	if err != nil {
+		s.RemoveFile(uniqueFilename)
+		return failedCrawlItem(err.Error())
+	}
+
+	return CrawlRunItem{Status: status, DocumentID: &detail.DocumentID, DetailID: &detail.ID}
+}
+
+// crawlTarget finds the latest version of the document a URL feeds: the one
+// it created before, or a document of the same name no other URL feeds.
+func (s *DocumentService) crawlTarget(link, name string, crawled *CrawledURL) (*DocumentDetail, error) {
+	if crawled != nil && crawled.DocumentID != nil {
+		if document, err := s.repo.GetDocumentByID(*crawled.DocumentID); err == nil && document.DeletedAt != nil {
+			return nil, fmt.Errorf("document %d is in the recycle bin", document.ID)
+		}
+		details, err := s.repo.GetDocumentDetailsByDocumentID(*crawled.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range details {
+			if details[i].IsLatest != nil && *details[i].IsLatest {
+				return &details[i], nil
+			}
+		}
+	}
+
+	byName, err := s.repo.GetLatestDetailByDocumentName(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.repo.GetDocumentDetailByID(byName.ID)
+	if err != nil {
+		return nil, err
+	}
+	if detail.SourceURL != nil && *detail.SourceURL != link {
+		return nil, fmt.Errorf("document %q is already fetched from %s", name, *detail.SourceURL)
+	}
+	return detail, nil
+}
+
+// crawlUpdateBlocker explains why a live document cannot take a new version
+// right now, or returns "" when it can.
+func (s *DocumentService) crawlUpdateBlocker(latest *DocumentDetail) string {
+	if latest.Status == nil || (*latest.Status != "Approved" && *latest.Status != statusExpired) {
+		status := "unknown"
+		if latest.Status != nil {
+			status = *latest.Status
+		}
+		return "Document exists with unhandled status: " + status
+	}
+
+	details, err := s.repo.GetDocumentDetailsByDocumentID(latest.DocumentID)
+	if err != nil {
+		return "Failed to check document versions"
+	}
+	for _, detail := range details {
+		if detail.Status != nil && *detail.Status == "Pending" && (detail.IsLatest == nil || !*detail.IsLatest) {
+			return "A newer version is already waiting for review"
+		}
+		if detail.Status != nil && *detail.Status == statusScheduled {
+			return "A newer version is already scheduled for publication"
+		}
+	}
+	return ""
+}
+
+// crawlFilename names a download after its Content-Disposition filename or
+// the last segment of its final URL, adding an extension from the content
+// type when the name has none.
+func crawlFilename(resp *http.Response) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = path.Base(strings.ReplaceAll(params["filename"], "\\", "/"))
+	}
+	if name == "" || name == "." || name == "/" {
+		name = path.Base(resp.Request.URL.Path)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+	if name == "" || name == "." || name == "/" {
+		name = resp.Request.URL.Hostname()
+	}
+
+	if filepath.Ext(name) == "" {
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if ext, ok := crawlContentTypes[mediaType]; ok {
+				name += "." + ext
+			}
+		}
+	}
+	return name
+}
+
+func failedCrawlItem(reason string) CrawlRunItem {
+	return CrawlRunItem{Status: crawlItemFailed, Reason: &reason}
+}
+
+func skippedCrawlItem(reason string) CrawlRunItem {
+	return CrawlRunItem{Status: crawlItemSkipped, Reason: &reason}
+}
@@ -0,0 +1,48 @@
+package document
+
+import (
+	"net"
+	"testing"
+)
+
+func TestBlockedCrawlIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"172.32.0.1", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := blockedCrawlIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("blockedCrawlIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlDialControl(t *testing.T) {
+	if err := crawlDialControl("tcp", "127.0.0.1:80", nil); err == nil {
+		t.Error("crawlDialControl() allowed a loopback address")
+	}
+	if err := crawlDialControl("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("crawlDialControl() error = %v for a public address", err)
+	}
+}
@@ -1,10 +1,26 @@
 package document
 
-import "time"
+import (
+	"dokuprime-be/metadata"
+	"dokuprime-be/util"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
 
 type Document struct {
-	ID       int    `db:"id" json:"id"`
-	Category string `db:"category" json:"category"`
+	ID              int             `db:"id" json:"id"`
+	Category        string          `db:"category" json:"category"`
+	Metadata        metadata.Values `db:"metadata" json:"metadata"`
+	Tags            pq.StringArray  `db:"tags" json:"tags"`
+	DeletedAt       *time.Time      `db:"deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy       *int64          `db:"deleted_by" json:"deleted_by,omitempty"`
+	PurgeAfter      *time.Time      `db:"purge_after" json:"purge_after,omitempty"`
+	LegalHold       bool            `db:"legal_hold" json:"legal_hold"`
+	LegalHoldReason *string         `db:"legal_hold_reason" json:"legal_hold_reason,omitempty"`
+	LegalHoldBy     *int64          `db:"legal_hold_by" json:"legal_hold_by,omitempty"`
+	LegalHoldAt     *time.Time      `db:"legal_hold_at" json:"legal_hold_at,omitempty"`
 }
 
 type DocumentDetail struct {
@@ -21,8 +37,29 @@ type DocumentDetail struct {
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 	Category     string    `db:"category" json:"category"`
 	IngestStatus *string   `db:"ingest_status" json:"ingest_status"`
+	IngestError    *string `db:"ingest_error" json:"ingest_error"`
+	IngestAttempts int     `db:"ingest_attempts" json:"ingest_attempts"`
+	ContentHash    *string `db:"content_hash" json:"content_hash"`
 	RequestType  *string    `db:"request_type" json:"request_type"` // NEW, UPDATE, DELETE
 	RequestedAt  *time.Time `db:"requested_at" json:"requested_at"`
+	ApprovedBy   *int64     `db:"approved_by" json:"approved_by"`
+	ApprovedAt   *time.Time `db:"approved_at" json:"approved_at"`
+	WorkflowID   *int       `db:"workflow_id" json:"workflow_id"`
+	CurrentStep  *int       `db:"current_step" json:"current_step"`
+	RejectionReason *string        `db:"rejection_reason" json:"rejection_reason"`
+	RejectionCodes  pq.StringArray `db:"rejection_codes" json:"rejection_codes"`
+	ResubmittedFrom *int           `db:"resubmitted_from" json:"resubmitted_from"`
+	ValidFrom        *time.Time `db:"valid_from" json:"valid_from"`
+	ReviewBy         *time.Time `db:"review_by" json:"review_by"`
+	ExpiresAt        *time.Time `db:"expires_at" json:"expires_at"`
+	ReviewNotifiedAt *time.Time `db:"review_notified_at" json:"review_notified_at"`
+	ExpiredAt        *time.Time `db:"expired_at" json:"expired_at"`
+	MimeType      *string    `db:"mime_type" json:"mime_type"`
+	ScanStatus    *string    `db:"scan_status" json:"scan_status"`
+	ScanSignature *string    `db:"scan_signature" json:"scan_signature"`
+	ScannedAt     *time.Time `db:"scanned_at" json:"scanned_at"`
+	SourceURL     *string    `db:"source_url" json:"source_url"`
+	FetchedAt     *time.Time `db:"fetched_at" json:"fetched_at"`
 }
 
 type DocumentWithDetail struct {
@@ -38,8 +75,437 @@ type DocumentWithDetail struct {
 	IsApprove    *bool     `db:"is_approve" json:"is_approve"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 	IngestStatus *string   `db:"ingest_status" json:"ingest_status"`
+	IngestError    *string `db:"ingest_error" json:"ingest_error"`
+	IngestAttempts int     `db:"ingest_attempts" json:"ingest_attempts"`
+	RequestType  *string    `db:"request_type" json:"request_type"`
+	RequestedAt  *time.Time `db:"requested_at" json:"requested_at"`
+	ValidFrom    *time.Time `db:"valid_from" json:"valid_from"`
+	ReviewBy     *time.Time `db:"review_by" json:"review_by"`
+	ExpiresAt    *time.Time `db:"expires_at" json:"expires_at"`
+	ExpiredAt    *time.Time `db:"expired_at" json:"expired_at"`
+	Metadata     metadata.Values `db:"metadata" json:"metadata"`
+	Tags         pq.StringArray  `db:"tags" json:"tags"`
+	LegalHold    bool            `db:"legal_hold" json:"legal_hold"`
+}
+
+// DocumentVersion is one entry of a document's version timeline. Versions
+// are numbered in upload order, starting at 1.
+type DocumentVersion struct {
+	Version      int        `db:"version" json:"version"`
+	DetailID     int        `db:"id" json:"detail_id"`
+	DocumentID   int        `db:"document_id" json:"document_id"`
+	DocumentName string     `db:"document_name" json:"document_name"`
+	DataType     string     `db:"data_type" json:"data_type"`
+	UploadedBy   string     `db:"staff" json:"uploaded_by"`
+	Team         string     `db:"team" json:"team"`
+	UploadedAt   time.Time  `db:"created_at" json:"uploaded_at"`
 	RequestType  *string    `db:"request_type" json:"request_type"`
 	RequestedAt  *time.Time `db:"requested_at" json:"requested_at"`
+	Status       *string    `db:"status" json:"status"`
+	IsLatest     *bool      `db:"is_latest" json:"is_latest"`
+	IsApprove    *bool      `db:"is_approve" json:"is_approve"`
+	IsActive     bool       `db:"is_active" json:"is_active"`
+	IngestStatus *string    `db:"ingest_status" json:"ingest_status"`
+	ContentHash  *string    `db:"content_hash" json:"content_hash"`
+	ApprovedBy   *int64     `db:"approved_by" json:"approved_by"`
+	ApproverName *string    `db:"approver_name" json:"approver_name"`
+	ApprovedAt   *time.Time `db:"approved_at" json:"approved_at"`
+}
+
+type VersionDiff struct {
+	DocumentID int              `json:"document_id"`
+	From       *DocumentVersion `json:"from"`
+	To         *DocumentVersion `json:"to"`
+	Stats      util.DiffStats   `json:"stats"`
+	Changes    []util.DiffLine  `json:"changes"`
+}
+
+const (
+	CommentKindComment   = "comment"
+	CommentKindRejection = "rejection"
+)
+
+// Reason codes a reviewer can attach to a rejection, with their labels.
+var RejectionReasons = []RejectionReason{
+	{Code: "incomplete", Label: "Content is incomplete"},
+	{Code: "outdated", Label: "Content is outdated"},
+	{Code: "incorrect", Label: "Content is incorrect"},
+	{Code: "wrong_category", Label: "Wrong category"},
+	{Code: "duplicate", Label: "Duplicates an existing document"},
+	{Code: "unreadable", Label: "File is unreadable or badly formatted"},
+	{Code: "confidential", Label: "Contains confidential information"},
+	{Code: "other", Label: "Other"},
+}
+
+type RejectionReason struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+type RejectRequest struct {
+	Reason      string   `json:"reason"`
+	ReasonCodes []string `json:"reason_codes"`
+}
+
+type ReviewComment struct {
+	ID           int64          `db:"id" json:"id"`
+	DetailID     int            `db:"detail_id" json:"detail_id"`
+	AuthorUserID *int64         `db:"author_user_id" json:"author_user_id"`
+	AuthorName   *string        `db:"author_name" json:"author_name"`
+	Kind         string         `db:"kind" json:"kind"`
+	Comment      string         `db:"comment" json:"comment"`
+	ReasonCodes  pq.StringArray `db:"reason_codes" json:"reason_codes"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+}
+
+const (
+	statusExpired   = "Expired"
+	statusScheduled = "Scheduled"
+
+	ExpiryExpiringSoon = "expiring_soon"
+	ExpiryExpired      = "expired"
+
+	NotificationReviewDue = "review_due"
+	NotificationExpired   = "expired"
+)
+
+// DocumentDates is the validity window of a version. Every field is optional.
+type DocumentDates struct {
+	ValidFrom *time.Time `json:"valid_from"`
+	ReviewBy  *time.Time `json:"review_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type DocumentDatesRequest struct {
+	ValidFrom string `json:"valid_from"`
+	ReviewBy  string `json:"review_by"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// MetadataRequest carries a document's metadata values and free tags.
+type MetadataRequest struct {
+	Metadata map[string]interface{} `json:"metadata"`
+	Tags     []string               `json:"tags"`
+}
+
+// LegalHoldRequest places or lifts a legal hold. Placing one needs a reason.
+type LegalHoldRequest struct {
+	Hold   bool   `json:"hold"`
+	Reason string `json:"reason"`
+}
+
+// TrashedDocument is a document in the recycle bin, shown by its latest
+// version.
+type TrashedDocument struct {
+	ID              int        `db:"id" json:"id"`
+	Category        string     `db:"category" json:"category"`
+	DocumentName    string     `db:"document_name" json:"document_name"`
+	Staff           string     `db:"staff" json:"staff"`
+	Team            string     `db:"team" json:"team"`
+	Status          *string    `db:"status" json:"status"`
+	Versions        int        `db:"versions" json:"versions"`
+	DeletedAt       time.Time  `db:"deleted_at" json:"deleted_at"`
+	DeletedBy       *int64     `db:"deleted_by" json:"deleted_by"`
+	PurgeAfter      time.Time  `db:"purge_after" json:"purge_after"`
+	LegalHold       bool       `db:"legal_hold" json:"legal_hold"`
+	LegalHoldReason *string    `db:"legal_hold_reason" json:"legal_hold_reason"`
+	LegalHoldAt     *time.Time `db:"legal_hold_at" json:"legal_hold_at"`
+}
+
+// ShareLink is a reusable link to a document. The token itself is only
+// returned when the link is created.
+type ShareLink struct {
+	ID           int64          `db:"id" json:"id"`
+	DocumentID   int            `db:"document_id" json:"document_id"`
+	DetailID     *int           `db:"detail_id" json:"detail_id"`
+	DocumentName string         `db:"document_name" json:"document_name"`
+	TokenPrefix  string         `db:"token_prefix" json:"token_prefix"`
+	CreatedBy    string         `db:"created_by" json:"created_by"`
+	ExpiresAt    time.Time      `db:"expires_at" json:"expires_at"`
+	MaxUses      *int           `db:"max_uses" json:"max_uses"`
+	UseCount     int            `db:"use_count" json:"use_count"`
+	RequireLogin bool           `db:"require_login" json:"require_login"`
+	AllowedTeams pq.StringArray `db:"allowed_teams" json:"allowed_teams"`
+	RevokedAt    *time.Time     `db:"revoked_at" json:"revoked_at"`
+	RevokedBy    *string        `db:"revoked_by" json:"revoked_by"`
+	LastUsedAt   *time.Time     `db:"last_used_at" json:"last_used_at"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+	Token        string         `db:"-" json:"token,omitempty"`
+	URL          string         `db:"-" json:"url,omitempty"`
+}
+
+// ShareLinkRequest creates a share link. Without detail_id the link follows
+// the published version; allowed_teams implies require_login.
+type ShareLinkRequest struct {
+	DocumentID     int      `json:"document_id" binding:"required"`
+	DetailID       *int     `json:"detail_id"`
+	ExpiresInHours int      `json:"expires_in_hours"`
+	MaxUses        *int     `json:"max_uses"`
+	RequireLogin   bool     `json:"require_login"`
+	AllowedTeams   []string `json:"allowed_teams"`
+}
+
+type ShareLinkAccess struct {
+	ID         int64     `db:"id" json:"id"`
+	LinkID     int64     `db:"link_id" json:"link_id"`
+	UserID     *int64    `db:"user_id" json:"user_id"`
+	Email      *string   `db:"email" json:"email"`
+	IPAddress  *string   `db:"ip_address" json:"ip_address"`
+	UserAgent  *string   `db:"user_agent" json:"user_agent"`
+	Granted    bool      `db:"granted" json:"granted"`
+	Reason     *string   `db:"reason" json:"reason"`
+	AccessedAt time.Time `db:"accessed_at" json:"accessed_at"`
+}
+
+// DocumentAccessEvent is one view or download of a document.
+type DocumentAccessEvent struct {
+	ID          int64     `db:"id" json:"id"`
+	DocumentID  int       `db:"document_id" json:"document_id"`
+	DetailID    *int      `db:"detail_id" json:"detail_id"`
+	UserID      *int64    `db:"user_id" json:"user_id"`
+	Email       *string   `db:"email" json:"email"`
+	Channel     string    `db:"channel" json:"channel"`
+	Source      string    `db:"source" json:"source"`
+	ShareLinkID *int64    `db:"share_link_id" json:"share_link_id"`
+	IPAddress   *string   `db:"ip_address" json:"ip_address"`
+	UserAgent   *string   `db:"user_agent" json:"user_agent"`
+	AccessedAt  time.Time `db:"accessed_at" json:"accessed_at"`
+}
+
+// DocumentUsage is the activity of one document over a report period.
+// UniqueViewers counts logged-in users; Citations counts the chat answers
+// that cited the document.
+type DocumentUsage struct {
+	DocumentID     int        `db:"document_id" json:"document_id"`
+	DocumentName   string     `db:"document_name" json:"document_name"`
+	Category       string     `db:"category" json:"category"`
+	Views          int        `db:"views" json:"views"`
+	Downloads      int        `db:"downloads" json:"downloads"`
+	UniqueViewers  int        `db:"unique_viewers" json:"unique_viewers"`
+	ShareLinkViews int        `db:"share_link_views" json:"share_link_views"`
+	CitationOpens  int        `db:"citation_opens" json:"citation_opens"`
+	Citations      int        `db:"citations" json:"citations"`
+	LastAccessedAt *time.Time `db:"last_accessed_at" json:"last_accessed_at"`
+	LastCitedAt    *time.Time `db:"last_cited_at" json:"last_cited_at"`
+}
+
+// UncitedDocument is a published document no chat answer has cited lately.
+type UncitedDocument struct {
+	DocumentID     int        `db:"document_id" json:"document_id"`
+	DocumentName   string     `db:"document_name" json:"document_name"`
+	Category       string     `db:"category" json:"category"`
+	Staff          string     `db:"staff" json:"staff"`
+	Team           string     `db:"team" json:"team"`
+	PublishedAt    time.Time  `db:"published_at" json:"published_at"`
+	Views          int        `db:"views" json:"views"`
+	LastAccessedAt *time.Time `db:"last_accessed_at" json:"last_accessed_at"`
+}
+
+// Citation is a source the RAG cited in an answer, resolved to the published
+// version of its document. Reference and Filename are the pair the RAG
+// returned; the document fields stay null when it cannot be resolved.
+type Citation struct {
+	Reference    string  `db:"-" json:"reference"`
+	Filename     string  `db:"-" json:"filename"`
+	DocumentID   *int    `db:"document_id" json:"document_id"`
+	DetailID     *int    `db:"detail_id" json:"detail_id"`
+	DocumentName *string `db:"document_name" json:"document_name"`
+	Category     *string `db:"category" json:"category"`
+	Version      *int    `db:"version" json:"version"`
+	ViewURL      string  `db:"-" json:"view_url,omitempty"`
+	SourceURL    string  `db:"-" json:"source_url,omitempty"`
+	StoredFile   string  `db:"filename" json:"-"`
+}
+
+type DocumentNotification struct {
+	ID           int64      `db:"id" json:"id"`
+	DetailID     int        `db:"detail_id" json:"detail_id"`
+	DocumentID   int        `db:"document_id" json:"document_id"`
+	DocumentName string     `db:"document_name" json:"document_name"`
+	Recipient    string     `db:"recipient" json:"recipient"`
+	Kind         string     `db:"kind" json:"kind"`
+	Message      string     `db:"message" json:"message"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	ReadAt       *time.Time `db:"read_at" json:"read_at"`
+}
+
+// QuarantinedFile is an upload the malware scanner flagged.
+type QuarantinedFile struct {
+	ID           int64     `db:"id" json:"id"`
+	OriginalName string    `db:"original_name" json:"original_name"`
+	StorageKey   string    `db:"storage_key" json:"storage_key"`
+	Source       string    `db:"source" json:"source"`
+	UploadedBy   *string   `db:"uploaded_by" json:"uploaded_by"`
+	Signature    string    `db:"signature" json:"signature"`
+	Engine       string    `db:"engine" json:"engine"`
+	Size         int64     `db:"size" json:"size"`
+	ContentHash  string    `db:"content_hash" json:"content_hash"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ResumableUpload is a tus upload session. Once all UploadLength bytes have
+// arrived the file becomes a document through the regular upload flow.
+type ResumableUpload struct {
+	ID              uuid.UUID       `db:"id" json:"id"`
+	Filename        string          `db:"filename" json:"filename"`
+	DataType        string          `db:"data_type" json:"data_type"`
+	Category        string          `db:"category" json:"category"`
+	UploadLength    int64           `db:"upload_length" json:"upload_length"`
+	UploadOffset    int64           `db:"upload_offset" json:"upload_offset"`
+	ValidFrom       *time.Time      `db:"valid_from" json:"valid_from"`
+	ReviewBy        *time.Time      `db:"review_by" json:"review_by"`
+	ExpiresAt       *time.Time      `db:"expires_at" json:"expires_at"`
+	Metadata        metadata.Values `db:"metadata" json:"metadata"`
+	Tags            pq.StringArray  `db:"tags" json:"tags"`
+	Staff           string          `db:"staff" json:"staff"`
+	Team            string          `db:"team" json:"team"`
+	Status          string          `db:"status" json:"status"`
+	Error           *string         `db:"error" json:"error"`
+	DocumentID      *int            `db:"document_id" json:"document_id"`
+	DetailID        *int            `db:"detail_id" json:"detail_id"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updated_at"`
+	UploadExpiresAt time.Time       `db:"upload_expires_at" json:"upload_expires_at"`
+}
+
+type UploadChunk struct {
+	UploadID    uuid.UUID `db:"upload_id"`
+	ChunkOffset int64     `db:"chunk_offset"`
+	Size        int64     `db:"size"`
+	StorageKey  string    `db:"storage_key"`
+}
+
+// UploadBatch is a multi-file upload processed in the background. The
+// counters are computed from its items when it is read.
+type UploadBatch struct {
+	ID          string            `db:"id" json:"batch_id"`
+	Category    string            `db:"category" json:"category"`
+	Staff       string            `db:"staff" json:"staff"`
+	Team        string            `db:"team" json:"team"`
+	AutoApprove bool              `db:"auto_approve" json:"auto_approve"`
+	Status      string            `db:"status" json:"status"`
+	Total       int               `db:"total" json:"total"`
+	Processed   int               `db:"processed" json:"processed"`
+	Successful  int               `db:"successful" json:"successful"`
+	Failed      int               `db:"failed" json:"failed"`
+	Infected    int               `db:"infected" json:"infected"`
+	Cancelled   int               `db:"cancelled" json:"cancelled"`
+	Extracted   int               `db:"extracted" json:"extracted"`
+	StartedAt   time.Time         `db:"started_at" json:"started_at"`
+	CompletedAt *time.Time        `db:"completed_at" json:"completed_at"`
+	CancelledAt *time.Time        `db:"cancelled_at" json:"cancelled_at"`
+	CancelledBy *string           `db:"cancelled_by" json:"cancelled_by"`
+	Files       []UploadBatchItem `db:"-" json:"files,omitempty"`
+}
+
+// UploadBatchItem is one file of a batch. The extraction fields follow the
+// created version, so they also cover extraction that ran after approval.
+type UploadBatchItem struct {
+	ID               int64     `db:"id" json:"id"`
+	BatchID          string    `db:"batch_id" json:"-"`
+	Position         int       `db:"position" json:"position"`
+	Filename         string    `db:"filename" json:"filename"`
+	Size             int64     `db:"size" json:"size"`
+	Status           string    `db:"status" json:"status"`
+	Error            *string   `db:"error" json:"reason"`
+	ScanStatus       *string   `db:"scan_status" json:"scan_status"`
+	ScanSignature    *string   `db:"scan_signature" json:"scan_signature"`
+	DocumentID       *int      `db:"document_id" json:"document_id"`
+	DetailID         *int      `db:"detail_id" json:"detail_id"`
+	ExtractionStatus *string   `db:"extraction_status" json:"extraction_status"`
+	ExtractionError  *string   `db:"extraction_error" json:"extraction_error"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CrawlSource is a place the backend fetches documents from. Sources without
+// a schedule only run when triggered by hand.
+type CrawlSource struct {
+	ID          int        `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	SourceType  string     `db:"source_type" json:"source_type"` // url, sitemap, listing
+	URL         string     `db:"url" json:"url"`
+	LinkPattern *string    `db:"link_pattern" json:"link_pattern"`
+	Category    string     `db:"category" json:"category"`
+	Schedule    *string    `db:"schedule" json:"schedule"`
+	IsActive    bool       `db:"is_active" json:"is_active"`
+	NextRunAt   *time.Time `db:"next_run_at" json:"next_run_at"`
+	LastRunAt   *time.Time `db:"last_run_at" json:"last_run_at"`
+	CreatedBy   string     `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type CrawlSourceInput struct {
+	Name        string `json:"name" binding:"required"`
+	SourceType  string `json:"source_type" binding:"required"`
+	URL         string `json:"url" binding:"required"`
+	LinkPattern string `json:"link_pattern"`
+	Category    string `json:"category" binding:"required"`
+	Schedule    string `json:"schedule"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// CrawledURL is the last successful fetch of a URL by a source.
+type CrawledURL struct {
+	SourceID     int       `db:"source_id"`
+	URL          string    `db:"url"`
+	ETag         *string   `db:"etag"`
+	LastModified *string   `db:"last_modified"`
+	ContentHash  *string   `db:"content_hash"`
+	DocumentID   *int      `db:"document_id"`
+	DetailID     *int      `db:"detail_id"`
+	FetchedAt    time.Time `db:"fetched_at"`
+}
+
+// CrawlRun is one run of a source. The counters are computed from its items.
+type CrawlRun struct {
+	ID          int64          `db:"id" json:"id"`
+	SourceID    int            `db:"source_id" json:"source_id"`
+	Trigger     string         `db:"trigger" json:"trigger"` // schedule, manual
+	Status      string         `db:"status" json:"status"`
+	Error       *string        `db:"error" json:"error"`
+	StartedAt   time.Time      `db:"started_at" json:"started_at"`
+	FinishedAt  *time.Time     `db:"finished_at" json:"finished_at"`
+	Discovered  int            `db:"discovered" json:"discovered"`
+	Created     int            `db:"created" json:"created"`
+	Updated     int            `db:"updated" json:"updated"`
+	NotModified int            `db:"not_modified" json:"not_modified"`
+	Skipped     int            `db:"skipped" json:"skipped"`
+	Failed      int            `db:"failed" json:"failed"`
+	Items       []CrawlRunItem `db:"-" json:"items,omitempty"`
+}
+
+type CrawlRunItem struct {
+	ID         int64     `db:"id" json:"id"`
+	RunID      int64     `db:"run_id" json:"-"`
+	URL        string    `db:"url" json:"url"`
+	Status     string    `db:"status" json:"status"`
+	Reason     *string   `db:"reason" json:"reason"`
+	DocumentID *int      `db:"document_id" json:"document_id"`
+	DetailID   *int      `db:"detail_id" json:"detail_id"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+type SearchFilter struct {
+	Query       string
+	Category    string
+	DocumentID  int
+	AllVersions bool
+	Limit       int
+	Offset      int
+}
+
+// SearchHit is one matching page. PageNumber is nil for formats without pages.
+type SearchHit struct {
+	DocumentID   int     `db:"document_id" json:"document_id"`
+	DetailID     int     `db:"detail_id" json:"detail_id"`
+	DocumentName string  `db:"document_name" json:"document_name"`
+	Category     string  `db:"category" json:"category"`
+	Status       *string `db:"status" json:"status"`
+	IsLatest     *bool   `db:"is_latest" json:"is_latest"`
+	PageNumber   *int    `db:"page_number" json:"page_number"`
+	Rank         float64 `db:"rank" json:"rank"`
+	Snippet      string  `db:"snippet" json:"snippet"`
 }
 
 type DocumentFilter struct {
@@ -54,6 +520,18 @@ type DocumentFilter struct {
 	StartDate     *time.Time
 	EndDate       *time.Time
 	IngestStatus  string
+	Expiry        string
+	ExpiryWindow  int
+	Tags          []string
+	Metadata      map[string][]string
+}
+
+type ContentHashMatch struct {
+	ContentHash  string  `db:"content_hash" json:"content_hash"`
+	DocumentID   int     `db:"document_id" json:"document_id"`
+	DetailID     int     `db:"detail_id" json:"detail_id"`
+	DocumentName string  `db:"document_name" json:"document_name"`
+	Status       *string `db:"status" json:"status"`
 }
 
 type DocumentDetailFilter struct {
@@ -63,6 +541,8 @@ type DocumentDetailFilter struct {
 	Status        string
 	DocumentName  string
 	RequestType   string
+	Expiry        string
+	ExpiryWindow  int
 	Limit         int
 	Offset        int
 	SortBy        string
@@ -70,3 +550,29 @@ type DocumentDetailFilter struct {
 	StartDate     *time.Time
 	EndDate       *time.Time
 }
+
+type ExtractionJobRecord struct {
+	ID          int        `db:"id" json:"id"`
+	DetailID    int        `db:"detail_id" json:"detail_id"`
+	ExternalID  string     `db:"external_id" json:"external_id"`
+	Category    string     `db:"category" json:"category"`
+	Filename    string     `db:"filename" json:"filename"`
+	FilePath    string     `db:"file_path" json:"file_path"`
+	Status      string     `db:"status" json:"status"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	MaxAttempts int        `db:"max_attempts" json:"max_attempts"`
+	LastError   *string    `db:"last_error" json:"last_error"`
+	NextRunAt   time.Time  `db:"next_run_at" json:"next_run_at"`
+	LockedBy    *string    `db:"locked_by" json:"locked_by"`
+	LockedUntil *time.Time `db:"locked_until" json:"locked_until"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type ExtractionQueueStats struct {
+	Queued    int `db:"queued" json:"queued"`
+	Running   int `db:"running" json:"running"`
+	Failed    int `db:"failed" json:"failed"`
+	Dead      int `db:"dead" json:"dead"`
+	Succeeded int `db:"succeeded" json:"succeeded"`
+}
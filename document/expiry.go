@@ -0,0 +1,181 @@
+package document
+
+import (
+	"context"
+	"dokuprime-be/audit"
+	"dokuprime-be/config"
+	"dokuprime-be/external"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultReviewNoticeDays = 14
+	defaultExpiringSoonDays = 30
+)
+
+// ExpiryService runs the scheduled validity checks: it reminds owners of
+// upcoming reviews and takes expired versions out of the RAG index.
+type ExpiryService struct {
+	repo           *DocumentRepository
+	externalClient *external.Client
+	audit          *audit.AuditService
+}
+
+func NewExpiryService(db *sqlx.DB) *ExpiryService {
+	return &ExpiryService{
+		repo:           NewDocumentRepository(db),
+		externalClient: external.NewClient(config.LoadExternalAPIConfig()),
+		audit:          audit.NewAuditService(audit.NewAuditRepository(db)),
+	}
+}
+
+func reviewNoticeDays() int {
+	return getEnvInt("DOCUMENT_REVIEW_NOTICE_DAYS", defaultReviewNoticeDays)
+}
+
+func expiringSoonDays() int {
+	return getEnvInt("DOCUMENT_EXPIRING_SOON_DAYS", defaultExpiringSoonDays)
+}
+
+// NotifyReviewsDue tells the owner of each active version that its review_by
+// date is coming up. Every version is announced once per review_by value.
+func (s *ExpiryService) NotifyReviewsDue() {
+	details, err := s.repo.GetDetailsDueForReview(reviewNoticeDays())
+	if err != nil {
+		log.Printf("Error getting documents due for review: %v", err)
+		return
+	}
+
+	for _, detail := range details {
+		message := fmt.Sprintf("Dokumen %q perlu ditinjau paling lambat %s", detail.DocumentName, detail.ReviewBy.Format("2006-01-02"))
+		if err := s.notify(detail, NotificationReviewDue, message); err != nil {
+			log.Printf("Warning: Failed to notify owner of detail ID %d: %v", detail.ID, err)
+			continue
+		}
+		if err := s.repo.MarkReviewNotified(detail.ID); err != nil {
+			log.Printf("Warning: Failed to mark review notice for detail ID %d: %v", detail.ID, err)
+		}
+	}
+
+	if len(details) > 0 {
+		log.Printf("Sent %d document review reminder(s)", len(details))
+	}
+}
+
+// ExpireDocuments removes every version past its expires_at from the RAG
+// index and marks it Expired. A version whose removal fails stays active and
+// is retried on the next run.
+func (s *ExpiryService) ExpireDocuments() {
+	details, err := s.repo.GetExpiredActiveDetails()
+	if err != nil {
+		log.Printf("Error getting expired documents: %v", err)
+		return
+	}
+
+	expired := 0
+	for _, detail := range details {
+		deleteReq := external.DeleteRequest{
+			ID:       detail.DocumentID,
+			Category: detail.Category,
+		}
+		if err := s.externalClient.DeleteDocument(context.Background(), deleteReq); err != nil {
+			log.Printf("Warning: Failed to delete expired document from external API (ID: %d): %v", detail.DocumentID, err)
+			continue
+		}
+
+		if err := s.repo.MarkDocumentDetailExpired(detail.ID); err != nil {
+			log.Printf("Warning: Failed to mark detail ID %d as expired: %v", detail.ID, err)
+			continue
+		}
+		expired++
+
+		after, _ := s.repo.GetDocumentDetailByID(detail.ID)
+		s.audit.Record(audit.Actor{}, audit.ActionDocumentExpire, audit.EntityDocumentDetail, detail.ID, detail, after)
+
+		message := fmt.Sprintf("Dokumen %q telah kedaluwarsa pada %s dan tidak lagi digunakan untuk menjawab pertanyaan", detail.DocumentName, detail.ExpiresAt.Format("2006-01-02"))
+		if err := s.notify(detail, NotificationExpired, message); err != nil {
+			log.Printf("Warning: Failed to notify owner of detail ID %d: %v", detail.ID, err)
+		}
+	}
+
+	if expired > 0 {
+		log.Printf("Expired %d document(s)", expired)
+	}
+}
+
+func (s *ExpiryService) notify(detail DocumentDetail, kind, message string) error {
+	notification := &DocumentNotification{
+		DetailID:  detail.ID,
+		Recipient: detail.Staff,
+		Kind:      kind,
+		Message:   message,
+	}
+	if err := s.repo.CreateNotification(notification); err != nil {
+		return err
+	}
+	log.Printf("Notified %s about detail ID %d (%s)", detail.Staff, detail.ID, kind)
+	return nil
+}
+
+// validateDates checks that the validity window is in order.
+func validateDates(dates DocumentDates) error {
+	if dates.ValidFrom != nil && dates.ExpiresAt != nil && !dates.ValidFrom.Before(*dates.ExpiresAt) {
+		return fmt.Errorf("expires_at must be after valid_from")
+	}
+	if dates.ReviewBy != nil && dates.ExpiresAt != nil && dates.ReviewBy.After(*dates.ExpiresAt) {
+		return fmt.Errorf("review_by must not be after expires_at")
+	}
+	return nil
+}
+
+func pastExpiry(detail *DocumentDetail) bool {
+	return detail.ExpiresAt != nil && !detail.ExpiresAt.After(time.Now())
+}
+
+func notYetValid(detail *DocumentDetail) bool {
+	return detail.ValidFrom != nil && detail.ValidFrom.After(time.Now())
+}
+
+// PublishScheduledDocuments publishes every approved version whose valid_from
+// has arrived. A version that expired while it waited is marked Expired
+// instead.
+func (s *DocumentService) PublishScheduledDocuments() {
+	details, err := s.repo.GetScheduledDetailsDue()
+	if err != nil {
+		log.Printf("Error getting scheduled documents: %v", err)
+		return
+	}
+
+	published := 0
+	for _, detail := range details {
+		if pastExpiry(&detail) {
+			if err := s.repo.MarkDocumentDetailExpired(detail.ID); err != nil {
+				log.Printf("Warning: Failed to mark detail ID %d as expired: %v", detail.ID, err)
+				continue
+			}
+			s.audit.Record(audit.Actor{}, audit.ActionDocumentExpire, audit.EntityDocumentDetail, detail.ID, detail, s.detailSnapshot(detail.ID))
+			continue
+		}
+
+		document, err := s.repo.GetDocumentByID(detail.DocumentID)
+		if err != nil {
+			log.Printf("Warning: Failed to get document ID %d to publish: %v", detail.DocumentID, err)
+			continue
+		}
+
+		if err := s.publishVersion(&detail, document); err != nil {
+			log.Printf("Warning: Failed to publish detail ID %d: %v", detail.ID, err)
+			continue
+		}
+		s.audit.Record(audit.Actor{}, audit.ActionDocumentPublish, audit.EntityDocumentDetail, detail.ID, detail, s.detailSnapshot(detail.ID))
+		published++
+	}
+
+	if published > 0 {
+		log.Printf("Published %d scheduled document(s)", published)
+	}
+}
@@ -2,11 +2,18 @@ package document
 
 import (
 	"context"
-	"dokuprime-be/config"
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/external"
+	"dokuprime-be/metadata"
+	"dokuprime-be/scanner"
+	"dokuprime-be/storage"
 	"dokuprime-be/util"
+	"dokuprime-be/workflow"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -16,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -26,6 +34,17 @@ const (
 	accountNotFoundResponse = "Account type not found"
 	failedParseFormResponse = "Failed to parse multipart form"
 	viewTokenCookieName = "document_view_token"
+	shareSessionCookie  = "document_share_session"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+)
+
+const (
+	batchProgressInterval  = time.Second
+	batchKeepAliveInterval = 15 * time.Second
 )
 
 type FileUploadConfig struct {
@@ -34,10 +53,12 @@ type FileUploadConfig struct {
 }
 
 type UploadContext struct {
-	Category  string
-	Email     string
-	TeamName  string
-	UploadDir string
+	Category string
+	Email    string
+	TeamName string
+	Dates    DocumentDates
+	Metadata metadata.Values
+	Tags     []string
 }
 
 type DocumentHandler struct {
@@ -61,7 +82,7 @@ func (h *DocumentHandler) GenerateViewURL(ctx *gin.Context) {
         return
     }
 
-    token, err := h.service.GenerateViewToken(req.Filename)
+    token, err := h.service.GenerateViewToken(req.Filename, accessEvent(ctx, accessSourceDirect))
     if err != nil {
         util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
         return
@@ -110,7 +131,7 @@ func (h *DocumentHandler) GenerateViewURLByID(ctx *gin.Context) {
         return
     }
 
-    token, err := h.service.GenerateViewTokenByID(req.ID)
+    token, err := h.service.GenerateViewTokenByID(req.ID, accessEvent(ctx, accessSourceDirect))
     if err != nil {
         util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
         return
@@ -144,22 +165,19 @@ func (h *DocumentHandler) GenerateViewURLByID(ctx *gin.Context) {
 }
 
 func (h *DocumentHandler) ViewDocument(ctx *gin.Context) {
-    token, err := ctx.Cookie(viewTokenCookieName)
-    if err != nil || token == "" {
+    // Link sitasi membawa token di URL; token itu didahulukan dari cookie
+    // yang mungkin masih tersisa dari dokumen lain.
+    token := ctx.Query("token")
+    if token == "" {
+        token, _ = ctx.Cookie(viewTokenCookieName)
+    }
+    if token == "" {
         util.ErrorResponse(ctx, http.StatusUnauthorized, "Missing access token (cookie required)")
         return
     }
 
-    // Ambil konfigurasi Path & Domain agar DELETE berhasil
-    // Browser butuh path & domain yang SAMA PERSIS untuk menghapus cookie
-    domain := os.Getenv("COOKIE_DOMAIN")
-    path := "/api/documents/view-file"
-
-    // Hapus cookie (MaxAge -1)
-    // Parameter Secure & HttpOnly saat delete tidak terlalu kritikal, tapi sebaiknya disamakan
-    ctx.SetCookie(viewTokenCookieName, "", -1, path, domain, false, true)
-
-    // ... (Lanjutan logika validasi Redis & Serve File tetap sama) ...
+    // Token dan cookie tetap berlaku sampai kedaluwarsa: viewer meminta
+    // file lagi untuk setiap byte range yang dibutuhkan.
     key := "view_token:" + token
     ctxRedis := context.Background()
 
@@ -175,42 +193,21 @@ func (h *DocumentHandler) ViewDocument(ctx *gin.Context) {
         return
     }
 
-    h.redis.Del(ctxRedis, key)
-
-    filePath := config.GetDocumentPath(filename)
-    // ... (sisanya sama dengan kode Anda) ...
-    if _, err := os.Stat(filePath); os.IsNotExist(err) {
+    content, file, err := h.service.OpenFile(filename, "inline")
+    if errors.Is(err, storage.ErrNotFound) {
         util.ErrorResponse(ctx, http.StatusNotFound, "File not found")
         return
     }
-    
-    file, err := os.Open(filePath)
     if err != nil {
         util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to open file")
         return
     }
-    defer file.Close()
-
-    fileInfo, err := file.Stat()
-    if err != nil {
-        util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get file info")
-        return
-    }
+    defer content.Close()
 
-    ext := strings.ToLower(filepath.Ext(filename))
-    contentType := "application/octet-stream"
-    if ext == ".pdf" {
-        contentType = "application/pdf"
-    } else if ext == ".txt" {
-        contentType = "text/plain"
-    }
+    h.service.RecordViewAccess(token, filename)
 
     ctx.Header("Content-Description", "File View")
-    ctx.Header("Content-Type", contentType)
-    ctx.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-    ctx.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", filename))
-
-    io.Copy(ctx.Writer, file)
+    util.ServeFile(ctx, content, file)
 }
 
 func (h *DocumentHandler) getTeamNameForUser(ctx *gin.Context) string {
@@ -248,6 +245,21 @@ func (h *DocumentHandler) UploadDocument(ctx *gin.Context) {
 		util.ErrorResponse(ctx, http.StatusBadRequest, "Category is required")
 		return
 	}
+	dates, err := documentDatesFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	metadataReq, _, err := metadataFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	metadataValues, tags, err := h.service.PrepareMetadata(metadataReq)
+	if err != nil {
+		h.metadataError(ctx, err)
+		return
+	}
 	email, exists := ctx.Get("email")
 	if !exists {
 		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
@@ -258,11 +270,6 @@ func (h *DocumentHandler) UploadDocument(ctx *gin.Context) {
 		return
 	}
 	teamName := h.getTeamNameForUser(ctx)
-	uploadDir := config.GetUploadPath()
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create upload directory")
-		return
-	}
 
 	maxFileSize, validTypes := h.getUploadConfig()
 
@@ -273,10 +280,12 @@ func (h *DocumentHandler) UploadDocument(ctx *gin.Context) {
 	}
 
 	uploadCtx := UploadContext{
-		Category:  category,
-		Email:     email.(string),
-		TeamName:  teamName,
-		UploadDir: uploadDir,
+		Category: category,
+		Email:    email.(string),
+		TeamName: teamName,
+		Dates:    dates,
+		Metadata: metadataValues,
+		Tags:     tags,
 	}
 
 	var uploadedDocuments []map[string]interface{}
@@ -294,7 +303,7 @@ func (h *DocumentHandler) UploadDocument(ctx *gin.Context) {
 }
 
 func (h *DocumentHandler) getUploadConfig() (int, map[string]bool) {
-	validTypes := map[string]bool{"pdf": true, "txt": true}
+	validTypes := acceptedFileTypes()
 
 	maxFileSizeFromEnv, err := strconv.Atoi(os.Getenv("MAX_FILE_SIZE_ALLOWED"))
 	if err != nil {
@@ -324,20 +333,25 @@ func (h *DocumentHandler) processSingleFile(
 	if !config.ValidTypes[dataType] {
 		return nil, map[string]string{
 			"filename": originalFilename,
-			"reason":   "Invalid file type. Only PDF and TXT are allowed",
+			"reason":   invalidFileTypeMessage(),
 		}
 	}
 
+	screened, err := h.service.screenUploadedFile(file, dataType, uploadCtx.Email)
+	if err != nil {
+		return nil, screeningFailure(originalFilename, err)
+	}
+
 	uniqueFilename := GenerateUniqueFilename(originalFilename)
-	filePath := filepath.Join(uploadCtx.UploadDir, uniqueFilename)
-	if err := ctx.SaveUploadedFile(file, filePath); err != nil {
+	contentHash, err := h.service.SaveUploadedFile(file, uniqueFilename)
+	if err != nil {
 		return nil, map[string]string{
 			"filename": originalFilename,
 			"reason":   fmt.Sprintf("Failed to save file: %v", err),
 		}
 	}
 
-	document := &Document{Category: uploadCtx.Category}
+	document := &Document{Category: uploadCtx.Category, Metadata: uploadCtx.Metadata, Tags: uploadCtx.Tags}
 	isLatest := true
 	pendingStatus := "Pending"
 	detail := &DocumentDetail{
@@ -349,12 +363,15 @@ func (h *DocumentHandler) processSingleFile(
 		Status:       &pendingStatus,
 		IsLatest:     &isLatest,
 		IsApprove:    nil,
+		ContentHash:  &contentHash,
+		ValidFrom:    uploadCtx.Dates.ValidFrom,
+		ReviewBy:     uploadCtx.Dates.ReviewBy,
+		ExpiresAt:    uploadCtx.Dates.ExpiresAt,
 	}
+	screened.apply(detail)
 
 	if err := h.service.CreateDocument(document, detail); err != nil {
-		if removeErr := os.Remove(filePath); removeErr != nil {
-			log.Printf("Warning: Failed to remove file %s after DB error: %v", filePath, removeErr)
-		}
+		h.service.RemoveFile(uniqueFilename)
 		return nil, map[string]string{
 			"filename": originalFilename,
 			"reason":   err.Error(),
@@ -405,6 +422,50 @@ func (h *DocumentHandler) sendUploadResponse(ctx *gin.Context, uploadedDocuments
 	})
 }
 
+func (h *DocumentHandler) SearchDocuments(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	documentID, _ := strconv.Atoi(ctx.Query("document_id"))
+
+	filter := SearchFilter{
+		Query:       query,
+		Category:    ctx.Query("category"),
+		DocumentID:  documentID,
+		AllVersions: ctx.Query("all_versions") == "true",
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	hits, total, err := h.service.SearchDocuments(filter)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Search completed successfully", map[string]interface{}{
+		"query":  query,
+		"hits":   hits,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
 func (h *DocumentHandler) GetDocuments(ctx *gin.Context) {
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
@@ -443,6 +504,10 @@ func (h *DocumentHandler) GetDocuments(ctx *gin.Context) {
 		StartDate:     startDatePtr,
 		EndDate:       endDatePtr,
 		IngestStatus:  ctx.Query("ingest_status"),
+		Expiry:        ctx.Query("expiry"),
+		ExpiryWindow:  expiryWindowFromQuery(ctx),
+		Tags:          tagsFromQuery(ctx),
+		Metadata:      metadataFiltersFromQuery(ctx),
 	}
 
 	documents, total, err := h.service.GetAllDocuments(filter)
@@ -451,16 +516,26 @@ func (h *DocumentHandler) GetDocuments(ctx *gin.Context) {
 		return
 	}
 
+	facets, err := h.service.GetDocumentFacets(filter)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	response := map[string]interface{}{
 		"documents": documents,
 		"total":     total,
 		"limit":     limit,
 		"offset":    offset,
+		"facets":    facets,
 		"filters": map[string]interface{}{
 			"search":    filter.Search,
 			"data_type": filter.DataType,
 			"category":  filter.Category,
 			"status":    filter.Status,
+			"expiry":    filter.Expiry,
+			"tags":      filter.Tags,
+			"metadata":  filter.Metadata,
 		},
 	}
 
@@ -502,6 +577,24 @@ func (h *DocumentHandler) UpdateDocument(ctx *gin.Context) {
 		return
 	}
 
+	dates, err := documentDatesFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metadataReq, hasMetadata, err := metadataFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if hasMetadata {
+		if _, _, err := h.service.PrepareMetadata(metadataReq); err != nil {
+			h.metadataError(ctx, err)
+			return
+		}
+	}
+
 	email, exists := ctx.Get("email")
 	if !exists {
 		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
@@ -519,22 +612,21 @@ func (h *DocumentHandler) UpdateDocument(ctx *gin.Context) {
 	ext := strings.ToLower(filepath.Ext(originalFilename))
 	dataType := strings.TrimPrefix(ext, ".")
 
-	validTypes := map[string]bool{"pdf": true, "txt": true}
-	if !validTypes[dataType] {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid file type. Only PDF and TXT are allowed")
+	if !acceptedFileTypes()[dataType] {
+		util.ErrorResponse(ctx, http.StatusBadRequest, invalidFileTypeMessage())
 		return
 	}
 
-	uniqueFilename := GenerateUniqueFilename(originalFilename)
-
-	uploadDir := config.GetUploadPath()
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create upload directory")
+	screened, err := h.service.screenUploadedFile(file, dataType, email.(string))
+	if err != nil {
+		h.screeningError(ctx, err)
 		return
 	}
 
-	filePath := filepath.Join(uploadDir, uniqueFilename)
-	if err := ctx.SaveUploadedFile(file, filePath); err != nil {
+	uniqueFilename := GenerateUniqueFilename(originalFilename)
+
+	contentHash, err := h.service.SaveUploadedFile(file, uniqueFilename)
+	if err != nil {
 		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to save file")
 		return
 	}
@@ -548,13 +640,25 @@ func (h *DocumentHandler) UpdateDocument(ctx *gin.Context) {
 		Team:         teamName,
 		Status:       &pendingStatus,
 		IsApprove:    nil,
-	}
-
-	if err := h.service.UpdateDocument(documentID, detail); err != nil {
-		if removeErr := os.Remove(filePath); removeErr != nil {
-			log.Printf("Warning: Failed to remove file %s after DB error: %v", filePath, removeErr)
+		ContentHash:  &contentHash,
+		ValidFrom:    dates.ValidFrom,
+		ReviewBy:     dates.ReviewBy,
+		ExpiresAt:    dates.ExpiresAt,
+	}
+	screened.apply(detail)
+
+	var documentMetadata *MetadataRequest
+	if hasMetadata {
+		documentMetadata = &metadataReq
+	}
+	if err := h.service.UpdateDocumentWithMetadata(documentID, detail, documentMetadata, audit.ActorFromContext(ctx)); err != nil {
+		h.service.RemoveFile(uniqueFilename)
+		var duplicateErr *DuplicateContentError
+		if errors.As(err, &duplicateErr) {
+			util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+			return
 		}
-		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		h.metadataError(ctx, err)
 		return
 	}
 
@@ -568,171 +672,147 @@ func (h *DocumentHandler) ApproveDocument(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.service.ApproveDocument(detailID); err != nil {
+	if err := h.service.ApproveDocument(detailID, audit.ActorFromContext(ctx)); err != nil {
+		if errors.Is(err, workflow.ErrNotReviewer) {
+			util.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, errLegalHold) || errors.Is(err, errDocumentTrashed) {
+			util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+			return
+		}
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	util.SuccessResponse(ctx, "Document approved successfully", nil)
+	progress, err := h.service.GetApprovalProgress(detailID)
+	if err != nil || progress.CurrentStep == nil {
+		util.SuccessResponse(ctx, "Document approved successfully", nil)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Approval step recorded, waiting for the next reviewer", progress)
 }
 
-func (h *DocumentHandler) RejectDocument(ctx *gin.Context) {
+func (h *DocumentHandler) GetApprovalProgress(ctx *gin.Context) {
 	detailID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
 		return
 	}
 
-	if err := h.service.RejectDocument(detailID); err != nil {
+	progress, err := h.service.GetApprovalProgress(detailID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document detail not found")
+			return
+		}
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	util.SuccessResponse(ctx, "Document rejected successfully", nil)
+	util.SuccessResponse(ctx, "Approval progress retrieved successfully", progress)
 }
 
-func (h *DocumentHandler) DeleteDocument(ctx *gin.Context) {
-	documentID, err := strconv.Atoi(ctx.Param("id"))
+func (h *DocumentHandler) RejectDocument(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
 		return
 	}
 
-	if err := h.service.DeleteDocument(documentID); err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	var req RejectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "A rejection reason and reason codes are required")
 		return
 	}
 
-	util.SuccessResponse(ctx, "Request hapus berhasil dikirim. Menunggu persetujuan Admin.", nil)
-}
-
-func (h *DocumentHandler) DownloadDocument(ctx *gin.Context) {
-	filename := ctx.Param("filename")
-	if filename == "" {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "Filename is required")
+	if err := h.service.RejectDocument(detailID, req, audit.ActorFromContext(ctx)); err != nil {
+		switch {
+		case errors.Is(err, workflow.ErrNotReviewer):
+			util.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+		case errors.Is(err, errInvalidReview):
+			util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		default:
+			util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	filePath := config.GetDocumentPath(filename)
+	util.SuccessResponse(ctx, "Document rejected successfully", nil)
+}
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		util.ErrorResponse(ctx, http.StatusNotFound, "File not found")
-		return
-	}
+func (h *DocumentHandler) GetRejectionReasons(ctx *gin.Context) {
+	util.SuccessResponse(ctx, "Rejection reasons retrieved successfully", RejectionReasons)
+}
 
-	file, err := os.Open(filePath)
+func (h *DocumentHandler) GetReviewComments(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to open file")
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
 		return
 	}
-	defer file.Close()
 
-	fileInfo, err := file.Stat()
+	comments, err := h.service.GetReviewComments(detailID)
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get file info")
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document detail not found")
+			return
+		}
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	ctx.Header("Content-Type", "application/octet-stream")
-	ctx.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-
-	io.Copy(ctx.Writer, file)
+	util.SuccessResponse(ctx, "Review comments retrieved successfully", comments)
 }
 
-func (h *DocumentHandler) GetAllDocumentDetails(ctx *gin.Context) {
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
-
-	if limit <= 0 {
-		limit = 10
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
+func (h *DocumentHandler) AddReviewComment(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
+		return
 	}
 
-	var startDatePtr, endDatePtr *time.Time
-	if sd := ctx.Query("start_date"); sd != "" {
-		if t, err := parseDate(sd); err == nil {
-			startDatePtr = &t
-		}
-	}
-	if ed := ctx.Query("end_date"); ed != "" {
-		if t, err := parseDate(ed); err == nil {
-			endDatePtr = &t
-		}
+	var req struct {
+		Comment string `json:"comment" binding:"required"`
 	}
-
-	filter := DocumentDetailFilter{
-		Search:        ctx.Query("search"),
-		DataType:      ctx.Query("data_type"),
-		Category:      ctx.Query("category"),
-		Status:        ctx.Query("status"),
-		DocumentName:  ctx.Query("document_name"),
-		RequestType:   ctx.Query("request_type"),
-		Limit:         limit,
-		Offset:        offset,
-		SortBy:        ctx.Query("sort_by"),
-		SortDirection: ctx.Query("sort_direction"),
-		StartDate:     startDatePtr,
-		EndDate:       endDatePtr,
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Comment is required")
+		return
 	}
 
-	details, total, err := h.service.GetAllDocumentDetails(filter)
+	comment, err := h.service.AddReviewComment(detailID, req.Comment, audit.ActorFromContext(ctx))
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document detail not found")
+		case errors.Is(err, errInvalidReview):
+			util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		default:
+			util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	response := map[string]interface{}{
-		"document_details": details,
-		"total":            total,
-		"limit":            limit,
-		"offset":           offset,
-		"filters": map[string]interface{}{
-			"search":        filter.Search,
-			"data_type":     filter.DataType,
-			"category":      filter.Category,
-			"status":        filter.Status,
-			"document_name": filter.DocumentName,
-		},
-	}
-
-	util.SuccessResponse(ctx, "Document details retrieved successfully", response)
-}
-
-func (h *DocumentHandler) GetQueueStatus(ctx *gin.Context) {
-	queueSize := h.service.GetExtractionQueueSize()
-
-	response := gin.H{
-		"pending_jobs": queueSize,
-		"message":      fmt.Sprintf("There are %d extraction jobs in the queue", queueSize),
-	}
-
-	util.SuccessResponse(ctx, "Queue status retrieved successfully", response)
+	util.CreatedResponse(ctx, "Comment added successfully", comment)
 }
 
-func (h *DocumentHandler) BatchUploadDocument(ctx *gin.Context) {
-	form, err := ctx.MultipartForm()
+func (h *DocumentHandler) ResubmitDocument(ctx *gin.Context) {
+	rejectedID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusBadRequest, failedParseFormResponse)
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
 		return
 	}
 
-	files := form.File["files"]
-	if len(files) == 0 {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "At least one file is required")
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "File is required")
 		return
 	}
 
-	category := ctx.PostForm("category")
-	if category == "" {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "Category is required")
+	dates, err := documentDatesFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -742,57 +822,1618 @@ func (h *DocumentHandler) BatchUploadDocument(ctx *gin.Context) {
 		return
 	}
 
-	if !exists {
-		util.ErrorResponse(ctx, http.StatusUnauthorized, accountNotFoundResponse)
+	originalFilename := file.Filename
+	dataType := strings.TrimPrefix(strings.ToLower(filepath.Ext(originalFilename)), ".")
+	if !acceptedFileTypes()[dataType] {
+		util.ErrorResponse(ctx, http.StatusBadRequest, invalidFileTypeMessage())
 		return
 	}
-	teamName := h.getTeamNameForUser(ctx)
 
-	autoApproveStr := ctx.DefaultPostForm("auto_approve", "false")
-	autoApprove := autoApproveStr == "true"
+	screened, err := h.service.screenUploadedFile(file, dataType, email.(string))
+	if err != nil {
+		h.screeningError(ctx, err)
+		return
+	}
 
-	batchID, err := h.service.StartBatchUpload(files, category, email.(string), teamName, autoApprove)
+	uniqueFilename := GenerateUniqueFilename(originalFilename)
+	contentHash, err := h.service.SaveUploadedFile(file, uniqueFilename)
 	if err != nil {
-		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to save file")
 		return
 	}
 
-	util.SuccessResponse(ctx, "Batch upload started", gin.H{
-		"batch_id":     batchID,
-		"total_files":  len(files),
-		"auto_approve": autoApprove,
-		"message":      "Files are being processed. Use the batch_id to check status",
-	})
+	pendingStatus := "Pending"
+	detail := &DocumentDetail{
+		DocumentName: originalFilename,
+		Filename:     uniqueFilename,
+		DataType:     dataType,
+		Staff:        email.(string),
+		Team:         h.getTeamNameForUser(ctx),
+		Status:       &pendingStatus,
+		ContentHash:  &contentHash,
+		ValidFrom:    dates.ValidFrom,
+		ReviewBy:     dates.ReviewBy,
+		ExpiresAt:    dates.ExpiresAt,
+	}
+	screened.apply(detail)
+
+	if err := h.service.ResubmitDocument(rejectedID, detail, ctx.PostForm("comment"), audit.ActorFromContext(ctx)); err != nil {
+		h.service.RemoveFile(uniqueFilename)
+		var duplicateErr *DuplicateContentError
+		switch {
+		case errors.As(err, &duplicateErr):
+			util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+		case errors.Is(err, sql.ErrNoRows):
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document detail not found")
+		case errors.Is(err, errInvalidReview):
+			util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		case errors.Is(err, errNotResubmitter):
+			util.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+		default:
+			util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document resubmitted successfully", detail)
 }
 
-func (h *DocumentHandler) GetBatchUploadStatus(ctx *gin.Context) {
-	batchID := ctx.Query("batch_id")
-	if batchID == "" {
-		util.ErrorResponse(ctx, http.StatusBadRequest, "batch_id is required")
+func (h *DocumentHandler) GetDocumentVersions(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
 		return
 	}
 
-	status, err := h.service.GetBatchStatus(batchID)
+	versions, err := h.service.GetDocumentVersions(documentID)
 	if err != nil {
-		if err.Error() == "batch not found" {
-			util.ErrorResponse(ctx, http.StatusNotFound, "Batch ID not found or expired")
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document not found")
 			return
 		}
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	util.SuccessResponse(ctx, "Batch status retrieved successfully", status)
+	util.SuccessResponse(ctx, "Document versions retrieved successfully", versions)
 }
 
-func parseDate(s string) (time.Time, error) {
-	layouts := []string{time.RFC3339, "2006-01-02"}
-	for _, l := range layouts {
-		if t, err := time.Parse(l, s); err == nil {
-			return t, nil
-		}
+func (h *DocumentHandler) DiffDocumentVersions(ctx *gin.Context) {
+	fromID, err := strconv.Atoi(ctx.Query("from"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid from version ID")
+		return
 	}
-	return time.Time{}, fmt.Errorf("invalid date format: %s", s)
+	toID, err := strconv.Atoi(ctx.Query("to"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid to version ID")
+		return
+	}
+
+	diff, err := h.service.DiffVersions(fromID, toID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			util.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+		case errors.Is(err, external.ErrTextUnsupported), errors.Is(err, external.ErrPDFEncrypted):
+			util.ErrorResponse(ctx, http.StatusUnprocessableEntity, err.Error())
+		default:
+			util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document versions compared successfully", diff)
+}
+
+func (h *DocumentHandler) RollbackDocument(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
+		return
+	}
+
+	if err := h.service.RollbackDocument(detailID, audit.ActorFromContext(ctx)); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document rolled back successfully", nil)
+}
+
+func (h *DocumentHandler) UpdateDocumentDates(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
+		return
+	}
+
+	var req DocumentDatesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	dates, err := parseDocumentDates(req)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	detail, err := h.service.UpdateDocumentDates(detailID, dates, audit.ActorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document detail not found")
+			return
+		}
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document dates updated successfully", detail)
+}
+
+func (h *DocumentHandler) UpdateDocumentMetadata(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	var req MetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	document, err := h.service.UpdateDocumentMetadata(documentID, req, audit.ActorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Document not found")
+			return
+		}
+		h.metadataError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document metadata updated successfully", document)
+}
+
+func (h *DocumentHandler) GetNotifications(ctx *gin.Context) {
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	unreadOnly := ctx.Query("unread") == "true"
+
+	notifications, total, err := h.service.GetNotifications(email.(string), unreadOnly, limit, offset)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Notifications retrieved successfully", gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+func (h *DocumentHandler) GetQuarantinedFiles(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	files, total, err := h.service.GetQuarantinedFiles(limit, offset)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Quarantined files retrieved successfully", gin.H{
+		"files":  files,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *DocumentHandler) MarkNotificationRead(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	if err := h.service.MarkNotificationRead(id, email.(string)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Notification not found")
+			return
+		}
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Notification marked as read", nil)
+}
+
+func (h *DocumentHandler) ReingestDocument(ctx *gin.Context) {
+	detailID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document detail ID")
+		return
+	}
+
+	if err := h.service.ReingestDocument(detailID); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document ingestion re-triggered successfully", nil)
+}
+
+func (h *DocumentHandler) DeleteDocument(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	if err := h.service.DeleteDocument(documentID, audit.ActorFromContext(ctx)); err != nil {
+		h.trashError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Request hapus berhasil dikirim. Menunggu persetujuan Admin.", nil)
+}
+
+func (h *DocumentHandler) GetTrashedDocuments(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	documents, total, err := h.service.GetTrashedDocuments(ctx.Query("search"), limit, offset)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Recycle bin retrieved successfully", gin.H{
+		"documents": documents,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+func (h *DocumentHandler) RestoreDocument(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	document, err := h.service.RestoreDocument(documentID, audit.ActorFromContext(ctx))
+	if err != nil {
+		h.trashError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document restored successfully", document)
+}
+
+func (h *DocumentHandler) PurgeDocument(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	if err := h.service.PurgeDocument(documentID, audit.ActorFromContext(ctx)); err != nil {
+		h.trashError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document permanently deleted", nil)
+}
+
+func (h *DocumentHandler) SetLegalHold(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	var req LegalHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	document, err := h.service.SetLegalHold(documentID, req, audit.ActorFromContext(ctx))
+	if err != nil {
+		h.trashError(ctx, err)
+		return
+	}
+
+	message := "Legal hold lifted successfully"
+	if document.LegalHold {
+		message = "Legal hold placed successfully"
+	}
+	util.SuccessResponse(ctx, message, document)
+}
+
+func (h *DocumentHandler) trashError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Document not found")
+	case errors.Is(err, errInvalidLegalHold):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errLegalHold), errors.Is(err, errDocumentTrashed),
+		errors.Is(err, errNotTrashed), errors.Is(err, errRestoreConflict):
+		util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *DocumentHandler) DownloadDocument(ctx *gin.Context) {
+	filename := ctx.Param("filename")
+	if filename == "" {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Filename is required")
+		return
+	}
+
+	content, file, err := h.service.OpenFile(filename, "attachment")
+	if errors.Is(err, storage.ErrNotFound) {
+		util.ErrorResponse(ctx, http.StatusNotFound, "File not found")
+		return
+	}
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to open file")
+		return
+	}
+	defer content.Close()
+
+	if !continuesDownload(ctx.Request) {
+		h.service.RecordDownload(filename, accessEvent(ctx, accessSourceDirect))
+	}
+
+	ctx.Header("Content-Description", "File Transfer")
+	util.ServeFile(ctx, content, file)
+}
+
+func (h *DocumentHandler) GetAllDocumentDetails(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var startDatePtr, endDatePtr *time.Time
+	if sd := ctx.Query("start_date"); sd != "" {
+		if t, err := parseDate(sd); err == nil {
+			startDatePtr = &t
+		}
+	}
+	if ed := ctx.Query("end_date"); ed != "" {
+		if t, err := parseDate(ed); err == nil {
+			endDatePtr = &t
+		}
+	}
+
+	filter := DocumentDetailFilter{
+		Search:        ctx.Query("search"),
+		DataType:      ctx.Query("data_type"),
+		Category:      ctx.Query("category"),
+		Status:        ctx.Query("status"),
+		DocumentName:  ctx.Query("document_name"),
+		RequestType:   ctx.Query("request_type"),
+		Expiry:        ctx.Query("expiry"),
+		ExpiryWindow:  expiryWindowFromQuery(ctx),
+		Limit:         limit,
+		Offset:        offset,
+		SortBy:        ctx.Query("sort_by"),
+		SortDirection: ctx.Query("sort_direction"),
+		StartDate:     startDatePtr,
+		EndDate:       endDatePtr,
+	}
+
+	details, total, err := h.service.GetAllDocumentDetails(filter)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"document_details": details,
+		"total":            total,
+		"limit":            limit,
+		"offset":           offset,
+		"filters": map[string]interface{}{
+			"search":        filter.Search,
+			"data_type":     filter.DataType,
+			"category":      filter.Category,
+			"status":        filter.Status,
+			"document_name": filter.DocumentName,
+			"expiry":        filter.Expiry,
+		},
+	}
+
+	util.SuccessResponse(ctx, "Document details retrieved successfully", response)
+}
+
+func (h *DocumentHandler) ResumableUploadOptions(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+	ctx.Header("Tus-Version", tusVersion)
+	ctx.Header("Tus-Extension", tusExtensions)
+	ctx.Header("Tus-Max-Size", strconv.FormatInt(maxUploadSize(), 10))
+	ctx.Status(http.StatusNoContent)
+}
+
+// CreateResumableUpload opens a tus upload. Upload-Metadata carries the same
+// fields as the multipart upload: filename, category, valid_from, review_by,
+// expires_at, metadata (JSON) and tags (comma separated).
+func (h *DocumentHandler) CreateResumableUpload(ctx *gin.Context) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+	if ctx.GetHeader("Upload-Defer-Length") != "" {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Upload-Length is required")
+		return
+	}
+
+	fields, err := parseUploadMetadata(ctx.GetHeader("Upload-Metadata"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	dates, err := parseDocumentDates(DocumentDatesRequest{
+		ValidFrom: fields["valid_from"],
+		ReviewBy:  fields["review_by"],
+		ExpiresAt: fields["expires_at"],
+	})
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var metadataReq MetadataRequest
+	if raw := strings.TrimSpace(fields["metadata"]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &metadataReq.Metadata); err != nil {
+			util.ErrorResponse(ctx, http.StatusBadRequest, "metadata must be a JSON object")
+			return
+		}
+	}
+	if raw := fields["tags"]; raw != "" {
+		metadataReq.Tags = strings.Split(raw, ",")
+	}
+	metadataValues, tags, err := h.service.PrepareMetadata(metadataReq)
+	if err != nil {
+		h.metadataError(ctx, err)
+		return
+	}
+
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	filename := fields["filename"]
+	if filename == "" {
+		filename = fields["name"]
+	}
+	upload := &ResumableUpload{
+		Filename:     filename,
+		Category:     fields["category"],
+		UploadLength: length,
+		ValidFrom:    dates.ValidFrom,
+		ReviewBy:     dates.ReviewBy,
+		ExpiresAt:    dates.ExpiresAt,
+		Metadata:     metadataValues,
+		Tags:         tags,
+		Staff:        email.(string),
+		Team:         h.getTeamNameForUser(ctx),
+	}
+	if err := h.service.CreateResumableUpload(upload); err != nil {
+		h.uploadError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/api/documents/uploads/"+upload.ID.String())
+	setUploadHeaders(ctx, upload)
+	util.CreatedResponse(ctx, "Upload created successfully", upload)
+}
+
+func (h *DocumentHandler) GetResumableUploadOffset(ctx *gin.Context) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+	upload, ok := h.resumableUpload(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	setUploadHeaders(ctx, upload)
+	ctx.Status(http.StatusOK)
+}
+
+func (h *DocumentHandler) PatchResumableUpload(ctx *gin.Context) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+	if ctx.ContentType() != "application/offset+octet-stream" {
+		util.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Upload-Offset is required")
+		return
+	}
+	id, ok := uploadIDParam(ctx)
+	if !ok {
+		return
+	}
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	upload, err := h.service.AppendUploadChunk(id, email.(string), offset, ctx.Request.Body)
+	if upload != nil {
+		setUploadHeaders(ctx, upload)
+	}
+	if err != nil {
+		h.uploadError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *DocumentHandler) DeleteResumableUpload(ctx *gin.Context) {
+	if !checkTusVersion(ctx) {
+		return
+	}
+	id, ok := uploadIDParam(ctx)
+	if !ok {
+		return
+	}
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	if err := h.service.DeleteResumableUpload(id, email.(string)); err != nil {
+		h.uploadError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetResumableUpload reports a session as JSON, including the document it
+// became once completed. It is not part of tus.
+func (h *DocumentHandler) GetResumableUpload(ctx *gin.Context) {
+	upload, ok := h.resumableUpload(ctx)
+	if !ok {
+		return
+	}
+	util.SuccessResponse(ctx, "Upload retrieved successfully", upload)
+}
+
+func (h *DocumentHandler) resumableUpload(ctx *gin.Context) (*ResumableUpload, bool) {
+	id, ok := uploadIDParam(ctx)
+	if !ok {
+		return nil, false
+	}
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return nil, false
+	}
+
+	upload, err := h.service.GetResumableUpload(id, email.(string))
+	if err != nil {
+		h.uploadError(ctx, err)
+		return nil, false
+	}
+	return upload, true
+}
+
+func (h *DocumentHandler) uploadError(ctx *gin.Context, err error) {
+	var duplicateErr *DuplicateContentError
+	var infectedErr *InfectedFileError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Upload not found")
+	case errors.Is(err, errUploadExpired):
+		util.ErrorResponse(ctx, http.StatusGone, err.Error())
+	case errors.Is(err, errOffsetConflict), errors.Is(err, errUploadFinished), errors.As(err, &duplicateErr):
+		util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, errUploadTooLarge):
+		util.ErrorResponse(ctx, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, errInvalidUpload):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.As(err, &infectedErr), errors.Is(err, scanner.ErrContentMismatch), errors.Is(err, ErrScanUnavailable):
+		h.screeningError(ctx, err)
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func uploadIDParam(ctx *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusNotFound, "Upload not found")
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// checkTusVersion rejects requests for a tus version other than the one
+// implemented here.
+func checkTusVersion(ctx *gin.Context) bool {
+	ctx.Header("Tus-Resumable", tusVersion)
+	if ctx.GetHeader("Tus-Resumable") != tusVersion {
+		ctx.Header("Tus-Version", tusVersion)
+		util.ErrorResponse(ctx, http.StatusPreconditionFailed, "Unsupported Tus-Resumable version, use "+tusVersion)
+		return false
+	}
+	return true
+}
+
+func setUploadHeaders(ctx *gin.Context, upload *ResumableUpload) {
+	ctx.Header("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(upload.UploadLength, 10))
+	ctx.Header("Upload-Expires", upload.UploadExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header: comma separated
+// pairs of a key and an optional base64 value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 0:
+			continue
+		case 1:
+			fields[parts[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid Upload-Metadata value for %q", parts[0])
+			}
+			fields[parts[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("invalid Upload-Metadata pair %q", strings.TrimSpace(pair))
+		}
+	}
+	return fields, nil
+}
+
+func (h *DocumentHandler) GetQueueStatus(ctx *gin.Context) {
+	stats, err := h.service.GetExtractionQueueStats()
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	queueSize := stats.Queued + stats.Failed + stats.Running
+
+	response := gin.H{
+		"pending_jobs": queueSize,
+		"queued":       stats.Queued,
+		"running":      stats.Running,
+		"retrying":     stats.Failed,
+		"dead":         stats.Dead,
+		"message":      fmt.Sprintf("There are %d extraction jobs in the queue", queueSize),
+	}
+
+	util.SuccessResponse(ctx, "Queue status retrieved successfully", response)
+}
+
+func (h *DocumentHandler) BatchUploadDocument(ctx *gin.Context) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, failedParseFormResponse)
+		return
+	}
+
+	files := form.File["files"]
+	if len(files) == 0 {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "At least one file is required")
+		return
+	}
+
+	category := ctx.PostForm("category")
+	if category == "" {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Category is required")
+		return
+	}
+
+	metadataReq, _, err := metadataFromForm(ctx)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	metadataValues, tags, err := h.service.PrepareMetadata(metadataReq)
+	if err != nil {
+		h.metadataError(ctx, err)
+		return
+	}
+
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	teamName := h.getTeamNameForUser(ctx)
+
+	batch, err := h.service.StartBatchUpload(files, category, email.(string), teamName, metadataValues, tags)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Batch upload started", gin.H{
+		"batch_id":     batch.ID,
+		"total_files":  len(files),
+		"auto_approve": batch.AutoApprove,
+		"message":      "Files are being processed. Use the batch_id to check status",
+	})
+}
+
+func (h *DocumentHandler) GetBatchUploadStatus(ctx *gin.Context) {
+	batchID := ctx.Query("batch_id")
+	if batchID == "" {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "batch_id is required")
+		return
+	}
+
+	if strings.Contains(ctx.GetHeader("Accept"), "text/event-stream") {
+		h.streamBatchStatus(ctx, batchID)
+		return
+	}
+
+	status, err := h.service.GetBatchStatus(batchID)
+	if err != nil {
+		h.batchError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Batch status retrieved successfully", status)
+}
+
+// streamBatchStatus sends a progress event whenever the batch's counters
+// change and a final complete event, with every file's outcome, once the
+// workers are done.
+func (h *DocumentHandler) streamBatchStatus(ctx *gin.Context, batchID string) {
+	progress, err := h.service.GetBatchProgress(batchID)
+	if err != nil {
+		h.batchError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	writeEvent := func(name string, data interface{}) {
+		ctx.SSEvent(name, data)
+		ctx.Writer.Flush()
+	}
+
+	ticker := time.NewTicker(batchProgressInterval)
+	defer ticker.Stop()
+
+	var last *UploadBatch
+	var lastSent time.Time
+	for {
+		if progress.CompletedAt != nil {
+			final, err := h.service.GetBatchStatus(batchID)
+			if err != nil {
+				writeEvent("error", gin.H{"message": err.Error()})
+				return
+			}
+			writeEvent("complete", final)
+			return
+		}
+
+		// Unchanged progress is resent now and then so proxies keep the
+		// connection open.
+		if last == nil || batchProgressChanged(last, progress) || time.Since(lastSent) >= batchKeepAliveInterval {
+			writeEvent("progress", progress)
+			last = progress
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		progress, err = h.service.GetBatchProgress(batchID)
+		if err != nil {
+			writeEvent("error", gin.H{"message": err.Error()})
+			return
+		}
+	}
+}
+
+func batchProgressChanged(previous, current *UploadBatch) bool {
+	return previous.Status != current.Status ||
+		previous.Processed != current.Processed ||
+		previous.Extracted != current.Extracted
+}
+
+func (h *DocumentHandler) GetUploadBatches(ctx *gin.Context) {
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	batches, total, err := h.service.GetUploadBatches(email.(string), limit, offset)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Batches retrieved successfully", gin.H{
+		"batches": batches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (h *DocumentHandler) CancelUploadBatch(ctx *gin.Context) {
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	batch, err := h.service.CancelUploadBatch(ctx.Param("id"), email.(string))
+	if err != nil {
+		h.batchError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Batch cancelled successfully", batch)
+}
+
+func (h *DocumentHandler) batchError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errBatchNotFound):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Batch ID not found")
+	case errors.Is(err, errNotBatchOwner):
+		util.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, errBatchFinished):
+		util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *DocumentHandler) GetCrawlSources(ctx *gin.Context) {
+	sources, err := h.service.GetCrawlSources()
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl sources retrieved successfully", sources)
+}
+
+func (h *DocumentHandler) GetCrawlSource(ctx *gin.Context) {
+	id, ok := crawlSourceIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	source, err := h.service.GetCrawlSource(id)
+	if err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl source retrieved successfully", source)
+}
+
+func (h *DocumentHandler) CreateCrawlSource(ctx *gin.Context) {
+	var input CrawlSourceInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	source, err := h.service.CreateCrawlSource(input, email.(string), audit.ActorFromContext(ctx))
+	if err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.CreatedResponse(ctx, "Crawl source created successfully", source)
+}
+
+func (h *DocumentHandler) UpdateCrawlSource(ctx *gin.Context) {
+	id, ok := crawlSourceIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var input CrawlSourceInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	source, err := h.service.UpdateCrawlSource(id, input, audit.ActorFromContext(ctx))
+	if err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl source updated successfully", source)
+}
+
+func (h *DocumentHandler) DeleteCrawlSource(ctx *gin.Context) {
+	id, ok := crawlSourceIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCrawlSource(id, audit.ActorFromContext(ctx)); err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl source deleted successfully", nil)
+}
+
+func (h *DocumentHandler) RunCrawlSource(ctx *gin.Context) {
+	id, ok := crawlSourceIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	run, err := h.service.StartCrawl(id)
+	if err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl started. Use the run ID to check its progress", run)
+}
+
+func (h *DocumentHandler) GetCrawlRuns(ctx *gin.Context) {
+	id, ok := crawlSourceIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	runs, total, err := h.service.GetCrawlRuns(id, limit, offset)
+	if err != nil {
+		h.crawlError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl runs retrieved successfully", gin.H{
+		"runs":   runs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *DocumentHandler) GetCrawlRun(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid crawl run ID")
+		return
+	}
+
+	run, err := h.service.GetCrawlRun(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(ctx, http.StatusNotFound, "Crawl run not found")
+			return
+		}
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Crawl run retrieved successfully", run)
+}
+
+func crawlSourceIDParam(ctx *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid crawl source ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *DocumentHandler) crawlError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Crawl source not found")
+	case errors.Is(err, errInvalidCrawlSource):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errCrawlRunning):
+		util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *DocumentHandler) CreateShareLink(ctx *gin.Context) {
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return
+	}
+
+	var req ShareLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	link, err := h.service.CreateShareLink(req, email.(string), audit.ActorFromContext(ctx))
+	if err != nil {
+		h.shareLinkError(ctx, err)
+		return
+	}
+
+	link.URL = fmt.Sprintf("%s/api/documents/shared/%s", util.PublicBaseURL(), link.Token)
+	util.CreatedResponse(ctx, "Share link created successfully", link)
+}
+
+func (h *DocumentHandler) GetShareLinks(ctx *gin.Context) {
+	email, userID, ok := shareLinkUser(ctx)
+	if !ok {
+		return
+	}
+
+	documentID := 0
+	if value := ctx.Query("document_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+			return
+		}
+		documentID = id
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	links, total, err := h.service.GetShareLinks(documentID, email, userID, limit, offset)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(ctx, "Share links retrieved successfully", gin.H{
+		"links":  links,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *DocumentHandler) RevokeShareLink(ctx *gin.Context) {
+	email, userID, ok := shareLinkUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := shareLinkIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	link, err := h.service.RevokeShareLink(id, email, userID, audit.ActorFromContext(ctx))
+	if err != nil {
+		h.shareLinkError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Share link revoked successfully", link)
+}
+
+func (h *DocumentHandler) GetShareLinkAccesses(ctx *gin.Context) {
+	email, userID, ok := shareLinkUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := shareLinkIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	accesses, total, err := h.service.GetShareLinkAccesses(id, email, userID, limit, offset)
+	if err != nil {
+		h.shareLinkError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Share link accesses retrieved successfully", gin.H{
+		"accesses": accesses,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// ViewSharedDocument serves the document behind a share link. Login is
+// optional; links that require it answer 401 to anonymous visitors.
+func (h *DocumentHandler) ViewSharedDocument(ctx *gin.Context) {
+	token := ctx.Param("token")
+	viewer := ShareViewer{
+		Email:     ctx.GetString("email"),
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+	if userID, exists := ctx.Get("user_id"); exists {
+		id := userID.(int64)
+		viewer.UserID = &id
+	}
+
+	session, _ := ctx.Cookie(shareSessionCookie)
+	detail, newSession, err := h.service.AccessShareLink(token, session, viewer)
+	if err != nil {
+		h.shareLinkError(ctx, err)
+		return
+	}
+
+	if newSession != "" {
+		isSecure := ctx.Request.TLS != nil || ctx.Request.Header.Get("X-Forwarded-Proto") == "https"
+		if envSecure := os.Getenv("COOKIE_SECURE"); envSecure != "" {
+			isSecure = envSecure == "true"
+		}
+
+		ctx.SetSameSite(getSameSiteMode(os.Getenv("COOKIE_SAME_SITE")))
+		ctx.SetCookie(shareSessionCookie, newSession, int(shareSessionTTL().Seconds()),
+			"/api/documents/shared/"+token, os.Getenv("COOKIE_DOMAIN"), isSecure, getEnvBool("COOKIE_HTTP_ONLY", true))
+	}
+
+	content, file, err := h.service.OpenFile(detail.Filename, "inline")
+	if errors.Is(err, storage.ErrNotFound) {
+		util.ErrorResponse(ctx, http.StatusNotFound, "File not found")
+		return
+	}
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to open file")
+		return
+	}
+	defer content.Close()
+
+	ctx.Header("Content-Description", "File View")
+	util.ServeFile(ctx, content, file)
+}
+
+func shareLinkUser(ctx *gin.Context) (string, int64, bool) {
+	email, exists := ctx.Get("email")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, emailNotFoundResponse)
+		return "", 0, false
+	}
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		util.ErrorResponse(ctx, http.StatusUnauthorized, "User ID not found")
+		return "", 0, false
+	}
+	return email.(string), userID.(int64), true
+}
+
+func shareLinkIDParam(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid share link ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *DocumentHandler) shareLinkError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Document not found")
+	case errors.Is(err, errShareLinkNotFound):
+		util.ErrorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, errInvalidShareLink):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errShareLoginRequired):
+		util.ErrorResponse(ctx, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, errShareTeamNotAllowed), errors.Is(err, errNotShareLinkOwner):
+		util.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, errShareLinkGone):
+		util.ErrorResponse(ctx, http.StatusGone, err.Error())
+	case errors.Is(err, errDocumentTrashed):
+		util.ErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *DocumentHandler) GetDocumentUsage(ctx *gin.Context) {
+	from, to, ok := reportPeriod(ctx)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	usage, total, err := h.service.GetDocumentUsage(from, to, ctx.Query("sort"), limit, offset)
+	if err != nil {
+		h.analyticsError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Document usage retrieved successfully", gin.H{
+		"documents": usage,
+		"from":      from,
+		"to":        to,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+func (h *DocumentHandler) GetUncitedDocuments(ctx *gin.Context) {
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", strconv.Itoa(defaultUncitedDays)))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid days")
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	documents, total, err := h.service.GetUncitedDocuments(days, limit, offset)
+	if err != nil {
+		h.analyticsError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Uncited documents retrieved successfully", gin.H{
+		"documents": documents,
+		"days":      days,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+func (h *DocumentHandler) GetDocumentAccessEvents(ctx *gin.Context) {
+	documentID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	from, to, ok := reportPeriod(ctx)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	events, total, err := h.service.GetDocumentAccessEvents(documentID, from, to, limit, offset)
+	if err != nil {
+		h.analyticsError(ctx, err)
+		return
+	}
+
+	util.SuccessResponse(ctx, "Access events retrieved successfully", gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// reportPeriod reads the from and to query parameters. The period defaults
+// to the last 30 days; a date-only to includes that whole day.
+func reportPeriod(ctx *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := parseDate(value)
+		if err != nil {
+			util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid to date")
+			return time.Time{}, time.Time{}, false
+		}
+		if len(value) == len("2006-01-02") {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -defaultReportDays)
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := parseDate(value)
+		if err != nil {
+			util.ErrorResponse(ctx, http.StatusBadRequest, "Invalid from date")
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	return from, to, true
+}
+
+func (h *DocumentHandler) analyticsError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(ctx, http.StatusNotFound, "Document not found")
+	case errors.Is(err, errInvalidReport):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// accessEvent describes the logged-in user of the request as a viewer.
+func accessEvent(ctx *gin.Context, source string) DocumentAccessEvent {
+	event := DocumentAccessEvent{
+		Email:     optionalString(ctx.GetString("email")),
+		Source:    source,
+		IPAddress: optionalString(ctx.ClientIP()),
+		UserAgent: optionalString(ctx.Request.UserAgent()),
+	}
+	if userID, exists := ctx.Get("user_id"); exists {
+		id := userID.(int64)
+		event.UserID = &id
+	}
+	return event
+}
+
+// continuesDownload reports whether the request resumes a download, which
+// was counted when it started.
+func continuesDownload(r *http.Request) bool {
+	rangeHeader := r.Header.Get("Range")
+	return rangeHeader != "" && !strings.HasPrefix(rangeHeader, "bytes=0-")
+}
+
+func parseDate(s string) (time.Time, error) {
+	layouts := []string{time.RFC3339, "2006-01-02"}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", s)
+}
+
+func parseOptionalDate(field, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseDate(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, use YYYY-MM-DD or RFC3339", field)
+	}
+	return &t, nil
+}
+
+func parseDocumentDates(req DocumentDatesRequest) (DocumentDates, error) {
+	var dates DocumentDates
+	var err error
+	if dates.ValidFrom, err = parseOptionalDate("valid_from", req.ValidFrom); err != nil {
+		return dates, err
+	}
+	if dates.ReviewBy, err = parseOptionalDate("review_by", req.ReviewBy); err != nil {
+		return dates, err
+	}
+	if dates.ExpiresAt, err = parseOptionalDate("expires_at", req.ExpiresAt); err != nil {
+		return dates, err
+	}
+	return dates, validateDates(dates)
+}
+
+func documentDatesFromForm(ctx *gin.Context) (DocumentDates, error) {
+	return parseDocumentDates(DocumentDatesRequest{
+		ValidFrom: ctx.PostForm("valid_from"),
+		ReviewBy:  ctx.PostForm("review_by"),
+		ExpiresAt: ctx.PostForm("expires_at"),
+	})
+}
+
+// metadataFromForm reads the optional "metadata" (a JSON object) and "tags"
+// (repeated or comma separated) form fields. It reports whether either was
+// sent.
+func metadataFromForm(ctx *gin.Context) (MetadataRequest, bool, error) {
+	var req MetadataRequest
+	raw, hasMetadata := ctx.GetPostForm("metadata")
+	if hasMetadata && strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &req.Metadata); err != nil {
+			return req, false, fmt.Errorf("metadata must be a JSON object")
+		}
+	}
+
+	values, hasTags := ctx.GetPostFormArray("tags")
+	for _, value := range values {
+		req.Tags = append(req.Tags, strings.Split(value, ",")...)
+	}
+	return req, hasMetadata || hasTags, nil
+}
+
+func (h *DocumentHandler) screeningError(ctx *gin.Context, err error) {
+	var infectedErr *InfectedFileError
+	switch {
+	case errors.As(err, &infectedErr):
+		util.ErrorResponse(ctx, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, scanner.ErrContentMismatch):
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrScanUnavailable):
+		util.ErrorResponse(ctx, http.StatusServiceUnavailable, err.Error())
+	default:
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func screeningFailure(filename string, err error) map[string]string {
+	failure := map[string]string{
+		"filename": filename,
+		"reason":   err.Error(),
+	}
+	var infectedErr *InfectedFileError
+	if errors.As(err, &infectedErr) {
+		failure["scan_status"] = scanner.StatusInfected
+		failure["scan_signature"] = infectedErr.Signature
+	}
+	return failure
+}
+
+func (h *DocumentHandler) metadataError(ctx *gin.Context, err error) {
+	if errors.Is(err, metadata.ErrInvalidMetadata) {
+		util.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+}
+
+func tagsFromQuery(ctx *gin.Context) []string {
+	var tags []string
+	for _, value := range ctx.QueryArray("tags") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// metadataFiltersFromQuery reads meta[<key>]=v1,v2 parameters. Values of one
+// key are alternatives; different keys must all match.
+func metadataFiltersFromQuery(ctx *gin.Context) map[string][]string {
+	filters := make(map[string][]string)
+	for key, value := range ctx.QueryMap("meta") {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				filters[key] = append(filters[key], item)
+			}
+		}
+	}
+	return filters
+}
+
+func expiryWindowFromQuery(ctx *gin.Context) int {
+	days, err := strconv.Atoi(ctx.Query("expiring_within_days"))
+	if err != nil || days <= 0 {
+		return expiringSoonDays()
+	}
+	return days
 }
 
 type BatchDeleteRequest struct {
@@ -806,7 +2447,7 @@ func (h *DocumentHandler) BatchDeleteDocument(c *gin.Context) {
 		return
 	}
 
-	successCount, errors := h.service.BatchDeleteDocuments(req.IDs)
+	successCount, errors := h.service.BatchDeleteDocuments(req.IDs, audit.ActorFromContext(c))
 
 	if len(errors) > 0 && successCount == 0 {
 		util.ErrorResponse(c, http.StatusInternalServerError, "Failed to request delete for all selected documents")
@@ -834,7 +2475,7 @@ func (h *DocumentHandler) GenerateViewURLByDocumentID(ctx *gin.Context) {
         return
     }
 
-    token, err := h.service.GenerateViewTokenByDocumentID(req.DocumentID)
+    token, err := h.service.GenerateViewTokenByDocumentID(req.DocumentID, accessEvent(ctx, accessSourceDirect))
     if err != nil {
         util.ErrorResponse(ctx, http.StatusNotFound, err.Error())
         return
@@ -903,6 +2544,7 @@ func (h *DocumentHandler) CrawlerBatchUpload(c *gin.Context) {
 	replacedCount := 0
 	skippedCount := 0
 	errorCount := 0
+	quarantinedCount := 0
 
 	for _, r := range results {
 		switch r.Status {
@@ -914,6 +2556,8 @@ func (h *DocumentHandler) CrawlerBatchUpload(c *gin.Context) {
 			skippedCount++
 		case "Error":
 			errorCount++
+		case "Quarantined":
+			quarantinedCount++
 		}
 	}
 
@@ -921,10 +2565,11 @@ func (h *DocumentHandler) CrawlerBatchUpload(c *gin.Context) {
 		"status":  "success",
 		"message": fmt.Sprintf("Processed %d files", len(files)),
 		"summary": gin.H{
-			"uploaded": successCount,
-			"replaced": replacedCount,
-			"skipped":  skippedCount,
-			"errors":   errorCount,
+			"uploaded":    successCount,
+			"replaced":    replacedCount,
+			"skipped":     skippedCount,
+			"errors":      errorCount,
+			"quarantined": quarantinedCount,
 		},
 		"details": results,
 	})
@@ -932,7 +2577,8 @@ func (h *DocumentHandler) CrawlerBatchUpload(c *gin.Context) {
 
 func (h *DocumentHandler) CheckDuplicates(ctx *gin.Context) {
 	var req struct {
-		Filenames []string `json:"filenames" binding:"required"`
+		Filenames []string `json:"filenames"`
+		Hashes    []string `json:"hashes"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -940,14 +2586,26 @@ func (h *DocumentHandler) CheckDuplicates(ctx *gin.Context) {
 		return
 	}
 
+	if len(req.Filenames) == 0 && len(req.Hashes) == 0 {
+		util.ErrorResponse(ctx, http.StatusBadRequest, "filenames or hashes is required")
+		return
+	}
+
 	duplicates, err := h.service.CheckDuplicates(req.Filenames)
 	if err != nil {
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	hashDuplicates, err := h.service.CheckDuplicateHashes(req.Hashes)
+	if err != nil {
+		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	util.SuccessResponse(ctx, "Scan completed", gin.H{
-		"duplicates": duplicates,
+		"duplicates":      duplicates,
+		"hash_duplicates": hashDuplicates,
 	})
 }
 
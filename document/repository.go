@@ -1,9 +1,16 @@
 package document
 
 import (
+	"database/sql"
+	"dokuprime-be/external"
+	"dokuprime-be/metadata"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
@@ -17,16 +24,16 @@ func NewDocumentRepository(db *sqlx.DB) *DocumentRepository {
 }
 
 func (r *DocumentRepository) CreateDocument(document *Document) error {
-	query := `INSERT INTO documents (category) VALUES ($1) RETURNING id`
-	return r.db.QueryRow(query, document.Category).Scan(&document.ID)
+	query := `INSERT INTO documents (category, metadata, tags) VALUES ($1, $2, COALESCE($3::text[], '{}')) RETURNING id`
+	return r.db.QueryRow(query, document.Category, document.Metadata, document.Tags).Scan(&document.ID)
 }
 
 func (r *DocumentRepository) CreateDocumentDetail(detail *DocumentDetail) error {
 
 	query := `
 		INSERT INTO document_details 
-		(document_id, document_name, filename, data_type, staff, team, status, is_latest, is_approve, created_at, ingest_status, request_type, requested_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), $10, $11, NOW()) 
+		(document_id, document_name, filename, data_type, staff, team, status, is_latest, is_approve, created_at, ingest_status, request_type, requested_at, content_hash, workflow_id, current_step, resubmitted_from, valid_from, review_by, expires_at, mime_type, scan_status, scan_signature, scanned_at, source_url, fetched_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), $10, $11, NOW(), $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24) 
 		RETURNING id, created_at, requested_at
 	`
 
@@ -48,6 +55,19 @@ func (r *DocumentRepository) CreateDocumentDetail(detail *DocumentDetail) error
 		detail.IsApprove,
 		detail.IngestStatus,
 		reqType,
+		detail.ContentHash,
+		detail.WorkflowID,
+		detail.CurrentStep,
+		detail.ResubmittedFrom,
+		detail.ValidFrom,
+		detail.ReviewBy,
+		detail.ExpiresAt,
+		detail.MimeType,
+		detail.ScanStatus,
+		detail.ScanSignature,
+		detail.ScannedAt,
+		detail.SourceURL,
+		detail.FetchedAt,
 	).Scan(&detail.ID, &detail.CreatedAt, &detail.RequestedAt)
 }
 
@@ -68,11 +88,20 @@ func (r *DocumentRepository) GetAllDocuments(filter DocumentFilter) ([]DocumentW
 			dd.is_approve AS is_approve,
 			dd.created_at AS created_at,
 			dd.ingest_status AS ingest_status,
+			dd.ingest_error AS ingest_error,
+			dd.ingest_attempts AS ingest_attempts,
 			dd.request_type AS request_type,
-			dd.requested_at AS requested_at
+			dd.requested_at AS requested_at,
+			dd.valid_from AS valid_from,
+			dd.review_by AS review_by,
+			dd.expires_at AS expires_at,
+			dd.expired_at AS expired_at,
+			d.metadata AS metadata,
+			d.tags AS tags,
+			d.legal_hold AS legal_hold
 		FROM documents d
 		INNER JOIN document_details dd ON d.id = dd.document_id
-		WHERE dd.is_latest = true
+		WHERE dd.is_latest = true AND d.deleted_at IS NULL
 	`
 	query := base
 	if len(conditions) > 0 {
@@ -135,6 +164,26 @@ func (r *DocumentRepository) buildDocumentFilters(filter DocumentFilter) ([]stri
 		}
 	}
 
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, "d.tags @> $"+fmt.Sprint(argIndex))
+		args = append(args, pq.Array(filter.Tags))
+		argIndex++
+	}
+
+	for _, key := range sortedKeys(filter.Metadata) {
+		keyPlaceholder := "$" + fmt.Sprint(argIndex) + "::text"
+		valuesPlaceholder := "$" + fmt.Sprint(argIndex+1) + "::text[]"
+		conditions = append(conditions, "(d.metadata ->> "+keyPlaceholder+" = ANY("+valuesPlaceholder+") OR jsonb_exists_any(d.metadata -> "+keyPlaceholder+", "+valuesPlaceholder+"))")
+		args = append(args, key, pq.Array(filter.Metadata[key]))
+		argIndex += 2
+	}
+
+	if condition, expiryArgs := expiryCondition(filter.Expiry, filter.ExpiryWindow, argIndex); condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, expiryArgs...)
+		argIndex += len(expiryArgs)
+	}
+
 	if filter.StartDate != nil {
 		conditions = append(conditions, "dd.created_at >= $"+fmt.Sprint(argIndex))
 		args = append(args, *filter.StartDate)
@@ -149,6 +198,117 @@ func (r *DocumentRepository) buildDocumentFilters(filter DocumentFilter) ([]stri
 	return conditions, args, argIndex
 }
 
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetDocumentFacets counts tags and the values of the given metadata keys
+// over the documents matching filter, ignoring pagination.
+func (r *DocumentRepository) GetDocumentFacets(filter DocumentFilter, metadataKeys []string) (*metadata.Facets, error) {
+	conditions, args, argIndex := r.buildDocumentFilters(filter)
+	where := "WHERE dd.is_latest = true AND d.deleted_at IS NULL"
+	if len(conditions) > 0 {
+		where += " AND " + strings.Join(conditions, " AND ")
+	}
+
+	facets := &metadata.Facets{Tags: []metadata.FacetValue{}, Metadata: map[string][]metadata.FacetValue{}}
+
+	tagQuery := `
+		SELECT t.value, COUNT(*) AS count
+		FROM documents d
+		INNER JOIN document_details dd ON d.id = dd.document_id
+		CROSS JOIN LATERAL unnest(d.tags) AS t(value)
+		` + where + `
+		GROUP BY t.value
+		ORDER BY count DESC, t.value
+		LIMIT 50
+	`
+	if err := r.db.Select(&facets.Tags, tagQuery, args...); err != nil {
+		return nil, err
+	}
+
+	if len(metadataKeys) == 0 {
+		return facets, nil
+	}
+
+	var rows []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+		Count int    `db:"count"`
+	}
+	metadataQuery := `
+		SELECT m.key, v.value, COUNT(*) AS count
+		FROM documents d
+		INNER JOIN document_details dd ON d.id = dd.document_id
+		CROSS JOIN LATERAL jsonb_each(d.metadata) AS m(key, val)
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(m.val) = 'array' THEN m.val ELSE jsonb_build_array(m.val) END
+		) AS v(value)
+		` + where + ` AND m.key = ANY($` + fmt.Sprint(argIndex) + `)
+		GROUP BY m.key, v.value
+		ORDER BY m.key, count DESC, v.value
+	`
+	if err := r.db.Select(&rows, metadataQuery, append(args, pq.Array(metadataKeys))...); err != nil {
+		return nil, err
+	}
+	for _, key := range metadataKeys {
+		facets.Metadata[key] = []metadata.FacetValue{}
+	}
+	for _, row := range rows {
+		facets.Metadata[row.Key] = append(facets.Metadata[row.Key], metadata.FacetValue{Value: row.Value, Count: row.Count})
+	}
+	return facets, nil
+}
+
+func (r *DocumentRepository) UpdateDocumentMetadata(id int, values metadata.Values, tags []string) error {
+	query := `UPDATE documents SET metadata = $1, tags = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, values, pq.Array(tags), id)
+	return err
+}
+
+const documentColumns = `
+	id, category, metadata, tags, deleted_at, deleted_by, purge_after,
+	legal_hold, legal_hold_reason, legal_hold_by, legal_hold_at
+`
+
+const prefixedDocumentColumns = `
+	d.id, d.category, d.metadata, d.tags, d.deleted_at, d.deleted_by, d.purge_after,
+	d.legal_hold, d.legal_hold_reason, d.legal_hold_by, d.legal_hold_at
+`
+
+func (r *DocumentRepository) GetDocumentByDetailID(detailID int) (*Document, error) {
+	var document Document
+	query := `
+		SELECT ` + prefixedDocumentColumns + `
+		FROM documents d
+		INNER JOIN document_details dd ON dd.document_id = d.id
+		WHERE dd.id = $1
+	`
+	if err := r.db.Get(&document, query, detailID); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// expiryCondition filters on the validity window: "expiring_soon" keeps
+// versions whose expires_at falls within the next windowDays days, "expired"
+// keeps versions already taken down or past their expiry.
+func expiryCondition(expiry string, windowDays, argIndex int) (string, []interface{}) {
+	switch expiry {
+	case ExpiryExpiringSoon:
+		condition := "(dd.expired_at IS NULL AND dd.expires_at > NOW() AND dd.expires_at <= NOW() + make_interval(days => $" + fmt.Sprint(argIndex) + "))"
+		return condition, []interface{}{windowDays}
+	case ExpiryExpired:
+		return "(dd.expired_at IS NOT NULL OR dd.expires_at <= NOW())", nil
+	}
+	return "", nil
+}
+
 func (r *DocumentRepository) buildSortClause(filter DocumentFilter) string {
 	allowedSort := map[string]bool{"dd.created_at": true, "dd.document_name": true, "dd.staff": true}
 	sortBy := "dd.created_at"
@@ -185,59 +345,10 @@ func (r *DocumentRepository) GetTotalDocuments(filter DocumentFilter) (int, erro
 		SELECT COUNT(*)
 		FROM documents d
 		INNER JOIN document_details dd ON d.id = dd.document_id
-		WHERE dd.is_latest = true
+		WHERE dd.is_latest = true AND d.deleted_at IS NULL
 	`
 
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	if filter.Search != "" {
-		placeholder := "$" + fmt.Sprint(argIndex)
-		conditions = append(conditions, "(dd.document_name ILIKE "+placeholder+" OR dd.staff ILIKE "+placeholder+" OR dd.team ILIKE "+placeholder+")")
-		args = append(args, "%"+filter.Search+"%")
-		argIndex++
-	}
-
-	if filter.DataType != "" {
-		conditions = append(conditions, "dd.data_type = $"+fmt.Sprint(argIndex))
-		args = append(args, filter.DataType)
-		argIndex++
-	}
-
-	if filter.Category != "" {
-		conditions = append(conditions, "d.category = $"+fmt.Sprint(argIndex))
-		args = append(args, filter.Category)
-		argIndex++
-	}
-
-	if filter.Status != "" {
-		conditions = append(conditions, "dd.status = $"+fmt.Sprint(argIndex))
-		args = append(args, filter.Status)
-		argIndex++
-	}
-
-	if filter.IngestStatus != "" {
-		if filter.IngestStatus == "null" {
-			conditions = append(conditions, "dd.ingest_status IS NULL")
-		} else {
-			conditions = append(conditions, "dd.ingest_status = $"+fmt.Sprint(argIndex))
-			args = append(args, filter.IngestStatus)
-			argIndex++
-		}
-	}
-
-	if filter.StartDate != nil {
-		conditions = append(conditions, "dd.created_at >= $"+fmt.Sprint(argIndex))
-		args = append(args, *filter.StartDate)
-		argIndex++
-	}
-	if filter.EndDate != nil {
-		conditions = append(conditions, "dd.created_at <= $"+fmt.Sprint(argIndex))
-		args = append(args, *filter.EndDate)
-		argIndex++
-	}
-
+	conditions, args, _ := r.buildDocumentFilters(filter)
 	query := base
 	if len(conditions) > 0 {
 		query += " AND " + strings.Join(conditions, " AND ")
@@ -250,7 +361,7 @@ func (r *DocumentRepository) GetTotalDocuments(filter DocumentFilter) (int, erro
 
 func (r *DocumentRepository) GetDocumentByID(id int) (*Document, error) {
 	var document Document
-	err := r.db.Get(&document, `SELECT id, category FROM documents WHERE id = $1`, id)
+	err := r.db.Get(&document, `SELECT `+documentColumns+` FROM documents WHERE id = $1`, id)
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +374,12 @@ func (r *DocumentRepository) GetDocumentDetailsByDocumentID(documentID int) ([]D
 		SELECT 
 			id, document_id, document_name, filename, data_type, staff, team, 
 			status, is_latest, is_approve, created_at, ingest_status,
-			request_type, requested_at
+			ingest_error, ingest_attempts, content_hash, request_type, requested_at,
+			approved_by, approved_at, workflow_id, current_step,
+			rejection_reason, rejection_codes, resubmitted_from,
+			valid_from, review_by, expires_at, review_notified_at, expired_at,
+			mime_type, scan_status, scan_signature, scanned_at,
+			source_url, fetched_at
 		FROM document_details
 		WHERE document_id = $1
 		ORDER BY created_at DESC
@@ -293,7 +409,12 @@ func (r *DocumentRepository) GetDocumentDetailByID(id int) (*DocumentDetail, err
 		SELECT 
 			id, document_id, document_name, filename, data_type, staff, team, 
 			status, is_latest, is_approve, created_at, ingest_status,
-			request_type, requested_at
+			ingest_error, ingest_attempts, content_hash, request_type, requested_at,
+			approved_by, approved_at, workflow_id, current_step,
+			rejection_reason, rejection_codes, resubmitted_from,
+			valid_from, review_by, expires_at, review_notified_at, expired_at,
+			mime_type, scan_status, scan_signature, scanned_at,
+			source_url, fetched_at
 		FROM document_details
 		WHERE id = $1
 	`
@@ -304,6 +425,20 @@ func (r *DocumentRepository) GetDocumentDetailByID(id int) (*DocumentDetail, err
 	return &detail, nil
 }
 
+func (r *DocumentRepository) GetDocumentDetailByFilename(filename string) (*DocumentDetail, error) {
+	var detail DocumentDetail
+	query := `
+		SELECT id, document_id, document_name, filename, data_type, mime_type
+		FROM document_details
+		WHERE filename = $1
+		LIMIT 1
+	`
+	if err := r.db.Get(&detail, query, filename); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
 func (r *DocumentRepository) UpdateDocumentDetailApprove(id int, isApprove bool) error {
 	query := `UPDATE document_details SET is_approve = $1 WHERE id = $2`
 	_, err := r.db.Exec(query, isApprove, id)
@@ -316,6 +451,160 @@ func (r *DocumentRepository) UpdateAllDocumentDetailsApprove(documentID int, isA
 	return err
 }
 
+func (r *DocumentRepository) UpdateDocumentDetailRejection(id int, reason string, codes []string) error {
+	query := `UPDATE document_details SET rejection_reason = $1, rejection_codes = $2 WHERE id = $3`
+	_, err := r.db.Exec(query, reason, pq.Array(codes), id)
+	return err
+}
+
+func (r *DocumentRepository) GetResubmissionOf(detailID int) (*DocumentDetail, error) {
+	var detail DocumentDetail
+	query := `SELECT id, document_id, status FROM document_details WHERE resubmitted_from = $1 LIMIT 1`
+	err := r.db.Get(&detail, query, detailID)
+	if err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func (r *DocumentRepository) CreateReviewComment(comment *ReviewComment) error {
+	query := `
+		INSERT INTO document_review_comments (detail_id, author_user_id, kind, comment, reason_codes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		comment.DetailID,
+		comment.AuthorUserID,
+		comment.Kind,
+		comment.Comment,
+		comment.ReasonCodes,
+	).Scan(&comment.ID, &comment.CreatedAt)
+}
+
+// GetReviewComments returns the thread of a version together with the
+// threads of the rejected versions it was resubmitted from.
+func (r *DocumentRepository) GetReviewComments(detailID int) ([]ReviewComment, error) {
+	comments := []ReviewComment{}
+	query := `
+		WITH RECURSIVE chain AS (
+			SELECT id, resubmitted_from, 0 AS depth FROM document_details WHERE id = $1
+			UNION ALL
+			SELECT dd.id, dd.resubmitted_from, chain.depth + 1
+			FROM document_details dd
+			JOIN chain ON dd.id = chain.resubmitted_from
+			WHERE chain.depth < 50
+		)
+		SELECT 
+			c.id, c.detail_id, c.author_user_id, u.name AS author_name,
+			c.kind, c.comment, c.reason_codes, c.created_at
+		FROM document_review_comments c
+		JOIN chain ON chain.id = c.detail_id
+		LEFT JOIN users u ON u.id = c.author_user_id
+		ORDER BY c.created_at, c.id
+	`
+	err := r.db.Select(&comments, query, detailID)
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *DocumentRepository) UpdateDocumentDetailStep(id int, step *int) error {
+	query := `UPDATE document_details SET current_step = $1 WHERE id = $2`
+	_, err := r.db.Exec(query, step, id)
+	return err
+}
+
+func (r *DocumentRepository) UpdateDocumentDetailApprover(id int, approvedBy *int64) error {
+	query := `UPDATE document_details SET approved_by = $1, approved_at = NOW(), expired_at = NULL WHERE id = $2`
+	_, err := r.db.Exec(query, approvedBy, id)
+	return err
+}
+
+// PrepareRollback turns a superseded version back into a pending update so it
+// can go through the normal approval path again. The version was reviewed
+// before, so it skips any multi-step workflow; only masters may roll back.
+func (r *DocumentRepository) PrepareRollback(id int) error {
+	query := `
+		UPDATE document_details 
+		SET status = 'Pending', request_type = 'UPDATE', requested_at = NOW(),
+			workflow_id = NULL, current_step = NULL
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// RevertRollback puts back the columns of a version that PrepareRollback and
+// a failed approval changed, as they were in detail.
+func (r *DocumentRepository) RevertRollback(detail *DocumentDetail) error {
+	query := `
+		UPDATE document_details
+		SET status = $1, request_type = $2, requested_at = $3,
+			workflow_id = $4, current_step = $5,
+			approved_by = $6, approved_at = $7, expired_at = $8,
+			is_approve = $9, is_latest = $10
+		WHERE id = $11
+	`
+	_, err := r.db.Exec(query, detail.Status, detail.RequestType, detail.RequestedAt,
+		detail.WorkflowID, detail.CurrentStep,
+		detail.ApprovedBy, detail.ApprovedAt, detail.ExpiredAt,
+		detail.IsApprove, detail.IsLatest, detail.ID)
+	return err
+}
+
+func (r *DocumentRepository) GetDocumentVersions(documentID int) ([]DocumentVersion, error) {
+	versions := []DocumentVersion{}
+	query := `
+		SELECT * FROM (
+			SELECT 
+				ROW_NUMBER() OVER (ORDER BY dd.created_at, dd.id) AS version,
+				dd.id, dd.document_id, dd.document_name, dd.data_type, dd.staff, dd.team,
+				dd.created_at, dd.request_type, dd.requested_at, dd.status,
+				dd.is_latest, dd.is_approve,
+				COALESCE(dd.is_latest AND dd.is_approve, false) AS is_active,
+				dd.ingest_status, dd.content_hash,
+				dd.approved_by, u.name AS approver_name, dd.approved_at
+			FROM document_details dd
+			LEFT JOIN users u ON u.id = dd.approved_by
+			WHERE dd.document_id = $1
+		) v
+		ORDER BY version DESC
+	`
+	err := r.db.Select(&versions, query, documentID)
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *DocumentRepository) GetDocumentVersion(detailID int) (*DocumentVersion, error) {
+	var version DocumentVersion
+	query := `
+		SELECT * FROM (
+			SELECT 
+				ROW_NUMBER() OVER (ORDER BY dd.created_at, dd.id) AS version,
+				dd.id, dd.document_id, dd.document_name, dd.data_type, dd.staff, dd.team,
+				dd.created_at, dd.request_type, dd.requested_at, dd.status,
+				dd.is_latest, dd.is_approve,
+				COALESCE(dd.is_latest AND dd.is_approve, false) AS is_active,
+				dd.ingest_status, dd.content_hash,
+				dd.approved_by, u.name AS approver_name, dd.approved_at
+			FROM document_details dd
+			LEFT JOIN users u ON u.id = dd.approved_by
+			WHERE dd.document_id = (SELECT document_id FROM document_details WHERE id = $1)
+		) v
+		WHERE id = $1
+	`
+	err := r.db.Get(&version, query, detailID)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
 func (r *DocumentRepository) UpdateDocumentDetailStatus(id int, status string) error {
 	query := `UPDATE document_details SET status = $1 WHERE id = $2`
 	_, err := r.db.Exec(query, status, id)
@@ -331,11 +620,30 @@ func (r *DocumentRepository) GetAllDocumentDetails(filter DocumentDetailFilter)
 			dd.staff, dd.team, dd.status, dd.is_latest, dd.is_approve, dd.created_at,
 			d.category,
 			dd.ingest_status,
+			dd.ingest_error,
+			dd.ingest_attempts,
+			dd.content_hash,
 			dd.request_type,
-			dd.requested_at
+			dd.requested_at,
+			dd.workflow_id,
+			dd.current_step,
+			dd.rejection_reason,
+			dd.rejection_codes,
+			dd.resubmitted_from,
+			dd.valid_from,
+			dd.review_by,
+			dd.expires_at,
+			dd.review_notified_at,
+			dd.expired_at,
+			dd.mime_type,
+			dd.scan_status,
+			dd.scan_signature,
+			dd.scanned_at,
+			dd.source_url,
+			dd.fetched_at
 		FROM document_details dd
 		INNER JOIN documents d ON dd.document_id = d.id
-		WHERE 1=1
+		WHERE d.deleted_at IS NULL
 	`
 
 	query := base
@@ -401,6 +709,12 @@ func (r *DocumentRepository) buildDocumentDetailFilters(filter DocumentDetailFil
 		argIndex++
 	}
 
+	if condition, expiryArgs := expiryCondition(filter.Expiry, filter.ExpiryWindow, argIndex); condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, expiryArgs...)
+		argIndex += len(expiryArgs)
+	}
+
 	if filter.StartDate != nil {
 		conditions = append(conditions, "dd.requested_at >= $"+fmt.Sprint(argIndex))
 		args = append(args, *filter.StartDate)
@@ -441,7 +755,7 @@ func (r *DocumentRepository) GetTotalDocumentDetails(filter DocumentDetailFilter
 		SELECT COUNT(*)
 		FROM document_details dd
 		INNER JOIN documents d ON dd.document_id = d.id
-		WHERE 1=1
+		WHERE d.deleted_at IS NULL
 	`
 
 	var conditions []string
@@ -479,6 +793,12 @@ func (r *DocumentRepository) GetTotalDocumentDetails(filter DocumentDetailFilter
 		argIndex++
 	}
 
+	if condition, expiryArgs := expiryCondition(filter.Expiry, filter.ExpiryWindow, argIndex); condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, expiryArgs...)
+		argIndex += len(expiryArgs)
+	}
+
 	if filter.StartDate != nil {
 		conditions = append(conditions, "dd.created_at >= $"+fmt.Sprint(argIndex))
 		args = append(args, *filter.StartDate)
@@ -534,12 +854,19 @@ func (r *DocumentRepository) UpdateDocumentDetailIngestStatus(id int, status str
 	return err
 }
 
+func (r *DocumentRepository) UpdateDocumentDetailIngestResult(id int, status string, attempts int, ingestError *string) error {
+	query := `UPDATE document_details SET ingest_status = $1, ingest_attempts = $2, ingest_error = $3 WHERE id = $4`
+	_, err := r.db.Exec(query, status, attempts, ingestError, id)
+	return err
+}
+
 func (r *DocumentRepository) GetApprovedLatestDocumentDetailByDocumentID(documentID int) (*DocumentDetail, error) {
 	var detail DocumentDetail
 	query := `
 		SELECT 
 			id, document_id, document_name, filename, data_type, staff, team, 
-			status, is_latest, is_approve, created_at, ingest_status
+			status, is_latest, is_approve, created_at, ingest_status,
+			request_type, requested_at
 		FROM document_details
 		WHERE document_id = $1 AND is_latest = true AND is_approve = true
 		LIMIT 1
@@ -559,7 +886,7 @@ func (r *DocumentRepository) GetLatestDetailByDocumentName(docName string) (*Doc
 			id, document_id, document_name, filename, data_type, staff, team, 
 			status, is_latest, is_approve, created_at, ingest_status
 		FROM document_details
-		WHERE document_name = $1 AND is_latest = true
+		WHERE document_name = $1 AND is_latest = true AND ` + liveDocumentCondition + `
 		LIMIT 1
 	`
 	err := r.db.Get(&detail, query, docName)
@@ -580,7 +907,7 @@ func (r *DocumentRepository) CheckDuplicationFileByDocumentName(docName string)
 	query := `
 		SELECT id, document_name 
 		FROM document_details
-		WHERE document_name = $1 AND is_latest = true
+		WHERE document_name = $1 AND is_latest = true AND ` + liveDocumentCondition + `
 		LIMIT 1
 	`
 	err := r.db.Get(&detail, query, docName)
@@ -590,13 +917,61 @@ func (r *DocumentRepository) CheckDuplicationFileByDocumentName(docName string)
 	return &detail, nil
 }
 
+// liveDocumentCondition keeps versions of documents outside the recycle bin,
+// so a deleted document does not block uploads of the same name or content.
+const liveDocumentCondition = `document_id IN (SELECT id FROM documents WHERE deleted_at IS NULL)`
+
+// activeContentHashCondition matches the current version of a document or a
+// change still waiting for review or for its valid_from; rejected uploads and
+// superseded versions do not count as duplicates.
+const activeContentHashCondition = `
+	(is_latest = true OR status IN ('Pending', 'Scheduled')) AND COALESCE(status, '') <> 'Rejected'
+	AND ` + liveDocumentCondition + `
+`
+
+func (r *DocumentRepository) FindDetailByContentHash(hash string) (*DocumentDetail, error) {
+	var detail DocumentDetail
+
+	query := `
+		SELECT id, document_id, document_name, filename, status, content_hash
+		FROM document_details
+		WHERE content_hash = $1 AND ` + activeContentHashCondition + `
+		ORDER BY is_latest DESC, created_at DESC
+		LIMIT 1
+	`
+	err := r.db.Get(&detail, query, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func (r *DocumentRepository) CheckExistingContentHashes(hashes []string) ([]ContentHashMatch, error) {
+	matches := make([]ContentHashMatch, 0)
+
+	query := `
+		SELECT DISTINCT ON (content_hash)
+			content_hash, document_id, id AS detail_id, document_name, status
+		FROM document_details
+		WHERE content_hash = ANY($1) AND ` + activeContentHashCondition + `
+		ORDER BY content_hash, is_latest DESC, created_at DESC
+	`
+
+	err := r.db.Select(&matches, query, pq.Array(hashes))
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
 func (r *DocumentRepository) CheckExistingDocuments(names []string) ([]string, error) {
 	duplicates := make([]string, 0)
 
 	query := `
 		SELECT document_name 
 		FROM document_details 
-		WHERE document_name = ANY($1) AND is_latest = true
+		WHERE document_name = ANY($1) AND is_latest = true AND ` + liveDocumentCondition + `
 	`
 
 	err := r.db.Select(&duplicates, query, pq.Array(names))
@@ -652,3 +1027,1441 @@ func (r *DocumentRepository) RestoreStatus(id int) error {
 	_, err := r.db.Exec(query, id)
 	return err
 }
+
+// TrashDocument moves a document to the recycle bin until retentionDays have
+// passed. It returns false when the document is already there.
+func (r *DocumentRepository) TrashDocument(id int, deletedBy *int64, retentionDays int) (bool, error) {
+	query := `
+		UPDATE documents
+		SET deleted_at = NOW(), deleted_by = $2, purge_after = NOW() + make_interval(days => $3)
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(query, id, deletedBy, retentionDays)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *DocumentRepository) RestoreDocument(id int) (bool, error) {
+	query := `
+		UPDATE documents
+		SET deleted_at = NULL, deleted_by = NULL, purge_after = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *DocumentRepository) GetTrashedDocuments(search string, limit, offset int) ([]TrashedDocument, int, error) {
+	where := "WHERE d.deleted_at IS NOT NULL"
+	args := []interface{}{}
+	if search != "" {
+		where += " AND dd.document_name ILIKE $1"
+		args = append(args, "%"+search+"%")
+	}
+
+	from := `
+		FROM documents d
+		CROSS JOIN LATERAL (
+			SELECT document_name, staff, team, status
+			FROM document_details
+			WHERE document_id = d.id
+			ORDER BY is_latest DESC, created_at DESC
+			LIMIT 1
+		) dd
+		` + where
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) `+from, args...); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			d.id, d.category, dd.document_name, dd.staff, dd.team, dd.status,
+			(SELECT COUNT(*) FROM document_details v WHERE v.document_id = d.id) AS versions,
+			d.deleted_at, d.deleted_by, d.purge_after,
+			d.legal_hold, d.legal_hold_reason, d.legal_hold_at
+		` + from + `
+		ORDER BY d.deleted_at DESC
+		LIMIT $` + fmt.Sprint(len(args)+1) + ` OFFSET $` + fmt.Sprint(len(args)+2)
+
+	documents := []TrashedDocument{}
+	if err := r.db.Select(&documents, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	return documents, total, nil
+}
+
+// GetDocumentsDueForPurge returns documents whose time in the recycle bin is
+// over. Documents under legal hold are never returned.
+func (r *DocumentRepository) GetDocumentsDueForPurge(limit int) ([]int, error) {
+	query := `
+		SELECT id
+		FROM documents
+		WHERE deleted_at IS NOT NULL
+		AND purge_after <= NOW()
+		AND legal_hold = false
+		ORDER BY purge_after
+		LIMIT $1
+	`
+	ids := []int{}
+	if err := r.db.Select(&ids, query, limit); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *DocumentRepository) SetLegalHold(id int, hold bool, reason *string, by *int64) error {
+	query := `
+		UPDATE documents
+		SET legal_hold = $2,
+			legal_hold_reason = CASE WHEN $2 THEN $3::text END,
+			legal_hold_by = CASE WHEN $2 THEN $4::bigint END,
+			legal_hold_at = CASE WHEN $2 THEN NOW() END
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(query, id, hold, reason, by)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *DocumentRepository) UpdateDocumentDetailDates(id int, dates DocumentDates) error {
+	query := `
+		UPDATE document_details
+		SET
+			valid_from = $1,
+			review_by = $2,
+			expires_at = $3,
+			review_notified_at = CASE WHEN review_by IS DISTINCT FROM $2 THEN NULL ELSE review_notified_at END
+		WHERE id = $4
+	`
+	_, err := r.db.Exec(query, dates.ValidFrom, dates.ReviewBy, dates.ExpiresAt, id)
+	return err
+}
+
+// GetDetailsDueForReview returns active versions whose review_by falls within
+// the next noticeDays days and whose owner has not been told yet.
+func (r *DocumentRepository) GetDetailsDueForReview(noticeDays int) ([]DocumentDetail, error) {
+	var details []DocumentDetail
+	query := `
+		SELECT
+			dd.id, dd.document_id, dd.document_name, dd.filename, dd.data_type,
+			dd.staff, dd.team, dd.status, dd.is_latest, dd.is_approve, dd.created_at,
+			d.category, dd.review_by, dd.expires_at
+		FROM document_details dd
+		INNER JOIN documents d ON dd.document_id = d.id
+		WHERE dd.is_approve = true
+		AND d.deleted_at IS NULL
+		AND dd.expired_at IS NULL
+		AND dd.review_notified_at IS NULL
+		AND dd.review_by <= NOW() + make_interval(days => $1)
+		ORDER BY dd.review_by
+	`
+	if err := r.db.Select(&details, query, noticeDays); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+func (r *DocumentRepository) MarkReviewNotified(id int) error {
+	query := `UPDATE document_details SET review_notified_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// GetExpiredActiveDetails returns published versions whose expires_at has
+// passed. Versions still being extracted are left for the next run.
+func (r *DocumentRepository) GetExpiredActiveDetails() ([]DocumentDetail, error) {
+	var details []DocumentDetail
+	query := `
+		SELECT
+			dd.id, dd.document_id, dd.document_name, dd.filename, dd.data_type,
+			dd.staff, dd.team, dd.status, dd.is_latest, dd.is_approve, dd.created_at,
+			d.category, dd.ingest_status, dd.request_type, dd.expires_at
+		FROM document_details dd
+		INNER JOIN documents d ON dd.document_id = d.id
+		WHERE dd.is_approve = true
+		AND d.deleted_at IS NULL
+		AND dd.expired_at IS NULL
+		AND dd.expires_at <= NOW()
+		AND (dd.ingest_status IS NULL OR dd.ingest_status <> 'processing')
+		ORDER BY dd.expires_at
+	`
+	if err := r.db.Select(&details, query); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// GetScheduledDetailsDue lists the approved versions of live documents that
+// wait for a valid_from that has now passed.
+func (r *DocumentRepository) GetScheduledDetailsDue() ([]DocumentDetail, error) {
+	var details []DocumentDetail
+	query := `
+		SELECT
+			dd.id, dd.document_id, dd.document_name, dd.filename, dd.data_type,
+			dd.staff, dd.team, dd.status, dd.is_latest, dd.is_approve, dd.created_at,
+			d.category, dd.ingest_status, dd.request_type, dd.valid_from, dd.expires_at
+		FROM document_details dd
+		INNER JOIN documents d ON dd.document_id = d.id
+		WHERE dd.status = 'Scheduled'
+		AND d.deleted_at IS NULL
+		AND (dd.valid_from IS NULL OR dd.valid_from <= NOW())
+		ORDER BY dd.valid_from
+	`
+	if err := r.db.Select(&details, query); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+func (r *DocumentRepository) MarkDocumentDetailExpired(id int) error {
+	query := `
+		UPDATE document_details
+		SET status = 'Expired', is_approve = false, expired_at = NOW(), current_step = NULL
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *DocumentRepository) CreateNotification(notification *DocumentNotification) error {
+	query := `
+		INSERT INTO document_notifications (detail_id, recipient, kind, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		notification.DetailID,
+		notification.Recipient,
+		notification.Kind,
+		notification.Message,
+	).Scan(&notification.ID, &notification.CreatedAt)
+}
+
+func (r *DocumentRepository) GetNotifications(recipient string, unreadOnly bool, limit, offset int) ([]DocumentNotification, int, error) {
+	where := `WHERE n.recipient = $1`
+	if unreadOnly {
+		where += ` AND n.read_at IS NULL`
+	}
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM document_notifications n `+where, recipient); err != nil {
+		return nil, 0, err
+	}
+
+	notifications := []DocumentNotification{}
+	query := `
+		SELECT
+			n.id, n.detail_id, dd.document_id, dd.document_name, n.recipient,
+			n.kind, n.message, n.created_at, n.read_at
+		FROM document_notifications n
+		INNER JOIN document_details dd ON dd.id = n.detail_id
+		` + where + `
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	if err := r.db.Select(&notifications, query, recipient, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (r *DocumentRepository) MarkNotificationRead(id int64, recipient string) error {
+	query := `
+		UPDATE document_notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND recipient = $2
+	`
+	result, err := r.db.Exec(query, id, recipient)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *DocumentRepository) CreateQuarantinedFile(file *QuarantinedFile) error {
+	query := `
+		INSERT INTO quarantined_files (original_name, storage_key, source, uploaded_by, signature, engine, size, content_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, file.OriginalName, file.StorageKey, file.Source, file.UploadedBy,
+		file.Signature, file.Engine, file.Size, file.ContentHash).Scan(&file.ID, &file.CreatedAt)
+}
+
+func (r *DocumentRepository) GetQuarantinedFiles(limit, offset int) ([]QuarantinedFile, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM quarantined_files`); err != nil {
+		return nil, 0, err
+	}
+
+	files := []QuarantinedFile{}
+	query := `SELECT * FROM quarantined_files ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+	if err := r.db.Select(&files, query, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return files, total, nil
+}
+
+func (r *DocumentRepository) CreateResumableUpload(upload *ResumableUpload) error {
+	query := `
+		INSERT INTO resumable_uploads
+		(id, filename, data_type, category, upload_length, valid_from, review_by, expires_at,
+		 metadata, tags, staff, team, upload_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::text[], '{}'), $11, $12, $13)
+		RETURNING upload_offset, status, created_at, updated_at
+	`
+	return r.db.QueryRow(query, upload.ID, upload.Filename, upload.DataType, upload.Category, upload.UploadLength,
+		upload.ValidFrom, upload.ReviewBy, upload.ExpiresAt, upload.Metadata, upload.Tags,
+		upload.Staff, upload.Team, upload.UploadExpiresAt).
+		Scan(&upload.UploadOffset, &upload.Status, &upload.CreatedAt, &upload.UpdatedAt)
+}
+
+func (r *DocumentRepository) GetResumableUpload(id uuid.UUID) (*ResumableUpload, error) {
+	var upload ResumableUpload
+	if err := r.db.Get(&upload, `SELECT * FROM resumable_uploads WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// AppendUploadChunk records a stored chunk and moves the offset past it. It
+// fails with errOffsetConflict when another request moved the offset first.
+func (r *DocumentRepository) AppendUploadChunk(chunk UploadChunk, expiresAt time.Time) (int64, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var offset int64
+	query := `
+		UPDATE resumable_uploads
+		SET upload_offset = upload_offset + $1, upload_expires_at = $2, updated_at = NOW()
+		WHERE id = $3 AND upload_offset = $4 AND status = $5
+		RETURNING upload_offset
+	`
+	err = tx.QueryRow(query, chunk.Size, expiresAt, chunk.UploadID, chunk.ChunkOffset, uploadStatusUploading).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errOffsetConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query = `INSERT INTO resumable_upload_chunks (upload_id, chunk_offset, size, storage_key) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(query, chunk.UploadID, chunk.ChunkOffset, chunk.Size, chunk.StorageKey); err != nil {
+		return 0, err
+	}
+	return offset, tx.Commit()
+}
+
+func (r *DocumentRepository) GetUploadChunks(id uuid.UUID) ([]UploadChunk, error) {
+	chunks := []UploadChunk{}
+	query := `SELECT * FROM resumable_upload_chunks WHERE upload_id = $1 ORDER BY chunk_offset`
+	if err := r.db.Select(&chunks, query, id); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *DocumentRepository) DeleteUploadChunks(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM resumable_upload_chunks WHERE upload_id = $1`, id)
+	return err
+}
+
+// ClaimUploadForProcessing moves a fully received upload to processing. A
+// session stuck in processing after a crash can be claimed again once stale.
+func (r *DocumentRepository) ClaimUploadForProcessing(id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE resumable_uploads
+		SET status = $1, error = NULL, updated_at = NOW()
+		WHERE id = $2 AND upload_offset = upload_length
+		AND (status IN ($3, $4) OR (status = $1 AND updated_at < NOW() - INTERVAL '10 minutes'))
+	`
+	result, err := r.db.Exec(query, uploadStatusProcessing, id, uploadStatusUploading, uploadStatusFailed)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *DocumentRepository) FinishResumableUpload(id uuid.UUID, status string, uploadErr *string, documentID, detailID *int) error {
+	query := `
+		UPDATE resumable_uploads
+		SET status = $1, error = $2, document_id = $3, detail_id = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	_, err := r.db.Exec(query, status, uploadErr, documentID, detailID, id)
+	return err
+}
+
+func (r *DocumentRepository) DeleteResumableUpload(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM resumable_uploads WHERE id = $1`, id)
+	return err
+}
+
+// GetExpiredResumableUploads returns sessions past their expiry, skipping
+// ones that are being turned into documents right now.
+func (r *DocumentRepository) GetExpiredResumableUploads(limit int) ([]ResumableUpload, error) {
+	uploads := []ResumableUpload{}
+	query := `
+		SELECT * FROM resumable_uploads
+		WHERE upload_expires_at < NOW()
+		AND (status <> $1 OR updated_at < NOW() - INTERVAL '10 minutes')
+		ORDER BY upload_expires_at
+		LIMIT $2
+	`
+	if err := r.db.Select(&uploads, query, uploadStatusProcessing, limit); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// CreateUploadBatch stores a batch with its items and fills in the item IDs.
+func (r *DocumentRepository) CreateUploadBatch(batch *UploadBatch, items []UploadBatchItem) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO upload_batches (id, category, staff, team, auto_approve, status, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING started_at
+	`
+	err = tx.QueryRow(query, batch.ID, batch.Category, batch.Staff, batch.Team, batch.AutoApprove, batch.Status, batch.Total).
+		Scan(&batch.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO upload_batch_items (batch_id, position, filename, size, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, updated_at
+	`
+	for i := range items {
+		item := &items[i]
+		item.BatchID = batch.ID
+		err := tx.QueryRow(query, item.BatchID, item.Position, item.Filename, item.Size, item.Status, item.Error).
+			Scan(&item.ID, &item.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// uploadBatchQuery selects batches with their counters. Extraction counts the
+// version's current ingest status, falling back to what the worker recorded.
+const uploadBatchQuery = `
+	SELECT b.*,
+		COUNT(i.id) FILTER (WHERE i.status NOT IN ('pending', 'processing')) AS processed,
+		COUNT(i.id) FILTER (WHERE i.status = 'uploaded') AS successful,
+		COUNT(i.id) FILTER (WHERE i.status IN ('failed', 'quarantined', 'interrupted')) AS failed,
+		COUNT(i.id) FILTER (WHERE i.status = 'quarantined') AS infected,
+		COUNT(i.id) FILTER (WHERE i.status = 'cancelled') AS cancelled,
+		COUNT(i.id) FILTER (WHERE COALESCE(dd.ingest_status, i.extraction_status) = 'success') AS extracted
+	FROM upload_batches b
+	LEFT JOIN upload_batch_items i ON i.batch_id = b.id
+	LEFT JOIN document_details dd ON dd.id = i.detail_id
+`
+
+func (r *DocumentRepository) GetUploadBatch(id string) (*UploadBatch, error) {
+	var batch UploadBatch
+	query := uploadBatchQuery + ` WHERE b.id = $1 GROUP BY b.id`
+	if err := r.db.Get(&batch, query, id); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *DocumentRepository) GetUploadBatches(staff string, limit, offset int) ([]UploadBatch, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM upload_batches WHERE staff = $1`, staff); err != nil {
+		return nil, 0, err
+	}
+
+	batches := []UploadBatch{}
+	query := uploadBatchQuery + `
+		WHERE b.staff = $1
+		GROUP BY b.id
+		ORDER BY b.started_at DESC, b.id
+		LIMIT $2 OFFSET $3
+	`
+	if err := r.db.Select(&batches, query, staff, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return batches, total, nil
+}
+
+func (r *DocumentRepository) GetUploadBatchItems(batchID string) ([]UploadBatchItem, error) {
+	items := []UploadBatchItem{}
+	query := `
+		SELECT i.id, i.batch_id, i.position, i.filename, i.size, i.status, i.error,
+			i.scan_status, i.scan_signature, i.document_id, i.detail_id,
+			COALESCE(dd.ingest_status, i.extraction_status) AS extraction_status,
+			CASE WHEN dd.ingest_status IS NOT NULL THEN dd.ingest_error ELSE i.extraction_error END AS extraction_error,
+			i.updated_at
+		FROM upload_batch_items i
+		LEFT JOIN document_details dd ON dd.id = i.detail_id
+		WHERE i.batch_id = $1
+		ORDER BY i.position
+	`
+	if err := r.db.Select(&items, query, batchID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ClaimUploadBatchItem marks a pending item as being processed. It returns
+// false when the item was cancelled in the meantime.
+func (r *DocumentRepository) ClaimUploadBatchItem(id int64) (bool, error) {
+	query := `UPDATE upload_batch_items SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`
+	result, err := r.db.Exec(query, batchItemStatusProcessing, id, batchItemStatusPending)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *DocumentRepository) FinishUploadBatchItem(item *UploadBatchItem) error {
+	query := `
+		UPDATE upload_batch_items
+		SET status = $1, error = $2, scan_status = $3, scan_signature = $4, document_id = $5, detail_id = $6,
+			extraction_status = $7, extraction_error = $8, updated_at = NOW()
+		WHERE id = $9
+	`
+	_, err := r.db.Exec(query, item.Status, item.Error, item.ScanStatus, item.ScanSignature, item.DocumentID,
+		item.DetailID, item.ExtractionStatus, item.ExtractionError, item.ID)
+	return err
+}
+
+// CancelUploadBatch stops a running batch. Files that have not been picked
+// up yet are marked cancelled; files already being processed still finish.
+func (r *DocumentRepository) CancelUploadBatch(id, cancelledBy string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE upload_batches
+		SET status = $1, cancelled_at = NOW(), cancelled_by = $2
+		WHERE id = $3 AND status = $4
+	`
+	result, err := tx.Exec(query, batchStatusCancelled, cancelledBy, id, batchStatusProcessing)
+	if err != nil {
+		return false, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	query = `
+		UPDATE upload_batch_items
+		SET status = $1, error = $2, updated_at = NOW()
+		WHERE batch_id = $3 AND status = $4
+	`
+	if _, err := tx.Exec(query, batchItemStatusCancelled, "Batch cancelled by "+cancelledBy, id, batchItemStatusPending); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// CompleteUploadBatch records the end of processing. A cancelled batch keeps
+// its status.
+func (r *DocumentRepository) CompleteUploadBatch(id string) error {
+	query := `
+		UPDATE upload_batches
+		SET status = CASE WHEN status = $1 THEN $2 ELSE status END, completed_at = NOW()
+		WHERE id = $3
+	`
+	_, err := r.db.Exec(query, batchStatusProcessing, batchStatusCompleted, id)
+	return err
+}
+
+// InterruptStaleUploadBatches ends the processing batches none of whose files
+// has moved for staleAfter, and marks their unfinished files interrupted. It
+// returns the IDs of the batches it ended.
+func (r *DocumentRepository) InterruptStaleUploadBatches(staleAfter time.Duration, reason string) ([]string, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := []string{}
+	query := `
+		UPDATE upload_batches b
+		SET status = $1, completed_at = NOW()
+		WHERE b.status = $2
+		AND b.started_at < NOW() - ($3 * INTERVAL '1 second')
+		AND NOT EXISTS (
+			SELECT 1 FROM upload_batch_items i
+			WHERE i.batch_id = b.id AND i.updated_at >= NOW() - ($3 * INTERVAL '1 second')
+		)
+		RETURNING b.id
+	`
+	if err := tx.Select(&ids, query, batchStatusInterrupted, batchStatusProcessing, int(staleAfter.Seconds())); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	query = `
+		UPDATE upload_batch_items
+		SET status = $1, error = $2, updated_at = NOW()
+		WHERE batch_id = ANY($3) AND status IN ($4, $5)
+	`
+	if _, err := tx.Exec(query, batchItemStatusInterrupted, reason, pq.Array(ids),
+		batchItemStatusPending, batchItemStatusProcessing); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// GetDetailsPendingText returns versions whose text has not been indexed yet,
+// newest first, plus failed ones after a day so transient errors recover.
+func (r *DocumentRepository) GetDetailsPendingText(limit int) ([]DocumentDetail, error) {
+	var details []DocumentDetail
+	query := `
+		SELECT dd.id, dd.document_id, dd.document_name, dd.filename, dd.data_type
+		FROM document_details dd
+		LEFT JOIN document_texts t ON t.detail_id = dd.id
+		WHERE t.detail_id IS NULL
+		OR (t.status = 'failed' AND t.indexed_at < NOW() - INTERVAL '1 day')
+		ORDER BY dd.id DESC
+		LIMIT $1
+	`
+	if err := r.db.Select(&details, query, limit); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// GetDetailsMissingContentHash lists stored versions saved before content
+// hashes were recorded, in ID order after afterID.
+func (r *DocumentRepository) GetDetailsMissingContentHash(afterID, limit int) ([]DocumentDetail, error) {
+	var details []DocumentDetail
+	query := `
+		SELECT id, document_id, filename
+		FROM document_details
+		WHERE content_hash IS NULL AND COALESCE(filename, '') <> '' AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	if err := r.db.Select(&details, query, afterID, limit); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+func (r *DocumentRepository) UpdateContentHash(detailID int, hash string) error {
+	query := `UPDATE document_details SET content_hash = $1 WHERE id = $2 AND content_hash IS NULL`
+	_, err := r.db.Exec(query, hash, detailID)
+	return err
+}
+
+// SaveDocumentText replaces the indexed text of a version.
+func (r *DocumentRepository) SaveDocumentText(detailID int, status string, indexErr *string, pages []external.TextPage) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM document_text_pages WHERE detail_id = $1`, detailID); err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		var pageNumber *int
+		if page.Number > 0 {
+			number := page.Number
+			pageNumber = &number
+		}
+		query := `INSERT INTO document_text_pages (detail_id, page_number, content) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(query, detailID, pageNumber, page.Text); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO document_texts (detail_id, status, error, page_count, indexed_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (detail_id) DO UPDATE
+		SET status = EXCLUDED.status, error = EXCLUDED.error, page_count = EXCLUDED.page_count, indexed_at = NOW()
+	`
+	if _, err := tx.Exec(query, detailID, status, indexErr, len(pages)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SearchDocumentText ranks matching pages and builds highlighted snippets for
+// the requested page of results only.
+func (r *DocumentRepository) SearchDocumentText(filter SearchFilter) ([]SearchHit, int, error) {
+	conditions := []string{"p.search_vector @@ q.query", "d.deleted_at IS NULL"}
+	args := []interface{}{filter.Query}
+	argIndex := 2
+
+	if !filter.AllVersions {
+		conditions = append(conditions, "dd.is_latest = true")
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "d.category = $"+fmt.Sprint(argIndex))
+		args = append(args, filter.Category)
+		argIndex++
+	}
+	if filter.DocumentID > 0 {
+		conditions = append(conditions, "dd.document_id = $"+fmt.Sprint(argIndex))
+		args = append(args, filter.DocumentID)
+		argIndex++
+	}
+
+	from := `
+		FROM document_text_pages p
+		CROSS JOIN (
+			SELECT
+				websearch_to_tsquery('dokuprime_indonesian', $1) AS native_query,
+				websearch_to_tsquery('english', $1) AS english_query,
+				websearch_to_tsquery('dokuprime_indonesian', $1) || websearch_to_tsquery('english', $1) AS query
+		) q
+		INNER JOIN document_details dd ON dd.id = p.detail_id
+		INNER JOIN documents d ON d.id = dd.document_id
+		WHERE ` + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) `+from, args...); err != nil {
+		return nil, 0, err
+	}
+
+	// Each configuration highlights the words its own half of the query
+	// matched; the English headline is used when the Indonesian one has no
+	// match. The markers are sentinels, replaced by highlightSnippet once the
+	// text is escaped.
+	headline := `'StartSel="` + snippetStartSel + `", StopSel="` + snippetStopSel +
+		`", MaxFragments=2, MaxWords=35, MinWords=15, FragmentDelimiter=" … "'`
+	limit, offset := r.ensurePagination(filter.Limit, filter.Offset)
+	query := `
+		SELECT
+			m.document_id, m.detail_id, m.document_name, m.category, m.status, m.is_latest,
+			m.page_number, m.rank,
+			CASE WHEN strpos(h.native, '` + snippetStartSel + `') > 0 OR strpos(h.english, '` + snippetStartSel + `') = 0
+				THEN h.native ELSE h.english END AS snippet
+		FROM (
+			SELECT
+				p.id, dd.document_id, p.detail_id, dd.document_name, d.category, dd.status, dd.is_latest,
+				p.page_number, ts_rank_cd(p.search_vector, q.query) AS rank, q.native_query, q.english_query
+			` + from + `
+			ORDER BY rank DESC, p.detail_id DESC, p.page_number
+			LIMIT $` + fmt.Sprint(argIndex) + ` OFFSET $` + fmt.Sprint(argIndex+1) + `
+		) m
+		INNER JOIN document_text_pages p ON p.id = m.id
+		CROSS JOIN LATERAL (
+			SELECT
+				ts_headline('dokuprime_indonesian', p.content, m.native_query, ` + headline + `) AS native,
+				ts_headline('english', p.content, m.english_query, ` + headline + `) AS english
+		) h
+		ORDER BY m.rank DESC, m.detail_id DESC, m.page_number
+	`
+	hits := []SearchHit{}
+	if err := r.db.Select(&hits, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	for i := range hits {
+		hits[i].Snippet = highlightSnippet(hits[i].Snippet)
+	}
+	return hits, total, nil
+}
+
+const extractionJobColumns = `
+	id, detail_id, external_id, category, filename, file_path, status, attempts,
+	max_attempts, last_error, next_run_at, locked_by, locked_until, created_at, updated_at
+`
+
+func (r *DocumentRepository) EnqueueExtractionJob(job *ExtractionJobRecord) error {
+	query := `
+		INSERT INTO extraction_jobs
+		(detail_id, external_id, category, filename, file_path, status, attempts, max_attempts, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'queued', 0, $6, NOW(), NOW(), NOW())
+		RETURNING id, status, next_run_at, created_at, updated_at
+	`
+	return r.db.QueryRow(
+		query,
+		job.DetailID,
+		job.ExternalID,
+		job.Category,
+		job.Filename,
+		job.FilePath,
+		job.MaxAttempts,
+	).Scan(&job.ID, &job.Status, &job.NextRunAt, &job.CreatedAt, &job.UpdatedAt)
+}
+
+// ClaimExtractionJob takes the next due job, or a running job whose lease has
+// expired (its replica died), and leases it to workerID.
+func (r *DocumentRepository) ClaimExtractionJob(workerID string, lease time.Duration) (*ExtractionJobRecord, error) {
+	query := `
+		UPDATE extraction_jobs
+		SET status = 'running',
+			attempts = attempts + 1,
+			locked_by = $1,
+			locked_until = NOW() + ($2 * INTERVAL '1 second'),
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM extraction_jobs
+			WHERE (status IN ('queued', 'failed') AND next_run_at <= NOW())
+			OR (status = 'running' AND locked_until < NOW())
+			ORDER BY next_run_at ASC, id ASC
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + extractionJobColumns
+
+	var job ExtractionJobRecord
+	err := r.db.Get(&job, query, workerID, int(lease.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *DocumentRepository) ExtendExtractionJobLease(id int, workerID string, lease time.Duration) error {
+	query := `
+		UPDATE extraction_jobs
+		SET locked_until = NOW() + ($1 * INTERVAL '1 second'), updated_at = NOW()
+		WHERE id = $2 AND locked_by = $3 AND status = 'running'
+	`
+	_, err := r.db.Exec(query, int(lease.Seconds()), id, workerID)
+	return err
+}
+
+func (r *DocumentRepository) CompleteExtractionJob(id int, workerID string) error {
+	query := `
+		UPDATE extraction_jobs
+		SET status = 'succeeded', last_error = NULL, locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2
+	`
+	_, err := r.db.Exec(query, id, workerID)
+	return err
+}
+
+func (r *DocumentRepository) FailExtractionJob(id int, workerID, status, lastError string, nextRunAt time.Time) error {
+	query := `
+		UPDATE extraction_jobs
+		SET status = $1, last_error = $2, next_run_at = $3, locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $4 AND locked_by = $5
+	`
+	_, err := r.db.Exec(query, status, lastError, nextRunAt, id, workerID)
+	return err
+}
+
+// RequeueExtractionJobs resets failed or dead jobs of a detail so they run
+// again immediately with a fresh attempt budget.
+func (r *DocumentRepository) RequeueExtractionJobs(detailID int) (int64, error) {
+	query := `
+		UPDATE extraction_jobs
+		SET status = 'queued', attempts = 0, last_error = NULL, next_run_at = NOW(), updated_at = NOW()
+		WHERE detail_id = $1 AND status IN ('failed', 'dead')
+	`
+	result, err := r.db.Exec(query, detailID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *DocumentRepository) ReleaseExtractionJobs(workerID string) (int64, error) {
+	query := `
+		UPDATE extraction_jobs
+		SET status = 'queued', attempts = GREATEST(attempts - 1, 0), locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE locked_by = $1 AND status = 'running'
+	`
+	result, err := r.db.Exec(query, workerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *DocumentRepository) GetExtractionQueueStats() (*ExtractionQueueStats, error) {
+	var stats ExtractionQueueStats
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'queued') AS queued,
+			COUNT(*) FILTER (WHERE status = 'running') AS running,
+			COUNT(*) FILTER (WHERE status = 'failed') AS failed,
+			COUNT(*) FILTER (WHERE status = 'dead') AS dead,
+			COUNT(*) FILTER (WHERE status = 'succeeded') AS succeeded
+		FROM extraction_jobs
+	`
+	if err := r.db.Get(&stats, query); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *DocumentRepository) GetCrawlSources() ([]CrawlSource, error) {
+	sources := []CrawlSource{}
+	if err := r.db.Select(&sources, `SELECT * FROM crawl_sources ORDER BY id`); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (r *DocumentRepository) GetCrawlSource(id int) (*CrawlSource, error) {
+	var source CrawlSource
+	if err := r.db.Get(&source, `SELECT * FROM crawl_sources WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (r *DocumentRepository) CreateCrawlSource(source *CrawlSource) error {
+	query := `
+		INSERT INTO crawl_sources (name, source_type, url, link_pattern, category, schedule, is_active, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(query, source.Name, source.SourceType, source.URL, source.LinkPattern, source.Category,
+		source.Schedule, source.IsActive, source.NextRunAt, source.CreatedBy).
+		Scan(&source.ID, &source.CreatedAt, &source.UpdatedAt)
+}
+
+func (r *DocumentRepository) UpdateCrawlSource(source *CrawlSource) error {
+	query := `
+		UPDATE crawl_sources
+		SET name = $1, source_type = $2, url = $3, link_pattern = $4, category = $5, schedule = $6,
+			is_active = $7, next_run_at = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(query, source.Name, source.SourceType, source.URL, source.LinkPattern, source.Category,
+		source.Schedule, source.IsActive, source.NextRunAt, source.ID).Scan(&source.UpdatedAt)
+}
+
+func (r *DocumentRepository) DeleteCrawlSource(id int) error {
+	result, err := r.db.Exec(`DELETE FROM crawl_sources WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *DocumentRepository) GetDueCrawlSources() ([]CrawlSource, error) {
+	sources := []CrawlSource{}
+	query := `
+		SELECT * FROM crawl_sources
+		WHERE is_active = true AND next_run_at <= NOW()
+		ORDER BY next_run_at
+	`
+	if err := r.db.Select(&sources, query); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// ClaimCrawlSource moves a due source to its next run time. Only the instance
+// whose update succeeds runs the source.
+func (r *DocumentRepository) ClaimCrawlSource(source *CrawlSource, nextRunAt *time.Time) (bool, error) {
+	query := `UPDATE crawl_sources SET next_run_at = $1 WHERE id = $2 AND next_run_at = $3`
+	result, err := r.db.Exec(query, nextRunAt, source.ID, source.NextRunAt)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// StartCrawlRun records a run unless the source is already running. Runs
+// older than two hours are treated as crashed.
+func (r *DocumentRepository) StartCrawlRun(sourceID int, trigger string) (*CrawlRun, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the source serializes concurrent starts.
+	if _, err := tx.Exec(`SELECT id FROM crawl_sources WHERE id = $1 FOR UPDATE`, sourceID); err != nil {
+		return nil, err
+	}
+
+	var running bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM crawl_runs
+			WHERE source_id = $1 AND status = $2 AND started_at > NOW() - INTERVAL '2 hours'
+		)
+	`
+	if err := tx.Get(&running, query, sourceID, crawlRunStatusRunning); err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, errCrawlRunning
+	}
+
+	run := &CrawlRun{SourceID: sourceID, Trigger: trigger, Status: crawlRunStatusRunning}
+	query = `INSERT INTO crawl_runs (source_id, trigger, status) VALUES ($1, $2, $3) RETURNING id, started_at`
+	if err := tx.QueryRow(query, sourceID, trigger, run.Status).Scan(&run.ID, &run.StartedAt); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE crawl_sources SET last_run_at = $1 WHERE id = $2`, run.StartedAt, sourceID); err != nil {
+		return nil, err
+	}
+	return run, tx.Commit()
+}
+
+func (r *DocumentRepository) AddCrawlRunItem(item *CrawlRunItem) error {
+	query := `
+		INSERT INTO crawl_run_items (run_id, url, status, reason, document_id, detail_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, item.RunID, item.URL, item.Status, item.Reason, item.DocumentID, item.DetailID).
+		Scan(&item.ID, &item.CreatedAt)
+}
+
+func (r *DocumentRepository) FinishCrawlRun(id int64, status string, runErr *string) error {
+	query := `UPDATE crawl_runs SET status = $1, error = $2, finished_at = NOW() WHERE id = $3`
+	_, err := r.db.Exec(query, status, runErr, id)
+	return err
+}
+
+// PruneCrawlRuns keeps the newest keep runs of a source.
+func (r *DocumentRepository) PruneCrawlRuns(sourceID, keep int) error {
+	query := `
+		DELETE FROM crawl_runs
+		WHERE source_id = $1 AND id NOT IN (
+			SELECT id FROM crawl_runs WHERE source_id = $1 ORDER BY started_at DESC, id DESC LIMIT $2
+		)
+	`
+	_, err := r.db.Exec(query, sourceID, keep)
+	return err
+}
+
+const crawlRunQuery = `
+	SELECT r.*,
+		COUNT(i.id) AS discovered,
+		COUNT(i.id) FILTER (WHERE i.status = 'created') AS created,
+		COUNT(i.id) FILTER (WHERE i.status IN ('updated', 'replaced')) AS updated,
+		COUNT(i.id) FILTER (WHERE i.status IN ('not_modified', 'unchanged')) AS not_modified,
+		COUNT(i.id) FILTER (WHERE i.status = 'skipped') AS skipped,
+		COUNT(i.id) FILTER (WHERE i.status IN ('failed', 'quarantined')) AS failed
+	FROM crawl_runs r
+	LEFT JOIN crawl_run_items i ON i.run_id = r.id
+`
+
+func (r *DocumentRepository) GetCrawlRuns(sourceID, limit, offset int) ([]CrawlRun, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM crawl_runs WHERE source_id = $1`, sourceID); err != nil {
+		return nil, 0, err
+	}
+
+	runs := []CrawlRun{}
+	query := crawlRunQuery + `
+		WHERE r.source_id = $1
+		GROUP BY r.id
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	if err := r.db.Select(&runs, query, sourceID, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+func (r *DocumentRepository) GetCrawlRun(id int64) (*CrawlRun, error) {
+	var run CrawlRun
+	if err := r.db.Get(&run, crawlRunQuery+` WHERE r.id = $1 GROUP BY r.id`, id); err != nil {
+		return nil, err
+	}
+
+	run.Items = []CrawlRunItem{}
+	query := `SELECT * FROM crawl_run_items WHERE run_id = $1 ORDER BY id`
+	if err := r.db.Select(&run.Items, query, id); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *DocumentRepository) GetCrawledURL(sourceID int, url string) (*CrawledURL, error) {
+	var crawled CrawledURL
+	query := `SELECT * FROM crawled_urls WHERE source_id = $1 AND url = $2`
+	if err := r.db.Get(&crawled, query, sourceID, url); err != nil {
+		return nil, err
+	}
+	return &crawled, nil
+}
+
+func (r *DocumentRepository) SaveCrawledURL(crawled *CrawledURL) error {
+	query := `
+		INSERT INTO crawled_urls (source_id, url, etag, last_modified, content_hash, document_id, detail_id, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (source_id, url) DO UPDATE
+		SET etag = EXCLUDED.etag, last_modified = EXCLUDED.last_modified, content_hash = EXCLUDED.content_hash,
+			document_id = EXCLUDED.document_id, detail_id = EXCLUDED.detail_id, fetched_at = EXCLUDED.fetched_at
+	`
+	_, err := r.db.Exec(query, crawled.SourceID, crawled.URL, crawled.ETag, crawled.LastModified,
+		crawled.ContentHash, crawled.DocumentID, crawled.DetailID)
+	return err
+}
+
+// TouchCrawledURL records that a URL was checked and had not changed.
+func (r *DocumentRepository) TouchCrawledURL(sourceID int, url string, detailID *int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE crawled_urls SET fetched_at = NOW() WHERE source_id = $1 AND url = $2`
+	if _, err := tx.Exec(query, sourceID, url); err != nil {
+		return err
+	}
+	if detailID != nil {
+		if _, err := tx.Exec(`UPDATE document_details SET fetched_at = NOW() WHERE id = $1`, *detailID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const shareLinkQuery = `
+	SELECT
+		l.id, l.document_id, l.detail_id, l.token_prefix, l.created_by, l.expires_at,
+		l.max_uses, l.use_count, l.require_login, l.allowed_teams, l.revoked_at,
+		l.revoked_by, l.last_used_at, l.created_at,
+		COALESCE((
+			SELECT dd.document_name FROM document_details dd
+			WHERE dd.document_id = l.document_id
+			ORDER BY dd.is_latest DESC, dd.created_at DESC
+			LIMIT 1
+		), '') AS document_name
+	FROM document_share_links l
+`
+
+func (r *DocumentRepository) CreateShareLink(link *ShareLink, tokenHash string) error {
+	query := `
+		INSERT INTO document_share_links
+			(token_hash, token_prefix, document_id, detail_id, created_by, expires_at, max_uses, require_login, allowed_teams)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::text[], '{}'))
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, tokenHash, link.TokenPrefix, link.DocumentID, link.DetailID, link.CreatedBy,
+		link.ExpiresAt, link.MaxUses, link.RequireLogin, link.AllowedTeams).Scan(&link.ID, &link.CreatedAt)
+}
+
+func (r *DocumentRepository) GetShareLink(id int64) (*ShareLink, error) {
+	var link ShareLink
+	if err := r.db.Get(&link, shareLinkQuery+` WHERE l.id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *DocumentRepository) GetShareLinkByTokenHash(tokenHash string) (*ShareLink, error) {
+	var link ShareLink
+	if err := r.db.Get(&link, shareLinkQuery+` WHERE l.token_hash = $1`, tokenHash); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetShareLinks lists links newest first. A zero documentID or empty
+// createdBy does not filter.
+func (r *DocumentRepository) GetShareLinks(documentID int, createdBy string, limit, offset int) ([]ShareLink, int, error) {
+	where := `WHERE ($1 = 0 OR l.document_id = $1) AND ($2 = '' OR l.created_by = $2)`
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM document_share_links l `+where, documentID, createdBy); err != nil {
+		return nil, 0, err
+	}
+
+	links := []ShareLink{}
+	query := shareLinkQuery + where + ` ORDER BY l.created_at DESC LIMIT $3 OFFSET $4`
+	if err := r.db.Select(&links, query, documentID, createdBy, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return links, total, nil
+}
+
+// UseShareLink counts one use of a link. It returns false when the link is
+// revoked, expired or has no uses left.
+func (r *DocumentRepository) UseShareLink(id int64) (bool, error) {
+	query := `
+		UPDATE document_share_links
+		SET use_count = use_count + 1, last_used_at = NOW()
+		WHERE id = $1
+		AND revoked_at IS NULL
+		AND expires_at > NOW()
+		AND (max_uses IS NULL OR use_count < max_uses)
+	`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *DocumentRepository) RevokeShareLink(id int64, revokedBy string) (bool, error) {
+	query := `UPDATE document_share_links SET revoked_at = NOW(), revoked_by = $2 WHERE id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, id, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *DocumentRepository) RecordShareLinkAccess(access *ShareLinkAccess) error {
+	query := `
+		INSERT INTO document_share_link_accesses (link_id, user_id, email, ip_address, user_agent, granted, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, accessed_at
+	`
+	return r.db.QueryRow(query, access.LinkID, access.UserID, access.Email, access.IPAddress, access.UserAgent,
+		access.Granted, access.Reason).Scan(&access.ID, &access.AccessedAt)
+}
+
+func (r *DocumentRepository) GetShareLinkAccesses(linkID int64, limit, offset int) ([]ShareLinkAccess, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM document_share_link_accesses WHERE link_id = $1`, linkID); err != nil {
+		return nil, 0, err
+	}
+
+	accesses := []ShareLinkAccess{}
+	query := `
+		SELECT * FROM document_share_link_accesses
+		WHERE link_id = $1
+		ORDER BY accessed_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	if err := r.db.Select(&accesses, query, linkID, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return accesses, total, nil
+}
+
+// GetExistingTeamNames returns which of names are teams.
+func (r *DocumentRepository) GetExistingTeamNames(names []string) ([]string, error) {
+	existing := []string{}
+	if err := r.db.Select(&existing, `SELECT DISTINCT name FROM teams WHERE name = ANY($1)`, pq.Array(names)); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *DocumentRepository) RecordAccessEvent(event *DocumentAccessEvent) error {
+	query := `
+		INSERT INTO document_access_events
+		(document_id, detail_id, user_id, email, channel, source, share_link_id, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, accessed_at
+	`
+	return r.db.QueryRow(query,
+		event.DocumentID, event.DetailID, event.UserID, event.Email, event.Channel, event.Source,
+		event.ShareLinkID, event.IPAddress, event.UserAgent,
+	).Scan(&event.ID, &event.AccessedAt)
+}
+
+func (r *DocumentRepository) GetDocumentAccessEvents(documentID int, from, to time.Time, limit, offset int) ([]DocumentAccessEvent, int, error) {
+	where := `WHERE document_id = $1 AND accessed_at >= $2 AND accessed_at < $3`
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM document_access_events `+where, documentID, from, to); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, document_id, detail_id, user_id, email, channel, source, share_link_id,
+			ip_address, user_agent, accessed_at
+		FROM document_access_events
+		` + where + `
+		ORDER BY accessed_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`
+	events := []DocumentAccessEvent{}
+	if err := r.db.Select(&events, query, documentID, from, to, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// accessStatsQuery sums the access events of each document between $1 and
+// $2.
+const accessStatsQuery = `
+	SELECT
+		document_id,
+		COUNT(*) FILTER (WHERE channel = 'view') AS views,
+		COUNT(*) FILTER (WHERE channel = 'download') AS downloads,
+		COUNT(DISTINCT COALESCE(user_id::text, email)) AS unique_viewers,
+		COUNT(*) FILTER (WHERE source = 'share_link') AS share_link_views,
+		COUNT(*) FILTER (WHERE source = 'citation') AS citation_opens,
+		MAX(accessed_at) AS last_accessed_at
+	FROM document_access_events
+	WHERE accessed_at >= $1 AND accessed_at < $2
+	GROUP BY document_id`
+
+// citationStatsQuery counts the chat answers between $1 and $2 that cited
+// each document. The RAG stores citations as [document ID, filename] pairs;
+// an answer citing several chunks of one document counts once.
+const citationStatsQuery = `
+	SELECT ref::int AS document_id, COUNT(DISTINCT id) AS citations, MAX(created_at) AS last_cited_at
+	FROM (
+		SELECT ch.id, ch.created_at,
+			CASE jsonb_typeof(c.elem)
+				WHEN 'array' THEN c.elem->>0
+				WHEN 'object' THEN c.elem->>'id'
+			END AS ref
+		FROM chat_history ch
+		CROSS JOIN LATERAL jsonb_array_elements(
+			CASE WHEN jsonb_typeof(ch.citation) = 'array' THEN ch.citation ELSE '[]'::jsonb END
+		) AS c(elem)
+		WHERE ch.created_at >= $1 AND ch.created_at < $2
+	) refs
+	WHERE ref ~ '^[0-9]{1,9}$'
+	GROUP BY ref::int`
+
+// GetDocumentUsage ranks the live documents that were opened or cited
+// between from and to by sortBy, which must be a DocumentUsage column.
+func (r *DocumentRepository) GetDocumentUsage(from, to time.Time, sortBy string, limit, offset int) ([]DocumentUsage, int, error) {
+	base := `
+		WITH access AS (` + accessStatsQuery + `),
+		cited AS (` + citationStatsQuery + `)
+		SELECT
+			d.id AS document_id, dd.document_name, d.category,
+			COALESCE(a.views, 0) AS views,
+			COALESCE(a.downloads, 0) AS downloads,
+			COALESCE(a.unique_viewers, 0) AS unique_viewers,
+			COALESCE(a.share_link_views, 0) AS share_link_views,
+			COALESCE(a.citation_opens, 0) AS citation_opens,
+			COALESCE(c.citations, 0) AS citations,
+			a.last_accessed_at, c.last_cited_at
+		FROM documents d
+		LEFT JOIN access a ON a.document_id = d.id
+		LEFT JOIN cited c ON c.document_id = d.id
+		CROSS JOIN LATERAL (
+			SELECT document_name
+			FROM document_details
+			WHERE document_id = d.id
+			ORDER BY is_latest DESC, created_at DESC
+			LIMIT 1
+		) dd
+		WHERE d.deleted_at IS NULL
+		AND (a.document_id IS NOT NULL OR c.document_id IS NOT NULL)
+	`
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM (`+base+`) usage`, from, to); err != nil {
+		return nil, 0, err
+	}
+
+	query := base + `
+		ORDER BY ` + sortBy + ` DESC, document_id
+		LIMIT $3 OFFSET $4
+	`
+	usage := []DocumentUsage{}
+	if err := r.db.Select(&usage, query, from, to, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return usage, total, nil
+}
+
+// GetUncitedDocuments returns the live, published documents that no chat
+// answer has cited since since. Documents published after since are left out
+// because they have not had the whole period to be cited. A version is
+// published when it is approved, not when it is uploaded.
+func (r *DocumentRepository) GetUncitedDocuments(since time.Time, limit, offset int) ([]UncitedDocument, int, error) {
+	base := `
+		WITH access AS (` + accessStatsQuery + `),
+		cited AS (` + citationStatsQuery + `)
+		SELECT
+			d.id AS document_id, dd.document_name, d.category, dd.staff, dd.team,
+			COALESCE(dd.approved_at, dd.created_at) AS published_at,
+			COALESCE(a.views, 0) AS views,
+			a.last_accessed_at
+		FROM documents d
+		JOIN document_details dd ON dd.document_id = d.id AND dd.is_latest = true AND dd.is_approve = true
+		LEFT JOIN access a ON a.document_id = d.id
+		WHERE d.deleted_at IS NULL
+		AND COALESCE(dd.approved_at, dd.created_at) < $1
+		AND NOT EXISTS (SELECT 1 FROM cited c WHERE c.document_id = d.id)
+	`
+	now := time.Now()
+
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM (`+base+`) uncited`, since, now); err != nil {
+		return nil, 0, err
+	}
+
+	query := base + `
+		ORDER BY views, published_at, document_id
+		LIMIT $3 OFFSET $4
+	`
+	documents := []UncitedDocument{}
+	if err := r.db.Select(&documents, query, since, now, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return documents, total, nil
+}
+
+// GetCitedDocuments returns the published versions of the live documents
+// with one of ids, or whose published version is named one of names. Version
+// numbers count like GetDocumentVersions.
+func (r *DocumentRepository) GetCitedDocuments(ids []int64, names []string) ([]Citation, error) {
+	query := `
+		SELECT
+			d.id AS document_id, dd.id AS detail_id, dd.document_name, d.category, dd.filename,
+			(
+				SELECT COUNT(*) FROM document_details v
+				WHERE v.document_id = d.id
+				AND (v.created_at < dd.created_at OR (v.created_at = dd.created_at AND v.id <= dd.id))
+			) AS version
+		FROM documents d
+		JOIN document_details dd ON dd.document_id = d.id AND dd.is_latest = true AND dd.is_approve = true
+		WHERE d.deleted_at IS NULL
+		AND (d.id = ANY($1) OR dd.document_name = ANY($2))
+	`
+	citations := []Citation{}
+	if err := r.db.Select(&citations, query, pq.Array(ids), pq.Array(names)); err != nil {
+		return nil, err
+	}
+	return citations, nil
+}
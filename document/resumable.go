@@ -0,0 +1,371 @@
+package document
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"dokuprime-be/scanner"
+	"dokuprime-be/storage"
+	"dokuprime-be/util"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	uploadStatusUploading  = "uploading"
+	uploadStatusProcessing = "processing"
+	uploadStatusCompleted  = "completed"
+	uploadStatusFailed     = "failed"
+	uploadStatusRejected   = "rejected"
+
+	uploadChunkPrefix = "uploads/"
+
+	defaultUploadExpiryHours = 24
+
+	// defaultBatchStaleMinutes is how long a processing batch may go without
+	// any of its files moving before it is considered orphaned.
+	defaultBatchStaleMinutes = 30
+)
+
+var (
+	errInvalidUpload  = errors.New("invalid upload")
+	errUploadTooLarge = errors.New("upload exceeds the maximum file size")
+	errUploadExpired  = errors.New("upload has expired")
+	errUploadFinished = errors.New("upload is no longer accepting data")
+	errOffsetConflict = errors.New("upload offset does not match the stored offset")
+)
+
+func maxUploadSize() int64 {
+	return int64(getEnvInt("MAX_FILE_SIZE_ALLOWED", 70)) * 1024 * 1024
+}
+
+func uploadExpiry() time.Duration {
+	return time.Duration(getEnvInt("TUS_UPLOAD_EXPIRY_HOURS", defaultUploadExpiryHours)) * time.Hour
+}
+
+// CreateResumableUpload validates a new tus upload and opens its session.
+// Metadata, tags and dates must already be normalized by the caller.
+func (s *DocumentService) CreateResumableUpload(upload *ResumableUpload) error {
+	upload.Filename = filepath.Base(strings.TrimSpace(upload.Filename))
+	if upload.Filename == "." || upload.Filename == string(filepath.Separator) {
+		return fmt.Errorf("%w: filename is required in Upload-Metadata", errInvalidUpload)
+	}
+	upload.DataType = strings.TrimPrefix(strings.ToLower(filepath.Ext(upload.Filename)), ".")
+	if !acceptedFileTypes()[upload.DataType] {
+		return fmt.Errorf("%w: %s", errInvalidUpload, invalidFileTypeMessage())
+	}
+	if strings.TrimSpace(upload.Category) == "" {
+		return fmt.Errorf("%w: category is required in Upload-Metadata", errInvalidUpload)
+	}
+	if upload.UploadLength <= 0 {
+		return fmt.Errorf("%w: Upload-Length must be greater than zero", errInvalidUpload)
+	}
+	if upload.UploadLength > maxUploadSize() {
+		return fmt.Errorf("%w of %d MB", errUploadTooLarge, maxUploadSize()/(1024*1024))
+	}
+
+	upload.ID = uuid.New()
+	upload.UploadExpiresAt = time.Now().Add(uploadExpiry())
+	if err := s.repo.CreateResumableUpload(upload); err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	return nil
+}
+
+// GetResumableUpload returns an upload session of the given uploader. Other
+// users' sessions are reported as not found.
+func (s *DocumentService) GetResumableUpload(id uuid.UUID, email string) (*ResumableUpload, error) {
+	upload, err := s.repo.GetResumableUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Staff != email {
+		return nil, sql.ErrNoRows
+	}
+	if upload.Status != uploadStatusCompleted && upload.UploadExpiresAt.Before(time.Now()) {
+		return nil, errUploadExpired
+	}
+	return upload, nil
+}
+
+// AppendUploadChunk stores the bytes of a PATCH request at offset. The body
+// is spooled to disk first so a dropped connection still keeps what arrived.
+// When the last byte arrives the file is handed to the regular upload flow;
+// a PATCH without data on a fully received upload retries that step.
+func (s *DocumentService) AppendUploadChunk(id uuid.UUID, email string, offset int64, body io.Reader) (*ResumableUpload, error) {
+	upload, err := s.GetResumableUpload(id, email)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.UploadOffset {
+		return upload, errOffsetConflict
+	}
+
+	remaining := upload.UploadLength - upload.UploadOffset
+	if remaining == 0 {
+		// Also covers a backend that stopped between storing the last chunk
+		// and creating the document.
+		if upload.Status != uploadStatusCompleted && upload.Status != uploadStatusRejected {
+			return s.finishResumableUpload(upload)
+		}
+		return upload, errUploadFinished
+	}
+	if upload.Status != uploadStatusUploading {
+		return upload, errUploadFinished
+	}
+
+	tmp, err := os.CreateTemp("", "upload-chunk-*")
+	if err != nil {
+		return upload, fmt.Errorf("failed to buffer chunk: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, copyErr := io.Copy(tmp, io.LimitReader(body, remaining+1))
+	if size > remaining {
+		return upload, fmt.Errorf("%w: the chunk goes past Upload-Length", errUploadTooLarge)
+	}
+	if size == 0 {
+		return upload, copyErr
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return upload, err
+	}
+
+	// The suffix keeps concurrent PATCHes at the same offset from writing the
+	// same key: the one that loses the offset check removes only its own chunk.
+	key := fmt.Sprintf("%s%s_%020d_%s", uploadChunkPrefix, upload.ID, offset, util.RandString(8))
+	if err := s.storage.Put(context.Background(), key, tmp, size, "application/octet-stream"); err != nil {
+		return upload, fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	chunk := UploadChunk{UploadID: upload.ID, ChunkOffset: offset, Size: size, StorageKey: key}
+	newOffset, err := s.repo.AppendUploadChunk(chunk, time.Now().Add(uploadExpiry()))
+	if err != nil {
+		s.RemoveFile(key)
+		return upload, err
+	}
+	upload.UploadOffset = newOffset
+
+	if copyErr != nil {
+		log.Printf("Warning: Upload %s interrupted at offset %d: %v", upload.ID, newOffset, copyErr)
+		return upload, nil
+	}
+	if newOffset == upload.UploadLength {
+		return s.finishResumableUpload(upload)
+	}
+	return upload, nil
+}
+
+// finishResumableUpload turns a fully received upload into a document.
+// Rejections by screening are final; other failures can be retried.
+func (s *DocumentService) finishResumableUpload(upload *ResumableUpload) (*ResumableUpload, error) {
+	claimed, err := s.repo.ClaimUploadForProcessing(upload.ID)
+	if err != nil {
+		return upload, fmt.Errorf("failed to claim upload: %w", err)
+	}
+	if !claimed {
+		return upload, errUploadFinished
+	}
+
+	document, detail, err := s.assembleUpload(upload)
+	if err != nil {
+		status := uploadStatusFailed
+		var infectedErr *InfectedFileError
+		if errors.As(err, &infectedErr) || errors.Is(err, scanner.ErrContentMismatch) {
+			status = uploadStatusRejected
+			removeUploadChunks(s.repo, s.storage, upload.ID)
+		}
+		msg := err.Error()
+		if finishErr := s.repo.FinishResumableUpload(upload.ID, status, &msg, nil, nil); finishErr != nil {
+			log.Printf("Warning: Failed to record outcome of upload %s: %v", upload.ID, finishErr)
+		}
+		upload.Status = status
+		upload.Error = &msg
+		return upload, err
+	}
+
+	if err := s.repo.FinishResumableUpload(upload.ID, uploadStatusCompleted, nil, &document.ID, &detail.ID); err != nil {
+		log.Printf("Warning: Failed to record outcome of upload %s: %v", upload.ID, err)
+	}
+	removeUploadChunks(s.repo, s.storage, upload.ID)
+
+	upload.Status = uploadStatusCompleted
+	upload.Error = nil
+	upload.DocumentID = &document.ID
+	upload.DetailID = &detail.ID
+	return upload, nil
+}
+
+// assembleUpload joins the chunks into one file, screens it and creates the
+// document exactly like a regular upload would.
+func (s *DocumentService) assembleUpload(upload *ResumableUpload) (*Document, *DocumentDetail, error) {
+	chunks, err := s.repo.GetUploadChunks(upload.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get upload chunks: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "upload-*"+filepath.Ext(upload.Filename))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var size int64
+	for _, chunk := range chunks {
+		if chunk.ChunkOffset != size {
+			return nil, nil, fmt.Errorf("upload is missing bytes at offset %d", size)
+		}
+		n, err := s.copyObject(tmp, chunk.StorageKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read chunk at offset %d: %w", chunk.ChunkOffset, err)
+		}
+		size += n
+	}
+	if size != upload.UploadLength {
+		return nil, nil, fmt.Errorf("upload has %d of %d bytes", size, upload.UploadLength)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	screened, err := s.screenFile(tmp, upload.Filename, upload.DataType, uploadSourceResumable, upload.Staff)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	uniqueFilename := GenerateUniqueFilename(upload.Filename)
+	hasher := sha256.New()
+	if err := s.storage.Put(context.Background(), uniqueFilename, io.TeeReader(tmp, hasher), size, storage.ContentTypeFor(uniqueFilename)); err != nil {
+		return nil, nil, fmt.Errorf("failed to save file: %w", err)
+	}
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
+
+	document := &Document{Category: upload.Category, Metadata: upload.Metadata, Tags: upload.Tags}
+	isLatest := true
+	pendingStatus := "Pending"
+	detail := &DocumentDetail{
+		DocumentName: upload.Filename,
+		Filename:     uniqueFilename,
+		DataType:     upload.DataType,
+		Staff:        upload.Staff,
+		Team:         upload.Team,
+		Status:       &pendingStatus,
+		IsLatest:     &isLatest,
+		ContentHash:  &contentHash,
+		ValidFrom:    upload.ValidFrom,
+		ReviewBy:     upload.ReviewBy,
+		ExpiresAt:    upload.ExpiresAt,
+	}
+	screened.apply(detail)
+
+	if err := s.CreateDocument(document, detail); err != nil {
+		s.RemoveFile(uniqueFilename)
+		return nil, nil, err
+	}
+	return document, detail, nil
+}
+
+func (s *DocumentService) copyObject(w io.Writer, key string) (int64, error) {
+	rc, _, err := s.storage.Get(context.Background(), key)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(w, rc)
+}
+
+// DeleteResumableUpload implements tus termination: the session and the
+// bytes received so far are removed. A finished document is kept.
+func (s *DocumentService) DeleteResumableUpload(id uuid.UUID, email string) error {
+	upload, err := s.GetResumableUpload(id, email)
+	if err != nil {
+		return err
+	}
+	if upload.Status == uploadStatusProcessing {
+		return errUploadFinished
+	}
+
+	removeUploadChunks(s.repo, s.storage, id)
+	return s.repo.DeleteResumableUpload(id)
+}
+
+func removeUploadChunks(repo *DocumentRepository, backend storage.Backend, id uuid.UUID) {
+	chunks, err := repo.GetUploadChunks(id)
+	if err != nil {
+		log.Printf("Warning: Failed to get chunks of upload %s: %v", id, err)
+		return
+	}
+	for _, chunk := range chunks {
+		if err := backend.Delete(context.Background(), chunk.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Warning: Failed to remove chunk %s: %v", chunk.StorageKey, err)
+		}
+	}
+	if err := repo.DeleteUploadChunks(id); err != nil {
+		log.Printf("Warning: Failed to delete chunk records of upload %s: %v", id, err)
+	}
+}
+
+// UploadCleanupService removes upload sessions that were abandoned or have
+// been finished for longer than TUS_UPLOAD_EXPIRY_HOURS.
+type UploadCleanupService struct {
+	repo    *DocumentRepository
+	storage storage.Backend
+}
+
+func NewUploadCleanupService(db *sqlx.DB, fileStorage storage.Backend) *UploadCleanupService {
+	return &UploadCleanupService{
+		repo:    NewDocumentRepository(db),
+		storage: fileStorage,
+	}
+}
+
+func (s *UploadCleanupService) CleanupExpiredUploads() {
+	uploads, err := s.repo.GetExpiredResumableUploads(100)
+	if err != nil {
+		log.Printf("Error getting expired uploads: %v", err)
+		return
+	}
+
+	for _, upload := range uploads {
+		removeUploadChunks(s.repo, s.storage, upload.ID)
+		if err := s.repo.DeleteResumableUpload(upload.ID); err != nil {
+			log.Printf("Warning: Failed to delete upload %s: %v", upload.ID, err)
+		}
+	}
+
+	if len(uploads) > 0 {
+		log.Printf("Removed %d expired upload(s)", len(uploads))
+	}
+}
+
+// InterruptOrphanedBatches ends batch uploads whose workers stopped with a
+// previous run of the backend. Their files were only held in memory, so they
+// cannot be resumed; without this the batch stays processing and its status
+// stream never completes.
+func (s *UploadCleanupService) InterruptOrphanedBatches() {
+	staleAfter := time.Duration(getEnvInt("DOCUMENT_BATCH_STALE_MINUTES", defaultBatchStaleMinutes)) * time.Minute
+	ids, err := s.repo.InterruptStaleUploadBatches(staleAfter, "Processing was interrupted; upload the file again")
+	if err != nil {
+		log.Printf("Error interrupting orphaned batches: %v", err)
+		return
+	}
+
+	if len(ids) > 0 {
+		log.Printf("Marked %d orphaned batch upload(s) as interrupted: %s", len(ids), strings.Join(ids, ", "))
+	}
+}
@@ -1,53 +1,109 @@
 package document
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/config"
 	"dokuprime-be/external"
+	"dokuprime-be/metadata"
 	"dokuprime-be/middleware"
+	"dokuprime-be/scanner"
+	"dokuprime-be/storage"
+	"dokuprime-be/workflow"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
 )
 
-func RegisterRoutesWithProcessor(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) *AsyncProcessor {
+const (
+	permDocumentCreate = "document-management:create"
+	permDocumentRead   = "document-management:read"
+	permDocumentUpdate = "document-management:update"
+	permDocumentDelete = "document-management:delete"
+	permDocumentMaster = "document-management:master"
+)
+
+func RegisterRoutesWithProcessor(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend) *AsyncProcessor {
 	externalConfig := config.LoadExternalAPIConfig()
 	externalClient := external.NewClient(externalConfig)
 
-	asyncProcessor := NewAsyncProcessor(externalClient, 5)
-
 	repo := NewDocumentRepository(db)
-	service := NewDocumentService(repo, redisClient, asyncProcessor, externalClient)
+	asyncProcessor := NewAsyncProcessor(repo, externalClient, fileStorage, getEnvInt("EXTRACTION_WORKERS", 5))
+
+	service := newDocumentService(db, redisClient, fileStorage, asyncProcessor, externalClient)
 	handler := NewDocumentHandler(service, redisClient)
 
 	r.GET("/api/documents/view-file", handler.ViewDocument)
+	r.GET("/api/documents/shared/:token", middleware.OptionalAuthMiddleware(), handler.ViewSharedDocument)
 
 	documentRoutes := r.Group("/api/documents")
 
 	documentRoutes.Use(middleware.AuthMiddleware())
 	{
-		documentRoutes.POST("/batch-upload", handler.BatchUploadDocument)
-		documentRoutes.GET("/batch-status", handler.GetBatchUploadStatus)
-		documentRoutes.POST("/batch-delete", handler.BatchDeleteDocument)
-
-		documentRoutes.POST("/generate-view-url", handler.GenerateViewURL)
-		documentRoutes.POST("/generate-view-url-id", handler.GenerateViewURLByID)
-		documentRoutes.POST("/generate-view-url-docid", handler.GenerateViewURLByDocumentID)
-		documentRoutes.POST("/upload", handler.UploadDocument)
-		documentRoutes.GET("", handler.GetDocuments)
-		documentRoutes.GET("/details", handler.GetDocumentDetails)
-		documentRoutes.PUT("/update", handler.UpdateDocument)
-		documentRoutes.PUT("/approve/:id", handler.ApproveDocument)
-		documentRoutes.PUT("/reject/:id", handler.RejectDocument)
-		documentRoutes.DELETE("/:id", handler.DeleteDocument)
-		documentRoutes.GET("/download/:filename", handler.DownloadDocument)
-		documentRoutes.GET("/all-details", handler.GetAllDocumentDetails)
-		documentRoutes.GET("/queue-status", handler.GetQueueStatus)
-		documentRoutes.POST("/check-duplicates", handler.CheckDuplicates)
+		documentRoutes.POST("/batch-upload", middleware.RequirePermission(permDocumentCreate), handler.BatchUploadDocument)
+		documentRoutes.GET("/batch-status", middleware.RequirePermission(permDocumentRead), handler.GetBatchUploadStatus)
+		documentRoutes.GET("/batches", middleware.RequirePermission(permDocumentCreate), handler.GetUploadBatches)
+		documentRoutes.POST("/batches/:id/cancel", middleware.RequirePermission(permDocumentCreate), handler.CancelUploadBatch)
+		documentRoutes.POST("/batch-delete", middleware.RequirePermission(permDocumentDelete), handler.BatchDeleteDocument)
+
+		documentRoutes.POST("/generate-view-url", middleware.RequirePermission(permDocumentRead), handler.GenerateViewURL)
+		documentRoutes.POST("/generate-view-url-id", middleware.RequirePermission(permDocumentRead), handler.GenerateViewURLByID)
+		documentRoutes.POST("/generate-view-url-docid", middleware.RequirePermission(permDocumentRead), handler.GenerateViewURLByDocumentID)
+		documentRoutes.POST("/upload", middleware.RequirePermission(permDocumentCreate), handler.UploadDocument)
+		documentRoutes.OPTIONS("/uploads", handler.ResumableUploadOptions)
+		documentRoutes.POST("/uploads", middleware.RequirePermission(permDocumentCreate), handler.CreateResumableUpload)
+		documentRoutes.HEAD("/uploads/:id", middleware.RequirePermission(permDocumentCreate), handler.GetResumableUploadOffset)
+		documentRoutes.PATCH("/uploads/:id", middleware.RequirePermission(permDocumentCreate), handler.PatchResumableUpload)
+		documentRoutes.DELETE("/uploads/:id", middleware.RequirePermission(permDocumentCreate), handler.DeleteResumableUpload)
+		documentRoutes.GET("/uploads/:id", middleware.RequirePermission(permDocumentCreate), handler.GetResumableUpload)
+		documentRoutes.GET("", middleware.RequirePermission(permDocumentRead), handler.GetDocuments)
+		documentRoutes.GET("/search", middleware.RequirePermission(permDocumentRead), handler.SearchDocuments)
+		documentRoutes.GET("/details", middleware.RequirePermission(permDocumentRead), handler.GetDocumentDetails)
+		documentRoutes.PUT("/update", middleware.RequirePermission(permDocumentUpdate), handler.UpdateDocument)
+		documentRoutes.PUT("/approve/:id", middleware.RequirePermission(permDocumentUpdate), handler.ApproveDocument)
+		documentRoutes.PUT("/reject/:id", middleware.RequirePermission(permDocumentUpdate), handler.RejectDocument)
+		documentRoutes.GET("/approval/:id", middleware.RequirePermission(permDocumentRead), handler.GetApprovalProgress)
+		documentRoutes.GET("/rejection-reasons", middleware.RequirePermission(permDocumentRead), handler.GetRejectionReasons)
+		documentRoutes.GET("/comments/:id", middleware.RequirePermission(permDocumentRead), handler.GetReviewComments)
+		documentRoutes.POST("/comments/:id", middleware.RequirePermission(permDocumentCreate, permDocumentUpdate), handler.AddReviewComment)
+		documentRoutes.POST("/resubmit/:id", middleware.RequirePermission(permDocumentCreate), handler.ResubmitDocument)
+		documentRoutes.PUT("/reingest/:id", middleware.RequirePermission(permDocumentUpdate), handler.ReingestDocument)
+		documentRoutes.PUT("/rollback/:id", middleware.RequirePermission(permDocumentMaster), handler.RollbackDocument)
+		documentRoutes.GET("/versions/:id", middleware.RequirePermission(permDocumentRead), handler.GetDocumentVersions)
+		documentRoutes.GET("/diff", middleware.RequirePermission(permDocumentRead), handler.DiffDocumentVersions)
+		documentRoutes.PUT("/dates/:id", middleware.RequirePermission(permDocumentUpdate), handler.UpdateDocumentDates)
+		documentRoutes.PUT("/metadata/:id", middleware.RequirePermission(permDocumentUpdate), handler.UpdateDocumentMetadata)
+		documentRoutes.GET("/notifications", middleware.RequirePermission(permDocumentRead), handler.GetNotifications)
+		documentRoutes.PUT("/notifications/:id/read", middleware.RequirePermission(permDocumentRead), handler.MarkNotificationRead)
+		documentRoutes.GET("/quarantine", middleware.RequirePermission(permDocumentMaster), handler.GetQuarantinedFiles)
+		documentRoutes.GET("/crawl-sources", middleware.RequirePermission(permDocumentMaster), handler.GetCrawlSources)
+		documentRoutes.POST("/crawl-sources", middleware.RequirePermission(permDocumentMaster), handler.CreateCrawlSource)
+		documentRoutes.GET("/crawl-sources/:id", middleware.RequirePermission(permDocumentMaster), handler.GetCrawlSource)
+		documentRoutes.PUT("/crawl-sources/:id", middleware.RequirePermission(permDocumentMaster), handler.UpdateCrawlSource)
+		documentRoutes.DELETE("/crawl-sources/:id", middleware.RequirePermission(permDocumentMaster), handler.DeleteCrawlSource)
+		documentRoutes.POST("/crawl-sources/:id/run", middleware.RequirePermission(permDocumentMaster), handler.RunCrawlSource)
+		documentRoutes.GET("/crawl-sources/:id/runs", middleware.RequirePermission(permDocumentMaster), handler.GetCrawlRuns)
+		documentRoutes.GET("/crawl-runs/:id", middleware.RequirePermission(permDocumentMaster), handler.GetCrawlRun)
+		documentRoutes.GET("/trash", middleware.RequirePermission(permDocumentDelete), handler.GetTrashedDocuments)
+		documentRoutes.PUT("/trash/:id/restore", middleware.RequirePermission(permDocumentDelete), handler.RestoreDocument)
+		documentRoutes.DELETE("/trash/:id", middleware.RequirePermission(permDocumentMaster), handler.PurgeDocument)
+		documentRoutes.PUT("/legal-hold/:id", middleware.RequirePermission(permDocumentMaster), handler.SetLegalHold)
+		documentRoutes.POST("/share-links", middleware.RequirePermission(permDocumentRead), handler.CreateShareLink)
+		documentRoutes.GET("/share-links", middleware.RequirePermission(permDocumentRead), handler.GetShareLinks)
+		documentRoutes.POST("/share-links/:id/revoke", middleware.RequirePermission(permDocumentRead), handler.RevokeShareLink)
+		documentRoutes.GET("/share-links/:id/accesses", middleware.RequirePermission(permDocumentRead), handler.GetShareLinkAccesses)
+		documentRoutes.GET("/analytics/usage", middleware.RequirePermission(permDocumentMaster), handler.GetDocumentUsage)
+		documentRoutes.GET("/analytics/uncited", middleware.RequirePermission(permDocumentMaster), handler.GetUncitedDocuments)
+		documentRoutes.GET("/analytics/documents/:id/events", middleware.RequirePermission(permDocumentMaster), handler.GetDocumentAccessEvents)
+		documentRoutes.DELETE("/:id", middleware.RequirePermission(permDocumentDelete), handler.DeleteDocument)
+		documentRoutes.GET("/download/:filename", middleware.RequirePermission(permDocumentRead), handler.DownloadDocument)
+		documentRoutes.GET("/all-details", middleware.RequirePermission(permDocumentRead), handler.GetAllDocumentDetails)
+		documentRoutes.GET("/queue-status", middleware.RequirePermission(permDocumentRead), handler.GetQueueStatus)
+		documentRoutes.POST("/check-duplicates", middleware.RequirePermission(permDocumentCreate), handler.CheckDuplicates)
 	}
 
 	crawlerRoutes := r.Group("/api/documents/crawler")
-	crawlerRoutes.Use(middleware.APIKeyMiddleware()) 
+	crawlerRoutes.Use(middleware.APIKeyMiddleware())
 	{
 		crawlerRoutes.POST("/upload", handler.CrawlerBatchUpload)
 	}
@@ -55,6 +111,21 @@ func RegisterRoutesWithProcessor(r *gin.Engine, db *sqlx.DB, redisClient *redis.
 	return asyncProcessor
 }
 
-func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
-	RegisterRoutesWithProcessor(r, db, redisClient)
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend) {
+	RegisterRoutesWithProcessor(r, db, redisClient, fileStorage)
+}
+
+// NewSchedulerService builds the document service the crawl and recycle bin
+// schedulers run with. It shares the extraction processor of the API.
+func NewSchedulerService(db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend, asyncProcessor *AsyncProcessor) *DocumentService {
+	externalClient := external.NewClient(config.LoadExternalAPIConfig())
+	return newDocumentService(db, redisClient, fileStorage, asyncProcessor, externalClient)
+}
+
+func newDocumentService(db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend, asyncProcessor *AsyncProcessor, externalClient *external.Client) *DocumentService {
+	auditService := audit.NewAuditService(audit.NewAuditRepository(db))
+	workflowService := workflow.NewWorkflowService(workflow.NewWorkflowRepository(db), auditService)
+	metadataService := metadata.NewMetadataService(metadata.NewMetadataRepository(db), auditService)
+
+	return NewDocumentService(NewDocumentRepository(db), redisClient, asyncProcessor, externalClient, fileStorage, auditService, workflowService, metadataService, scanner.InitScanner())
 }
@@ -0,0 +1,139 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"dokuprime-be/scanner"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"time"
+)
+
+const (
+	uploadSourceUpload    = "upload"
+	uploadSourceBatch     = "batch"
+	uploadSourceCrawler   = "crawler"
+	uploadSourceResumable = "resumable"
+
+	quarantinePrefix = "quarantine/"
+)
+
+var ErrScanUnavailable = errors.New("malware scan is unavailable, please try again later")
+
+type InfectedFileError struct {
+	Filename  string
+	Signature string
+}
+
+func (e *InfectedFileError) Error() string {
+	return fmt.Sprintf("menolak upload karena file %q terdeteksi mengandung malware (%s)", e.Filename, e.Signature)
+}
+
+// screening is the outcome of checking a file before it is stored.
+type screening struct {
+	mimeType  string
+	result    *scanner.Result
+	scannedAt time.Time
+}
+
+func (sc *screening) apply(detail *DocumentDetail) {
+	detail.MimeType = &sc.mimeType
+	detail.ScanStatus = &sc.result.Status
+	if sc.result.Signature != "" {
+		detail.ScanSignature = &sc.result.Signature
+	}
+	detail.ScannedAt = &sc.scannedAt
+}
+
+func (s *DocumentService) screenUploadedFile(fileHeader *multipart.FileHeader, dataType, uploader string) (*screening, error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	return s.screenFile(src, fileHeader.Filename, dataType, uploadSourceUpload, uploader)
+}
+
+func (s *DocumentService) screenContent(content []byte, name, dataType, source, uploader string) (*screening, error) {
+	return s.screenFile(bytes.NewReader(content), name, dataType, source, uploader)
+}
+
+// screenFile checks that the content matches the extension and scans it for
+// malware. Infected files are quarantined and reported as *InfectedFileError;
+// a scanner failure is reported as ErrScanUnavailable.
+func (s *DocumentService) screenFile(r io.ReadSeeker, name, dataType, source, uploader string) (*screening, error) {
+	head := make([]byte, scanner.SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	mimeType, err := scanner.SniffContentType(dataType, head[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	result, err := s.scanner.Scan(context.Background(), r)
+	if err != nil {
+		log.Printf("Error scanning %s: %v", name, err)
+		return nil, ErrScanUnavailable
+	}
+
+	if result.Status == scanner.StatusInfected {
+		log.Printf("Warning: %s upload %s from %s is infected (%s)", source, name, uploader, result.Signature)
+		if err := s.quarantine(r, name, source, uploader, result); err != nil {
+			log.Printf("Warning: Failed to quarantine %s: %v", name, err)
+		}
+		return nil, &InfectedFileError{Filename: name, Signature: result.Signature}
+	}
+
+	return &screening{mimeType: mimeType, result: result, scannedAt: time.Now()}, nil
+}
+
+// quarantine keeps a copy of an infected upload outside the document keys,
+// where it cannot be downloaded, and records it for the administrators.
+func (s *DocumentService) quarantine(r io.ReadSeeker, name, source, uploader string, result *scanner.Result) error {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := quarantinePrefix + GenerateUniqueFilename(name)
+	hasher := sha256.New()
+	if err := s.storage.Put(context.Background(), key, io.TeeReader(r, hasher), size, "application/octet-stream"); err != nil {
+		return err
+	}
+
+	file := &QuarantinedFile{
+		OriginalName: name,
+		StorageKey:   key,
+		Source:       source,
+		Signature:    result.Signature,
+		Engine:       result.Engine,
+		Size:         size,
+		ContentHash:  hex.EncodeToString(hasher.Sum(nil)),
+	}
+	if uploader != "" {
+		file.UploadedBy = &uploader
+	}
+	if err := s.repo.CreateQuarantinedFile(file); err != nil {
+		s.RemoveFile(key)
+		return err
+	}
+	return nil
+}
+
+func (s *DocumentService) GetQuarantinedFiles(limit, offset int) ([]QuarantinedFile, int, error) {
+	return s.repo.GetQuarantinedFiles(limit, offset)
+}
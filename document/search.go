@@ -0,0 +1,151 @@
+package document
+
+import (
+	"context"
+	"dokuprime-be/external"
+	"dokuprime-be/storage"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	textStatusIndexed     = "indexed"
+	textStatusUnsupported = "unsupported"
+	textStatusFailed      = "failed"
+
+	defaultTextIndexBatch = 20
+
+	// maxChunkChars keeps each row well below the 1MB tsvector limit.
+	maxChunkChars = 100000
+
+	// snippetStartSel and snippetStopSel mark matches in ts_headline output.
+	// They are private-use characters, removed from the text when indexed.
+	snippetStartSel = "\uE000"
+	snippetStopSel  = "\uE001"
+)
+
+var snippetCleaner = strings.NewReplacer("\x00", "", snippetStartSel, "", snippetStopSel, "")
+
+// TextIndexService extracts the plain text of stored versions and saves it
+// page by page for full-text search.
+type TextIndexService struct {
+	repo    *DocumentRepository
+	storage storage.Backend
+	running sync.Mutex
+}
+
+func NewTextIndexService(db *sqlx.DB, fileStorage storage.Backend) *TextIndexService {
+	return &TextIndexService{
+		repo:    NewDocumentRepository(db),
+		storage: fileStorage,
+	}
+}
+
+// IndexPendingTexts indexes a batch of versions that have no text yet. A run
+// that overlaps a slow previous run is skipped.
+func (s *TextIndexService) IndexPendingTexts() {
+	if !s.running.TryLock() {
+		return
+	}
+	defer s.running.Unlock()
+
+	details, err := s.repo.GetDetailsPendingText(getEnvInt("DOCUMENT_TEXT_INDEX_BATCH", defaultTextIndexBatch))
+	if err != nil {
+		log.Printf("Error getting documents to index: %v", err)
+		return
+	}
+
+	indexed := 0
+	for _, detail := range details {
+		if s.index(detail) {
+			indexed++
+		}
+	}
+
+	if indexed > 0 {
+		log.Printf("Indexed text of %d document version(s)", indexed)
+	}
+}
+
+func (s *TextIndexService) index(detail DocumentDetail) bool {
+	pages, err := s.extract(detail)
+	status := textStatusIndexed
+	var indexErr *string
+	if err != nil {
+		status = textStatusFailed
+		if errors.Is(err, external.ErrTextUnsupported) {
+			status = textStatusUnsupported
+		} else {
+			log.Printf("Warning: Failed to extract text of detail ID %d: %v", detail.ID, err)
+		}
+		msg := err.Error()
+		indexErr = &msg
+		pages = nil
+	}
+
+	if err := s.repo.SaveDocumentText(detail.ID, status, indexErr, splitTextPages(pages)); err != nil {
+		log.Printf("Warning: Failed to save text of detail ID %d: %v", detail.ID, err)
+		return false
+	}
+	return status == textStatusIndexed
+}
+
+// extract turns a parser panic on a malformed file into an error, so the
+// version is marked failed instead of being retried on every run.
+func (s *TextIndexService) extract(detail DocumentDetail) (pages []external.TextPage, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("text extraction panicked: %v", r)
+		}
+	}()
+
+	filePath, cleanup, err := storage.LocalPath(context.Background(), s.storage, detail.Filename)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return external.ExtractTextPages(filePath, detail.DataType)
+}
+
+// splitTextPages cleans the text, drops empty pages and cuts very long ones
+// into chunks that keep their page number.
+func splitTextPages(pages []external.TextPage) []external.TextPage {
+	result := make([]external.TextPage, 0, len(pages))
+	for _, page := range pages {
+		// Postgres rejects NUL bytes and invalid UTF-8 in text columns, and
+		// the snippet markers must not occur in the text.
+		text := snippetCleaner.Replace(strings.ToValidUTF8(page.Text, ""))
+		text = strings.TrimSpace(text)
+		for len(text) > maxChunkChars {
+			cut := strings.LastIndexAny(text[:maxChunkChars], "\n ")
+			if cut <= 0 {
+				cut = maxChunkChars
+				for cut > 0 && !utf8.RuneStart(text[cut]) {
+					cut--
+				}
+			}
+			result = append(result, external.TextPage{Number: page.Number, Text: text[:cut]})
+			text = strings.TrimSpace(text[cut:])
+		}
+		if text != "" {
+			result = append(result, external.TextPage{Number: page.Number, Text: text})
+		}
+	}
+	return result
+}
+
+// highlightSnippet escapes a ts_headline snippet, which is document text, and
+// only then turns the match markers into <mark> tags.
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, snippetStartSel, "<mark>")
+	return strings.ReplaceAll(snippet, snippetStopSel, "</mark>")
+}
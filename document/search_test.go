@@ -0,0 +1,34 @@
+package document
+
+import (
+	"dokuprime-be/external"
+	"testing"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+	}{
+		{"plain", "no match here", "no match here"},
+		{"match", "the \uE000tax\uE001 form", "the <mark>tax</mark> form"},
+		{"markup in text", "<script>alert(1)</script> \uE000form\uE001", "&lt;script&gt;alert(1)&lt;/script&gt; <mark>form</mark>"},
+		{"literal mark tags", "<mark>x</mark> & \"y\"", "&lt;mark&gt;x&lt;/mark&gt; &amp; &#34;y&#34;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.snippet); got != tt.want {
+				t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextPagesRemovesMarkers(t *testing.T) {
+	pages := splitTextPages([]external.TextPage{{Number: 1, Text: "a\x00b\uE000c\uE001d"}})
+	if len(pages) != 1 || pages[0].Text != "abcd" {
+		t.Errorf("splitTextPages() = %+v, want one page with text %q", pages, "abcd")
+	}
+}
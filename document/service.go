@@ -1,11 +1,20 @@
 package document
 
 import (
+	"bytes"
 	"context"
-	"dokuprime-be/config"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"dokuprime-be/audit"
 	"dokuprime-be/external"
+	"dokuprime-be/metadata"
+	"dokuprime-be/middleware"
+	"dokuprime-be/scanner"
+	"dokuprime-be/storage"
 	"dokuprime-be/util"
-	"encoding/json"
+	"dokuprime-be/workflow"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -21,67 +30,189 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-const isBatchUpload = "batch_upload:"
+const maxReviewCommentLength = 5000
+
+const (
+	batchStatusProcessing = "processing"
+	batchStatusCompleted  = "completed"
+	batchStatusCancelled  = "cancelled"
+	// batchStatusInterrupted is a batch whose workers stopped with the
+	// backend, so its unfinished files will never be processed.
+	batchStatusInterrupted = "interrupted"
+
+	batchItemStatusPending     = "pending"
+	batchItemStatusProcessing  = "processing"
+	batchItemStatusUploaded    = "uploaded"
+	batchItemStatusFailed      = "failed"
+	batchItemStatusQuarantined = "quarantined"
+	batchItemStatusCancelled   = "cancelled"
+	batchItemStatusInterrupted = "interrupted"
+)
+
+var (
+	errInvalidReview  = errors.New("invalid review request")
+	errNotResubmitter = errors.New("only the uploader of the rejected version or a document master can resubmit it")
+)
+
+var (
+	errBatchNotFound = errors.New("batch not found")
+	errNotBatchOwner = errors.New("only the uploader can cancel a batch")
+	errBatchFinished = errors.New("batch has already finished")
+)
 
 type DocumentService struct {
 	repo           *DocumentRepository
 	redis          *redis.Client
 	asyncProcessor *AsyncProcessor
 	externalClient *external.Client
+	storage        storage.Backend
+	audit          *audit.AuditService
+	workflows      *workflow.WorkflowService
+	metadata       *metadata.MetadataService
+	scanner        scanner.Scanner
 }
 
 type FileData struct {
 	Filename string
 	Size     int64
 	Content  []byte
+	ItemID   int64
 }
 
 type CrawlerUploadResult struct {
-	Filename string `json:"filename"`
-	Status   string `json:"status"`
-	Reason   string `json:"reason,omitempty"`
+	Filename      string `json:"filename"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	DuplicateOf   int    `json:"duplicate_of,omitempty"`
+	ScanStatus    string `json:"scan_status,omitempty"`
+	ScanSignature string `json:"scan_signature,omitempty"`
 }
 
 type fileProcessingContext struct {
 	category    string
 	email       string
 	accountType string
-	uploadDir   string
 	validTypes  map[string]bool
 	maxFileSize int
 	batchID     string
 	workerID    int
-	autoApprove bool
+	metadata    metadata.Values
+	tags        []string
 }
 
-func NewDocumentService(repo *DocumentRepository, redisClient *redis.Client, asyncProcessor *AsyncProcessor, externalClient *external.Client) *DocumentService {
+func NewDocumentService(repo *DocumentRepository, redisClient *redis.Client, asyncProcessor *AsyncProcessor, externalClient *external.Client, fileStorage storage.Backend, auditService *audit.AuditService, workflowService *workflow.WorkflowService, metadataService *metadata.MetadataService, malwareScanner scanner.Scanner) *DocumentService {
 	return &DocumentService{
 		repo:           repo,
 		redis:          redisClient,
 		asyncProcessor: asyncProcessor,
 		externalClient: externalClient,
+		storage:        fileStorage,
+		audit:          auditService,
+		workflows:      workflowService,
+		metadata:       metadataService,
+		scanner:        malwareScanner,
+	}
+}
+
+// SaveUploadedFile stores the upload under key and returns its SHA-256.
+func (s *DocumentService) SaveUploadedFile(fileHeader *multipart.FileHeader, key string) (string, error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	hasher := sha256.New()
+	reader := io.TeeReader(src, hasher)
+	if err := s.storage.Put(context.Background(), key, reader, fileHeader.Size, storage.ContentTypeFor(key)); err != nil {
+		return "", err
 	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (s *DocumentService) saveFileContent(key string, content []byte) error {
+	return s.storage.Put(context.Background(), key, bytes.NewReader(content), int64(len(content)), storage.ContentTypeFor(key))
+}
+
+// OpenFile opens a stored version for serving under its document name and
+// content type. Files without a version keep their storage name.
+func (s *DocumentService) OpenFile(key, disposition string) (io.ReadSeekCloser, util.ServedFile, error) {
+	content, info, err := storage.OpenSeeker(context.Background(), s.storage, key)
+	if err != nil {
+		return nil, util.ServedFile{}, err
+	}
+
+	file := util.ServedFile{
+		Name:         key,
+		Disposition:  disposition,
+		ContentType:  storage.ContentTypeFor(key),
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+	if detail, err := s.repo.GetDocumentDetailByFilename(key); err == nil {
+		file.Name = detail.DocumentName
+		if file.ContentType == "application/octet-stream" && detail.MimeType != nil {
+			file.ContentType = *detail.MimeType
+		}
+	}
+	return content, file, nil
+}
+
+func (s *DocumentService) RemoveFile(key string) {
+	if err := s.storage.Delete(context.Background(), key); err != nil {
+		log.Printf("Warning: Failed to remove file %s: %v", key, err)
+	}
+}
+
+func (s *DocumentService) ensureFileExists(key string) error {
+	_, err := s.storage.Stat(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("document file not found: %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check document file: %w", err)
+	}
+	return nil
 }
 
-func (s *DocumentService) GenerateViewToken(filename string) (string, error) {
+const viewTokenTTL = 5 * time.Minute
+
+// GenerateViewToken issues a token for the view-file URL. viewer is recorded
+// as a view of the document once the file is opened.
+func (s *DocumentService) GenerateViewToken(filename string, viewer DocumentAccessEvent) (string, error) {
+	return issueViewToken(s.redis, filename, viewer, viewTokenTTL)
+}
+
+func issueViewToken(redisClient *redis.Client, filename string, viewer DocumentAccessEvent, ttl time.Duration) (string, error) {
 	token := util.RandString(32)
 	key := "view_token:" + token
 
 	ctx := context.Background()
-	err := s.redis.Set(ctx, key, filename, 5*time.Minute).Err()
+	err := redisClient.Set(ctx, key, filename, ttl).Err()
 	if err != nil {
 		return "", fmt.Errorf("failed to store view token: %w", err)
 	}
+	trackViewToken(redisClient, token, viewer, ttl)
 
 	return token, nil
 }
 
-func (s *DocumentService) GenerateViewTokenByID(id int) (string, error) {
+func (s *DocumentService) GenerateViewTokenByID(id int, viewer DocumentAccessEvent) (string, error) {
 	detail, err := s.repo.GetDocumentDetailByID(id)
 	if err != nil {
 		return "", fmt.Errorf("document detail not found: %w", err)
 	}
-	return s.GenerateViewToken(detail.Filename)
+	return s.GenerateViewToken(detail.Filename, viewer)
+}
+
+type DuplicateContentError struct {
+	Existing *DocumentDetail
+}
+
+func (e *DuplicateContentError) Error() string {
+	return fmt.Sprintf("menolak upload karena isi file sama dengan dokumen %q yang sudah ada (document ID %d)",
+		e.Existing.DocumentName, e.Existing.DocumentID)
 }
 
 func (s *DocumentService) CreateDocument(document *Document, detail *DocumentDetail) error {
@@ -89,6 +220,9 @@ func (s *DocumentService) CreateDocument(document *Document, detail *DocumentDet
 	if err == nil && existing != nil {
 		return fmt.Errorf("menolak upload karena file dengan document yang sama namanya sudah ada")
 	}
+	if err := s.checkContentDuplicate(detail); err != nil {
+		return err
+	}
 	if err := s.repo.CreateDocument(document); err != nil {
 		return err
 	}
@@ -97,14 +231,41 @@ func (s *DocumentService) CreateDocument(document *Document, detail *DocumentDet
 	detail.RequestType = &newReq
 	detail.DocumentID = document.ID
 
-	return s.repo.CreateDocumentDetail(detail)
+	autoApprove := s.assignWorkflow(document.Category, detail)
+	if err := s.repo.CreateDocumentDetail(detail); err != nil {
+		return err
+	}
+	if autoApprove {
+		s.autoApproveDetail(detail)
+	}
+	return nil
 }
 
 func (s *DocumentService) UpdateDocument(documentID int, detail *DocumentDetail) error {
-	_, err := s.repo.GetDocumentByID(documentID)
+	return s.UpdateDocumentWithMetadata(documentID, detail, nil, audit.Actor{})
+}
+
+// UpdateDocumentWithMetadata submits detail as a new version of the document.
+// When metadataReq is set, the document's metadata and tags are replaced
+// first, and an invalid value stops the update.
+func (s *DocumentService) UpdateDocumentWithMetadata(documentID int, detail *DocumentDetail, metadataReq *MetadataRequest, actor audit.Actor) error {
+	document, err := s.repo.GetDocumentByID(documentID)
 	if err != nil {
 		return err
 	}
+	if document.DeletedAt != nil {
+		return errDocumentTrashed
+	}
+
+	if err := s.checkContentDuplicate(detail); err != nil {
+		return err
+	}
+
+	if metadataReq != nil {
+		if _, err := s.UpdateDocumentMetadata(documentID, *metadataReq, actor); err != nil {
+			return err
+		}
+	}
 
 	falseValue := false
 	detail.IsLatest = &falseValue
@@ -113,8 +274,91 @@ func (s *DocumentService) UpdateDocument(documentID int, detail *DocumentDetail)
 	reqType := "UPDATE"
 	detail.RequestType = &reqType
 
-	return s.repo.CreateDocumentDetail(detail)
+	autoApprove := s.assignWorkflow(document.Category, detail)
+	if err := s.repo.CreateDocumentDetail(detail); err != nil {
+		return err
+	}
+	if autoApprove {
+		s.autoApproveDetail(detail)
+	}
+	return nil
 }
+
+// assignWorkflow puts a pending version on the first step of the workflow for
+// its category and team. It returns true when that workflow auto-approves.
+func (s *DocumentService) assignWorkflow(category string, detail *DocumentDetail) bool {
+	if detail.Status == nil || *detail.Status != "Pending" {
+		return false
+	}
+
+	wf, err := s.workflows.Resolve(category, detail.Team)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return false
+	}
+	if wf == nil {
+		return false
+	}
+	if wf.AutoApprove {
+		return true
+	}
+	if len(wf.Steps) == 0 {
+		return false
+	}
+
+	firstStep := wf.Steps[0].StepOrder
+	detail.WorkflowID = &wf.ID
+	detail.CurrentStep = &firstStep
+	return false
+}
+
+func (s *DocumentService) autoApproveDetail(detail *DocumentDetail) {
+	if err := s.ApproveDocument(detail.ID, audit.Actor{}); err != nil {
+		log.Printf("Warning: Failed to auto-approve detail ID %d: %v", detail.ID, err)
+		return
+	}
+
+	approved := "Approved"
+	isTrue := true
+	detail.Status = &approved
+	detail.IsApprove = &isTrue
+	detail.IsLatest = &isTrue
+}
+
+// checkContentDuplicate fills in the SHA-256 of the saved file when the caller
+// has not already computed it, and rejects content that is already live.
+func (s *DocumentService) checkContentDuplicate(detail *DocumentDetail) error {
+	if detail.ContentHash == nil {
+		hash, err := s.hashStoredFile(detail.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to hash document: %w", err)
+		}
+		detail.ContentHash = &hash
+	}
+
+	existing, err := s.repo.FindDetailByContentHash(*detail.ContentHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicate content: %w", err)
+	}
+	return &DuplicateContentError{Existing: existing}
+}
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *DocumentService) hashStoredFile(key string) (string, error) {
+	hasher := sha256.New()
+	if _, err := s.storage.Stream(context.Background(), key, hasher); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 func (s *DocumentService) GetAllDocuments(filter DocumentFilter) ([]DocumentWithDetail, int, error) {
 	documents, err := s.repo.GetAllDocuments(filter)
 	if err != nil {
@@ -129,41 +373,178 @@ func (s *DocumentService) GetAllDocuments(filter DocumentFilter) ([]DocumentWith
 	return documents, total, nil
 }
 
+func (s *DocumentService) GetDocumentFacets(filter DocumentFilter) (*metadata.Facets, error) {
+	keys, err := s.metadata.FacetKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata fields: %w", err)
+	}
+	return s.repo.GetDocumentFacets(filter, keys)
+}
+
+// SearchDocuments runs a full-text query over the indexed text of the
+// versions. Only the latest versions are searched unless AllVersions is set.
+func (s *DocumentService) SearchDocuments(filter SearchFilter) ([]SearchHit, int, error) {
+	hits, total, err := s.repo.SearchDocumentText(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search documents: %w", err)
+	}
+	return hits, total, nil
+}
+
+// PrepareMetadata validates submitted metadata and tags against the field
+// definitions and returns them in their stored form.
+func (s *DocumentService) PrepareMetadata(req MetadataRequest) (metadata.Values, []string, error) {
+	values, err := s.metadata.Normalize(req.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := metadata.NormalizeTags(req.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, tags, nil
+}
+
+// UpdateDocumentMetadata replaces a document's metadata and tags and queues
+// the published version for extraction, so the RAG index gets the new values.
+func (s *DocumentService) UpdateDocumentMetadata(documentID int, req MetadataRequest, actor audit.Actor) (*Document, error) {
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+	if document.DeletedAt != nil {
+		return nil, errDocumentTrashed
+	}
+
+	values, tags, err := s.PrepareMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateDocumentMetadata(documentID, values, tags); err != nil {
+		return nil, fmt.Errorf("failed to update document metadata: %w", err)
+	}
+
+	s.resyncMetadata(document)
+
+	updated := &Document{ID: document.ID, Category: document.Category, Metadata: values, Tags: tags}
+	s.audit.Record(actor, audit.ActionDocumentMetadata, audit.EntityDocument, documentID, document, updated)
+	return updated, nil
+}
+
+// resyncMetadata replaces the RAG copy of a published document, the same way
+// an approval does, since the RAG only takes metadata with the file.
+func (s *DocumentService) resyncMetadata(document *Document) {
+	if _, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(document.ID); err != nil {
+		return
+	}
+
+	deleteReq := external.DeleteRequest{ID: document.ID, Category: document.Category}
+	if err := s.externalClient.DeleteDocument(context.Background(), deleteReq); err != nil {
+		log.Printf("Warning: Failed to delete document from external API (ID: %d): %v", document.ID, err)
+	}
+	s.reingestPublished(document)
+}
+
 func (s *DocumentService) GetDocumentDetailsByDocumentID(documentID int) ([]DocumentDetail, error) {
 	return s.repo.GetDocumentDetailsByDocumentID(documentID)
 }
 
-func (s *DocumentService) ApproveDocument(detailID int) error {
+// detailSnapshot returns the current row for audit "after" data, or nil when
+// it no longer exists.
+func (s *DocumentService) detailSnapshot(detailID int) interface{} {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return nil
+	}
+	return detail
+}
+
+func (s *DocumentService) ApproveDocument(detailID int, actor audit.Actor) error {
 	detail, err := s.repo.GetDocumentDetailByID(detailID)
 	if err != nil {
 		return fmt.Errorf("failed to get document detail: %w", err)
 	}
 
 	if detail.RequestType != nil && *detail.RequestType == "DELETE" {
-
-		return s.ExecuteHardDelete(detail.DocumentID)
+		if err := s.TrashDocument(detail.DocumentID, actor); err != nil {
+			return err
+		}
+		// The version goes to the recycle bin as it was before the request,
+		// so a restore publishes it again.
+		if err := s.repo.RestoreStatus(detailID); err != nil {
+			log.Printf("Warning: Failed to close delete request of detail ID %d: %v", detailID, err)
+		}
+		s.audit.Record(actor, audit.ActionDocumentDelete, audit.EntityDocument, detail.DocumentID, detail, nil)
+		return nil
 	}
 
-	if detail.Status != nil && *detail.Status == "Approved" {
+	if detail.Status != nil && (*detail.Status == "Approved" || *detail.Status == statusScheduled) {
 		return fmt.Errorf("document is already approved")
 	}
+	if detail.WorkflowID != nil && detail.Status != nil && *detail.Status == "Rejected" {
+		return fmt.Errorf("document was rejected in its approval workflow")
+	}
+	if pastExpiry(detail) {
+		return fmt.Errorf("document expired on %s, set a later expires_at first", detail.ExpiresAt.Format("2006-01-02"))
+	}
 
 	document, err := s.repo.GetDocumentByID(detail.DocumentID)
 	if err != nil {
 		return fmt.Errorf("failed to get document: %w", err)
 	}
+	if document.DeletedAt != nil {
+		return errDocumentTrashed
+	}
+
+	if err := s.ensureFileExists(detail.Filename); err != nil {
+		return err
+	}
+
+	final, err := s.approveStep(detail, actor)
+	if err != nil {
+		return err
+	}
+	if !final {
+		return nil
+	}
 
-	filePath := config.GetDocumentPath(detail.Filename)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("document file not found: %s", detail.Filename)
+	if err := s.repo.UpdateDocumentDetailApprover(detailID, actor.UserID); err != nil {
+		return fmt.Errorf("failed to record approver: %w", err)
 	}
 
+	if detail.CurrentStep != nil {
+		if err := s.repo.UpdateDocumentDetailStep(detailID, nil); err != nil {
+			return fmt.Errorf("failed to close approval workflow: %w", err)
+		}
+	}
+
+	// A version approved before its valid_from waits for the scheduler; the
+	// current version stays published until then.
+	if notYetValid(detail) {
+		if err := s.repo.UpdateDocumentDetailStatus(detailID, statusScheduled); err != nil {
+			return fmt.Errorf("failed to set status to Scheduled: %w", err)
+		}
+		s.audit.Record(actor, audit.ActionDocumentApprove, audit.EntityDocumentDetail, detailID, detail, s.detailSnapshot(detailID))
+		return nil
+	}
+
+	if err := s.publishVersion(detail, document); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionDocumentApprove, audit.EntityDocumentDetail, detailID, detail, s.detailSnapshot(detailID))
+	return nil
+}
+
+// publishVersion makes an approved version the live one: the previous
+// version leaves the RAG index and the new one is queued for extraction.
+func (s *DocumentService) publishVersion(detail *DocumentDetail, document *Document) error {
 	deleteReq := external.DeleteRequest{
 		ID:       detail.DocumentID,
 		Category: document.Category,
 	}
 
-	if err := s.externalClient.DeleteDocument(deleteReq); err != nil {
+	if err := s.externalClient.DeleteDocument(context.Background(), deleteReq); err != nil {
 		log.Printf("Warning: Failed to delete document from external API (ID: %d): %v", detail.DocumentID, err)
 
 	} else {
@@ -174,11 +555,11 @@ func (s *DocumentService) ApproveDocument(detailID int) error {
 		return fmt.Errorf("failed to update is_approve for other documents: %w", err)
 	}
 
-	if err := s.repo.UpdateDocumentDetailApprove(detailID, true); err != nil {
+	if err := s.repo.UpdateDocumentDetailApprove(detail.ID, true); err != nil {
 		return fmt.Errorf("failed to set is_approve: %w", err)
 	}
 
-	if err := s.repo.UpdateDocumentDetailStatus(detailID, "Approved"); err != nil {
+	if err := s.repo.UpdateDocumentDetailStatus(detail.ID, "Approved"); err != nil {
 		return fmt.Errorf("failed to set status to Approved: %w", err)
 	}
 
@@ -186,7 +567,7 @@ func (s *DocumentService) ApproveDocument(detailID int) error {
 		return fmt.Errorf("failed to update is_latest for other documents: %w", err)
 	}
 
-	if err := s.repo.UpdateDocumentDetailLatestByID(detailID, true); err != nil {
+	if err := s.repo.UpdateDocumentDetailLatestByID(detail.ID, true); err != nil {
 		return fmt.Errorf("failed to set is_latest for approved document: %w", err)
 	}
 
@@ -194,29 +575,111 @@ func (s *DocumentService) ApproveDocument(detailID int) error {
 		ID:       strconv.Itoa(detail.DocumentID),
 		Category: document.Category,
 		Filename: detail.DocumentName,
-		FilePath: filePath,
+		FilePath: detail.Filename,
 	}
 
 	job := ExtractionJob{
-		DetailID: detailID,
+		DetailID: detail.ID,
 		Request:  extractReq,
 	}
 
 	if err := s.asyncProcessor.SubmitJob(job); err != nil {
-		log.Printf("Warning: Failed to submit extraction job for detail ID %d: %v", detailID, err)
+		log.Printf("Warning: Failed to submit extraction job for detail ID %d: %v", detail.ID, err)
 	}
-
 	return nil
 }
 
-func (s *DocumentService) RejectDocument(detailID int) error {
+// approveStep records the actor's approval of the version's current workflow
+// step. It returns true when no steps remain and the version can be published.
+func (s *DocumentService) approveStep(detail *DocumentDetail, actor audit.Actor) (bool, error) {
+	if detail.WorkflowID == nil || detail.CurrentStep == nil {
+		if actor.UserID != nil {
+			s.recordDecision(detail, nil, workflow.DecisionApproved, actor)
+		}
+		return true, nil
+	}
+
+	wf, step, err := s.workflows.Step(*detail.WorkflowID, *detail.CurrentStep)
+	if err != nil {
+		return false, err
+	}
+	if step == nil {
+		// The workflow was shortened after this version entered it.
+		s.recordDecision(detail, nil, workflow.DecisionApproved, actor)
+		return true, nil
+	}
+
+	if err := s.workflows.CheckReviewer(step, detail.ID, actor); err != nil {
+		return false, err
+	}
+	s.recordDecision(detail, step, workflow.DecisionApproved, actor)
+
+	var next *int
+	for _, candidate := range wf.Steps {
+		if candidate.StepOrder > step.StepOrder {
+			order := candidate.StepOrder
+			next = &order
+			break
+		}
+	}
+	if next == nil {
+		return true, nil
+	}
+
+	if err := s.repo.UpdateDocumentDetailStep(detail.ID, next); err != nil {
+		return false, fmt.Errorf("failed to advance approval workflow: %w", err)
+	}
+	s.audit.Record(actor, audit.ActionDocumentApproveStep, audit.EntityDocumentDetail, detail.ID, detail, s.detailSnapshot(detail.ID))
+	return false, nil
+}
+
+func (s *DocumentService) recordDecision(detail *DocumentDetail, step *workflow.Step, decision string, actor audit.Actor) {
+	if err := s.workflows.RecordDecision(detail.ID, detail.WorkflowID, step, decision, actor); err != nil {
+		log.Printf("Warning: Failed to record %s decision for detail ID %d: %v", decision, detail.ID, err)
+	}
+}
+
+func (s *DocumentService) GetApprovalProgress(detailID int) (*workflow.Progress, error) {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return nil, fmt.Errorf("document detail not found: %w", err)
+	}
+	return s.workflows.Progress(detail.ID, detail.WorkflowID, detail.CurrentStep)
+}
+
+// RejectDocument ends the review of a version. The reason and codes go to
+// the version itself and to its comment thread, where the uploader sees them.
+func (s *DocumentService) RejectDocument(detailID int, req RejectRequest, actor audit.Actor) error {
+	reason, codes, err := validateRejection(req)
+	if err != nil {
+		return err
+	}
+
 	detail, err := s.repo.GetDocumentDetailByID(detailID)
 	if err != nil {
 		return err
 	}
 
 	if detail.RequestType != nil && *detail.RequestType == "DELETE" {
-		return s.repo.RestoreStatus(detailID)
+		if err := s.repo.RestoreStatus(detailID); err != nil {
+			return err
+		}
+		s.addRejectionComment(detailID, reason, codes, actor)
+		s.audit.Record(actor, audit.ActionDocumentReject, audit.EntityDocumentDetail, detailID, detail, s.detailSnapshot(detailID))
+		return nil
+	}
+
+	var step *workflow.Step
+	if detail.WorkflowID != nil && detail.CurrentStep != nil {
+		_, step, err = s.workflows.Step(*detail.WorkflowID, *detail.CurrentStep)
+		if err != nil {
+			return err
+		}
+		if step != nil {
+			if err := s.workflows.CheckReviewer(step, detailID, actor); err != nil {
+				return err
+			}
+		}
 	}
 
 	if err := s.repo.UpdateDocumentDetailApprove(detailID, false); err != nil {
@@ -228,10 +691,345 @@ func (s *DocumentService) RejectDocument(detailID int) error {
 	if err := s.repo.UpdateDocumentDetailIngestStatus(detailID, "unprocessed"); err != nil {
 		return fmt.Errorf("failed to set ingest_status to unprocessed: %w", err)
 	}
+	if detail.CurrentStep != nil {
+		if err := s.repo.UpdateDocumentDetailStep(detailID, nil); err != nil {
+			return fmt.Errorf("failed to close approval workflow: %w", err)
+		}
+	}
+	if err := s.repo.UpdateDocumentDetailRejection(detailID, reason, codes); err != nil {
+		return fmt.Errorf("failed to save rejection reason: %w", err)
+	}
+	s.recordDecision(detail, step, workflow.DecisionRejected, actor)
+	s.addRejectionComment(detailID, reason, codes, actor)
+
+	s.audit.Record(actor, audit.ActionDocumentReject, audit.EntityDocumentDetail, detailID, detail, s.detailSnapshot(detailID))
+	return nil
+}
+
+func validateRejection(req RejectRequest) (string, []string, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return "", nil, fmt.Errorf("%w: a rejection reason is required", errInvalidReview)
+	}
+	if len(reason) > maxReviewCommentLength {
+		return "", nil, fmt.Errorf("%w: rejection reason is longer than %d characters", errInvalidReview, maxReviewCommentLength)
+	}
+	if len(req.ReasonCodes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one reason code is required", errInvalidReview)
+	}
+
+	known := make(map[string]bool, len(RejectionReasons))
+	for _, r := range RejectionReasons {
+		known[r.Code] = true
+	}
+
+	codes := make([]string, 0, len(req.ReasonCodes))
+	seen := make(map[string]bool)
+	for _, code := range req.ReasonCodes {
+		code = strings.TrimSpace(code)
+		if !known[code] {
+			return "", nil, fmt.Errorf("%w: unknown reason code %q", errInvalidReview, code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return reason, codes, nil
+}
+
+func (s *DocumentService) addRejectionComment(detailID int, reason string, codes []string, actor audit.Actor) {
+	comment := &ReviewComment{
+		DetailID:     detailID,
+		AuthorUserID: actor.UserID,
+		Kind:         CommentKindRejection,
+		Comment:      reason,
+		ReasonCodes:  codes,
+	}
+	if err := s.repo.CreateReviewComment(comment); err != nil {
+		log.Printf("Warning: Failed to store rejection comment for detail ID %d: %v", detailID, err)
+	}
+}
+
+func (s *DocumentService) AddReviewComment(detailID int, text string, actor audit.Actor) (*ReviewComment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: comment cannot be empty", errInvalidReview)
+	}
+	if len(text) > maxReviewCommentLength {
+		return nil, fmt.Errorf("%w: comment is longer than %d characters", errInvalidReview, maxReviewCommentLength)
+	}
+
+	if _, err := s.repo.GetDocumentDetailByID(detailID); err != nil {
+		return nil, fmt.Errorf("document detail not found: %w", err)
+	}
+
+	comment := &ReviewComment{
+		DetailID:     detailID,
+		AuthorUserID: actor.UserID,
+		Kind:         CommentKindComment,
+		Comment:      text,
+	}
+	if err := s.repo.CreateReviewComment(comment); err != nil {
+		return nil, fmt.Errorf("failed to save comment: %w", err)
+	}
+	return comment, nil
+}
+
+func (s *DocumentService) GetReviewComments(detailID int) ([]ReviewComment, error) {
+	if _, err := s.repo.GetDocumentDetailByID(detailID); err != nil {
+		return nil, fmt.Errorf("document detail not found: %w", err)
+	}
+	return s.repo.GetReviewComments(detailID)
+}
+
+// ResubmitDocument uploads a corrected file for a rejected version. The new
+// version points back at the rejected one and starts a fresh review.
+func (s *DocumentService) ResubmitDocument(rejectedID int, detail *DocumentDetail, comment string, actor audit.Actor) error {
+	rejected, err := s.repo.GetDocumentDetailByID(rejectedID)
+	if err != nil {
+		return fmt.Errorf("document detail not found: %w", err)
+	}
+	if rejected.Status == nil || *rejected.Status != "Rejected" {
+		return fmt.Errorf("%w: only rejected versions can be resubmitted", errInvalidReview)
+	}
+	if !s.canResubmit(rejected, detail.Staff, actor) {
+		return errNotResubmitter
+	}
+	if existing, err := s.repo.GetResubmissionOf(rejectedID); err == nil && existing != nil {
+		return fmt.Errorf("%w: version %d was already resubmitted as version %d", errInvalidReview, rejectedID, existing.ID)
+	}
+
+	document, err := s.repo.GetDocumentByID(rejected.DocumentID)
+	if err != nil {
+		return err
+	}
+	if document.DeletedAt != nil {
+		return errDocumentTrashed
+	}
+	if err := s.checkContentDuplicate(detail); err != nil {
+		return err
+	}
+
+	reqType := "UPDATE"
+	if rejected.RequestType != nil && *rejected.RequestType == "NEW" {
+		reqType = "NEW"
+	}
+	// A document that was never approved keeps showing its newest upload.
+	isLatest := reqType == "NEW"
+
+	detail.DocumentID = rejected.DocumentID
+	detail.RequestType = &reqType
+	detail.IsLatest = &isLatest
+	detail.ResubmittedFrom = &rejectedID
+	if detail.ValidFrom == nil && detail.ReviewBy == nil && detail.ExpiresAt == nil {
+		detail.ValidFrom, detail.ReviewBy, detail.ExpiresAt = rejected.ValidFrom, rejected.ReviewBy, rejected.ExpiresAt
+	}
+
+	autoApprove := s.assignWorkflow(document.Category, detail)
+	if err := s.repo.CreateDocumentDetail(detail); err != nil {
+		return err
+	}
+	if isLatest {
+		if err := s.repo.UpdateDocumentDetailLatestByID(rejectedID, false); err != nil {
+			log.Printf("Warning: Failed to clear is_latest on rejected detail ID %d: %v", rejectedID, err)
+		}
+	}
+
+	if strings.TrimSpace(comment) != "" {
+		if _, err := s.AddReviewComment(detail.ID, comment, actor); err != nil {
+			log.Printf("Warning: Failed to store resubmission comment for detail ID %d: %v", detail.ID, err)
+		}
+	}
+
+	s.audit.Record(actor, audit.ActionDocumentResubmit, audit.EntityDocumentDetail, detail.ID, rejected, detail)
+
+	if autoApprove {
+		s.autoApproveDetail(detail)
+	}
+	return nil
+}
+
+// canResubmit is true for the uploader of the rejected version and for
+// document masters.
+func (s *DocumentService) canResubmit(rejected *DocumentDetail, email string, actor audit.Actor) bool {
+	if rejected.Staff == email {
+		return true
+	}
+	if actor.UserID == nil {
+		return false
+	}
+	ok, err := middleware.UserHasPermission(*actor.UserID, permDocumentMaster)
+	if err != nil {
+		log.Printf("Warning: Failed to check document permissions of user %d: %v", *actor.UserID, err)
+		return false
+	}
+	return ok
+}
+
+func (s *DocumentService) GetDocumentVersions(documentID int) ([]DocumentVersion, error) {
+	if _, err := s.repo.GetDocumentByID(documentID); err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+	return s.repo.GetDocumentVersions(documentID)
+}
+
+// DiffVersions compares the extracted text of two versions of the same
+// document.
+func (s *DocumentService) DiffVersions(fromDetailID, toDetailID int) (*VersionDiff, error) {
+	from, err := s.repo.GetDocumentVersion(fromDetailID)
+	if err != nil {
+		return nil, fmt.Errorf("version %d not found: %w", fromDetailID, err)
+	}
+	to, err := s.repo.GetDocumentVersion(toDetailID)
+	if err != nil {
+		return nil, fmt.Errorf("version %d not found: %w", toDetailID, err)
+	}
+	if from.DocumentID != to.DocumentID {
+		return nil, fmt.Errorf("versions belong to different documents")
+	}
+
+	fromText, err := s.versionText(fromDetailID)
+	if err != nil {
+		return nil, err
+	}
+	toText, err := s.versionText(toDetailID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := util.DiffLines(util.SplitLines(fromText), util.SplitLines(toText))
+	return &VersionDiff{
+		DocumentID: from.DocumentID,
+		From:       from,
+		To:         to,
+		Stats:      util.SummarizeDiff(changes),
+		Changes:    changes,
+	}, nil
+}
+
+func (s *DocumentService) versionText(detailID int) (string, error) {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get document detail: %w", err)
+	}
+
+	filePath, cleanup, err := storage.LocalPath(context.Background(), s.storage, detail.Filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read version %d: %w", detailID, err)
+	}
+	defer cleanup()
+
+	text, err := external.ExtractText(filePath, detail.DataType)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text of version %d: %w", detailID, err)
+	}
+	return text, nil
+}
+
+// RollbackDocument makes an earlier approved version the active one again.
+// It goes through ApproveDocument so the RAG index is rebuilt from that file.
+// The version passed review before, so it skips the category's workflow;
+// that is why the route only allows document-management:master.
+func (s *DocumentService) RollbackDocument(detailID int, actor audit.Actor) error {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return fmt.Errorf("document detail not found: %w", err)
+	}
 
+	if detail.IsLatest != nil && *detail.IsLatest && detail.IsApprove != nil && *detail.IsApprove {
+		return fmt.Errorf("this version is already the active version")
+	}
+	if detail.ApprovedAt == nil && (detail.Status == nil || *detail.Status != "Approved") {
+		return fmt.Errorf("only previously approved versions can be restored")
+	}
+	if pastExpiry(detail) {
+		return fmt.Errorf("this version expired on %s, set a later expires_at before restoring it", detail.ExpiresAt.Format("2006-01-02"))
+	}
+
+	current, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(detail.DocumentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get active version: %w", err)
+	}
+	if current != nil && current.RequestType != nil && *current.RequestType == "DELETE" {
+		return fmt.Errorf("document has a pending delete request")
+	}
+	if current != nil && current.IngestStatus != nil && *current.IngestStatus == ingestStatusProcessing {
+		return fmt.Errorf("the active version is still being extracted")
+	}
+
+	if err := s.repo.PrepareRollback(detailID); err != nil {
+		return fmt.Errorf("failed to prepare rollback: %w", err)
+	}
+
+	if err := s.ApproveDocument(detailID, actor); err != nil {
+		if restoreErr := s.repo.RevertRollback(detail); restoreErr != nil {
+			log.Printf("Warning: Failed to restore detail ID %d after failed rollback: %v", detailID, restoreErr)
+		}
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionDocumentRollback, audit.EntityDocumentDetail, detailID, current, s.detailSnapshot(detailID))
 	return nil
 }
+
+func (s *DocumentService) ReingestDocument(detailID int) error {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return fmt.Errorf("document detail not found: %w", err)
+	}
+
+	if detail.IngestStatus == nil || *detail.IngestStatus != ingestStatusFailed {
+		return fmt.Errorf("only details with a failed ingestion can be re-triggered")
+	}
+	if detail.IsApprove == nil || !*detail.IsApprove {
+		return fmt.Errorf("document detail is not approved")
+	}
+
+	document, err := s.repo.GetDocumentByID(detail.DocumentID)
+	if err != nil {
+		return fmt.Errorf("failed to get document: %w", err)
+	}
+	if document.DeletedAt != nil {
+		return errDocumentTrashed
+	}
+
+	requeued, err := s.asyncProcessor.RetryJobs(detailID)
+	if err != nil {
+		return err
+	}
+	if requeued {
+		return nil
+	}
+
+	if err := s.ensureFileExists(detail.Filename); err != nil {
+		return err
+	}
+
+	job := ExtractionJob{
+		DetailID: detailID,
+		Request: external.ExtractRequest{
+			ID:       strconv.Itoa(detail.DocumentID),
+			Category: document.Category,
+			Filename: detail.DocumentName,
+			FilePath: detail.Filename,
+		},
+	}
+
+	return s.asyncProcessor.SubmitJob(job)
+}
+
 func (s *DocumentService) RequestDelete(documentID int) error {
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return fmt.Errorf("cannot delete: document not found")
+	}
+	if document.LegalHold {
+		return errLegalHold
+	}
+	if document.DeletedAt != nil {
+		return errDocumentTrashed
+	}
 
 	detail, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(documentID)
 	if err != nil {
@@ -239,7 +1037,7 @@ func (s *DocumentService) RequestDelete(documentID int) error {
 		return fmt.Errorf("cannot delete: active document detail not found")
 	}
 
-	if detail.IngestStatus != nil && *detail.IngestStatus == "processing" {
+	if detail.IngestStatus != nil && *detail.IngestStatus == ingestStatusProcessing {
 		return fmt.Errorf("tidak dapat mengajukan penghapusan karena dokumen sedang dalam proses ekstraksi")
 	}
 
@@ -268,18 +1066,11 @@ func (s *DocumentService) ExecuteHardDelete(documentID int) error {
 	if err != nil {
 		return fmt.Errorf("failed to get document: %w", err)
 	}
-
-	deleteReq := external.DeleteRequest{
-		ID:       documentID,
-		Category: document.Category,
+	if document.LegalHold {
+		return errLegalHold
 	}
 
-	if err := s.externalClient.DeleteDocument(deleteReq); err != nil {
-		log.Printf("Warning: Failed to delete document from external API (ID: %d): %v", documentID, err)
-
-	} else {
-		log.Printf("Successfully deleted document from external API (ID: %d)", documentID)
-	}
+	s.removeFromIndex(document)
 
 	details, err := s.repo.GetDocumentDetailsByDocumentID(documentID)
 	if err != nil {
@@ -287,10 +1078,7 @@ func (s *DocumentService) ExecuteHardDelete(documentID int) error {
 	}
 
 	for _, detail := range details {
-		filePath := config.GetDocumentPath(detail.Filename)
-		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: Failed to delete file %s: %v\n", filePath, err)
-		}
+		s.RemoveFile(detail.Filename)
 	}
 
 	if err := s.repo.DeleteDocumentDetails(documentID); err != nil {
@@ -304,7 +1092,7 @@ func (s *DocumentService) ExecuteHardDelete(documentID int) error {
 	return nil
 }
 
-func (s *DocumentService) DeleteDocument(documentID int) error {
+func (s *DocumentService) DeleteDocument(documentID int, actor audit.Actor) error {
     details, err := s.repo.GetDocumentDetailsByDocumentID(documentID)
     if err != nil || len(details) == 0 {
         return fmt.Errorf("dokumen tidak ditemukan")
@@ -322,7 +1110,11 @@ func (s *DocumentService) DeleteDocument(documentID int) error {
     
     if activeDetail != nil {
         log.Printf("Mengajukan request delete untuk dokumen ID %d (Active Detail ID: %d)", documentID, activeDetail.ID)
-        return s.RequestDelete(documentID)
+        if err := s.RequestDelete(documentID); err != nil {
+            return err
+        }
+        s.audit.Record(actor, audit.ActionDocumentDeleteRequest, audit.EntityDocumentDetail, activeDetail.ID, activeDetail, s.detailSnapshot(activeDetail.ID))
+        return nil
     }
 
     
@@ -330,17 +1122,53 @@ func (s *DocumentService) DeleteDocument(documentID int) error {
     latest := details[0]
 
     isRejected := latest.Status != nil && *latest.Status == "Rejected"
-    isPendingNew := latest.Status != nil && *latest.Status == "Pending" &&
+    isPendingNew := latest.Status != nil && (*latest.Status == "Pending" || *latest.Status == statusScheduled) &&
         latest.RequestType != nil && *latest.RequestType == "NEW"
+    isExpired := latest.Status != nil && *latest.Status == statusExpired
 
-    if isPendingNew || isRejected {
-        log.Printf("Menghapus permanen dokumen ID %d", documentID)
-        return s.ExecuteHardDelete(documentID)
+    if isPendingNew || isRejected || isExpired {
+        log.Printf("Memindahkan dokumen ID %d ke recycle bin", documentID)
+        if err := s.TrashDocument(documentID, actor); err != nil {
+            return err
+        }
+        s.audit.Record(actor, audit.ActionDocumentDelete, audit.EntityDocument, documentID, details, nil)
+        return nil
     }
 
     return fmt.Errorf("dokumen tidak dapat dihapus dalam status saat ini")
 }
 
+// UpdateDocumentDates changes the validity window of a version. Moving
+// review_by re-arms the review reminder.
+func (s *DocumentService) UpdateDocumentDates(detailID int, dates DocumentDates, actor audit.Actor) (*DocumentDetail, error) {
+	detail, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return nil, fmt.Errorf("document detail not found: %w", err)
+	}
+	if err := validateDates(dates); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateDocumentDetailDates(detailID, dates); err != nil {
+		return nil, fmt.Errorf("failed to update document dates: %w", err)
+	}
+
+	updated, err := s.repo.GetDocumentDetailByID(detailID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(actor, audit.ActionDocumentUpdateDates, audit.EntityDocumentDetail, detailID, detail, updated)
+	return updated, nil
+}
+
+func (s *DocumentService) GetNotifications(recipient string, unreadOnly bool, limit, offset int) ([]DocumentNotification, int, error) {
+	return s.repo.GetNotifications(recipient, unreadOnly, limit, offset)
+}
+
+func (s *DocumentService) MarkNotificationRead(id int64, recipient string) error {
+	return s.repo.MarkNotificationRead(id, recipient)
+}
+
 func GenerateUniqueFilename(originalFilename string) string {
 	ext := filepath.Ext(originalFilename)
 	timestamp := time.Now().Unix()
@@ -348,6 +1176,20 @@ func GenerateUniqueFilename(originalFilename string) string {
 	return fmt.Sprintf("%d_%s%s", timestamp, uniqueID, ext)
 }
 
+// acceptedFileTypes is the single list of upload types, taken from the
+// extractors registered in the external package.
+func acceptedFileTypes() map[string]bool {
+	validTypes := make(map[string]bool)
+	for _, ext := range external.SupportedExtensions() {
+		validTypes[ext] = true
+	}
+	return validTypes
+}
+
+func invalidFileTypeMessage() string {
+	return "Invalid file type. Allowed types: " + strings.Join(external.SupportedExtensions(), ", ")
+}
+
 func (s *DocumentService) GetAllDocumentDetails(filter DocumentDetailFilter) ([]DocumentDetail, int, error) {
 	details, err := s.repo.GetAllDocumentDetails(filter)
 	if err != nil {
@@ -366,25 +1208,39 @@ func (s *DocumentService) GetExtractionQueueSize() int {
 	return s.asyncProcessor.GetQueueSize()
 }
 
-func (s *DocumentService) StartBatchUpload(files []*multipart.FileHeader, category, email, accountType string, autoApprove bool) (string, error) {
+func (s *DocumentService) GetExtractionQueueStats() (*ExtractionQueueStats, error) {
+	return s.asyncProcessor.GetQueueStats()
+}
+
+// StartBatchUpload processes files in the background. Every document of the
+// batch gets values and tags, which PrepareMetadata has already checked, and
+// goes through the workflow of its category and team like a single upload.
+func (s *DocumentService) StartBatchUpload(files []*multipart.FileHeader, category, email, accountType string, values metadata.Values, tags []string) (*UploadBatch, error) {
 	batchID := util.RandString(16)
 
 	fileDataList := make([]FileData, 0, len(files))
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			log.Printf("Failed to open file %s during preparation: %v", fileHeader.Filename, err)
-			continue
+	items := make([]UploadBatchItem, 0, len(files))
+	itemIndex := make([]int, 0, len(files))
+	for i, fileHeader := range files {
+		item := UploadBatchItem{
+			Position: i + 1,
+			Filename: fileHeader.Filename,
+			Size:     fileHeader.Size,
+			Status:   batchItemStatusPending,
 		}
 
-		content, err := io.ReadAll(file)
-		file.Close()
-
+		content, err := readUploadedFile(fileHeader)
 		if err != nil {
 			log.Printf("Failed to read file %s during preparation: %v", fileHeader.Filename, err)
+			reason := "Failed to read file"
+			item.Status = batchItemStatusFailed
+			item.Error = &reason
+			items = append(items, item)
 			continue
 		}
 
+		items = append(items, item)
+		itemIndex = append(itemIndex, len(items)-1)
 		fileDataList = append(fileDataList, FileData{
 			Filename: fileHeader.Filename,
 			Size:     fileHeader.Size,
@@ -393,70 +1249,87 @@ func (s *DocumentService) StartBatchUpload(files []*multipart.FileHeader, catego
 	}
 
 	if len(fileDataList) == 0 {
-		return "", fmt.Errorf("no valid files to process")
+		return nil, fmt.Errorf("no valid files to process")
+	}
+
+	// Recorded for the uploader; each version is approved by its workflow.
+	autoApprove := false
+	wf, err := s.workflows.Resolve(category, accountType)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	} else if wf != nil {
+		autoApprove = wf.AutoApprove
 	}
 
-	if err := s.setBatchStatus(batchID, map[string]interface{}{
-		"total":        len(fileDataList),
-		"processed":    0,
-		"successful":   0,
-		"failed":       0,
-		"extracted":    0,
-		"status":       "processing",
-		"auto_approve": autoApprove,
-		"started_at":   time.Now().Format(time.RFC3339),
-	}); err != nil {
-		return "", fmt.Errorf("failed to set batch status: %w", err)
+	batch := &UploadBatch{
+		ID:          batchID,
+		Category:    category,
+		Staff:       email,
+		Team:        accountType,
+		AutoApprove: autoApprove,
+		Status:      batchStatusProcessing,
+		Total:       len(items),
+	}
+	if err := s.repo.CreateUploadBatch(batch, items); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	for i := range fileDataList {
+		fileDataList[i].ItemID = items[itemIndex[i]].ID
 	}
 
-	go s.processBatchUpload(batchID, fileDataList, category, email, accountType, autoApprove)
+	go s.processBatchUpload(batchID, fileDataList, category, email, accountType, values, tags)
 
-	return batchID, nil
+	return batch, nil
 }
 
-type batchStats struct {
-	processed   int
-	successful  int
-	failed      int
-	extracted   int
-	total       int
-	batchID     string
-	autoApprove bool
-	mu          sync.Mutex
+func readUploadedFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// batchFileResult is the outcome of one file, stored on its batch item.
+type batchFileResult struct {
+	Status           string
+	Reason           string
+	ScanStatus       string
+	ScanSignature    string
+	ExtractionStatus string
+	ExtractionError  string
+}
+
+func failedBatchFile(reason string) batchFileResult {
+	return batchFileResult{Status: batchItemStatusFailed, Reason: reason}
 }
 
 type batchWorkerConfig struct {
 	category    string
 	email       string
 	accountType string
-	uploadDir   string
 	validTypes  map[string]bool
 	maxFileSize int
 	batchID     string
-	autoApprove bool
+	metadata    metadata.Values
+	tags        []string
 }
 
-func (s *DocumentService) processBatchUpload(batchID string, files []FileData, category, email, accountType string, autoApprove bool) {
-	uploadDir, maxFileSize, validTypes, err := s.prepareBatchEnv(batchID)
-	if err != nil {
-		return
-	}
-
-	stats := &batchStats{
-		total:       len(files),
-		batchID:     batchID,
-		autoApprove: autoApprove,
-	}
+func (s *DocumentService) processBatchUpload(batchID string, files []FileData, category, email, accountType string, values metadata.Values, tags []string) {
+	maxFileSize, validTypes := s.prepareBatchEnv()
 
 	config := &batchWorkerConfig{
 		category:    category,
 		email:       email,
 		accountType: accountType,
-		uploadDir:   uploadDir,
 		validTypes:  validTypes,
 		maxFileSize: maxFileSize,
 		batchID:     batchID,
-		autoApprove: autoApprove,
+		metadata:    values,
+		tags:        tags,
 	}
 
 	workerCount := 10
@@ -465,7 +1338,7 @@ func (s *DocumentService) processBatchUpload(batchID string, files []FileData, c
 
 	for w := 0; w < workerCount; w++ {
 		wg.Add(1)
-		go s.runBatchWorker(w, jobs, &wg, stats, config)
+		go s.runBatchWorker(w, jobs, &wg, config)
 	}
 
 	for _, file := range files {
@@ -474,17 +1347,11 @@ func (s *DocumentService) processBatchUpload(batchID string, files []FileData, c
 	close(jobs)
 
 	wg.Wait()
-	s.finalizeBatch(stats)
+	s.finalizeBatch(batchID)
 }
 
-func (s *DocumentService) prepareBatchEnv(batchID string) (string, int, map[string]bool, error) {
-	uploadDir := config.GetUploadPath()
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("Batch %s: Failed to create upload directory: %v", batchID, err)
-		return "", 0, nil, err
-	}
-
-	validTypes := map[string]bool{"pdf": true, "docx": true, "txt": true, "doc": true}
+func (s *DocumentService) prepareBatchEnv() (int, map[string]bool) {
+	validTypes := acceptedFileTypes()
 
 	maxFileSizeFromEnv, err := strconv.Atoi(os.Getenv("MAX_FILE_SIZE_ALLOWED"))
 	if err != nil {
@@ -492,119 +1359,131 @@ func (s *DocumentService) prepareBatchEnv(batchID string) (string, int, map[stri
 	}
 	maxFileSize := maxFileSizeFromEnv * 1024 * 1024
 
-	return uploadDir, maxFileSize, validTypes, nil
+	return maxFileSize, validTypes
 }
 
-func (s *DocumentService) runBatchWorker(workerID int, jobs <-chan FileData, wg *sync.WaitGroup, stats *batchStats, config *batchWorkerConfig) {
+func (s *DocumentService) runBatchWorker(workerID int, jobs <-chan FileData, wg *sync.WaitGroup, config *batchWorkerConfig) {
 	defer wg.Done()
 
 	for file := range jobs {
+		// A cancelled batch has already marked its pending items; skip them.
+		claimed, err := s.repo.ClaimUploadBatchItem(file.ItemID)
+		if err != nil {
+			log.Printf("Batch %s Worker %d: Failed to claim file %s: %v", config.batchID, workerID, file.Filename, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
 		ctx := &fileProcessingContext{
 			category:    config.category,
 			email:       config.email,
 			accountType: config.accountType,
-			uploadDir:   config.uploadDir,
 			validTypes:  config.validTypes,
 			maxFileSize: config.maxFileSize,
 			batchID:     config.batchID,
 			workerID:    workerID,
-			autoApprove: config.autoApprove,
+			metadata:    config.metadata,
+			tags:        config.tags,
 		}
 
-		documentID, detailID, success := s.processFileDataWithExtraction(file, ctx)
+		documentID, detailID, result := s.processFileDataWithExtraction(file, ctx)
 
-		s.updateBatchStats(stats, success, documentID, detailID)
+		s.recordBatchItem(file.ItemID, result, documentID, detailID)
 	}
 }
 
-func (s *DocumentService) updateBatchStats(stats *batchStats, success bool, documentID, detailID int) {
-	stats.mu.Lock()
-	defer stats.mu.Unlock()
+func (s *DocumentService) recordBatchItem(itemID int64, result batchFileResult, documentID, detailID int) {
+	item := &UploadBatchItem{
+		ID:               itemID,
+		Status:           result.Status,
+		Error:            optionalString(result.Reason),
+		ScanStatus:       optionalString(result.ScanStatus),
+		ScanSignature:    optionalString(result.ScanSignature),
+		ExtractionStatus: optionalString(result.ExtractionStatus),
+		ExtractionError:  optionalString(result.ExtractionError),
+	}
+	if documentID > 0 && detailID > 0 {
+		item.DocumentID = &documentID
+		item.DetailID = &detailID
+	}
 
-	stats.processed++
-	if success {
-		stats.successful++
-		if stats.autoApprove && documentID > 0 && detailID > 0 {
-			stats.extracted++
-		}
-	} else {
-		stats.failed++
-	}
-
-	if stats.processed%10 == 0 || stats.processed == stats.total {
-		s.setBatchStatus(stats.batchID, map[string]interface{}{
-			"total":        stats.total,
-			"processed":    stats.processed,
-			"successful":   stats.successful,
-			"failed":       stats.failed,
-			"extracted":    stats.extracted,
-			"status":       "processing",
-			"auto_approve": stats.autoApprove,
-			"started_at":   s.getBatchStartTime(stats.batchID),
-		})
+	if err := s.repo.FinishUploadBatchItem(item); err != nil {
+		log.Printf("Warning: Failed to record result of batch item %d: %v", itemID, err)
 	}
 }
 
-func (s *DocumentService) finalizeBatch(stats *batchStats) {
-	s.setBatchStatus(stats.batchID, map[string]interface{}{
-		"total":        stats.total,
-		"processed":    stats.processed,
-		"successful":   stats.successful,
-		"failed":       stats.failed,
-		"extracted":    stats.extracted,
-		"status":       "completed",
-		"auto_approve": stats.autoApprove,
-		"started_at":   s.getBatchStartTime(stats.batchID),
-		"completed_at": time.Now().Format(time.RFC3339),
-	})
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
 
-	log.Printf("Batch %s completed: %d/%d successful, %d failed, %d extracted",
-		stats.batchID, stats.successful, stats.total, stats.failed, stats.extracted)
+func (s *DocumentService) finalizeBatch(batchID string) {
+	if err := s.repo.CompleteUploadBatch(batchID); err != nil {
+		log.Printf("Warning: Failed to complete batch %s: %v", batchID, err)
+		return
+	}
+
+	batch, err := s.repo.GetUploadBatch(batchID)
+	if err != nil {
+		log.Printf("Batch %s completed", batchID)
+		return
+	}
+	log.Printf("Batch %s %s: %d/%d successful, %d failed, %d cancelled, %d extracted",
+		batchID, batch.Status, batch.Successful, batch.Total, batch.Failed, batch.Cancelled, batch.Extracted)
 }
 
 func (s *DocumentService) processFileDataWithExtraction(
 	fileData FileData,
 	ctx *fileProcessingContext,
-) (int, int, bool) {
+) (int, int, batchFileResult) {
 	originalFilename := fileData.Filename
 
 	if fileData.Size > int64(ctx.maxFileSize) {
 		log.Printf("Batch %s Worker %d: File %s exceeds size limit", ctx.batchID, ctx.workerID, originalFilename)
-		return 0, 0, false
+		return 0, 0, failedBatchFile(fmt.Sprintf("File size exceeds maximum limit of %d MB", ctx.maxFileSize/(1024*1024)))
 	}
 
 	ext := strings.ToLower(filepath.Ext(originalFilename))
 	dataType := strings.TrimPrefix(ext, ".")
 	if !ctx.validTypes[dataType] {
 		log.Printf("Batch %s Worker %d: File %s has invalid type", ctx.batchID, ctx.workerID, originalFilename)
-		return 0, 0, false
+		return 0, 0, failedBatchFile(invalidFileTypeMessage())
+	}
+
+	screened, err := s.screenContent(fileData.Content, originalFilename, dataType, uploadSourceBatch, ctx.email)
+	if err != nil {
+		log.Printf("Batch %s Worker %d: File %s rejected: %v", ctx.batchID, ctx.workerID, originalFilename, err)
+		result := failedBatchFile(err.Error())
+		var infectedErr *InfectedFileError
+		if errors.As(err, &infectedErr) {
+			result.Status = batchItemStatusQuarantined
+			result.ScanStatus = scanner.StatusInfected
+			result.ScanSignature = infectedErr.Signature
+		}
+		return 0, 0, result
 	}
 
 	uniqueFilename := GenerateUniqueFilename(originalFilename)
-	filePath := filepath.Join(ctx.uploadDir, uniqueFilename)
 
-	if err := os.WriteFile(filePath, fileData.Content, 0644); err != nil {
+	if err := s.saveFileContent(uniqueFilename, fileData.Content); err != nil {
 		log.Printf("Batch %s Worker %d: Failed to write file %s: %v", ctx.batchID, ctx.workerID, originalFilename, err)
-		return 0, 0, false
+		return 0, 0, failedBatchFile("Failed to save file")
 	}
 
 	document := &Document{
 		Category: ctx.category,
+		Metadata: ctx.metadata,
+		Tags:     ctx.tags,
 	}
 
 	isLatest := true
-	var status string
-	var isApprove *bool
-
-	if ctx.autoApprove {
-		status = "Approved"
-		approveTrue := true
-		isApprove = &approveTrue
-	} else {
-		status = "Pending"
-		isApprove = nil
-	}
+	status := "Pending"
 
+	contentHash := hashContent(fileData.Content)
 	detail := &DocumentDetail{
 		DocumentName: originalFilename,
 		Filename:     uniqueFilename,
@@ -613,98 +1492,92 @@ func (s *DocumentService) processFileDataWithExtraction(
 		Team:         ctx.accountType,
 		Status:       &status,
 		IsLatest:     &isLatest,
-		IsApprove:    isApprove,
+		ContentHash:  &contentHash,
 	}
+	screened.apply(detail)
 
 	if err := s.CreateDocument(document, detail); err != nil {
 		log.Printf("Batch %s Worker %d: Database error for file %s: %v", ctx.batchID, ctx.workerID, originalFilename, err)
-		os.Remove(filePath)
-		return 0, 0, false
+		s.RemoveFile(uniqueFilename)
+		return 0, 0, failedBatchFile(err.Error())
 	}
 
-	if ctx.autoApprove {
-		extractReq := external.ExtractRequest{
-			ID:       strconv.Itoa(document.ID),
-			Category: ctx.category,
-			Filename: originalFilename,
-			FilePath: filePath,
-		}
-
-		if err := s.externalClient.ExtractDocument(extractReq); err != nil {
-			log.Printf("Batch %s Worker %d: Failed to extract file %s (ID: %d) to external API: %v",
-				ctx.batchID, ctx.workerID, originalFilename, document.ID, err)
-
-			return document.ID, detail.ID, true
-		}
-
-		log.Printf("Batch %s Worker %d: Successfully extracted file %s (ID: %d) to external API",
-			ctx.batchID, ctx.workerID, originalFilename, document.ID)
+	uploaded := batchFileResult{
+		Status:        batchItemStatusUploaded,
+		ScanStatus:    screened.result.Status,
+		ScanSignature: screened.result.Signature,
 	}
 
-	return document.ID, detail.ID, true
+	return document.ID, detail.ID, uploaded
 }
 
-func (s *DocumentService) setBatchStatus(batchID string, status map[string]interface{}) error {
-	key := isBatchUpload + batchID
-	data, err := json.Marshal(status)
+// GetBatchStatus returns a batch with the outcome of each of its files.
+func (s *DocumentService) GetBatchStatus(batchID string) (*UploadBatch, error) {
+	batch, err := s.getUploadBatch(batchID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal status: %w", err)
+		return nil, err
 	}
 
-	ctx := context.Background()
-	return s.redis.Set(ctx, key, data, 24*time.Hour).Err()
-}
-
-func (s *DocumentService) getBatchStartTime(batchID string) string {
-	key := isBatchUpload + batchID
-	ctx := context.Background()
-
-	data, err := s.redis.Get(ctx, key).Result()
+	batch.Files, err = s.repo.GetUploadBatchItems(batchID)
 	if err != nil {
-		return time.Now().Format(time.RFC3339)
+		return nil, fmt.Errorf("failed to get batch files: %w", err)
 	}
+	return batch, nil
+}
 
-	var status map[string]interface{}
-	if err := json.Unmarshal([]byte(data), &status); err != nil {
-		return time.Now().Format(time.RFC3339)
-	}
+// GetBatchProgress returns a batch's counters without its files.
+func (s *DocumentService) GetBatchProgress(batchID string) (*UploadBatch, error) {
+	return s.getUploadBatch(batchID)
+}
 
-	if startTime, ok := status["started_at"].(string); ok {
-		return startTime
+func (s *DocumentService) getUploadBatch(batchID string) (*UploadBatch, error) {
+	batch, err := s.repo.GetUploadBatch(batchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errBatchNotFound
 	}
-	return time.Now().Format(time.RFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch status: %w", err)
+	}
+	return batch, nil
 }
 
-func (s *DocumentService) GetBatchStatus(batchID string) (map[string]interface{}, error) {
-	key := isBatchUpload + batchID
-	ctx := context.Background()
+func (s *DocumentService) GetUploadBatches(email string, limit, offset int) ([]UploadBatch, int, error) {
+	return s.repo.GetUploadBatches(email, limit, offset)
+}
 
-	data, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, fmt.Errorf("batch not found")
-	}
+// CancelUploadBatch stops the uploader's running batch. Documents already
+// created by it are kept.
+func (s *DocumentService) CancelUploadBatch(batchID, email string) (*UploadBatch, error) {
+	batch, err := s.getUploadBatch(batchID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get batch status: %w", err)
+		return nil, err
+	}
+	if batch.Staff != email {
+		return nil, errNotBatchOwner
 	}
 
-	var status map[string]interface{}
-	if err := json.Unmarshal([]byte(data), &status); err != nil {
-		return nil, fmt.Errorf("failed to parse batch status: %w", err)
+	cancelled, err := s.repo.CancelUploadBatch(batchID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel batch: %w", err)
+	}
+	if !cancelled {
+		return nil, errBatchFinished
 	}
 
-	return status, nil
+	log.Printf("Batch %s cancelled by %s", batchID, email)
+	return s.GetBatchStatus(batchID)
 }
 
 func (s *DocumentService) GetTeamNameByUserID(userID int64) (string, error) {
 	return s.repo.GetTeamNameByUserID(userID)
 }
 
-func (s *DocumentService) BatchDeleteDocuments(ids []int) (int, []string) {
+func (s *DocumentService) BatchDeleteDocuments(ids []int, actor audit.Actor) (int, []string) {
 	successCount := 0
 	var errorMessages []string
 
 	for _, id := range ids {
-		err := s.DeleteDocument(id)
+		err := s.DeleteDocument(id, actor)
 		if err != nil {
 			log.Printf("Batch Delete: Failed to delete document ID %d: %v", id, err)
 			errorMessages = append(errorMessages, fmt.Sprintf("ID %d: %v", id, err))
@@ -716,51 +1589,88 @@ func (s *DocumentService) BatchDeleteDocuments(ids []int) (int, []string) {
 	return successCount, errorMessages
 }
 
-func (s *DocumentService) GenerateViewTokenByDocumentID(documentID int) (string, error) {
+func (s *DocumentService) GenerateViewTokenByDocumentID(documentID int, viewer DocumentAccessEvent) (string, error) {
 
 	detail, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(documentID)
 	if err != nil {
 		return "", fmt.Errorf("approved and latest document detail not found for document_id %d: %w", documentID, err)
 	}
 
-	return s.GenerateViewToken(detail.Filename)
+	return s.GenerateViewToken(detail.Filename, viewer)
 }
 
 func (s *DocumentService) ProcessCrawlerBatch(files []*multipart.FileHeader, category string) ([]CrawlerUploadResult, error) {
-	uploadDir := config.GetUploadPath()
 	var results []CrawlerUploadResult
 
 	for _, fileHeader := range files {
-		res := s.processSingleCrawlerFile(fileHeader, category, uploadDir)
+		res := s.processSingleCrawlerFile(fileHeader, category)
 		results = append(results, res)
 	}
 
 	return results, nil
 }
 
-func (s *DocumentService) processSingleCrawlerFile(fileHeader *multipart.FileHeader, category, uploadDir string) CrawlerUploadResult {
+func (s *DocumentService) processSingleCrawlerFile(fileHeader *multipart.FileHeader, category string) CrawlerUploadResult {
 	originalName := fileHeader.Filename
 
+	dataType := strings.TrimPrefix(strings.ToLower(filepath.Ext(originalName)), ".")
+	if !acceptedFileTypes()[dataType] {
+		return CrawlerUploadResult{Filename: originalName, Status: "Skipped", Reason: invalidFileTypeMessage()}
+	}
+
+	content, err := s.readFileContent(fileHeader)
+	if err != nil {
+		return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: err.Error()}
+	}
+
+	screened, err := s.screenContent(content, originalName, dataType, uploadSourceCrawler, "")
+	if err != nil {
+		var infectedErr *InfectedFileError
+		switch {
+		case errors.As(err, &infectedErr):
+			return CrawlerUploadResult{
+				Filename:      originalName,
+				Status:        "Quarantined",
+				Reason:        err.Error(),
+				ScanStatus:    scanner.StatusInfected,
+				ScanSignature: infectedErr.Signature,
+			}
+		case errors.Is(err, scanner.ErrContentMismatch):
+			return CrawlerUploadResult{Filename: originalName, Status: "Skipped", Reason: err.Error()}
+		default:
+			return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: err.Error()}
+		}
+	}
+
+	contentHash := hashContent(content)
+	duplicate, err := s.repo.FindDetailByContentHash(contentHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: "Failed to check for duplicate content"}
+	}
+	if err == nil {
+		return CrawlerUploadResult{
+			Filename:    originalName,
+			Status:      "Skipped",
+			Reason:      "Identical content already exists as " + duplicate.DocumentName,
+			DuplicateOf: duplicate.DocumentID,
+		}
+	}
+
 	existing, err := s.repo.GetLatestDetailByDocumentName(originalName)
 	isExist := err == nil && existing != nil
 
 	if isExist {
-		if result, shouldReturn := s.handleExistingDocument(existing, originalName, uploadDir); shouldReturn {
+		if result, shouldReturn := s.handleExistingDocument(existing, originalName); shouldReturn {
 			return result
 		}
 	}
 
-	content, err := s.readFileContent(fileHeader)
+	uniqueFilename, err := s.saveCrawledFile(content, originalName)
 	if err != nil {
 		return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: err.Error()}
 	}
 
-	uniqueFilename, err := s.saveFileToDisk(content, originalName, uploadDir)
-	if err != nil {
-		return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: err.Error()}
-	}
-
-	if err := s.createDocumentRecord(originalName, uniqueFilename, category, uploadDir); err != nil {
+	if err := s.createDocumentRecord(originalName, uniqueFilename, category, contentHash, screened); err != nil {
 		return CrawlerUploadResult{Filename: originalName, Status: "Error", Reason: "Database insert failed"}
 	}
 
@@ -770,12 +1680,14 @@ func (s *DocumentService) processSingleCrawlerFile(fileHeader *multipart.FileHea
 	}
 
 	return CrawlerUploadResult{
-		Filename: originalName,
-		Status:   finalStatus,
+		Filename:      originalName,
+		Status:        finalStatus,
+		ScanStatus:    screened.result.Status,
+		ScanSignature: screened.result.Signature,
 	}
 }
 
-func (s *DocumentService) handleExistingDocument(existing *DocumentDetail, originalName, uploadDir string) (CrawlerUploadResult, bool) {
+func (s *DocumentService) handleExistingDocument(existing *DocumentDetail, originalName string) (CrawlerUploadResult, bool) {
 	if s.isApprovedAndIngested(existing) {
 		return CrawlerUploadResult{
 			Filename: originalName,
@@ -785,7 +1697,7 @@ func (s *DocumentService) handleExistingDocument(existing *DocumentDetail, origi
 	}
 
 	if s.shouldReplaceDocument(existing) {
-		if err := s.deleteOldDocument(existing, uploadDir); err != nil {
+		if err := s.deleteOldDocument(existing); err != nil {
 			return CrawlerUploadResult{
 				Filename: originalName,
 				Status:   "Error",
@@ -814,11 +1726,16 @@ func (s *DocumentService) shouldReplaceDocument(doc *DocumentDetail) bool {
 	return isPending || isRejected
 }
 
-func (s *DocumentService) deleteOldDocument(doc *DocumentDetail, uploadDir string) error {
-	oldFilePath := filepath.Join(uploadDir, doc.Filename)
-	_ = os.Remove(oldFilePath) 
+// deleteOldDocument drops a pending or rejected upload the crawler replaces.
+// It was never published, so it skips the recycle bin.
+func (s *DocumentService) deleteOldDocument(doc *DocumentDetail) error {
+	if err := s.ExecuteHardDelete(doc.DocumentID); err != nil {
+		return err
+	}
 
-	return s.DeleteDocument(doc.DocumentID)
+	// Crawler replacements have no user behind them.
+	s.audit.Record(audit.Actor{}, audit.ActionDocumentDelete, audit.EntityDocument, doc.DocumentID, doc, nil)
+	return nil
 }
 
 func (s *DocumentService) readFileContent(fileHeader *multipart.FileHeader) ([]byte, error) {
@@ -836,19 +1753,31 @@ func (s *DocumentService) readFileContent(fileHeader *multipart.FileHeader) ([]b
 	return content, nil
 }
 
-func (s *DocumentService) saveFileToDisk(content []byte, originalName, uploadDir string) (string, error) {
+func (s *DocumentService) saveCrawledFile(content []byte, originalName string) (string, error) {
 	uniqueFilename := GenerateUniqueFilename(originalName)
-	filePath := filepath.Join(uploadDir, uniqueFilename)
 
-	if err := os.WriteFile(filePath, content, 0644); err != nil {
-		return "", fmt.Errorf("Failed to save to disk")
+	if err := s.saveFileContent(uniqueFilename, content); err != nil {
+		return "", fmt.Errorf("Failed to save file")
 	}
 
 	return uniqueFilename, nil
 }
 
-func (s *DocumentService) createDocumentRecord(originalName, uniqueFilename, category, uploadDir string) error {
+func (s *DocumentService) createDocumentRecord(originalName, uniqueFilename, category, contentHash string, screened *screening) error {
 	doc := &Document{Category: category}
+	detail := newCrawledDetail(originalName, uniqueFilename, contentHash, screened)
+
+	if err := s.CreateDocument(doc, detail); err != nil {
+		s.RemoveFile(uniqueFilename)
+		return err
+	}
+
+	return nil
+}
+
+// newCrawledDetail builds the pending version of a crawled file. Crawled
+// files have no user behind them.
+func newCrawledDetail(originalName, uniqueFilename, contentHash string, screened *screening) *DocumentDetail {
 	isLatest := true
 	status := "Pending"
 
@@ -862,17 +1791,20 @@ func (s *DocumentService) createDocumentRecord(originalName, uniqueFilename, cat
 		IsLatest:     &isLatest,
 		IsApprove:    nil,
 		IngestStatus: nil,
+		ContentHash:  &contentHash,
 	}
-
-	if err := s.CreateDocument(doc, detail); err != nil {
-		filePath := filepath.Join(uploadDir, uniqueFilename)
-		os.Remove(filePath)
-		return err
-	}
-
-	return nil
+	screened.apply(detail)
+	return detail
 }
 
 func (s *DocumentService) CheckDuplicates(filenames []string) ([]string, error) {
 	return s.repo.CheckExistingDocuments(filenames)
 }
+
+func (s *DocumentService) CheckDuplicateHashes(hashes []string) ([]ContentHashMatch, error) {
+	normalized := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(hash)))
+	}
+	return s.repo.CheckExistingContentHashes(normalized)
+}
@@ -0,0 +1,42 @@
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateRejection(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       RejectRequest
+		wantCodes []string
+		wantErr   bool
+	}{
+		{name: "valid", req: RejectRequest{Reason: " Outdated figures ", ReasonCodes: []string{"outdated"}}, wantCodes: []string{"outdated"}},
+		{name: "duplicate codes", req: RejectRequest{Reason: "x", ReasonCodes: []string{"outdated", " outdated"}}, wantCodes: []string{"outdated"}},
+		{name: "missing reason", req: RejectRequest{Reason: "  ", ReasonCodes: []string{"outdated"}}, wantErr: true},
+		{name: "reason at limit", req: RejectRequest{Reason: strings.Repeat("a", maxReviewCommentLength), ReasonCodes: []string{"outdated"}}, wantCodes: []string{"outdated"}},
+		{name: "reason too long", req: RejectRequest{Reason: strings.Repeat("a", maxReviewCommentLength+1), ReasonCodes: []string{"outdated"}}, wantErr: true},
+		{name: "missing codes", req: RejectRequest{Reason: "x"}, wantErr: true},
+		{name: "unknown code", req: RejectRequest{Reason: "x", ReasonCodes: []string{"typo"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, codes, err := validateRejection(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidReview) {
+					t.Fatalf("validateRejection() error = %v, want errInvalidReview", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateRejection() error = %v", err)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.wantCodes, ",") {
+				t.Errorf("validateRejection() codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
+	"dokuprime-be/util"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultShareLinkHours      = 72
+	defaultShareLinkMaxHours   = 720
+	defaultShareSessionMinutes = 10
+
+	shareTokenLength = 40
+	shareSessionKey  = "share_session:"
+)
+
+// Reasons recorded for refused share link views.
+const (
+	shareReasonRevoked       = "revoked"
+	shareReasonExpired       = "expired"
+	shareReasonExhausted     = "exhausted"
+	shareReasonLoginRequired = "login_required"
+	shareReasonTeam          = "team_not_allowed"
+	shareReasonUnavailable   = "unavailable"
+)
+
+var (
+	errInvalidShareLink    = errors.New("invalid share link")
+	errShareLinkNotFound   = errors.New("share link not found")
+	errShareLinkGone       = errors.New("share link is no longer valid")
+	errShareLoginRequired  = errors.New("login is required to open this link")
+	errShareTeamNotAllowed = errors.New("this link is not shared with your team")
+	errNotShareLinkOwner   = errors.New("only the link creator or the document owner can manage this link")
+)
+
+// ShareViewer is who opens a share link. UserID and Email are empty for
+// visitors who are not logged in.
+type ShareViewer struct {
+	UserID    *int64
+	Email     string
+	IP        string
+	UserAgent string
+}
+
+func shareSessionTTL() time.Duration {
+	return time.Duration(getEnvInt("DOCUMENT_SHARE_SESSION_MINUTES", defaultShareSessionMinutes)) * time.Minute
+}
+
+// CreateShareLink creates a link to a document, or to one version of it
+// when DetailID is set.
+func (s *DocumentService) CreateShareLink(req ShareLinkRequest, email string, actor audit.Actor) (*ShareLink, error) {
+	document, err := s.repo.GetDocumentByID(req.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.DeletedAt != nil {
+		return nil, errDocumentTrashed
+	}
+
+	if req.DetailID != nil {
+		detail, err := s.repo.GetDocumentDetailByID(*req.DetailID)
+		if err != nil || detail.DocumentID != req.DocumentID {
+			return nil, fmt.Errorf("%w: version %d does not belong to document %d", errInvalidShareLink, *req.DetailID, req.DocumentID)
+		}
+		if !shareableVersion(detail) {
+			return nil, fmt.Errorf("%w: only approved versions can be shared", errInvalidShareLink)
+		}
+	}
+
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = getEnvInt("DOCUMENT_SHARE_DEFAULT_HOURS", defaultShareLinkHours)
+	}
+	maxHours := getEnvInt("DOCUMENT_SHARE_MAX_HOURS", defaultShareLinkMaxHours)
+	if hours < 0 || hours > maxHours {
+		return nil, fmt.Errorf("%w: expires_in_hours must be between 1 and %d", errInvalidShareLink, maxHours)
+	}
+	if req.MaxUses != nil && *req.MaxUses <= 0 {
+		return nil, fmt.Errorf("%w: max_uses must be positive", errInvalidShareLink)
+	}
+
+	teams, err := s.normalizeShareTeams(req.AllowedTeams)
+	if err != nil {
+		return nil, err
+	}
+
+	token := util.RandString(shareTokenLength)
+	link := &ShareLink{
+		DocumentID:   req.DocumentID,
+		DetailID:     req.DetailID,
+		TokenPrefix:  token[:8],
+		CreatedBy:    email,
+		ExpiresAt:    time.Now().Add(time.Duration(hours) * time.Hour),
+		MaxUses:      req.MaxUses,
+		RequireLogin: req.RequireLogin || len(teams) > 0,
+		AllowedTeams: teams,
+	}
+	if err := s.repo.CreateShareLink(link, hashContent([]byte(token))); err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionShareLinkCreate, audit.EntityShareLink, link.ID, nil, link)
+
+	created, err := s.repo.GetShareLink(link.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	created.Token = token
+	return created, nil
+}
+
+func (s *DocumentService) normalizeShareTeams(teams []string) ([]string, error) {
+	normalized := make([]string, 0, len(teams))
+	seen := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		team = strings.TrimSpace(team)
+		if team == "" || seen[team] {
+			continue
+		}
+		seen[team] = true
+		normalized = append(normalized, team)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+
+	existing, err := s.repo.GetExistingTeamNames(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check teams: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, team := range existing {
+		known[team] = true
+	}
+	for _, team := range normalized {
+		if !known[team] {
+			return nil, fmt.Errorf("%w: unknown team %q", errInvalidShareLink, team)
+		}
+	}
+	return normalized, nil
+}
+
+// GetShareLinks lists the links of a document for its owner, or the links
+// the user created.
+func (s *DocumentService) GetShareLinks(documentID int, email string, userID int64, limit, offset int) ([]ShareLink, int, error) {
+	createdBy := email
+	if documentID > 0 && s.canManageDocumentShares(documentID, email, userID) {
+		createdBy = ""
+	}
+
+	links, total, err := s.repo.GetShareLinks(documentID, createdBy, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get share links: %w", err)
+	}
+	return links, total, nil
+}
+
+func (s *DocumentService) RevokeShareLink(id int64, email string, userID int64, actor audit.Actor) (*ShareLink, error) {
+	link, err := s.managedShareLink(id, email, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.repo.RevokeShareLink(id, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if !revoked {
+		return nil, fmt.Errorf("%w: link is already revoked", errShareLinkGone)
+	}
+
+	updated, err := s.repo.GetShareLink(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	s.audit.Record(actor, audit.ActionShareLinkRevoke, audit.EntityShareLink, id, link, updated)
+	return updated, nil
+}
+
+func (s *DocumentService) GetShareLinkAccesses(id int64, email string, userID int64, limit, offset int) ([]ShareLinkAccess, int, error) {
+	if _, err := s.managedShareLink(id, email, userID); err != nil {
+		return nil, 0, err
+	}
+
+	accesses, total, err := s.repo.GetShareLinkAccesses(id, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get share link accesses: %w", err)
+	}
+	return accesses, total, nil
+}
+
+func (s *DocumentService) managedShareLink(id int64, email string, userID int64) (*ShareLink, error) {
+	link, err := s.repo.GetShareLink(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errShareLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	if link.CreatedBy != email && !s.canManageDocumentShares(link.DocumentID, email, userID) {
+		return nil, errNotShareLinkOwner
+	}
+	return link, nil
+}
+
+// canManageDocumentShares is true for the uploader of the document's latest
+// version and for document masters.
+func (s *DocumentService) canManageDocumentShares(documentID int, email string, userID int64) bool {
+	if ok, err := middleware.UserHasPermission(userID, permDocumentMaster); err == nil && ok {
+		return true
+	}
+
+	details, err := s.repo.GetDocumentDetailsByDocumentID(documentID)
+	if err != nil {
+		return false
+	}
+	for _, detail := range details {
+		if detail.IsLatest != nil && *detail.IsLatest {
+			return detail.Staff == email
+		}
+	}
+	return false
+}
+
+// AccessShareLink checks a share link for viewer and returns the version to
+// serve. A view counts as one use and opens a short session, returned as
+// sessionID, so the byte range requests of the same view are not counted
+// again.
+func (s *DocumentService) AccessShareLink(token, sessionID string, viewer ShareViewer) (*DocumentDetail, string, error) {
+	link, err := s.repo.GetShareLinkByTokenHash(hashContent([]byte(token)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", errShareLinkNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if reason := shareLinkDenial(link, viewer, s.viewerTeam(viewer)); reason != "" {
+		s.recordShareAccess(link.ID, viewer, false, reason)
+		return nil, "", shareDenialError(reason)
+	}
+
+	detail, err := s.sharedDetail(link)
+	if err != nil {
+		s.recordShareAccess(link.ID, viewer, false, shareReasonUnavailable)
+		return nil, "", err
+	}
+
+	if sessionID != "" && s.validShareSession(sessionID, link.ID) {
+		return detail, "", nil
+	}
+
+	used, err := s.repo.UseShareLink(link.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to record share link use: %w", err)
+	}
+	if !used {
+		s.recordShareAccess(link.ID, viewer, false, shareReasonExhausted)
+		return nil, "", shareDenialError(shareReasonExhausted)
+	}
+	s.recordShareAccess(link.ID, viewer, true, "")
+	s.recordSharedView(link, detail, viewer)
+
+	sessionID = util.RandString(32)
+	ctx := context.Background()
+	if err := s.redis.Set(ctx, shareSessionKey+sessionID, link.ID, shareSessionTTL()).Err(); err != nil {
+		log.Printf("Warning: Failed to store share session for link ID %d: %v", link.ID, err)
+		return detail, "", nil
+	}
+	return detail, sessionID, nil
+}
+
+func shareLinkDenial(link *ShareLink, viewer ShareViewer, team string) string {
+	switch {
+	case link.RevokedAt != nil:
+		return shareReasonRevoked
+	case !link.ExpiresAt.After(time.Now()):
+		return shareReasonExpired
+	case link.RequireLogin && viewer.Email == "":
+		return shareReasonLoginRequired
+	}
+
+	if len(link.AllowedTeams) == 0 {
+		return ""
+	}
+	for _, allowed := range link.AllowedTeams {
+		if strings.EqualFold(allowed, team) {
+			return ""
+		}
+	}
+	return shareReasonTeam
+}
+
+func shareDenialError(reason string) error {
+	switch reason {
+	case shareReasonLoginRequired:
+		return errShareLoginRequired
+	case shareReasonTeam:
+		return errShareTeamNotAllowed
+	default:
+		return fmt.Errorf("%w: %s", errShareLinkGone, reason)
+	}
+}
+
+func (s *DocumentService) viewerTeam(viewer ShareViewer) string {
+	if viewer.UserID == nil {
+		return ""
+	}
+	team, err := s.repo.GetTeamNameByUserID(*viewer.UserID)
+	if err != nil {
+		return ""
+	}
+	return team
+}
+
+// shareableVersion keeps drafts, rejected and expired versions out of share
+// links; a pinned version must have passed review and still be in force.
+func shareableVersion(detail *DocumentDetail) bool {
+	return detail.Status != nil && *detail.Status == "Approved"
+}
+
+// sharedDetail is the pinned version of the link, or the published version
+// of its document.
+func (s *DocumentService) sharedDetail(link *ShareLink) (*DocumentDetail, error) {
+	document, err := s.repo.GetDocumentByID(link.DocumentID)
+	if err != nil || document.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: document is no longer available", errShareLinkGone)
+	}
+
+	if link.DetailID != nil {
+		detail, err := s.repo.GetDocumentDetailByID(*link.DetailID)
+		if err != nil || !shareableVersion(detail) {
+			return nil, fmt.Errorf("%w: version is no longer available", errShareLinkGone)
+		}
+		return detail, nil
+	}
+
+	detail, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(link.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: document has no published version", errShareLinkGone)
+	}
+	return detail, nil
+}
+
+func (s *DocumentService) validShareSession(sessionID string, linkID int64) bool {
+	value, err := s.redis.Get(context.Background(), shareSessionKey+sessionID).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Warning: Failed to read share session: %v", err)
+		}
+		return false
+	}
+	return value == strconv.FormatInt(linkID, 10)
+}
+
+func (s *DocumentService) recordShareAccess(linkID int64, viewer ShareViewer, granted bool, reason string) {
+	access := &ShareLinkAccess{
+		LinkID:    linkID,
+		UserID:    viewer.UserID,
+		Email:     optionalString(viewer.Email),
+		IPAddress: optionalString(viewer.IP),
+		UserAgent: optionalString(viewer.UserAgent),
+		Granted:   granted,
+		Reason:    optionalString(reason),
+	}
+	if err := s.repo.RecordShareLinkAccess(access); err != nil {
+		log.Printf("Warning: Failed to record access to share link ID %d: %v", linkID, err)
+	}
+}
+
+func (s *DocumentService) recordSharedView(link *ShareLink, detail *DocumentDetail, viewer ShareViewer) {
+	event := DocumentAccessEvent{
+		DocumentID:  detail.DocumentID,
+		DetailID:    &detail.ID,
+		UserID:      viewer.UserID,
+		Email:       optionalString(viewer.Email),
+		Channel:     accessChannelView,
+		Source:      accessSourceShareLink,
+		ShareLinkID: &link.ID,
+		IPAddress:   optionalString(viewer.IP),
+		UserAgent:   optionalString(viewer.UserAgent),
+	}
+	if err := s.repo.RecordAccessEvent(&event); err != nil {
+		log.Printf("Warning: Failed to record view of document ID %d: %v", detail.DocumentID, err)
+	}
+}
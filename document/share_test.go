@@ -0,0 +1,65 @@
+package document
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShareLinkDenial(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Minute)
+	loggedIn := ShareViewer{Email: "user@example.com"}
+
+	tests := []struct {
+		name   string
+		link   ShareLink
+		viewer ShareViewer
+		team   string
+		want   string
+	}{
+		{name: "open link", link: ShareLink{ExpiresAt: future}, want: ""},
+		{name: "revoked", link: ShareLink{ExpiresAt: future, RevokedAt: &past}, want: shareReasonRevoked},
+		{name: "revoked beats expired", link: ShareLink{ExpiresAt: past, RevokedAt: &past}, want: shareReasonRevoked},
+		{name: "expired", link: ShareLink{ExpiresAt: past}, want: shareReasonExpired},
+		{name: "login required anonymous", link: ShareLink{ExpiresAt: future, RequireLogin: true}, want: shareReasonLoginRequired},
+		{name: "login required logged in", link: ShareLink{ExpiresAt: future, RequireLogin: true}, viewer: loggedIn, want: ""},
+		{name: "allowed team", link: ShareLink{ExpiresAt: future, AllowedTeams: []string{"legal", "hr"}}, viewer: loggedIn, team: "hr", want: ""},
+		{name: "team match ignores case", link: ShareLink{ExpiresAt: future, AllowedTeams: []string{"Legal"}}, viewer: loggedIn, team: "legal", want: ""},
+		{name: "other team", link: ShareLink{ExpiresAt: future, AllowedTeams: []string{"legal"}}, viewer: loggedIn, team: "hr", want: shareReasonTeam},
+		{name: "no team", link: ShareLink{ExpiresAt: future, AllowedTeams: []string{"legal"}}, want: shareReasonTeam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shareLinkDenial(&tt.link, tt.viewer, tt.team); got != tt.want {
+				t.Errorf("shareLinkDenial() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareableVersion(t *testing.T) {
+	status := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		status *string
+		want   bool
+	}{
+		{name: "approved", status: status("Approved"), want: true},
+		{name: "pending", status: status("Pending")},
+		{name: "rejected", status: status("Rejected")},
+		{name: "expired", status: status(statusExpired)},
+		{name: "scheduled", status: status(statusScheduled)},
+		{name: "no status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shareableVersion(&DocumentDetail{Status: tt.status}); got != tt.want {
+				t.Errorf("shareableVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/external"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	defaultTrashPurgeBatch    = 50
+)
+
+var (
+	errDocumentTrashed  = errors.New("document is in the recycle bin")
+	errNotTrashed       = errors.New("document is not in the recycle bin")
+	errLegalHold        = errors.New("document is under legal hold")
+	errInvalidLegalHold = errors.New("invalid legal hold")
+	errRestoreConflict  = errors.New("document cannot be restored")
+)
+
+func trashRetentionDays() int {
+	return getEnvInt("DOCUMENT_TRASH_RETENTION_DAYS", defaultTrashRetentionDays)
+}
+
+// TrashDocument takes a document out of the RAG index and moves it to the
+// recycle bin. Its files and versions are kept until the purge job removes
+// them after the retention period.
+func (s *DocumentService) TrashDocument(documentID int, actor audit.Actor) error {
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return fmt.Errorf("failed to get document: %w", err)
+	}
+	if document.LegalHold {
+		return errLegalHold
+	}
+
+	moved, err := s.repo.TrashDocument(documentID, actor.UserID, trashRetentionDays())
+	if err != nil {
+		return fmt.Errorf("failed to move document to the recycle bin: %w", err)
+	}
+	if !moved {
+		return errDocumentTrashed
+	}
+
+	s.removeFromIndex(document)
+	return nil
+}
+
+func (s *DocumentService) removeFromIndex(document *Document) {
+	deleteReq := external.DeleteRequest{
+		ID:       document.ID,
+		Category: document.Category,
+	}
+
+	if err := s.externalClient.DeleteDocument(context.Background(), deleteReq); err != nil {
+		log.Printf("Warning: Failed to delete document from external API (ID: %d): %v", document.ID, err)
+	} else {
+		log.Printf("Successfully deleted document from external API (ID: %d)", document.ID)
+	}
+}
+
+func (s *DocumentService) GetTrashedDocuments(search string, limit, offset int) ([]TrashedDocument, int, error) {
+	documents, total, err := s.repo.GetTrashedDocuments(strings.TrimSpace(search), limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get recycle bin: %w", err)
+	}
+	return documents, total, nil
+}
+
+// RestoreDocument brings a document back from the recycle bin with all its
+// versions and sends its published version to the RAG index again. It is
+// refused while a live document has the same name or content.
+func (s *DocumentService) RestoreDocument(documentID int, actor audit.Actor) (*Document, error) {
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.DeletedAt == nil {
+		return nil, errNotTrashed
+	}
+
+	details, err := s.repo.GetDocumentDetailsByDocumentID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document details: %w", err)
+	}
+	if err := s.checkRestoreConflicts(details); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.repo.RestoreDocument(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore document: %w", err)
+	}
+	if !restored {
+		return nil, errNotTrashed
+	}
+
+	s.reingestPublished(document)
+
+	updated, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	s.audit.Record(actor, audit.ActionDocumentRestore, audit.EntityDocument, documentID, document, updated)
+	return updated, nil
+}
+
+// checkRestoreConflicts applies the upload duplicate checks to the versions
+// that would become active again.
+func (s *DocumentService) checkRestoreConflicts(details []DocumentDetail) error {
+	for _, detail := range details {
+		if detail.IsLatest != nil && *detail.IsLatest {
+			_, err := s.repo.CheckDuplicationFileByDocumentName(detail.DocumentName)
+			if err == nil {
+				return fmt.Errorf("%w: a document named %q already exists", errRestoreConflict, detail.DocumentName)
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to check for a document with the same name: %w", err)
+			}
+		}
+
+		active := (detail.IsLatest != nil && *detail.IsLatest) || (detail.Status != nil && (*detail.Status == "Pending" || *detail.Status == statusScheduled))
+		if !active || detail.ContentHash == nil || (detail.Status != nil && *detail.Status == "Rejected") {
+			continue
+		}
+		existing, err := s.repo.FindDetailByContentHash(*detail.ContentHash)
+		if err == nil {
+			return fmt.Errorf("%w: identical content already exists as %s", errRestoreConflict, existing.DocumentName)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check for duplicate content: %w", err)
+		}
+	}
+	return nil
+}
+
+// reingestPublished queues the published version of document for extraction,
+// which sends the RAG its file with the document's current metadata.
+func (s *DocumentService) reingestPublished(document *Document) {
+	detail, err := s.repo.GetApprovedLatestDocumentDetailByDocumentID(document.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to get published version of document ID %d: %v", document.ID, err)
+		return
+	}
+	if detail.Status == nil || *detail.Status != "Approved" {
+		return
+	}
+	if err := s.ensureFileExists(detail.Filename); err != nil {
+		log.Printf("Warning: Cannot re-ingest document ID %d: %v", document.ID, err)
+		return
+	}
+
+	job := ExtractionJob{
+		DetailID: detail.ID,
+		Request: external.ExtractRequest{
+			ID:       strconv.Itoa(document.ID),
+			Category: document.Category,
+			Filename: detail.DocumentName,
+			FilePath: detail.Filename,
+		},
+	}
+	if err := s.asyncProcessor.SubmitJob(job); err != nil {
+		log.Printf("Warning: Failed to submit extraction job for detail ID %d: %v", detail.ID, err)
+	}
+}
+
+// PurgeDocument removes a document from the recycle bin for good without
+// waiting for its retention period.
+func (s *DocumentService) PurgeDocument(documentID int, actor audit.Actor) error {
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return err
+	}
+	if document.DeletedAt == nil {
+		return errNotTrashed
+	}
+
+	if err := s.ExecuteHardDelete(documentID); err != nil {
+		return err
+	}
+	s.audit.Record(actor, audit.ActionDocumentPurge, audit.EntityDocument, documentID, document, nil)
+	return nil
+}
+
+// PurgeExpiredTrash hard-deletes the documents whose retention period is
+// over. Each document is checked again right before it goes, so one restored
+// or put on hold in the meantime is kept.
+func (s *DocumentService) PurgeExpiredTrash() {
+	ids, err := s.repo.GetDocumentsDueForPurge(getEnvInt("DOCUMENT_TRASH_PURGE_BATCH", defaultTrashPurgeBatch))
+	if err != nil {
+		log.Printf("Error getting documents to purge: %v", err)
+		return
+	}
+
+	purged := 0
+	for _, id := range ids {
+		document, err := s.repo.GetDocumentByID(id)
+		if err != nil {
+			log.Printf("Warning: Failed to get document ID %d to purge: %v", id, err)
+			continue
+		}
+		if document.DeletedAt == nil || document.LegalHold || document.PurgeAfter == nil || document.PurgeAfter.After(time.Now()) {
+			continue
+		}
+
+		if err := s.ExecuteHardDelete(id); err != nil {
+			log.Printf("Warning: Failed to purge document ID %d: %v", id, err)
+			continue
+		}
+		s.audit.Record(audit.Actor{}, audit.ActionDocumentPurge, audit.EntityDocument, id, document, nil)
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d document(s) from the recycle bin", purged)
+	}
+}
+
+// SetLegalHold places or lifts a legal hold. A held document cannot be
+// deleted, and stays in the recycle bin past its retention period.
+func (s *DocumentService) SetLegalHold(documentID int, req LegalHoldRequest, actor audit.Actor) (*Document, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if req.Hold && reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", errInvalidLegalHold)
+	}
+
+	document, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetLegalHold(documentID, req.Hold, optionalString(reason), actor.UserID); err != nil {
+		return nil, fmt.Errorf("failed to update legal hold: %w", err)
+	}
+
+	updated, err := s.repo.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	s.audit.Record(actor, audit.ActionDocumentLegalHold, audit.EntityDocument, documentID, document, updated)
+	return updated, nil
+}
@@ -0,0 +1,160 @@
+package external
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("external service is temporarily unavailable")
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+const (
+	ServiceRAG      = "rag"
+	ServiceMessages = "messages"
+)
+
+// CircuitBreaker stops calling a service after `threshold` consecutive
+// failures and lets a single trial request through once `cooldown` has passed.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	state     string
+	failures  int
+	openedAt  time.Time
+	lastError string
+	trial     bool
+}
+
+type BreakerStatus struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Failures  int        `json:"consecutive_failures"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Breakers are shared per service so every Client built by the route
+// registrations sees the same state.
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*CircuitBreaker{}
+)
+
+func breakerFor(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	if b, ok := breakers[name]; ok {
+		return b
+	}
+	if threshold <= 0 {
+		threshold = 1
+	}
+	b := &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, state: BreakerClosed}
+	breakers[name] = b
+	return b
+}
+
+// BreakerStatuses reports every known breaker, sorted by name.
+func BreakerStatuses() []BreakerStatus {
+	breakersMu.Lock()
+	list := make([]*CircuitBreaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.trial = false
+	b.lastError = ""
+}
+
+func (b *CircuitBreaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Release gives back a half-open trial slot without judging the service, used
+// when the caller itself cancelled the request.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Name:      b.name,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.cooldown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
@@ -0,0 +1,284 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	defaultChatStreamPath = "/api/chat/stream"
+	maxChatStreamLine     = 1024 * 1024
+)
+
+// ChatDelta is one incremental piece of an answer produced by the RAG service.
+type ChatDelta struct {
+	ConversationID string
+	Text           string
+}
+
+// chatStreamEvent covers the payloads the RAG service sends on the stream,
+// either as SSE data lines or as newline-delimited JSON chunks.
+type chatStreamEvent struct {
+	Type           string          `json:"type"`
+	Event          string          `json:"event"`
+	Delta          string          `json:"delta"`
+	Token          string          `json:"token"`
+	Content        string          `json:"content"`
+	ConversationID string          `json:"conversation_id"`
+	Error          string          `json:"error"`
+	Message        string          `json:"message"`
+	Data           json.RawMessage `json:"data"`
+}
+
+func chatStreamURL(baseURL string) string {
+	path := os.Getenv("CHAT_STREAM_PATH")
+	if path == "" {
+		path = defaultChatStreamPath
+	}
+	return baseURL + path
+}
+
+// SendChatMessageStream asks the RAG service for a streamed answer and calls
+// onDelta for every token chunk. The returned response is the final answer
+// with citations, the same shape SendChatMessage returns. A service that
+// answers with plain JSON is handled as a single delta.
+func (c *Client) SendChatMessageStream(ctx context.Context, req ChatRequest, onDelta func(ChatDelta)) (*ChatResponse, error) {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if c.chatStreamTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.chatStreamTimeout)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", chatStreamURL(c.baseURL), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf(isFailedToRequest, err)
+	}
+
+	httpReq.Header.Set(isContentType, "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream, application/x-ndjson, application/json")
+	httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
+
+	if err := c.ragBreaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.ragBreaker.Failure(err)
+		return nil, fmt.Errorf(isFailedToSend, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		if isRetryableStatus(resp.StatusCode) {
+			c.ragBreaker.Failure(fmt.Errorf("status %d", resp.StatusCode))
+		} else {
+			c.ragBreaker.Success()
+		}
+		return nil, fmt.Errorf("external API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	c.ragBreaker.Success()
+
+	contentType := resp.Header.Get(isContentType)
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return readSSEChatStream(resp.Body, onDelta)
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return readNDJSONChatStream(resp.Body, onDelta)
+	default:
+		return readWholeChatResponse(resp.Body, onDelta)
+	}
+}
+
+func readSSEChatStream(body io.Reader, onDelta func(ChatDelta)) (*ChatResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChatStreamLine)
+
+	acc := &chatStreamAccumulator{onDelta: onDelta}
+	eventName := ""
+	var data []string
+
+	dispatch := func() (bool, error) {
+		defer func() {
+			eventName = ""
+			data = nil
+		}()
+		if len(data) == 0 {
+			return false, nil
+		}
+		return acc.handle(eventName, strings.Join(data, "\n"))
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			done, err := dispatch()
+			if err != nil || done {
+				return acc.result(err)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventName = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chat stream: %w", err)
+	}
+
+	_, err := dispatch()
+	return acc.result(err)
+}
+
+func readNDJSONChatStream(body io.Reader, onDelta func(ChatDelta)) (*ChatResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChatStreamLine)
+
+	acc := &chatStreamAccumulator{onDelta: onDelta}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		done, err := acc.handle("", line)
+		if err != nil || done {
+			return acc.result(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chat stream: %w", err)
+	}
+	return acc.result(nil)
+}
+
+func readWholeChatResponse(body io.Reader, onDelta func(ChatDelta)) (*ChatResponse, error) {
+	bodyBytes, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if chatResp.Answer != "" {
+		onDelta(ChatDelta{ConversationID: chatResp.ConversationID, Text: chatResp.Answer})
+	}
+	return &chatResp, nil
+}
+
+// chatStreamAccumulator collects deltas until the final event arrives.
+type chatStreamAccumulator struct {
+	onDelta        func(ChatDelta)
+	answer         strings.Builder
+	conversationID string
+	final          *ChatResponse
+}
+
+func (a *chatStreamAccumulator) handle(eventName, data string) (bool, error) {
+	if strings.TrimSpace(data) == "[DONE]" {
+		return true, nil
+	}
+
+	var event chatStreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		// Plain-text SSE data is a bare token.
+		if eventName == "" || isDeltaEvent(eventName) {
+			a.emit(data)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to parse chat stream event %q: %w", eventName, err)
+	}
+
+	kind := strings.ToLower(eventName)
+	if kind == "" || kind == "message" {
+		kind = strings.ToLower(event.Type)
+	}
+	if kind == "" {
+		kind = strings.ToLower(event.Event)
+	}
+	if event.ConversationID != "" {
+		a.conversationID = event.ConversationID
+	}
+
+	switch {
+	case isDeltaEvent(kind):
+		a.emit(event.Delta + event.Token + event.Content)
+		return false, nil
+	case kind == "error":
+		msg := event.Error
+		if msg == "" {
+			msg = event.Message
+		}
+		return true, fmt.Errorf("chat stream error: %s", msg)
+	case kind == "done" || kind == "final" || kind == "end" || kind == "complete":
+		payload := []byte(data)
+		if len(event.Data) > 0 && string(event.Data) != "null" {
+			payload = event.Data
+		}
+		var chatResp ChatResponse
+		if err := json.Unmarshal(payload, &chatResp); err != nil {
+			return true, fmt.Errorf("failed to unmarshal final chat response: %w", err)
+		}
+		a.final = &chatResp
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func isDeltaEvent(kind string) bool {
+	switch strings.ToLower(kind) {
+	case "delta", "token", "chunk":
+		return true
+	}
+	return false
+}
+
+func (a *chatStreamAccumulator) emit(text string) {
+	if text == "" {
+		return
+	}
+	a.answer.WriteString(text)
+	a.onDelta(ChatDelta{ConversationID: a.conversationID, Text: text})
+}
+
+func (a *chatStreamAccumulator) result(err error) (*ChatResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	if a.final == nil {
+		return nil, fmt.Errorf("chat stream ended without a final response")
+	}
+	if a.final.Answer == "" {
+		a.final.Answer = a.answer.String()
+	}
+	if a.final.ConversationID == "" {
+		a.final.ConversationID = a.conversationID
+	}
+	return a.final, nil
+}
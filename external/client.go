@@ -2,6 +2,7 @@ package external
 
 import (
 	"bytes"
+	"context"
 	"dokuprime-be/config"
 	"encoding/json"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -25,13 +27,41 @@ type Client struct {
 	baseURL     string
 	messagesURL string
 	httpClient  *http.Client
+	extractors  *ExtractorRegistry
+
+	chatTimeout       time.Duration
+	chatStreamTimeout time.Duration
+	extractTimeout    time.Duration
+	deleteTimeout     time.Duration
+	messagesTimeout   time.Duration
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+
+	ragBreaker      *CircuitBreaker
+	messagesBreaker *CircuitBreaker
 }
 
+// NewClient has no client-wide timeout because chat streams can run long;
+// every call gets its own deadline from cfg instead.
 func NewClient(cfg *config.ExternalAPIConfig) *Client {
 	return &Client{
 		baseURL:     cfg.BaseURL,
 		messagesURL: cfg.MessagesAPIURL,
 		httpClient:  &http.Client{},
+		extractors:  defaultExtractors,
+
+		chatTimeout:       cfg.ChatTimeout,
+		chatStreamTimeout: cfg.ChatStreamTimeout,
+		extractTimeout:    cfg.ExtractTimeout,
+		deleteTimeout:     cfg.DeleteTimeout,
+		messagesTimeout:   cfg.MessagesTimeout,
+
+		maxRetries:     cfg.MaxRetries,
+		retryBaseDelay: cfg.RetryBaseDelay,
+
+		ragBreaker:      breakerFor(ServiceRAG, cfg.BreakerThreshold, cfg.BreakerCooldown),
+		messagesBreaker: breakerFor(ServiceMessages, cfg.BreakerThreshold, cfg.BreakerCooldown),
 	}
 }
 
@@ -40,6 +70,9 @@ type ExtractRequest struct {
 	Category string
 	Filename string
 	FilePath string
+	// Metadata and Tags are forwarded so the RAG service can filter on them.
+	Metadata map[string]interface{}
+	Tags     []string
 }
 
 type DeleteRequest struct {
@@ -160,25 +193,41 @@ type ChatResponse struct {
 	AnswerID         int                   `json:"answer_id"`
 }
 
-func (c *Client) ExtractDocument(req ExtractRequest) error {
+func (c *Client) ExtractDocument(ctx context.Context, req ExtractRequest) error {
 
 	ext := strings.ToLower(filepath.Ext(req.Filename))
-	var endpoint string
-
-	switch ext {
-	case ".pdf":
-		endpoint = "/extract/pdf"
-	case ".txt":
-		endpoint = "/extract/txt"
-	default:
+	extractor, ok := c.extractors.Lookup(ext)
+	if !ok {
 		return fmt.Errorf("unsupported file type: %s", ext)
 	}
+	if err := extractor.validate(ext); err != nil {
+		return err
+	}
 
-	file, err := os.Open(req.FilePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+	endpoint := extractor.Endpoint
+	uploadName := req.Filename
+	var content io.Reader
+
+	if extractor.Convert != nil {
+		text, err := extractor.Convert(req.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to text: %w", ext, err)
+		}
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("no text could be extracted from %s", req.Filename)
+		}
+
+		endpoint = txtExtractEndpoint
+		uploadName = strings.TrimSuffix(req.Filename, filepath.Ext(req.Filename)) + ".txt"
+		content = strings.NewReader(text)
+	} else {
+		file, err := os.Open(req.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		content = file
 	}
-	defer file.Close()
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -195,12 +244,32 @@ func (c *Client) ExtractDocument(req ExtractRequest) error {
 		return fmt.Errorf("failed to write filename field: %w", err)
 	}
 
-	part, err := writer.CreateFormFile("file", req.Filename)
+	if len(req.Metadata) > 0 {
+		metadataJSON, err := json.Marshal(req.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		if err := writer.WriteField("metadata", string(metadataJSON)); err != nil {
+			return fmt.Errorf("failed to write metadata field: %w", err)
+		}
+	}
+
+	if len(req.Tags) > 0 {
+		tagsJSON, err := json.Marshal(req.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to encode tags: %w", err)
+		}
+		if err := writer.WriteField("tags", string(tagsJSON)); err != nil {
+			return fmt.Errorf("failed to write tags field: %w", err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", uploadName)
 	if err != nil {
 		return fmt.Errorf("failed to create form file: %w", err)
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
+	if _, err := io.Copy(part, content); err != nil {
 		return fmt.Errorf("failed to copy file content: %w", err)
 	}
 
@@ -209,53 +278,54 @@ func (c *Client) ExtractDocument(req ExtractRequest) error {
 	}
 
 	url := c.baseURL + endpoint
-	httpReq, err := http.NewRequest("POST", url, body)
-	if err != nil {
-		return fmt.Errorf(isFailedToRequest, err)
-	}
-
-	httpReq.Header.Set(isContentType, writer.FormDataContentType())
-	httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
+	payload := body.Bytes()
+	contentType := writer.FormDataContentType()
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.execute(ctx, callOptions{breaker: c.ragBreaker, timeout: c.extractTimeout, retryable: true}, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf(isFailedToRequest, err)
+		}
+		httpReq.Header.Set(isContentType, contentType)
+		httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
+		return httpReq, nil
+	})
 	if err != nil {
-		return fmt.Errorf(isFailedToSend, err)
+		return err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("external API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("external API returned status %d: %s", resp.StatusCode, string(resp.Body))
 	}
 
 	return nil
 }
 
-func (c *Client) DeleteDocument(req DeleteRequest) error {
+func (c *Client) DeleteDocument(ctx context.Context, req DeleteRequest) error {
 	url := fmt.Sprintf("%s/api/delete?id=%d&category=%s", c.baseURL, req.ID, strings.ToLower(req.Category))
 
-	httpReq, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create delete request: %w", err)
-	}
-
-	httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.execute(ctx, callOptions{breaker: c.ragBreaker, timeout: c.deleteTimeout, retryable: true}, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create delete request: %w", err)
+		}
+		httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
+		return httpReq, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to send delete request: %w", err)
+		return fmt.Errorf("delete request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("external API delete returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("external API delete returned status %d: %s", resp.StatusCode, string(resp.Body))
 	}
 
 	return nil
 }
 
-func (c *Client) SendChatMessage(req ChatRequest) (*ChatResponse, error) {
+// SendChatMessage is not retried: the RAG service stores every question it
+// receives.
+func (c *Client) SendChatMessage(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	url := c.baseURL + "/api/chat/"
 
 	jsonData, err := json.Marshal(req)
@@ -263,32 +333,25 @@ func (c *Client) SendChatMessage(req ChatRequest) (*ChatResponse, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf(isFailedToRequest, err)
-	}
-
-	httpReq.Header.Set(isContentType, "application/json")
-	httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf(isFailedToSend, err)
-	}
-
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
+	resp, err := c.execute(ctx, callOptions{breaker: c.ragBreaker, timeout: c.chatTimeout}, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf(isFailedToRequest, err)
+		}
+		httpReq.Header.Set(isContentType, "application/json")
+		httpReq.Header.Set(isXAPI, os.Getenv("X_API_KEY"))
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("external API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("external API returned status %d: %s", resp.StatusCode, string(resp.Body))
 	}
 
 	var chatResp ChatResponse
-	if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
+	if err := json.Unmarshal(resp.Body, &chatResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
@@ -301,7 +364,7 @@ type MessageAPIRequest struct {
 	Data    interface{} `json:"data"`
 }
 
-func (c *Client) SendMessageToAPI(data interface{}) error {
+func (c *Client) SendMessageToAPI(ctx context.Context, data interface{}) error {
 	url := c.messagesURL + "/api/send/reply"
 
 	requestBody := MessageAPIRequest{
@@ -315,25 +378,23 @@ func (c *Client) SendMessageToAPI(data interface{}) error {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf(isFailedToRequest, err)
-	}
-
-	httpReq.Header.Set(isContentType, "application/json")
-	httpReq.Header.Set(isXAPI, os.Getenv("MESSAGES_API_KEY"))
-
 	log.Println("MESSAGE API KEY -> ", c.messagesURL+"/api/send/reply")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.execute(ctx, callOptions{breaker: c.messagesBreaker, timeout: c.messagesTimeout}, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf(isFailedToRequest, err)
+		}
+		httpReq.Header.Set(isContentType, "application/json")
+		httpReq.Header.Set(isXAPI, os.Getenv("MESSAGES_API_KEY"))
+		return httpReq, nil
+	})
 	if err != nil {
-		return fmt.Errorf(isFailedToSend, err)
+		return err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("messages API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("messages API returned status %d: %s", resp.StatusCode, string(resp.Body))
 	}
 
 	return nil
@@ -0,0 +1,314 @@
+package external
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxZipEntrySize caps the decompressed size of one part of a docx or xlsx,
+// so a small zip bomb cannot exhaust memory while it is converted.
+const maxZipEntrySize = 64 << 20
+
+var (
+	htmlSkippedBlocks = regexp.MustCompile(`(?is)<(script|style|noscript|head)\b[^>]*>.*?</(script|style|noscript|head)>`)
+	htmlComments      = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBlockTags     = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/tr|/h[1-6]|/title|/table)\b[^>]*>`)
+	htmlCellTags      = regexp.MustCompile(`(?i)<\s*/t[dh]\s*>`)
+	htmlTags          = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines        = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+)
+
+func convertPlainText(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if !utf8.Valid(content) {
+		return "", fmt.Errorf("file is not valid UTF-8 text")
+	}
+	return string(content), nil
+}
+
+func convertHTML(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	text := htmlComments.ReplaceAllString(string(content), "")
+	text = htmlSkippedBlocks.ReplaceAllString(text, "")
+	text = htmlBlockTags.ReplaceAllString(text, "\n")
+	text = htmlCellTags.ReplaceAllString(text, "\t")
+	text = htmlTags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	return strings.TrimSpace(text), nil
+}
+
+func convertCSV(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var sb strings.Builder
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse csv: %w", err)
+		}
+		sb.WriteString(strings.Join(record, " | "))
+		sb.WriteString("\n")
+	}
+
+	return sb.String(), nil
+}
+
+// convertDOCX reads the paragraphs of word/document.xml. Tables come out one
+// cell per line, which is enough for retrieval.
+func convertDOCX(filePath string) (string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open docx: %w", err)
+	}
+	defer archive.Close()
+
+	var sb strings.Builder
+	found := false
+	for _, f := range archive.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		found = true
+
+		rc, err := openZipEntry(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to open document.xml: %w", err)
+		}
+		err = readWordXML(rc, &sb)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document.xml: %w", err)
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("docx has no word/document.xml")
+	}
+	return sb.String(), nil
+}
+
+func readWordXML(r io.Reader, sb *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type         string `xml:"t,attr"`
+			Value        string `xml:"v"`
+			InlineString string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// convertXLSX writes every sheet as "Sheet: <name>" followed by its rows, with
+// cells separated by " | ".
+func convertXLSX(filePath string) (string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open xlsx: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return "", fmt.Errorf("failed to parse shared strings: %w", err)
+		}
+	}
+	sharedStrings := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		text := item.Text
+		for _, run := range item.Runs {
+			text += run.Text
+		}
+		sharedStrings[i] = text
+	}
+
+	var workbook xlsxWorkbook
+	if f, ok := files["xl/workbook.xml"]; ok {
+		if err := decodeZipXML(f, &workbook); err != nil {
+			return "", fmt.Errorf("failed to parse workbook: %w", err)
+		}
+	}
+
+	sheetFiles := make([]string, 0)
+	for name := range files {
+		if strings.HasPrefix(name, "xl/worksheets/sheet") && strings.HasSuffix(name, ".xml") {
+			sheetFiles = append(sheetFiles, name)
+		}
+	}
+	sort.Slice(sheetFiles, func(i, j int) bool {
+		return sheetNumber(sheetFiles[i]) < sheetNumber(sheetFiles[j])
+	})
+
+	var sb strings.Builder
+	for i, name := range sheetFiles {
+		var sheet xlsxWorksheet
+		if err := decodeZipXML(files[name], &sheet); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		sheetName := fmt.Sprintf("Sheet%d", i+1)
+		if i < len(workbook.Sheets) {
+			sheetName = workbook.Sheets[i].Name
+		}
+		sb.WriteString("Sheet: " + sheetName + "\n")
+
+		for _, row := range sheet.Rows {
+			values := make([]string, 0, len(row.Cells))
+			for _, cell := range row.Cells {
+				values = append(values, xlsxCellText(cell.Type, cell.Value, cell.InlineString, sharedStrings))
+			}
+			line := strings.TrimRight(strings.Join(values, " | "), " |")
+			if line != "" {
+				sb.WriteString(line + "\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String(), nil
+}
+
+func xlsxCellText(cellType, value, inline string, sharedStrings []string) string {
+	switch cellType {
+	case "s":
+		idx, err := strconv.Atoi(value)
+		if err == nil && idx >= 0 && idx < len(sharedStrings) {
+			return sharedStrings[idx]
+		}
+		return ""
+	case "inlineStr":
+		return inline
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return value
+	}
+}
+
+func sheetNumber(name string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "xl/worksheets/sheet"), ".xml"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := openZipEntry(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// openZipEntry opens f for reading at most maxZipEntrySize bytes. The
+// declared size is checked first, and the limit also holds if it lies.
+func openZipEntry(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxZipEntrySize {
+		return nil, fmt.Errorf("%s is larger than %d MB uncompressed", f.Name, maxZipEntrySize>>20)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, maxZipEntrySize), rc}, nil
+}
@@ -0,0 +1,81 @@
+package external
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocumentXML = `<w:document><w:body><w:p><w:r><w:t>Hello</w:t></w:r></w:p></w:body></w:document>`
+
+// writeDOCX writes a docx whose word/document.xml declares uncompressedSize
+// instead of its real size, as a zip bomb would.
+func writeDOCX(t *testing.T, uncompressedSize uint64) string {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(testDocumentXML))
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "word/document.xml",
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE([]byte(testDocumentXML)),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: uncompressedSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.docx")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConvertDOCXEntrySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    uint64
+		want    string
+		wantErr string
+	}{
+		{name: "within limit", size: uint64(len(testDocumentXML)), want: "Hello\n"},
+		{name: "declared over limit", size: maxZipEntrySize + 1, wantErr: "larger than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertDOCX(writeDOCX(t, tt.size))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("convertDOCX() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertDOCX() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("convertDOCX() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
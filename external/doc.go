@@ -0,0 +1,424 @@
+package external
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// Legacy .doc files are OLE2 compound files. The text is read from the
+// WordDocument stream through the piece table stored in the table stream.
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSector = 0xFFFFFFFF
+
+	cfbTypeStream = 2
+	cfbTypeRoot   = 5
+
+	wordIdent = 0xA5EC
+
+	fibEncrypted   = 0x0100
+	fibWhichTblStm = 0x0200
+
+	// fcClx is the 34th FcLcb pair of the FIB.
+	fibClxIndex = 33
+
+	pieceCompressed = 0x40000000
+)
+
+var errInvalidDOC = errors.New("file is not a Word 97-2003 document")
+
+// cp1252High maps 0x80-0x9F of Windows-1252, which compressed pieces use;
+// the rest of the range matches Latin-1.
+var cp1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// convertDOC reads the text of a Word 97-2003 document, paragraph by line.
+// Field instructions are dropped and their results kept.
+func convertDOC(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return extractDOCText(content)
+}
+
+func extractDOCText(content []byte) (string, error) {
+	file, err := openCompoundFile(content)
+	if err != nil {
+		return "", err
+	}
+
+	word, err := file.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	if len(word) < 34 || binary.LittleEndian.Uint16(word) != wordIdent {
+		return "", errInvalidDOC
+	}
+	flags := binary.LittleEndian.Uint16(word[10:])
+	if flags&fibEncrypted != 0 {
+		return "", fmt.Errorf("encrypted Word documents are not supported")
+	}
+
+	tableName := "0Table"
+	if flags&fibWhichTblStm != 0 {
+		tableName = "1Table"
+	}
+	table, err := file.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	clx, err := readCLX(word, table)
+	if err != nil {
+		return "", err
+	}
+	text, err := readPieces(word, clx)
+	if err != nil {
+		return "", err
+	}
+	return cleanDOCText(text), nil
+}
+
+// readCLX locates the piece table through the variable-length FIB.
+func readCLX(word, table []byte) ([]byte, error) {
+	pos := 32
+	read16 := func() (int, bool) {
+		if pos+2 > len(word) {
+			return 0, false
+		}
+		v := int(binary.LittleEndian.Uint16(word[pos:]))
+		pos += 2
+		return v, true
+	}
+
+	csw, ok := read16()
+	if !ok {
+		return nil, errInvalidDOC
+	}
+	pos += csw * 2
+	cslw, ok := read16()
+	if !ok {
+		return nil, errInvalidDOC
+	}
+	pos += cslw * 4
+	cbRgFcLcb, ok := read16()
+	if !ok || cbRgFcLcb <= fibClxIndex || pos+(fibClxIndex+1)*8 > len(word) {
+		return nil, errInvalidDOC
+	}
+
+	entry := word[pos+fibClxIndex*8:]
+	fc := int64(binary.LittleEndian.Uint32(entry))
+	lcb := int64(binary.LittleEndian.Uint32(entry[4:]))
+	if lcb == 0 || fc+lcb > int64(len(table)) {
+		return nil, fmt.Errorf("%w: piece table is missing", errInvalidDOC)
+	}
+	return table[fc : fc+lcb], nil
+}
+
+// readPieces concatenates the text of every piece in character order.
+func readPieces(word, clx []byte) ([]rune, error) {
+	i := 0
+	for i < len(clx) && clx[i] == 0x01 {
+		// Prc: formatting properties that precede the piece table.
+		if i+3 > len(clx) {
+			return nil, errInvalidDOC
+		}
+		i += 3 + int(binary.LittleEndian.Uint16(clx[i+1:]))
+	}
+	if i+5 > len(clx) || clx[i] != 0x02 {
+		return nil, fmt.Errorf("%w: piece table is malformed", errInvalidDOC)
+	}
+	lcb := int(binary.LittleEndian.Uint32(clx[i+1:]))
+	plc := clx[i+5:]
+	if lcb < 4 || lcb > len(plc) || (lcb-4)%12 != 0 {
+		return nil, fmt.Errorf("%w: piece table is malformed", errInvalidDOC)
+	}
+
+	count := (lcb - 4) / 12
+	var text []rune
+	for n := 0; n < count; n++ {
+		cpStart := int64(binary.LittleEndian.Uint32(plc[n*4:]))
+		cpEnd := int64(binary.LittleEndian.Uint32(plc[(n+1)*4:]))
+		pcd := plc[(count+1)*4+n*8:]
+		rawFC := binary.LittleEndian.Uint32(pcd[2:])
+		fc := int64(rawFC & 0x3FFFFFFF)
+		chars := cpEnd - cpStart
+		if chars <= 0 {
+			continue
+		}
+
+		if rawFC&pieceCompressed != 0 {
+			start := fc / 2
+			if start+chars > int64(len(word)) {
+				return nil, fmt.Errorf("%w: text piece out of range", errInvalidDOC)
+			}
+			for _, b := range word[start : start+chars] {
+				if b >= 0x80 && b < 0xA0 {
+					text = append(text, cp1252High[b-0x80])
+				} else {
+					text = append(text, rune(b))
+				}
+			}
+			continue
+		}
+
+		if fc+chars*2 > int64(len(word)) {
+			return nil, fmt.Errorf("%w: text piece out of range", errInvalidDOC)
+		}
+		units := make([]uint16, chars)
+		for k := range units {
+			units[k] = binary.LittleEndian.Uint16(word[fc+int64(k)*2:])
+		}
+		text = append(text, utf16.Decode(units)...)
+	}
+	return text, nil
+}
+
+// cleanDOCText turns Word's control characters into plain text: paragraph,
+// line and page breaks become newlines, cell marks tabs, and fields keep
+// only their result.
+func cleanDOCText(text []rune) string {
+	var sb strings.Builder
+	// One entry per open field; true while in its instruction part.
+	var fields []bool
+	for _, r := range text {
+		switch r {
+		case 0x13:
+			fields = append(fields, true)
+			continue
+		case 0x14:
+			if len(fields) > 0 {
+				fields[len(fields)-1] = false
+			}
+			continue
+		case 0x15:
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+			continue
+		}
+		if inFieldInstruction(fields) {
+			continue
+		}
+
+		switch {
+		case r == '\r' || r == 0x0B || r == 0x0C:
+			sb.WriteByte('\n')
+		case r == 0x07:
+			sb.WriteByte('\t')
+		case r == 0x1E:
+			sb.WriteByte('-')
+		case r == 0xA0:
+			sb.WriteByte(' ')
+		case r == '\t' || r >= 0x20:
+			sb.WriteRune(r)
+		}
+	}
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func inFieldInstruction(fields []bool) bool {
+	for _, instruction := range fields {
+		if instruction {
+			return true
+		}
+	}
+	return false
+}
+
+// compoundFile is a read-only view of an OLE2 compound file.
+type compoundFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     int64
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	entries        []cfbEntry
+}
+
+type cfbEntry struct {
+	name  string
+	kind  byte
+	start uint32
+	size  int64
+}
+
+func openCompoundFile(data []byte) (*compoundFile, error) {
+	if len(data) < 512 || !bytes.HasPrefix(data, cfbSignature) {
+		return nil, errInvalidDOC
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(data[0x1E:])
+	miniShift := binary.LittleEndian.Uint16(data[0x20:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, fmt.Errorf("%w: unsupported sector size", errInvalidDOC)
+	}
+	f := &compoundFile{
+		data:           data,
+		sectorSize:     1 << sectorShift,
+		miniSectorSize: 1 << miniShift,
+		miniCutoff:     int64(binary.LittleEndian.Uint32(data[0x38:])),
+	}
+
+	if err := f.readFAT(); err != nil {
+		return nil, err
+	}
+
+	dir, err := f.chain(binary.LittleEndian.Uint32(data[0x30:]), f.fat, f.sector)
+	if err != nil {
+		return nil, err
+	}
+	for off := 0; off+128 <= len(dir); off += 128 {
+		raw := dir[off : off+128]
+		nameLen := int(binary.LittleEndian.Uint16(raw[0x40:]))
+		if nameLen < 2 || nameLen > 64 {
+			f.entries = append(f.entries, cfbEntry{})
+			continue
+		}
+		units := make([]uint16, nameLen/2-1)
+		for k := range units {
+			units[k] = binary.LittleEndian.Uint16(raw[k*2:])
+		}
+		f.entries = append(f.entries, cfbEntry{
+			name:  string(utf16.Decode(units)),
+			kind:  raw[0x42],
+			start: binary.LittleEndian.Uint32(raw[0x74:]),
+			// Version 3 files may leave garbage in the high half.
+			size: int64(binary.LittleEndian.Uint32(raw[0x78:])),
+		})
+	}
+	if len(f.entries) == 0 || f.entries[0].kind != cfbTypeRoot {
+		return nil, fmt.Errorf("%w: root directory entry is missing", errInvalidDOC)
+	}
+
+	miniFAT, err := f.chain(binary.LittleEndian.Uint32(data[0x3C:]), f.fat, f.sector)
+	if err != nil {
+		return nil, err
+	}
+	f.miniFAT = sectorTable(miniFAT)
+
+	root := f.entries[0]
+	if root.size > 0 {
+		if f.miniStream, err = f.chain(root.start, f.fat, f.sector); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// readFAT collects the FAT sectors listed in the header and the DIFAT chain.
+func (f *compoundFile) readFAT() error {
+	var sectors []uint32
+	for i := 0; i < 109; i++ {
+		sectors = append(sectors, binary.LittleEndian.Uint32(f.data[0x4C+i*4:]))
+	}
+
+	next := binary.LittleEndian.Uint32(f.data[0x44:])
+	perSector := f.sectorSize/4 - 1
+	for seen := 0; next != cfbEndOfChain && next != cfbFreeSector; seen++ {
+		sector := f.sector(next)
+		if sector == nil || seen > len(f.data)/f.sectorSize {
+			return fmt.Errorf("%w: broken DIFAT chain", errInvalidDOC)
+		}
+		for i := 0; i < perSector; i++ {
+			sectors = append(sectors, binary.LittleEndian.Uint32(sector[i*4:]))
+		}
+		next = binary.LittleEndian.Uint32(sector[perSector*4:])
+	}
+
+	for _, n := range sectors {
+		if n == cfbFreeSector || n == cfbEndOfChain {
+			continue
+		}
+		sector := f.sector(n)
+		if sector == nil {
+			return fmt.Errorf("%w: FAT sector out of range", errInvalidDOC)
+		}
+		f.fat = append(f.fat, sectorTable(sector)...)
+	}
+	return nil
+}
+
+func (f *compoundFile) sector(n uint32) []byte {
+	start := (int64(n) + 1) * int64(f.sectorSize)
+	if start+int64(f.sectorSize) > int64(len(f.data)) {
+		return nil
+	}
+	return f.data[start : start+int64(f.sectorSize)]
+}
+
+func (f *compoundFile) miniSector(n uint32) []byte {
+	start := int64(n) * int64(f.miniSectorSize)
+	if start+int64(f.miniSectorSize) > int64(len(f.miniStream)) {
+		return nil
+	}
+	return f.miniStream[start : start+int64(f.miniSectorSize)]
+}
+
+// chain follows a sector chain through table. A chain longer than the table
+// has a loop.
+func (f *compoundFile) chain(start uint32, table []uint32, read func(uint32) []byte) ([]byte, error) {
+	var out []byte
+	for n, steps := start, 0; n != cfbEndOfChain && n != cfbFreeSector; steps++ {
+		if int(n) >= len(table) || steps > len(table) {
+			return nil, fmt.Errorf("%w: broken sector chain", errInvalidDOC)
+		}
+		sector := read(n)
+		if sector == nil {
+			return nil, fmt.Errorf("%w: sector out of range", errInvalidDOC)
+		}
+		out = append(out, sector...)
+		n = table[n]
+	}
+	return out, nil
+}
+
+func (f *compoundFile) stream(name string) ([]byte, error) {
+	for _, entry := range f.entries {
+		if entry.kind != cfbTypeStream || entry.name != name {
+			continue
+		}
+
+		var data []byte
+		var err error
+		if entry.size < f.miniCutoff {
+			data, err = f.chain(entry.start, f.miniFAT, f.miniSector)
+		} else {
+			data, err = f.chain(entry.start, f.fat, f.sector)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) < entry.size {
+			return nil, fmt.Errorf("%w: stream %s is truncated", errInvalidDOC, name)
+		}
+		return data[:entry.size], nil
+	}
+	return nil, fmt.Errorf("%w: no %s stream", errInvalidDOC, name)
+}
+
+func sectorTable(data []byte) []uint32 {
+	table := make([]uint32, len(data)/4)
+	for i := range table {
+		table[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return table
+}
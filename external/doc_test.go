@@ -0,0 +1,175 @@
+package external
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildDOC writes a Word 97 document with two text pieces, one compressed and
+// one UTF-16, inside a compound file whose streams all live in the mini
+// stream.
+func buildDOC(flags uint16) []byte {
+	compressed := "Hello \x13 HYPERLINK \"http://x\" \x14World\x15\r\x93Quoted\x94\r"
+	unicode := utf16.Encode([]rune("Ünïcode €\x07\x07\r"))
+
+	word := make([]byte, 2048+len(unicode)*2)
+	binary.LittleEndian.PutUint16(word[0:], wordIdent)
+	binary.LittleEndian.PutUint16(word[2:], 0x00C1)
+	binary.LittleEndian.PutUint16(word[10:], flags)
+	binary.LittleEndian.PutUint16(word[32:], 14)
+	binary.LittleEndian.PutUint16(word[62:], 22)
+	binary.LittleEndian.PutUint16(word[152:], 93)
+	copy(word[1024:], compressed)
+	for i, unit := range unicode {
+		binary.LittleEndian.PutUint16(word[2048+i*2:], unit)
+	}
+
+	// A Prc that must be skipped, then the piece table.
+	clx := []byte{0x01, 0x02, 0x00, 0xAA, 0xBB, 0x02}
+	plc := make([]byte, 4*3+8*2)
+	binary.LittleEndian.PutUint32(plc[4:], uint32(len(compressed)))
+	binary.LittleEndian.PutUint32(plc[8:], uint32(len(compressed)+len(unicode)))
+	binary.LittleEndian.PutUint32(plc[12+2:], 1024*2|pieceCompressed)
+	binary.LittleEndian.PutUint32(plc[20+2:], 2048)
+	clx = binary.LittleEndian.AppendUint32(clx, uint32(len(plc)))
+	clx = append(clx, plc...)
+
+	table := make([]byte, 16+len(clx))
+	copy(table[16:], clx)
+	binary.LittleEndian.PutUint32(word[154+fibClxIndex*8:], 16)
+	binary.LittleEndian.PutUint32(word[154+fibClxIndex*8+4:], uint32(len(clx)))
+
+	return buildCompoundFile(map[string][]byte{"WordDocument": word, "1Table": table})
+}
+
+// buildCompoundFile lays out 512-byte sectors: the FAT, the directory, the
+// mini FAT, then the mini stream holding every stream.
+func buildCompoundFile(streams map[string][]byte) []byte {
+	names := []string{"WordDocument", "1Table"}
+
+	var mini []byte
+	var miniFAT []uint32
+	starts := map[string]uint32{}
+	for _, name := range names {
+		data := streams[name]
+		first := uint32(len(mini) / 64)
+		starts[name] = first
+		count := (len(data) + 63) / 64
+		for i := 0; i < count; i++ {
+			next := first + uint32(i) + 1
+			if i == count-1 {
+				next = cfbEndOfChain
+			}
+			miniFAT = append(miniFAT, next)
+		}
+		padded := make([]byte, count*64)
+		copy(padded, data)
+		mini = append(mini, padded...)
+	}
+
+	miniSectors := (len(mini) + 511) / 512
+	fat := []uint32{0xFFFFFFFD, cfbEndOfChain, cfbEndOfChain}
+	for i := 0; i < miniSectors; i++ {
+		next := uint32(3 + i + 1)
+		if i == miniSectors-1 {
+			next = cfbEndOfChain
+		}
+		fat = append(fat, next)
+	}
+
+	header := make([]byte, 512)
+	copy(header, cfbSignature)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], 1)
+	binary.LittleEndian.PutUint32(header[0x30:], 1)
+	binary.LittleEndian.PutUint32(header[0x38:], 4096)
+	binary.LittleEndian.PutUint32(header[0x3C:], 2)
+	binary.LittleEndian.PutUint32(header[0x40:], 1)
+	binary.LittleEndian.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], cfbFreeSector)
+	}
+	binary.LittleEndian.PutUint32(header[0x4C:], 0)
+
+	table := func(entries []uint32) []byte {
+		sector := make([]byte, 512)
+		for i := range 128 {
+			value := uint32(cfbFreeSector)
+			if i < len(entries) {
+				value = entries[i]
+			}
+			binary.LittleEndian.PutUint32(sector[i*4:], value)
+		}
+		return sector
+	}
+
+	dir := make([]byte, 512)
+	entry := func(i int, name string, kind byte, start uint32, size int) {
+		raw := dir[i*128:]
+		units := utf16.Encode([]rune(name))
+		for k, unit := range units {
+			binary.LittleEndian.PutUint16(raw[k*2:], unit)
+		}
+		binary.LittleEndian.PutUint16(raw[0x40:], uint16((len(units)+1)*2))
+		raw[0x42] = kind
+		binary.LittleEndian.PutUint32(raw[0x74:], start)
+		binary.LittleEndian.PutUint32(raw[0x78:], uint32(size))
+	}
+	entry(0, "Root Entry", cfbTypeRoot, 3, len(mini))
+	for i, name := range names {
+		entry(i+1, name, cfbTypeStream, starts[name], len(streams[name]))
+	}
+
+	out := append(header, table(fat)...)
+	out = append(out, dir...)
+	out = append(out, table(miniFAT)...)
+	padded := make([]byte, miniSectors*512)
+	copy(padded, mini)
+	return append(out, padded...)
+}
+
+func TestExtractDOCText(t *testing.T) {
+	valid := buildDOC(fibWhichTblStm)
+
+	loop := buildDOC(fibWhichTblStm)
+	// Point the directory's FAT entry back at itself.
+	binary.LittleEndian.PutUint32(loop[512+4:], 1)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", data: valid, want: "Hello World\n“Quoted”\nÜnïcode €"},
+		{name: "wrong table stream", data: buildDOC(0), wantErr: true},
+		{name: "encrypted", data: buildDOC(fibWhichTblStm | fibEncrypted), wantErr: true},
+		{name: "sector chain loop", data: loop, wantErr: true},
+		{name: "truncated", data: valid[:1024], wantErr: true},
+		{name: "not a compound file", data: []byte(strings.Repeat("x", 1024)), wantErr: true},
+		{name: "empty", data: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractDOCText(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractDOCText() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractDOCText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractDOCText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const txtExtractEndpoint = "/extract/txt"
+
+// TextConverter turns a local file into plain text, which is then uploaded to
+// the txt extraction endpoint instead of the original file.
+type TextConverter func(filePath string) (string, error)
+
+// Extractor describes how files of one extension reach the knowledge base:
+// either uploaded as-is to Endpoint, or converted locally with Convert.
+type Extractor struct {
+	Endpoint string
+	Convert  TextConverter
+}
+
+type ExtractorRegistry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+func NewExtractorRegistry() *ExtractorRegistry {
+	return &ExtractorRegistry{
+		extractors: make(map[string]Extractor),
+	}
+}
+
+var defaultExtractors = newDefaultExtractorRegistry()
+
+func newDefaultExtractorRegistry() *ExtractorRegistry {
+	registry := NewExtractorRegistry()
+	registry.Register("pdf", Extractor{Endpoint: "/extract/pdf"})
+	registry.Register("txt", Extractor{Endpoint: txtExtractEndpoint})
+	registry.Register("md", Extractor{Convert: convertPlainText})
+	registry.Register("html", Extractor{Convert: convertHTML})
+	registry.Register("htm", Extractor{Convert: convertHTML})
+	registry.Register("csv", Extractor{Convert: convertCSV})
+	registry.Register("docx", Extractor{Convert: convertDOCX})
+	registry.Register("xlsx", Extractor{Convert: convertXLSX})
+	registry.Register("doc", Extractor{Convert: convertDOC})
+	return registry
+}
+
+// RegisterExtractor adds or replaces the extractor used for an extension on
+// the default registry, which also makes the extension an accepted upload type.
+func RegisterExtractor(ext string, extractor Extractor) {
+	defaultExtractors.Register(ext, extractor)
+}
+
+// SupportedExtensions lists the extensions (without dot) that can be extracted.
+func SupportedExtensions() []string {
+	return defaultExtractors.Extensions()
+}
+
+func IsSupportedExtension(ext string) bool {
+	_, ok := defaultExtractors.Lookup(ext)
+	return ok
+}
+
+func (r *ExtractorRegistry) Register(ext string, extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors[normalizeExtension(ext)] = extractor
+}
+
+// Lookup resolves the extractor for an extension. EXTRACTOR_ENDPOINT_<EXT>
+// overrides the registered one, e.g. EXTRACTOR_ENDPOINT_DOCX=/extract/docx.
+func (r *ExtractorRegistry) Lookup(ext string) (Extractor, bool) {
+	ext = normalizeExtension(ext)
+
+	r.mu.RLock()
+	extractor, ok := r.extractors[ext]
+	r.mu.RUnlock()
+
+	if !ok {
+		return Extractor{}, false
+	}
+
+	if endpoint := os.Getenv("EXTRACTOR_ENDPOINT_" + strings.ToUpper(ext)); endpoint != "" {
+		return Extractor{Endpoint: endpoint}, true
+	}
+	return extractor, true
+}
+
+func (r *ExtractorRegistry) Extensions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	extensions := make([]string, 0, len(r.extractors))
+	for ext := range r.extractors {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+func (e Extractor) validate(ext string) error {
+	if e.Endpoint == "" && e.Convert == nil {
+		return fmt.Errorf("extractor for %s has neither an endpoint nor a converter", ext)
+	}
+	return nil
+}
+
+func normalizeExtension(ext string) string {
+	return strings.TrimPrefix(strings.ToLower(ext), ".")
+}
+
+// ErrTextUnsupported is returned by ExtractText for types that can only be
+// read by the RAG service.
+var ErrTextUnsupported = errors.New("text extraction is not supported for this file type")
+
+// ExtractText returns the plain text of a local file without calling the RAG
+// service: txt and pdf are read directly, other types use their converter.
+func ExtractText(filePath, ext string) (string, error) {
+	switch normalizeExtension(ext) {
+	case "txt":
+		return convertPlainText(filePath)
+	case "pdf":
+		return ExtractPDFText(filePath)
+	}
+
+	defaultExtractors.mu.RLock()
+	extractor, ok := defaultExtractors.extractors[normalizeExtension(ext)]
+	defaultExtractors.mu.RUnlock()
+
+	if !ok || extractor.Convert == nil {
+		return "", fmt.Errorf("%w: %s", ErrTextUnsupported, ext)
+	}
+	return extractor.Convert(filePath)
+}
+
+// TextPage is one page of extracted text. Number is 0 for formats that have
+// no pages.
+type TextPage struct {
+	Number int
+	Text   string
+}
+
+// ExtractTextPages works like ExtractText but keeps PDF page boundaries.
+func ExtractTextPages(filePath, ext string) ([]TextPage, error) {
+	if normalizeExtension(ext) != "pdf" {
+		text, err := ExtractText(filePath, ext)
+		if err != nil {
+			return nil, err
+		}
+		return []TextPage{{Text: text}}, nil
+	}
+
+	pages, err := ExtractPDFPages(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]TextPage, 0, len(pages))
+	for i, text := range pages {
+		if text != "" {
+			result = append(result, TextPage{Number: i + 1, Text: text})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,1149 @@
+package external
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ErrPDFEncrypted is returned for password protected PDFs, whose streams
+// cannot be read without decrypting them first.
+var ErrPDFEncrypted = errors.New("encrypted PDFs are not supported")
+
+const (
+	maxPDFStreamSize = 64 * 1024 * 1024
+	maxPDFFormDepth  = 8
+	maxPDFPageDepth  = 64
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// ExtractPDFText reads the text layer of a PDF locally, page by page. It
+// understands the common Flate/ASCII filters, object streams and ToUnicode
+// maps; scanned PDFs without a text layer come back empty.
+func ExtractPDFText(filePath string) (string, error) {
+	pages, err := ExtractPDFPages(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	nonEmpty := make([]string, 0, len(pages))
+	for _, page := range pages {
+		if page != "" {
+			nonEmpty = append(nonEmpty, page)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n"), nil
+}
+
+// ExtractPDFPages returns the text of every page in page order. Pages without
+// text are kept as empty strings so indexes match page numbers.
+func ExtractPDFPages(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return extractPDFPages(data)
+}
+
+func extractPDFPages(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\r "), []byte("%PDF")) {
+		return nil, fmt.Errorf("file is not a PDF")
+	}
+
+	doc := newPDFDocument(data)
+	trailer := doc.trailer()
+	if _, ok := trailer["Encrypt"]; ok {
+		return nil, ErrPDFEncrypted
+	}
+
+	pages := doc.pages(trailer)
+	texts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		content := doc.pageContent(page.dict)
+		if len(content) == 0 {
+			texts = append(texts, "")
+			continue
+		}
+		var sb strings.Builder
+		state := &pdfTextState{doc: doc, out: &sb}
+		state.run(content, page.resources, 0)
+		state.newline()
+		texts = append(texts, cleanPDFText(sb.String()))
+	}
+
+	return texts, nil
+}
+
+func cleanPDFText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// PDF object model. Strings are kept as raw bytes because their encoding
+// depends on the font they are shown with.
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfDelim   string
+	pdfString  []byte
+	pdfArray   []interface{}
+	pdfDict    map[string]interface{}
+	pdfRef     struct{ num, gen int }
+)
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// next returns one token: a number, name, string, keyword or delimiter.
+func (l *pdfLexer) next() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return pdfName(l.readName()), nil
+	case c == '(':
+		l.pos++
+		return pdfString(l.readLiteralString()), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfDelim("<<"), nil
+		}
+		l.pos++
+		return pdfString(l.readHexString()), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfDelim(">>"), nil
+		}
+		l.pos++
+		return l.next()
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfDelim(string(c)), nil
+	case c == ')':
+		l.pos++
+		return l.next()
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if n, err := strconv.ParseFloat(word, 64); err == nil {
+			return n, nil
+		}
+		return 0.0, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) readName() string {
+	var sb strings.Builder
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelimiter(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				sb.WriteByte(b[0])
+				l.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+	return sb.String()
+}
+
+func (l *pdfLexer) readLiteralString() []byte {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) readHexString() []byte {
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		if isPDFSpace(c) {
+			continue
+		}
+		digits = append(digits, c)
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+// readObject parses a full object. Indirect references are only recognised
+// when refs is set, since "1 0 R" never appears in content streams.
+func (l *pdfLexer) readObject(refs bool) (interface{}, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case pdfDelim:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				item, err := l.readObject(refs)
+				if err != nil || item == pdfDelim("]") {
+					return arr, nil
+				}
+				arr = append(arr, item)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, err := l.readObject(refs)
+				if err != nil || key == pdfDelim(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					continue
+				}
+				value, err := l.readObject(refs)
+				if err != nil || value == pdfDelim(">>") {
+					dict[string(name)] = nil
+					return dict, nil
+				}
+				dict[string(name)] = value
+			}
+		}
+		return t, nil
+	case float64:
+		if refs && t == math.Trunc(t) && t >= 0 {
+			save := l.pos
+			gen, err1 := l.next()
+			kw, err2 := l.next()
+			if g, ok := gen.(float64); ok && err1 == nil && err2 == nil && kw == pdfKeyword("R") {
+				return pdfRef{num: int(t), gen: int(g)}, nil
+			}
+			l.pos = save
+		}
+		return t, nil
+	case pdfKeyword:
+		switch t {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return tok, nil
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+type pdfDocument struct {
+	data      []byte
+	offsets   map[int]int
+	objects   map[int]interface{}
+	resolving map[int]bool
+	packed    map[int]interface{}
+	scanned   bool
+	fonts     map[int]*pdfFont
+}
+
+func newPDFDocument(data []byte) *pdfDocument {
+	doc := &pdfDocument{
+		data:      data,
+		offsets:   make(map[int]int),
+		objects:   make(map[int]interface{}),
+		resolving: make(map[int]bool),
+		fonts:     make(map[int]*pdfFont),
+	}
+	// Later definitions win, which is how incremental updates replace objects.
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] > 0 && !isPDFSpace(data[m[0]-1]) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		doc.offsets[num] = m[1]
+	}
+	return doc
+}
+
+func (d *pdfDocument) object(num int) interface{} {
+	if obj, ok := d.objects[num]; ok {
+		return obj
+	}
+	if d.resolving[num] {
+		return nil
+	}
+	d.resolving[num] = true
+	defer delete(d.resolving, num)
+
+	var obj interface{}
+	if offset, ok := d.offsets[num]; ok {
+		obj = d.parseObjectAt(offset)
+	} else {
+		obj = d.packedObject(num)
+	}
+	d.objects[num] = obj
+	return obj
+}
+
+func (d *pdfDocument) parseObjectAt(offset int) interface{} {
+	l := &pdfLexer{data: d.data, pos: offset}
+	obj, err := l.readObject(true)
+	if err != nil {
+		return nil
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj
+	}
+	save := l.pos
+	if tok, err := l.next(); err != nil || tok != pdfKeyword("stream") {
+		l.pos = save
+		return dict
+	}
+
+	start := l.pos
+	if start < len(d.data) && d.data[start] == '\r' {
+		start++
+	}
+	if start < len(d.data) && d.data[start] == '\n' {
+		start++
+	}
+	return &pdfStream{dict: dict, raw: d.streamBytes(dict, start)}
+}
+
+func (d *pdfDocument) streamBytes(dict pdfDict, start int) []byte {
+	// Bound the length while it is still a float: converting NaN, Inf or a
+	// huge /Length to int wraps around.
+	length, ok := d.resolve(dict["Length"]).(float64)
+	if ok && !math.IsNaN(length) && length >= 0 && length <= float64(len(d.data)-start) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(d.data[end:min(end+32, len(d.data))], "\r\n\t ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return d.data[start:end]
+		}
+	}
+
+	// Missing or wrong /Length: fall back to the endstream keyword.
+	end := bytes.Index(d.data[start:], []byte("endstream"))
+	if end < 0 {
+		return d.data[start:]
+	}
+	return bytes.TrimRight(d.data[start:start+end], "\r\n")
+}
+
+// packedObject looks the object up in the PDF 1.5 object streams, which are
+// indexed on first use.
+func (d *pdfDocument) packedObject(num int) interface{} {
+	if !d.scanned {
+		d.scanned = true
+		d.packed = make(map[int]interface{})
+
+		nums := make([]int, 0, len(d.offsets))
+		for n := range d.offsets {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+
+		for _, n := range nums {
+			stream, ok := d.object(n).(*pdfStream)
+			if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+				continue
+			}
+			d.unpackObjectStream(stream)
+		}
+	}
+	return d.packed[num]
+}
+
+func (d *pdfDocument) unpackObjectStream(stream *pdfStream) {
+	data, err := d.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	count, _ := d.resolve(stream.dict["N"]).(float64)
+	first, _ := d.resolve(stream.dict["First"]).(float64)
+
+	header := &pdfLexer{data: data}
+	for i := 0; i < int(count); i++ {
+		numTok, err1 := header.next()
+		offTok, err2 := header.next()
+		num, ok1 := numTok.(float64)
+		off, ok2 := offTok.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		pos := int(first) + int(off)
+		if pos < 0 || pos >= len(data) {
+			continue
+		}
+		if _, exists := d.packed[int(num)]; exists {
+			continue
+		}
+		obj, err := (&pdfLexer{data: data, pos: pos}).readObject(true)
+		if err == nil {
+			d.packed[int(num)] = obj
+		}
+	}
+}
+
+func (d *pdfDocument) resolve(v interface{}) interface{} {
+	for i := 0; i < 8; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.object(ref.num)
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(v interface{}) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+func (d *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters []interface{}
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := stream.raw
+	for _, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflatePDF(data)
+		case "ASCIIHexDecode", "AHx":
+			data = (&pdfLexer{data: append(bytes.TrimSpace(data), '>')}).readHexString()
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			err = fmt.Errorf("unsupported PDF filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func inflatePDF(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxPDFStreamSize))
+	// Truncated streams are common; keep whatever was recovered.
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ASCII85 stream: %w", err)
+	}
+	return out[:n], nil
+}
+
+// trailer merges the classic trailer dictionaries and cross-reference stream
+// dictionaries; only /Root and /Encrypt are needed.
+func (d *pdfDocument) trailer() pdfDict {
+	trailer := pdfDict{}
+	keyword := []byte("trailer")
+	for pos := 0; ; {
+		i := bytes.Index(d.data[pos:], keyword)
+		if i < 0 {
+			break
+		}
+		pos += i + len(keyword)
+		obj, err := (&pdfLexer{data: d.data, pos: pos}).readObject(true)
+		if dict, ok := obj.(pdfDict); ok && err == nil {
+			for k, v := range dict {
+				trailer[k] = v
+			}
+		}
+	}
+	if _, ok := trailer["Root"]; ok {
+		return trailer
+	}
+
+	for num := range d.offsets {
+		stream, ok := d.object(num).(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("XRef") {
+			continue
+		}
+		for _, key := range []string{"Root", "Encrypt"} {
+			if v, ok := stream.dict[key]; ok {
+				trailer[key] = v
+			}
+		}
+	}
+	return trailer
+}
+
+func (d *pdfDocument) pages(trailer pdfDict) []pdfPage {
+	var pages []pdfPage
+	if root := d.dict(trailer["Root"]); root != nil {
+		d.walkPages(root["Pages"], nil, map[int]bool{}, 0, &pages)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// Broken page tree: fall back to every page object in file order.
+	nums := make([]int, 0, len(d.offsets))
+	for n := range d.offsets {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		if dict, ok := d.object(n).(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (d *pdfDocument) walkPages(node interface{}, inherited pdfDict, visited map[int]bool, depth int, pages *[]pdfPage) {
+	if depth > maxPDFPageDepth {
+		return
+	}
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref.num] {
+			return
+		}
+		visited[ref.num] = true
+	}
+	dict := d.dict(node)
+	if dict == nil {
+		return
+	}
+
+	resources := inherited
+	if res := d.dict(dict["Resources"]); res != nil {
+		resources = res
+	}
+
+	kids, ok := d.resolve(dict["Kids"]).(pdfArray)
+	if !ok || dict["Type"] == pdfName("Page") {
+		*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+		return
+	}
+	for _, kid := range kids {
+		d.walkPages(kid, resources, visited, depth+1, pages)
+	}
+}
+
+func (d *pdfDocument) pageContent(page pdfDict) []byte {
+	var parts []interface{}
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		parts = []interface{}{c}
+	case pdfArray:
+		parts = c
+	}
+
+	var content []byte
+	for _, part := range parts {
+		stream, ok := d.resolve(part).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+	return content
+}
+
+func (d *pdfDocument) font(v interface{}) *pdfFont {
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if f, ok := d.fonts[ref.num]; ok {
+			return f
+		}
+	}
+
+	f := d.loadFont(d.dict(v))
+	if isRef {
+		d.fonts[ref.num] = f
+	}
+	return f
+}
+
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	f := &pdfFont{}
+	if dict == nil {
+		return f
+	}
+	f.twoByte = dict["Subtype"] == pdfName("Type0")
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(stream); err == nil {
+			f.cmap = parseToUnicode(data)
+		}
+	}
+
+	if enc := d.dict(dict["Encoding"]); enc != nil {
+		if diffs, ok := d.resolve(enc["Differences"]).(pdfArray); ok {
+			f.differences = make(map[byte]string)
+			code := 0
+			for _, item := range diffs {
+				switch t := d.resolve(item).(type) {
+				case float64:
+					code = int(t)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						if s, ok := glyphText(string(t)); ok {
+							f.differences[byte(code)] = s
+						}
+					}
+					code++
+				}
+			}
+		}
+	}
+	return f
+}
+
+type pdfFont struct {
+	twoByte     bool
+	cmap        *pdfCMap
+	differences map[byte]string
+}
+
+func (f *pdfFont) decode(s []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if f.cmap != nil {
+			if text, n := f.cmap.lookup(s[i:]); n > 0 {
+				sb.WriteString(text)
+				i += n
+				continue
+			}
+		}
+
+		width := 1
+		if f.twoByte && i+1 < len(s) {
+			width = 2
+		}
+		code := 0
+		for _, b := range s[i : i+width] {
+			code = code<<8 | int(b)
+		}
+		sb.WriteString(f.fallback(code, width))
+		i += width
+	}
+	return sb.String()
+}
+
+func (f *pdfFont) fallback(code, width int) string {
+	if width == 2 {
+		if r := rune(code); r >= ' ' && unicode.IsPrint(r) {
+			return string(r)
+		}
+		return ""
+	}
+	if text, ok := f.differences[byte(code)]; ok {
+		return text
+	}
+	if r, ok := winAnsiHigh[byte(code)]; ok {
+		return string(r)
+	}
+	if code < ' ' && code != '\t' {
+		return ""
+	}
+	return string(rune(code))
+}
+
+// pdfCMap is the subset of a ToUnicode CMap needed to map character codes
+// of each byte width to text.
+type pdfCMap struct {
+	widths   []int
+	mappings map[int]map[int]string
+}
+
+func (c *pdfCMap) add(width, code int, text string) {
+	if c.mappings[width] == nil {
+		c.mappings[width] = make(map[int]string)
+	}
+	c.mappings[width][code] = text
+}
+
+func (c *pdfCMap) lookup(s []byte) (string, int) {
+	for _, width := range c.widths {
+		if width > len(s) {
+			break
+		}
+		code := 0
+		for _, b := range s[:width] {
+			code = code<<8 | int(b)
+		}
+		if text, ok := c.mappings[width][code]; ok {
+			return text, width
+		}
+	}
+	return "", 0
+}
+
+func parseToUnicode(data []byte) *pdfCMap {
+	cmap := &pdfCMap{mappings: make(map[int]map[int]string)}
+	widths := map[int]bool{}
+	l := &pdfLexer{data: data}
+
+	var operands []interface{}
+	for {
+		obj, err := l.readObject(false)
+		if err != nil {
+			break
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if lo, ok := operands[i].(pdfString); ok && len(lo) > 0 {
+					widths[len(lo)] = true
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].(pdfString)
+				if !ok || len(src) == 0 || len(src) > 4 {
+					continue
+				}
+				if text, ok := cmapTarget(operands[i+1]); ok {
+					cmap.add(len(src), bytesToCode(src), text)
+					widths[len(src)] = true
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				addCMapRange(cmap, len(lo), bytesToCode(lo), bytesToCode(hi), operands[i+2])
+				widths[len(lo)] = true
+			}
+		}
+		if strings.HasPrefix(string(kw), "end") || strings.HasPrefix(string(kw), "begin") {
+			operands = operands[:0]
+		}
+	}
+
+	for w := range widths {
+		cmap.widths = append(cmap.widths, w)
+	}
+	sort.Ints(cmap.widths)
+	return cmap
+}
+
+func addCMapRange(cmap *pdfCMap, width, lo, hi int, target interface{}) {
+	if hi < lo || hi-lo > 0xFFFF {
+		return
+	}
+	switch t := target.(type) {
+	case pdfArray:
+		for i, item := range t {
+			if lo+i > hi {
+				break
+			}
+			if text, ok := cmapTarget(item); ok {
+				cmap.add(width, lo+i, text)
+			}
+		}
+	case pdfString:
+		base := utf16.Decode(bytesToUTF16(t))
+		if len(base) == 0 {
+			return
+		}
+		for code := lo; code <= hi; code++ {
+			runes := append([]rune(nil), base...)
+			runes[len(runes)-1] += rune(code - lo)
+			cmap.add(width, code, string(runes))
+		}
+	}
+}
+
+func cmapTarget(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case pdfString:
+		return string(utf16.Decode(bytesToUTF16(t))), true
+	case pdfName:
+		return glyphText(string(t))
+	}
+	return "", false
+}
+
+func bytesToCode(b []byte) int {
+	code := 0
+	for _, c := range b {
+		code = code<<8 | int(c)
+	}
+	return code
+}
+
+func bytesToUTF16(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+// pdfTextState walks a content stream and writes the text it shows, breaking
+// lines whenever the text baseline moves.
+type pdfTextState struct {
+	doc     *pdfDocument
+	out     *strings.Builder
+	font    *pdfFont
+	y       float64
+	lastY   float64
+	started bool
+	space   bool
+}
+
+func (st *pdfTextState) run(content []byte, resources pdfDict, depth int) {
+	fonts := st.doc.dict(resources["Font"])
+	xobjects := st.doc.dict(resources["XObject"])
+
+	l := &pdfLexer{data: content}
+	var operands []interface{}
+	for {
+		obj, err := l.readObject(false)
+		if err != nil {
+			return
+		}
+		kw, ok := obj.(pdfKeyword)
+		if !ok {
+			if _, isDelim := obj.(pdfDelim); !isDelim {
+				operands = append(operands, obj)
+			}
+			continue
+		}
+
+		switch kw {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok && fonts != nil {
+					st.font = st.doc.font(fonts[string(name)])
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				st.y += ty
+				if tx != 0 {
+					st.space = true
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				st.y, _ = operands[len(operands)-1].(float64)
+				st.space = true
+			}
+		case "T*":
+			st.newline()
+		case "Tj":
+			if len(operands) > 0 {
+				st.show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			st.newline()
+			if len(operands) > 0 {
+				st.show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			items, _ := operands[len(operands)-1].(pdfArray)
+			for _, item := range items {
+				if n, ok := item.(float64); ok {
+					// Offsets are in thousandths of an em; a large gap is a space.
+					if n < -200 {
+						st.space = true
+					}
+					continue
+				}
+				st.show(item)
+			}
+		case "Do":
+			if len(operands) > 0 && xobjects != nil && depth < maxPDFFormDepth {
+				name, _ := operands[len(operands)-1].(pdfName)
+				st.runForm(xobjects[string(name)], resources, depth)
+			}
+		case "BI":
+			skipInlineImage(l)
+		}
+		operands = operands[:0]
+	}
+}
+
+func (st *pdfTextState) runForm(v interface{}, resources pdfDict, depth int) {
+	stream, ok := st.doc.resolve(v).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := st.doc.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	if res := st.doc.dict(stream.dict["Resources"]); res != nil {
+		resources = res
+	}
+
+	font := st.font
+	st.run(data, resources, depth+1)
+	st.font = font
+}
+
+// skipInlineImage moves past BI ... ID <binary data> EI.
+func skipInlineImage(l *pdfLexer) {
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return
+		}
+		if tok == pdfKeyword("ID") {
+			break
+		}
+	}
+	for i := l.pos + 1; i+2 <= len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+func (st *pdfTextState) show(v interface{}) {
+	s, ok := v.(pdfString)
+	if !ok {
+		return
+	}
+	font := st.font
+	if font == nil {
+		font = &pdfFont{}
+	}
+	text := font.decode(s)
+	if text == "" {
+		return
+	}
+
+	if st.started && math.Abs(st.y-st.lastY) > 1 {
+		st.newline()
+	} else if st.space && !st.atBreak() && !strings.HasPrefix(text, " ") {
+		st.out.WriteByte(' ')
+	}
+	st.out.WriteString(text)
+	st.lastY = st.y
+	st.started = true
+	st.space = false
+}
+
+func (st *pdfTextState) newline() {
+	if s := st.out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		st.out.WriteByte('\n')
+	}
+	st.lastY = st.y
+	st.space = false
+}
+
+func (st *pdfTextState) atBreak() bool {
+	s := st.out.String()
+	return s == "" || strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n")
+}
+
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž',
+	0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "quoteright": "’", "quoteleft": "‘",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+", "comma": ",",
+	"hyphen": "-", "period": ".", "slash": "/", "colon": ":", "semicolon": ";",
+	"less": "<", "equal": "=", "greater": ">", "question": "?", "at": "@",
+	"bracketleft": "[", "backslash": "\\", "bracketright": "]", "underscore": "_",
+	"braceleft": "{", "bar": "|", "braceright": "}", "bullet": "•", "endash": "–",
+	"emdash": "—", "quotedblleft": "“", "quotedblright": "”", "ellipsis": "…",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+func glyphText(name string) (string, bool) {
+	if len(name) == 1 {
+		return name, true
+	}
+	if text, ok := glyphNames[name]; ok {
+		return text, true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if code, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return string(rune(code)), true
+		}
+	}
+	return "", false
+}
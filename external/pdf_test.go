@@ -0,0 +1,88 @@
+package external
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a one-page PDF whose content stream declares length as its
+// /Length, followed by a cross-reference table unless truncate cuts it off.
+func buildPDF(length string, truncate int) []byte {
+	content := "BT /F1 12 Tf 72 720 Td (Hello PDF) Tj ET"
+	if length == "" {
+		length = fmt.Sprint(len(content))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Length " + length + " >>\nstream\n" + content + "\nendstream",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = sb.Len()
+		fmt.Fprintf(&sb, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := sb.Len()
+	fmt.Fprintf(&sb, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&sb, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&sb, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	data := []byte(sb.String())
+	if truncate > 0 {
+		data = data[:len(data)-truncate]
+	}
+	return data
+}
+
+func TestExtractPDFPages(t *testing.T) {
+	valid := buildPDF("", 0)
+	xrefStart := strings.Index(string(valid), "xref\n")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", data: valid, want: "Hello PDF"},
+		{name: "huge length", data: buildPDF("1e30", 0), want: "Hello PDF"},
+		{name: "negative length", data: buildPDF("-5", 0), want: "Hello PDF"},
+		{name: "NaN length", data: buildPDF("NaN", 0), want: "Hello PDF"},
+		{name: "infinite length", data: buildPDF("+Inf", 0), want: "Hello PDF"},
+		{name: "length past end", data: buildPDF("100000", 0), want: "Hello PDF"},
+		{name: "wrong length", data: buildPDF("3", 0), want: "Hello PDF"},
+		{name: "truncated xref", data: buildPDF("", len(valid)-xrefStart-10), want: "Hello PDF"},
+		{name: "no trailer", data: valid[:xrefStart], want: "Hello PDF"},
+		{name: "truncated stream", data: valid[:strings.Index(string(valid), "Hello")+2]},
+		{name: "empty", data: []byte{}, wantErr: true},
+		{name: "not a PDF", data: []byte("<html></html>"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := extractPDFPages(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractPDFPages() = %q, want error", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractPDFPages() error = %v", err)
+			}
+			if got := strings.Join(pages, "\n"); got != tt.want {
+				t.Errorf("extractPDFPages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
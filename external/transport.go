@@ -0,0 +1,136 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const maxRetryDelay = 10 * time.Second
+
+type callOptions struct {
+	breaker *CircuitBreaker
+	timeout time.Duration
+	// retryable marks calls that are safe to repeat (DELETE, extraction keyed
+	// by document id).
+	retryable bool
+}
+
+type apiResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// execute runs a request built by newRequest with the per-operation timeout,
+// retrying transient failures when allowed and feeding the circuit breaker.
+// Non-2xx responses are returned as-is so callers keep their error messages.
+func (c *Client) execute(ctx context.Context, opts callOptions, newRequest func(ctx context.Context) (*http.Request, error)) (*apiResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attempts := 1
+	if opts.retryable {
+		attempts += c.maxRetries
+	}
+
+	var resp *apiResponse
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleepWithJitter(ctx, c.retryBaseDelay, attempt); waitErr != nil {
+				break
+			}
+		}
+
+		if allowErr := opts.breaker.Allow(); allowErr != nil {
+			return nil, allowErr
+		}
+
+		resp, err = c.attempt(ctx, opts.timeout, newRequest)
+
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the service.
+			opts.breaker.Release()
+			return nil, fmt.Errorf(isFailedToSend, ctx.Err())
+		}
+
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			opts.breaker.Success()
+			return resp, nil
+		}
+
+		if err != nil {
+			opts.breaker.Failure(err)
+		} else {
+			opts.breaker.Failure(fmt.Errorf("status %d", resp.StatusCode))
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(isFailedToSend, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) attempt(ctx context.Context, timeout time.Duration, newRequest func(ctx context.Context) (*http.Request, error)) (*apiResponse, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return &apiResponse{StatusCode: resp.StatusCode, Body: body}, nil
+}
+
+func isRetryableStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// sleepWithJitter waits between half and all of base*2^(attempt-1).
+func sleepWithJitter(ctx context.Context, base time.Duration, attempt int) error {
+	if base <= 0 {
+		return nil
+	}
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	delay = delay/2 + rand.N(delay/2+1)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsUnavailable reports whether err means the external service could not be
+// reached in time or its circuit is open.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -2,8 +2,9 @@ package guide
 
 import (
 	"context"
-	"dokuprime-be/config"
+	"dokuprime-be/storage"
 	"dokuprime-be/util"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -206,16 +207,8 @@ func (h *GuideHandler) ViewFile(c *gin.Context) {
 		return
 	}
 
-	
-	
-	domain := os.Getenv("COOKIE_DOMAIN")
-	path := os.Getenv("COOKIE_PATH")
-	if path == "" {
-		path = "/api/guides/view-file"
-	}
-	c.SetCookie(viewTokenCookieName, "", -1, path, domain, false, true)
-
-	
+	// The token stays valid until it expires: the viewer fetches the file
+	// again for every byte range it needs.
 	key := "view_guide_token:" + token
 	ctxRedis := context.Background()
 
@@ -229,17 +222,18 @@ func (h *GuideHandler) ViewFile(c *gin.Context) {
 		return
 	}
 
-	h.redis.Del(ctxRedis, key)
-	filePath := config.GetDocumentPath(filename)
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	content, file, err := h.service.OpenFile(filename)
+	if errors.Is(err, storage.ErrNotFound) {
 		util.ErrorResponse(c, http.StatusNotFound, "File not found on server")
 		return
 	}
+	if err != nil {
+		util.ErrorResponse(c, http.StatusInternalServerError, "Failed to open file")
+		return
+	}
+	defer content.Close()
 
-	c.Header("Content-Type", "application/pdf")
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", filename))
-	c.File(filePath)
+	util.ServeFile(c, content, file)
 }
 
 
@@ -94,6 +94,16 @@ func (r *GuideRepository) GetByID(id int) (*Guide, error) {
 	return &guide, nil
 }
 
+func (r *GuideRepository) GetByFilename(filename string) (*Guide, error) {
+	var guide Guide
+	query := `SELECT id, title, description, filename, original_filename, created_at, updated_at FROM guides WHERE filename = $1`
+	err := r.db.Get(&guide, query, filename)
+	if err != nil {
+		return nil, err
+	}
+	return &guide, nil
+}
+
 func (r *GuideRepository) Update(guide *Guide) error {
 	query := `
 		UPDATE guides 
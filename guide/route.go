@@ -2,15 +2,16 @@ package guide
 
 import (
 	"dokuprime-be/middleware"
+	"dokuprime-be/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
 )
 
-func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client, fileStorage storage.Backend) {
 	repo := NewGuideRepository(db)
-	service := NewGuideService(repo, redisClient)
+	service := NewGuideService(repo, redisClient, fileStorage)
 	handler := NewGuideHandler(service, redisClient)
 
 	r.GET("/api/guides/view-file", handler.ViewFile)
@@ -18,12 +19,12 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
 	guideGroup := r.Group("/api/guides")
 	guideGroup.Use(middleware.AuthMiddleware())
 	{
-		guideGroup.POST("", handler.UploadGuide)
-		guideGroup.GET("", handler.GetAll)
-		guideGroup.GET("/:id", handler.GetByID)
-		guideGroup.PUT("/:id", handler.UpdateGuide)
-		guideGroup.DELETE("/:id", handler.DeleteGuide)
+		guideGroup.POST("", middleware.RequirePermission("guide:create"), handler.UploadGuide)
+		guideGroup.GET("", middleware.RequirePermission("guide:read"), handler.GetAll)
+		guideGroup.GET("/:id", middleware.RequirePermission("guide:read"), handler.GetByID)
+		guideGroup.PUT("/:id", middleware.RequirePermission("guide:update"), handler.UpdateGuide)
+		guideGroup.DELETE("/:id", middleware.RequirePermission("guide:delete"), handler.DeleteGuide)
 
-		guideGroup.POST("/generate-view-url", handler.GenerateViewURL)
+		guideGroup.POST("/generate-view-url", middleware.RequirePermission("guide:read"), handler.GenerateViewURL)
 	}
 }
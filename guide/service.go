@@ -2,11 +2,12 @@ package guide
 
 import (
 	"context"
-	"dokuprime-be/config"
+	"dokuprime-be/storage"
 	"dokuprime-be/util"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -15,17 +16,58 @@ import (
 )
 
 type GuideService struct {
-	repo  *GuideRepository
-	redis *redis.Client
+	repo    *GuideRepository
+	redis   *redis.Client
+	storage storage.Backend
 }
 
-func NewGuideService(repo *GuideRepository, redisClient *redis.Client) *GuideService {
+func NewGuideService(repo *GuideRepository, redisClient *redis.Client, fileStorage storage.Backend) *GuideService {
 	return &GuideService{
-		repo:  repo,
-		redis: redisClient,
+		repo:    repo,
+		redis:   redisClient,
+		storage: fileStorage,
 	}
 }
 
+func (s *GuideService) saveFile(file *multipart.FileHeader, key string) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	if err := s.storage.Put(context.Background(), key, src, file.Size, storage.ContentTypeFor(key)); err != nil {
+		return fmt.Errorf("failed to save file content: %w", err)
+	}
+	return nil
+}
+
+func (s *GuideService) removeFile(key string) {
+	if err := s.storage.Delete(context.Background(), key); err != nil {
+		log.Printf("Warning: Failed to remove guide file %s: %v", key, err)
+	}
+}
+
+// OpenFile opens a guide file for serving under its original filename.
+func (s *GuideService) OpenFile(key string) (io.ReadSeekCloser, util.ServedFile, error) {
+	content, info, err := storage.OpenSeeker(context.Background(), s.storage, key)
+	if err != nil {
+		return nil, util.ServedFile{}, err
+	}
+
+	file := util.ServedFile{
+		Name:         key,
+		Disposition:  "inline",
+		ContentType:  storage.ContentTypeFor(key),
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+	if guide, err := s.repo.GetByFilename(key); err == nil && guide.OriginalFilename != "" {
+		file.Name = guide.OriginalFilename
+	}
+	return content, file, nil
+}
+
 func generateUniqueFilename(originalFilename string) string {
 	ext := filepath.Ext(originalFilename)
 	timestamp := time.Now().Unix()
@@ -36,24 +78,8 @@ func generateUniqueFilename(originalFilename string) string {
 func (s *GuideService) UploadGuide(title, description string, file *multipart.FileHeader) (*Guide, error) {
 
 	uniqueFilename := generateUniqueFilename(file.Filename)
-	uploadDir := config.GetUploadPath()
-	filePath := filepath.Join(uploadDir, uniqueFilename)
-
-	src, err := file.Open()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
-	}
-	defer src.Close()
-
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file on disk: %w", err)
-	}
-	defer dst.Close()
-
-	if _, err := dst.ReadFrom(src); err != nil {
-		os.Remove(filePath)
-		return nil, fmt.Errorf("failed to save file content: %w", err)
+	if err := s.saveFile(file, uniqueFilename); err != nil {
+		return nil, err
 	}
 
 	guide := &Guide{
@@ -64,7 +90,7 @@ func (s *GuideService) UploadGuide(title, description string, file *multipart.Fi
 	}
 
 	if err := s.repo.Create(guide); err != nil {
-		os.Remove(filePath)
+		s.removeFile(uniqueFilename)
 		return nil, err
 	}
 
@@ -90,28 +116,10 @@ func (s *GuideService) UpdateGuide(id int, title, description string, file *mult
 	existingGuide.Description = description
 
 	if file != nil {
-		uploadDir := config.GetUploadPath()
-
-		oldFilePath := filepath.Join(uploadDir, existingGuide.Filename)
-		os.Remove(oldFilePath)
+		s.removeFile(existingGuide.Filename)
 
 		uniqueFilename := generateUniqueFilename(file.Filename)
-		newFilePath := filepath.Join(uploadDir, uniqueFilename)
-
-		src, err := file.Open()
-		if err != nil {
-			return nil, err
-		}
-		defer src.Close()
-
-		dst, err := os.Create(newFilePath)
-		if err != nil {
-			return nil, err
-		}
-		defer dst.Close()
-
-		if _, err := dst.ReadFrom(src); err != nil {
-			os.Remove(newFilePath)
+		if err := s.saveFile(file, uniqueFilename); err != nil {
 			return nil, err
 		}
 
@@ -133,9 +141,7 @@ func (s *GuideService) DeleteGuide(id int) error {
 		return err
 	}
 
-	uploadDir := config.GetUploadPath()
-	filePath := filepath.Join(uploadDir, guide.Filename)
-	os.Remove(filePath)
+	s.removeFile(guide.Filename)
 
 	return s.repo.Delete(id)
 }
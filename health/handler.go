@@ -0,0 +1,38 @@
+package health
+
+import (
+	"dokuprime-be/external"
+	"dokuprime-be/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct{}
+
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{}
+}
+
+// External reports the circuit breaker state of every external service so the
+// UI can show "assistant unavailable" instead of failing requests.
+func (h *HealthHandler) External(ctx *gin.Context) {
+	services := external.BreakerStatuses()
+
+	status := "ok"
+	assistantAvailable := true
+	for _, s := range services {
+		if s.State == external.BreakerClosed {
+			continue
+		}
+		status = "degraded"
+		if s.Name == external.ServiceRAG && s.State == external.BreakerOpen {
+			assistantAvailable = false
+		}
+	}
+
+	util.SuccessResponse(ctx, "External service status retrieved successfully", gin.H{
+		"status":              status,
+		"assistant_available": assistantAvailable,
+		"services":            services,
+	})
+}
@@ -0,0 +1,11 @@
+package health
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine) {
+	handler := NewHealthHandler()
+
+	r.GET("/api/health/external", handler.External)
+}
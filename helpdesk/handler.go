@@ -1,6 +1,7 @@
 package helpdesk
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/messaging"
 	"dokuprime-be/util"
 	"net/http"
@@ -48,7 +49,7 @@ func (h *HelpdeskHandler) UpdateSwitchStatus(ctx *gin.Context) {
 		return
 	}
 
-	updatedStatus, err := h.service.UpdateSwitchStatus(req.Status)
+	updatedStatus, err := h.service.UpdateSwitchStatus(req.Status, audit.ActorFromContext(ctx))
 	if err != nil {
 		util.ErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
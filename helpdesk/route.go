@@ -1,6 +1,7 @@
 package helpdesk
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/config"
 	"dokuprime-be/external"
 	"dokuprime-be/messaging"
@@ -13,7 +14,7 @@ import (
 
 func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
 	repo := NewHelpdeskRepository(db)
-	service := NewHelpdeskService(repo)
+	service := NewHelpdeskService(repo, audit.NewAuditService(audit.NewAuditRepository(db)))
 
 	externalAPIConfig := config.LoadExternalAPIConfig()
 	externalClient := external.NewClient(externalAPIConfig)
@@ -35,15 +36,15 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
 	helpdeskRoutes := r.Group("/api/helpdesk")
 	helpdeskRoutes.Use(middleware.AuthMiddleware())
 	{
-		helpdeskRoutes.POST("", handler.CreateHelpdesk)
-		helpdeskRoutes.GET("", handler.GetAll)
-		helpdeskRoutes.GET("/:id", handler.GetHelpdeskByID)
-		helpdeskRoutes.PUT("/:id", handler.UpdateHelpdesk)
-		helpdeskRoutes.DELETE("/:id", handler.DeleteHelpdesk)
-		helpdeskRoutes.POST("/ask", handler.AskHelpdesk)
-
-		helpdeskRoutes.POST("/solved/:id", handler.SolvedConversation)
-		helpdeskRoutes.GET("/switch", handler.GetSwitchStatus)
-		helpdeskRoutes.POST("/switch", handler.UpdateSwitchStatus)
+		helpdeskRoutes.POST("", middleware.RequirePermission("helpdesk:read"), handler.CreateHelpdesk)
+		helpdeskRoutes.GET("", middleware.RequirePermission("helpdesk:read"), handler.GetAll)
+		helpdeskRoutes.GET("/:id", middleware.RequirePermission("helpdesk:read"), handler.GetHelpdeskByID)
+		helpdeskRoutes.PUT("/:id", middleware.RequirePermission("helpdesk:read"), handler.UpdateHelpdesk)
+		helpdeskRoutes.DELETE("/:id", middleware.RequirePermission("helpdesk:delete"), handler.DeleteHelpdesk)
+		helpdeskRoutes.POST("/ask", middleware.RequirePermission("helpdesk:read"), handler.AskHelpdesk)
+
+		helpdeskRoutes.POST("/solved/:id", middleware.RequirePermission("helpdesk:read"), handler.SolvedConversation)
+		helpdeskRoutes.GET("/switch", middleware.RequirePermission("helpdesk:read"), handler.GetSwitchStatus)
+		helpdeskRoutes.POST("/switch", middleware.RequirePermission("helpdesk:update"), handler.UpdateSwitchStatus)
 	}
 }
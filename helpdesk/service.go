@@ -1,25 +1,38 @@
 package helpdesk
 
 import (
+	"dokuprime-be/audit"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type HelpdeskService struct {
-	repo *HelpdeskRepository
+	repo  *HelpdeskRepository
+	audit *audit.AuditService
 }
 
-func NewHelpdeskService(repo *HelpdeskRepository) *HelpdeskService {
-	return &HelpdeskService{repo: repo}
+func NewHelpdeskService(repo *HelpdeskRepository, auditService *audit.AuditService) *HelpdeskService {
+	return &HelpdeskService{repo: repo, audit: auditService}
 }
 
 func (s *HelpdeskService) GetSwitchStatus() (*SwitchHelpdesk, error) {
 	return s.repo.GetSwitchStatus()
 }
 
-func (s *HelpdeskService) UpdateSwitchStatus(status bool) (*SwitchHelpdesk, error) {
-	return s.repo.UpdateSwitchStatus(status)
+func (s *HelpdeskService) UpdateSwitchStatus(status bool, actor audit.Actor) (*SwitchHelpdesk, error) {
+	before, err := s.repo.GetSwitchStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateSwitchStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, audit.ActionHelpdeskSwitch, audit.EntitySwitchHelpdesk, updated.ID, before, updated)
+	return updated, nil
 }
 
 func (s *HelpdeskService) Create(helpdesk *Helpdesk) error {
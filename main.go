@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"dokuprime-be/audit"
 	"dokuprime-be/azure"
 	"dokuprime-be/chat"
 	"dokuprime-be/config"
@@ -9,13 +10,19 @@ import (
 	"dokuprime-be/document"
 	"dokuprime-be/grafana"
 	"dokuprime-be/guide"
+	"dokuprime-be/health"
 	"dokuprime-be/helpdesk"
+	"dokuprime-be/metadata"
+	"dokuprime-be/middleware"
 	"dokuprime-be/migrate"
 	"dokuprime-be/permission"
 	"dokuprime-be/role"
 	"dokuprime-be/seeder"
+	"dokuprime-be/storage"
 	"dokuprime-be/team"
+	"dokuprime-be/urlformat"
 	"dokuprime-be/user"
+	"dokuprime-be/workflow"
 	"log"
 	"net/http"
 	"os"
@@ -25,6 +32,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 )
 
@@ -38,7 +46,7 @@ func main() {
 	defer db.Close()
 
 	if len(args) > 1 && args[1] == "--migrate" {
-		migrate.RunMigrations(db)
+		migrate.RunCommand(db, args[2:])
 		return
 	}
 
@@ -47,35 +55,75 @@ func main() {
 		return
 	}
 
+	if len(args) > 1 && args[1] == "--migrate-storage" {
+		runStorageMigration(db, len(args) > 2 && args[2] == "--delete-source")
+		return
+	}
+
+	fileStorage := storage.InitBackend()
+
 	redisClient := config.InitRedis()
 	defer redisClient.Close()
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.AccessLogger(), gin.Recovery())
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{os.Getenv("ALLOWED_ORIGINS")},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"},
+		ExposeHeaders:    []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	middleware.InitPermissionStore(db, redisClient)
+
 	user.RegisterRoutes(r, db, redisClient)
 	role.RegisterRoutes(r, db)
 	team.RegisterRoutes(r, db)
 	permission.RegisterRoutes(r, db)
 	grafana.RegisterRoutes(r, redisClient)
-	guide.RegisterRoutes(r, db, redisClient)
-	chat.RegisterRoutes(r, db)
+	guide.RegisterRoutes(r, db, redisClient, fileStorage)
+	chat.RegisterRoutes(r, db, redisClient)
+	audit.RegisterRoutes(r, db)
 	helpdesk.RegisterRoutes(r, db)
-	asyncProcessor := document.RegisterRoutesWithProcessor(r, db, redisClient)
+	workflow.RegisterRoutes(r, db)
+	metadata.RegisterRoutes(r, db)
+	urlformat.RegisterRoutes(r, db)
+	asyncProcessor := document.RegisterRoutesWithProcessor(r, db, redisClient, fileStorage)
 	azure.RegisterRoutes(r, db, redisClient)
+	health.RegisterRoutes(r)
 
 	scheduler := cron.NewScheduler()
 	helpdeskScheduler := cron.NewHelpdeskScheduler(db)
 	if err := helpdeskScheduler.RegisterJobs(scheduler); err != nil {
 		log.Fatalf("Failed to register helpdesk scheduler jobs: %v", err)
 	}
+	documentExpiryScheduler := cron.NewDocumentExpiryScheduler(db, redisClient, fileStorage, asyncProcessor)
+	if err := documentExpiryScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document expiry scheduler jobs: %v", err)
+	}
+	documentTextScheduler := cron.NewDocumentTextScheduler(db, fileStorage)
+	if err := documentTextScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document text scheduler jobs: %v", err)
+	}
+	documentUploadScheduler := cron.NewDocumentUploadScheduler(db, fileStorage)
+	if err := documentUploadScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document upload scheduler jobs: %v", err)
+	}
+	documentCrawlScheduler := cron.NewDocumentCrawlScheduler(db, redisClient, fileStorage, asyncProcessor)
+	if err := documentCrawlScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document crawl scheduler jobs: %v", err)
+	}
+	documentTrashScheduler := cron.NewDocumentTrashScheduler(db, redisClient, fileStorage, asyncProcessor)
+	if err := documentTrashScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document trash scheduler jobs: %v", err)
+	}
+	documentHashScheduler := cron.NewDocumentHashScheduler(db, fileStorage)
+	if err := documentHashScheduler.RegisterJobs(scheduler); err != nil {
+		log.Fatalf("Failed to register document hash scheduler jobs: %v", err)
+	}
 	scheduler.Start()
 	defer scheduler.Stop()
 
@@ -117,3 +165,23 @@ func main() {
 
 	log.Println("Server exited successfully")
 }
+
+// runStorageMigration copies files from the local upload directory into the
+// backend selected by STORAGE_BACKEND.
+func runStorageMigration(db *sqlx.DB, deleteSource bool) {
+	if os.Getenv("STORAGE_BACKEND") == "" || os.Getenv("STORAGE_BACKEND") == "local" {
+		log.Println("STORAGE_BACKEND is local, nothing to migrate.")
+		return
+	}
+
+	source := storage.NewLocalBackend(config.GetUploadPath())
+	target := storage.InitBackend()
+
+	result, err := storage.MigrateFiles(db, source, target, deleteSource)
+	if err != nil {
+		log.Fatalf("Storage migration failed: %v", err)
+	}
+
+	log.Printf("Storage migration completed: %d copied, %d already present, %d missing, %d failed",
+		result.Copied, result.Skipped, result.Missing, result.Failed)
+}
@@ -1,6 +1,7 @@
 package messaging
 
 import (
+	"context"
 	"dokuprime-be/config"
 	"dokuprime-be/external"
 	"encoding/json"
@@ -202,7 +203,7 @@ func (s *MessageService) handleExternalHelpdesk(sessionID uuid.UUID, message, us
 			IsHelpdesk:       true,
 		}
 
-		if err := s.externalClient.SendMessageToAPI(response); err != nil {
+		if err := s.externalClient.SendMessageToAPI(context.Background(), response); err != nil {
 			log.Printf("❌ Failed to send message to external API: %v", err)
 			return fmt.Errorf("failed to send message to external API: %w", err)
 		}
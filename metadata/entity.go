@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	FieldTypeString      = "string"
+	FieldTypeDate        = "date"
+	FieldTypeEnum        = "enum"
+	FieldTypeMultiSelect = "multi_select"
+)
+
+// Field is an admin-defined metadata attribute documents can carry, such as
+// a regulation number, region or sector.
+type Field struct {
+	ID         int            `db:"id" json:"id"`
+	Key        string         `db:"key" json:"key"`
+	Label      string         `db:"label" json:"label"`
+	FieldType  string         `db:"field_type" json:"field_type"`
+	Options    pq.StringArray `db:"options" json:"options"`
+	IsRequired bool           `db:"is_required" json:"is_required"`
+	IsActive   bool           `db:"is_active" json:"is_active"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+type FieldInput struct {
+	Key        string   `json:"key" binding:"required"`
+	Label      string   `json:"label" binding:"required"`
+	FieldType  string   `json:"field_type" binding:"required"`
+	Options    []string `json:"options"`
+	IsRequired bool     `json:"is_required"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// Values holds a document's metadata keyed by field key. Multi-select values
+// are string arrays, everything else is a string; dates use YYYY-MM-DD.
+type Values map[string]interface{}
+
+// Value encodes the values as JSON text; lib/pq would send raw bytes as bytea.
+func (v Values) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (v *Values) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*v = Values{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("cannot scan %T into metadata values", src)
+	}
+
+	values := Values{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+type FacetValue struct {
+	Value string `db:"value" json:"value"`
+	Count int    `db:"count" json:"count"`
+}
+
+type Facets struct {
+	Tags     []FacetValue            `json:"tags"`
+	Metadata map[string][]FacetValue `json:"metadata"`
+}
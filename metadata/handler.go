@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetadataHandler struct {
+	service *MetadataService
+}
+
+func NewMetadataHandler(service *MetadataService) *MetadataHandler {
+	return &MetadataHandler{service: service}
+}
+
+func (h *MetadataHandler) GetAll(c *gin.Context) {
+	fields, err := h.service.GetAll(c.Query("active") == "true")
+	if err != nil {
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Metadata fields fetched successfully", fields)
+}
+
+func (h *MetadataHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid metadata field ID")
+		return
+	}
+
+	field, err := h.service.GetByID(id)
+	if err != nil {
+		util.ErrorResponse(c, http.StatusNotFound, "Metadata field not found")
+		return
+	}
+
+	util.SuccessResponse(c, "Metadata field fetched successfully", field)
+}
+
+func (h *MetadataHandler) Create(c *gin.Context) {
+	var input FieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	field, err := h.service.Create(input, audit.ActorFromContext(c))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.CreatedResponse(c, "Metadata field created successfully", field)
+}
+
+func (h *MetadataHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid metadata field ID")
+		return
+	}
+
+	var input FieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	field, err := h.service.Update(id, input, audit.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(c, http.StatusNotFound, "Metadata field not found")
+			return
+		}
+		util.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Metadata field updated successfully", field)
+}
+
+func (h *MetadataHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid metadata field ID")
+		return
+	}
+
+	if err := h.service.Delete(id, audit.ActorFromContext(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(c, http.StatusNotFound, "Metadata field not found")
+			return
+		}
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Metadata field deleted successfully", nil)
+}
@@ -0,0 +1,86 @@
+package metadata
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type MetadataRepository struct {
+	db *sqlx.DB
+}
+
+func NewMetadataRepository(db *sqlx.DB) *MetadataRepository {
+	return &MetadataRepository{db: db}
+}
+
+func (r *MetadataRepository) GetAll(activeOnly bool) ([]Field, error) {
+	fields := []Field{}
+	query := `SELECT * FROM metadata_fields`
+	if activeOnly {
+		query += ` WHERE is_active = true`
+	}
+	query += ` ORDER BY id`
+	if err := r.db.Select(&fields, query); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (r *MetadataRepository) GetByID(id int) (*Field, error) {
+	var field Field
+	if err := r.db.Get(&field, `SELECT * FROM metadata_fields WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (r *MetadataRepository) KeyTaken(key string, excludeID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM metadata_fields WHERE key = $1 AND id <> $2)`, key, excludeID)
+	return exists, err
+}
+
+func (r *MetadataRepository) Create(field *Field) error {
+	query := `
+		INSERT INTO metadata_fields (key, label, field_type, options, is_required, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(query, field.Key, field.Label, field.FieldType, pq.Array(field.Options), field.IsRequired, field.IsActive).
+		Scan(&field.ID, &field.CreatedAt, &field.UpdatedAt)
+}
+
+func (r *MetadataRepository) Update(field *Field) error {
+	query := `
+		UPDATE metadata_fields
+		SET label = $1, field_type = $2, options = $3, is_required = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING key, created_at, updated_at
+	`
+	return r.db.QueryRow(query, field.Label, field.FieldType, pq.Array(field.Options), field.IsRequired, field.IsActive, field.ID).
+		Scan(&field.Key, &field.CreatedAt, &field.UpdatedAt)
+}
+
+// Delete removes a field and strips its values from every document.
+func (r *MetadataRepository) Delete(field *Field) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM metadata_fields WHERE id = $1`, field.ID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`UPDATE documents SET metadata = metadata - $1::text WHERE jsonb_exists(metadata, $1)`, field.Key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,30 @@
+package metadata
+
+import (
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	permMetadataRead   = "document-management:read"
+	permMetadataManage = "document-management:master"
+)
+
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
+	repo := NewMetadataRepository(db)
+	service := NewMetadataService(repo, audit.NewAuditService(audit.NewAuditRepository(db)))
+	handler := NewMetadataHandler(service)
+
+	metadataGroup := r.Group("/api/metadata-fields")
+	metadataGroup.Use(middleware.AuthMiddleware())
+	{
+		metadataGroup.GET("", middleware.RequirePermission(permMetadataRead), handler.GetAll)
+		metadataGroup.GET("/:id", middleware.RequirePermission(permMetadataRead), handler.GetByID)
+		metadataGroup.POST("", middleware.RequirePermission(permMetadataManage), handler.Create)
+		metadataGroup.PUT("/:id", middleware.RequirePermission(permMetadataManage), handler.Update)
+		metadataGroup.DELETE("/:id", middleware.RequirePermission(permMetadataManage), handler.Delete)
+	}
+}
@@ -0,0 +1,311 @@
+package metadata
+
+import (
+	"dokuprime-be/audit"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxTags      = 30
+	maxTagLength = 50
+)
+
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,99}$`)
+
+type MetadataService struct {
+	repo  *MetadataRepository
+	audit *audit.AuditService
+}
+
+func NewMetadataService(repo *MetadataRepository, auditService *audit.AuditService) *MetadataService {
+	return &MetadataService{repo: repo, audit: auditService}
+}
+
+func (s *MetadataService) GetAll(activeOnly bool) ([]Field, error) {
+	return s.repo.GetAll(activeOnly)
+}
+
+func (s *MetadataService) GetByID(id int) (*Field, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *MetadataService) Create(input FieldInput, actor audit.Actor) (*Field, error) {
+	field, err := s.build(input)
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := s.repo.KeyTaken(field.Key, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check field key: %w", err)
+	}
+	if taken {
+		return nil, fmt.Errorf("a metadata field with key %q already exists", field.Key)
+	}
+
+	if err := s.repo.Create(field); err != nil {
+		return nil, fmt.Errorf("failed to create metadata field: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionMetadataFieldCreate, audit.EntityMetadataField, field.ID, nil, field)
+	return field, nil
+}
+
+// Update changes a field's label, options and flags. The key and type stay
+// fixed because stored values depend on them.
+func (s *MetadataService) Update(id int, input FieldInput, actor audit.Actor) (*Field, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := s.build(input)
+	if err != nil {
+		return nil, err
+	}
+	if field.Key != existing.Key {
+		return nil, fmt.Errorf("the key of a metadata field cannot be changed")
+	}
+	if field.FieldType != existing.FieldType {
+		return nil, fmt.Errorf("the type of a metadata field cannot be changed")
+	}
+
+	field.ID = id
+	if err := s.repo.Update(field); err != nil {
+		return nil, fmt.Errorf("failed to update metadata field: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionMetadataFieldUpdate, audit.EntityMetadataField, id, existing, field)
+	return field, nil
+}
+
+func (s *MetadataService) Delete(id int, actor audit.Actor) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(existing); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionMetadataFieldDelete, audit.EntityMetadataField, id, existing, nil)
+	return nil
+}
+
+func (s *MetadataService) build(input FieldInput) (*Field, error) {
+	field := &Field{
+		Key:        strings.TrimSpace(input.Key),
+		Label:      strings.TrimSpace(input.Label),
+		FieldType:  strings.TrimSpace(input.FieldType),
+		Options:    []string{},
+		IsRequired: input.IsRequired,
+		IsActive:   input.IsActive == nil || *input.IsActive,
+	}
+
+	if !fieldKeyPattern.MatchString(field.Key) {
+		return nil, fmt.Errorf("key must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+	if field.Label == "" {
+		return nil, fmt.Errorf("label is required")
+	}
+
+	switch field.FieldType {
+	case FieldTypeString, FieldTypeDate:
+		if len(input.Options) > 0 {
+			return nil, fmt.Errorf("options are only allowed for enum and multi_select fields")
+		}
+	case FieldTypeEnum, FieldTypeMultiSelect:
+		seen := make(map[string]bool)
+		for _, option := range input.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[option] {
+				continue
+			}
+			seen[option] = true
+			field.Options = append(field.Options, option)
+		}
+		if len(field.Options) == 0 {
+			return nil, fmt.Errorf("%s fields need at least one option", field.FieldType)
+		}
+	default:
+		return nil, fmt.Errorf("field_type must be one of string, date, enum or multi_select")
+	}
+	return field, nil
+}
+
+// Normalize checks submitted values against the active field definitions and
+// returns them in their stored form. Empty values are dropped.
+func (s *MetadataService) Normalize(input map[string]interface{}) (Values, error) {
+	fields, err := s.repo.GetAll(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata fields: %w", err)
+	}
+
+	byKey := make(map[string]Field, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+
+	values := Values{}
+	for key, raw := range input {
+		field, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMetadata, key)
+		}
+		value, err := normalizeValue(field, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMetadata, key, err)
+		}
+		if value != nil {
+			values[key] = value
+		}
+	}
+
+	for _, field := range fields {
+		if _, ok := values[field.Key]; field.IsRequired && !ok {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidMetadata, field.Label)
+		}
+	}
+	return values, nil
+}
+
+func normalizeValue(field Field, raw interface{}) (interface{}, error) {
+	if field.FieldType == FieldTypeMultiSelect {
+		items, err := stringList(raw)
+		if err != nil {
+			return nil, err
+		}
+		selected := []string{}
+		seen := make(map[string]bool)
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			if item == "" || seen[item] {
+				continue
+			}
+			if !containsOption(field.Options, item) {
+				return nil, fmt.Errorf("%q is not one of the allowed options", item)
+			}
+			seen[item] = true
+			selected = append(selected, item)
+		}
+		if len(selected) == 0 {
+			return nil, nil
+		}
+		return selected, nil
+	}
+
+	var text string
+	switch value := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		text = strings.TrimSpace(value)
+	case float64, bool:
+		text = fmt.Sprint(value)
+	default:
+		return nil, fmt.Errorf("expected a single value")
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	switch field.FieldType {
+	case FieldTypeDate:
+		date, err := parseDate(text)
+		if err != nil {
+			return nil, err
+		}
+		return date.Format("2006-01-02"), nil
+	case FieldTypeEnum:
+		if !containsOption(field.Options, text) {
+			return nil, fmt.Errorf("%q is not one of the allowed options", text)
+		}
+	}
+	return text, nil
+}
+
+func stringList(raw interface{}) ([]string, error) {
+	switch value := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Split(value, ","), nil
+	case []string:
+		return value, nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings")
+			}
+			items = append(items, text)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("expected a list of strings")
+}
+
+func containsOption(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a valid date, use YYYY-MM-DD", value)
+}
+
+// NormalizeTags trims, lowercases and de-duplicates free tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidMetadata, tag, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidMetadata, maxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// FacetKeys returns the keys of the active fields that are offered as facets.
+// Free-text and date fields have too many distinct values to be useful.
+func (s *MetadataService) FacetKeys() ([]string, error) {
+	fields, err := s.repo.GetAll(true)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, field := range fields {
+		if field.FieldType == FieldTypeEnum || field.FieldType == FieldTypeMultiSelect {
+			keys = append(keys, field.Key)
+		}
+	}
+	return keys, nil
+}
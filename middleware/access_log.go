@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sharedDocumentPath = "/api/documents/shared/"
+	redacted           = "[redacted]"
+)
+
+// AccessLogger is gin's request log with share link tokens and token query
+// parameters, such as those of citation view URLs, redacted, since anyone
+// reading the log could otherwise open the documents.
+func AccessLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			redactAccessPath(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactAccessPath replaces the token of a share link path and the value of
+// any token query parameter.
+func redactAccessPath(path string) string {
+	path, query, hasQuery := strings.Cut(path, "?")
+	if rest, ok := strings.CutPrefix(path, sharedDocumentPath); ok && rest != "" {
+		end := strings.IndexByte(rest, '/')
+		if end < 0 {
+			end = len(rest)
+		}
+		path = sharedDocumentPath + redacted + rest[end:]
+	}
+	if !hasQuery {
+		return path
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		if key, _, ok := strings.Cut(param, "="); ok && key == "token" {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return path + "?" + strings.Join(params, "&")
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import "testing"
+
+func TestRedactAccessPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "share link", path: "/api/documents/shared/abc123", want: "/api/documents/shared/[redacted]"},
+		{name: "share link with query", path: "/api/documents/shared/abc123?download=1", want: "/api/documents/shared/[redacted]?download=1"},
+		{name: "share link with trailing path", path: "/api/documents/shared/abc123/", want: "/api/documents/shared/[redacted]/"},
+		{name: "share prefix only", path: "/api/documents/shared/", want: "/api/documents/shared/"},
+		{name: "share link list", path: "/api/documents/share-links", want: "/api/documents/share-links"},
+		{name: "other path", path: "/api/documents/12", want: "/api/documents/12"},
+		{name: "prefix elsewhere", path: "/x/api/documents/shared/abc", want: "/x/api/documents/shared/abc"},
+		{name: "view token", path: "/api/documents/view-file?token=abc123", want: "/api/documents/view-file?token=[redacted]"},
+		{name: "token among params", path: "/api/documents/view-file?download=1&token=abc&x=y", want: "/api/documents/view-file?download=1&token=[redacted]&x=y"},
+		{name: "other params kept", path: "/api/documents?page=2&tokens=a", want: "/api/documents?page=2&tokens=a"},
+		{name: "share link and token", path: "/api/documents/shared/abc?token=def", want: "/api/documents/shared/[redacted]?token=[redacted]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactAccessPath(tt.path); got != tt.want {
+				t.Errorf("redactAccessPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -47,3 +47,28 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware sets the user of a valid access token like
+// AuthMiddleware but lets anonymous requests through, for routes that also
+// serve visitors without an account.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie("access_token")
+		if err != nil {
+			parts := strings.Split(c.GetHeader("Authorization"), " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.Next()
+				return
+			}
+			token = parts[1]
+		}
+
+		if claims, err := auth.ValidateToken(token); err == nil {
+			c.Set("user_id", claims.UserID)
+			c.Set("name", claims.Name)
+			c.Set("email", claims.Email)
+			c.Set("account_type", claims.AccountType)
+		}
+		c.Next()
+	}
+}
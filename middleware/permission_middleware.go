@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"dokuprime-be/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userPermissionsKey = "user_permissions:%d"
+	permissionCacheTTL = 10 * time.Minute
+)
+
+type PermissionStore struct {
+	db    *sqlx.DB
+	redis *redis.Client
+}
+
+var permissionStore *PermissionStore
+
+func InitPermissionStore(db *sqlx.DB, redisClient *redis.Client) {
+	permissionStore = &PermissionStore{
+		db:    db,
+		redis: redisClient,
+	}
+}
+
+// RequirePermission lets the request through when the user holds at least one
+// of the given permissions. "<module>:master" grants every action on that module.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if permissionStore == nil {
+			util.ErrorResponse(c, http.StatusInternalServerError, "Permission store not configured")
+			c.Abort()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			util.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		granted, err := permissionStore.GetUserPermissions(userID.(int64))
+		if err != nil {
+			log.Printf("Failed to resolve permissions for user %d: %v", userID.(int64), err)
+			util.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve permissions")
+			c.Abort()
+			return
+		}
+
+		if !hasAnyPermission(granted, permissions) {
+			util.ErrorResponse(c, http.StatusForbidden, "You do not have permission to perform this action")
+			c.Abort()
+			return
+		}
+
+		c.Set("permissions", granted)
+		c.Next()
+	}
+}
+
+// UserHasPermission applies the RequirePermission rules outside of a route,
+// e.g. for checks that depend on the record being acted on.
+func UserHasPermission(userID int64, permissions ...string) (bool, error) {
+	if permissionStore == nil {
+		return false, fmt.Errorf("permission store not configured")
+	}
+
+	granted, err := permissionStore.GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	return hasAnyPermission(granted, permissions), nil
+}
+
+func hasAnyPermission(granted []string, required []string) bool {
+	grantedMap := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		grantedMap[p] = true
+	}
+
+	for _, p := range required {
+		if grantedMap[p] {
+			return true
+		}
+
+		module := strings.SplitN(p, ":", 2)[0]
+		if grantedMap[module+":master"] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PermissionStore) GetUserPermissions(userID int64) ([]string, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf(userPermissionsKey, userID)
+
+	cached, err := s.redis.Get(ctx, key).Result()
+	if err == nil {
+		var permissions []string
+		if err := json.Unmarshal([]byte(cached), &permissions); err == nil {
+			return permissions, nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("Warning: Failed to read permission cache for user %d: %v", userID, err)
+	}
+
+	permissions, err := s.loadUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(permissions)
+	if err == nil {
+		if err := s.redis.Set(ctx, key, data, permissionCacheTTL).Err(); err != nil {
+			log.Printf("Warning: Failed to cache permissions for user %d: %v", userID, err)
+		}
+	}
+
+	return permissions, nil
+}
+
+// loadUserPermissions only keeps permissions whose module is still one of the
+// team's pages, so a page removed from a team is revoked even for stale roles.
+func (s *PermissionStore) loadUserPermissions(userID int64) ([]string, error) {
+	permissions := make([]string, 0)
+	query := `
+		SELECT DISTINCT p.name
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		JOIN teams t ON t.id = r.team_id
+		JOIN permissions p ON p.id::text = ANY(r.permissions)
+		WHERE u.id = $1
+		AND split_part(p.name, ':', 1) = ANY(t.pages)
+		ORDER BY p.name
+	`
+	if err := s.db.Select(&permissions, query, userID); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (s *PermissionStore) invalidateUsers(userIDs []int64) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, fmt.Sprintf(userPermissionsKey, id))
+	}
+
+	if err := s.redis.Del(context.Background(), keys...).Err(); err != nil {
+		log.Printf("Warning: Failed to invalidate permission cache: %v", err)
+	}
+}
+
+func InvalidateUserPermissions(userIDs ...int64) {
+	if permissionStore == nil {
+		return
+	}
+	permissionStore.invalidateUsers(userIDs)
+}
+
+func InvalidateRolePermissions(roleIDs ...int) {
+	if permissionStore == nil || len(roleIDs) == 0 {
+		return
+	}
+
+	var userIDs []int64
+	err := permissionStore.db.Select(&userIDs, `SELECT id FROM users WHERE role_id = ANY($1)`, pq.Array(roleIDs))
+	if err != nil {
+		log.Printf("Warning: Failed to find users for role permission invalidation: %v", err)
+		return
+	}
+	permissionStore.invalidateUsers(userIDs)
+}
+
+func InvalidateTeamPermissions(teamID int) {
+	if permissionStore == nil {
+		return
+	}
+
+	var userIDs []int64
+	query := `SELECT u.id FROM users u JOIN roles r ON r.id = u.role_id WHERE r.team_id = $1`
+	if err := permissionStore.db.Select(&userIDs, query, teamID); err != nil {
+		log.Printf("Warning: Failed to find users for team permission invalidation: %v", err)
+		return
+	}
+	permissionStore.invalidateUsers(userIDs)
+}
@@ -1,315 +1,383 @@
 package migrate
 
 import (
+	"dokuprime-be/config"
+	"embed"
+	"fmt"
+	"io/fs"
 	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Key for pg_advisory_xact_lock so two instances never apply the same
+// migration concurrently.
+const migrationLockKey = 728119042
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int        `db:"version"`
+	Name      string     `db:"name"`
+	Applied   bool       `db:"-"`
+	AppliedAt *time.Time `db:"applied_at"`
+}
+
+// RunMigrations applies every pending migration and stops the process on failure.
 func RunMigrations(db *sqlx.DB) {
 	log.Println("Starting migrations...")
 
-	query := `
-    -- 1. Independent Tables
-    CREATE TABLE IF NOT EXISTS users (
-        id SERIAL PRIMARY KEY,
-        name VARCHAR(255) NOT NULL,
-        email VARCHAR(255) NOT NULL UNIQUE,
-        password VARCHAR(255) NOT NULL,
-        account_type VARCHAR(50),
-        phone VARCHAR(50),
-        role_id INT
-    );
-
-    CREATE TABLE IF NOT EXISTS permissions (
-        id SERIAL PRIMARY KEY,
-        name VARCHAR(100) NOT NULL UNIQUE
-    );
-
-    CREATE TABLE IF NOT EXISTS teams (
-        id SERIAL PRIMARY KEY,
-        name VARCHAR(100) NOT NULL,
-        pages TEXT[]
-    );
-
-    CREATE TABLE IF NOT EXISTS roles (
-        id SERIAL PRIMARY KEY,
-        name VARCHAR(100) NOT NULL,
-        permissions TEXT[],
-        team_id INT REFERENCES teams(id) ON DELETE CASCADE
-    );
-    
-    CREATE TABLE IF NOT EXISTS documents (
-        id SERIAL PRIMARY KEY,
-        category VARCHAR(100) NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS greetings (
-        id SERIAL PRIMARY KEY,
-        greetings_text TEXT
-    );
-
-    CREATE TABLE IF NOT EXISTS guides (
-        id SERIAL PRIMARY KEY,
-        title VARCHAR(255) NOT NULL,
-        description TEXT,
-        filename VARCHAR(255) NOT NULL,
-        original_filename VARCHAR(255),
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );
-
-    CREATE TABLE IF NOT EXISTS user_query_classifications (
-        id SERIAL PRIMARY KEY,
-        category TEXT NOT NULL,
-        sub_category TEXT NOT NULL,
-        detail TEXT
-    );
-
-    CREATE TABLE IF NOT EXISTS conversations (
-        id UUID PRIMARY KEY,
-        start_timestamp TIMESTAMP NOT NULL,
-        end_timestamp TIMESTAMP,
-        platform TEXT NOT NULL,
-        platform_unique_id TEXT NOT NULL,
-        is_helpdesk BOOLEAN DEFAULT false NOT NULL,
-        context TEXT,
-        is_positive_feedback BOOLEAN,
-        is_ask_helpdesk BOOLEAN
-    );
-
-    CREATE TABLE IF NOT EXISTS switch_helpdesk (
-        id SERIAL PRIMARY KEY,
-        status BOOLEAN
-    );
-
-    CREATE TABLE IF NOT EXISTS url_format (
-        id SERIAL PRIMARY KEY,
-        kode VARCHAR(50) NOT NULL UNIQUE,
-        url TEXT NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS processed_messages (
-        message_id TEXT NOT NULL,
-        platform TEXT NOT NULL,
-        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
-        CONSTRAINT processed_messages_pkey PRIMARY KEY (message_id, platform),
-        CONSTRAINT unique_msg_platform UNIQUE (message_id, platform)
-    );
-
-    -- 2. Tables with Foreign Keys or Dependencies
-    CREATE TABLE IF NOT EXISTS document_details (
-        id SERIAL PRIMARY KEY,
-        document_id INT NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
-        document_name VARCHAR(255) NOT NULL,
-        filename VARCHAR(255) NOT NULL,
-        data_type VARCHAR(10) NOT NULL,
-        staff VARCHAR(255) NOT NULL,
-        team VARCHAR(100) NOT NULL,
-        status VARCHAR(50),
-        is_latest BOOLEAN,
-        is_approve BOOLEAN,
-        created_at TIMESTAMP DEFAULT NOW(),
-        ingest_status TEXT
-    );
-
-    CREATE TABLE IF NOT EXISTS chat_history (
-        id SERIAL PRIMARY KEY,
-        session_id UUID NOT NULL,
-        message JSONB NOT NULL,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        user_id BIGINT,
-        is_cannot_answer BOOLEAN,
-        category TEXT,
-        feedback BOOLEAN,
-        question_category TEXT,
-        question_sub_category TEXT,
-        is_answered BOOLEAN DEFAULT false NOT NULL,
-        revision TEXT,
-        is_validated BOOLEAN,
-        validator INT,
-        start_timestamp TIMESTAMP,
-        citation JSONB
-    );
-
-    CREATE TABLE IF NOT EXISTS chat_history_outside_oss (
-        id BIGSERIAL PRIMARY KEY,
-        message TEXT NOT NULL,
-        question_category VARCHAR(100),
-        question_sub_category VARCHAR(100),
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        session_id UUID
-    );
-
-    CREATE TABLE IF NOT EXISTS helpdesk (
-        id SERIAL PRIMARY KEY,
-        session_id UUID NOT NULL REFERENCES conversations(id),
-        platform VARCHAR(50) NOT NULL,
-        platform_unique_id VARCHAR(100),
-        user_id INT,
-        status VARCHAR(50),
-        created_at TIMESTAMP DEFAULT NOW() NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS email_metadata (
-        conversation_id UUID NOT NULL,
-        subject VARCHAR,
-        in_reply_to VARCHAR,
-        "references" VARCHAR,
-        thread_key VARCHAR,
-        CONSTRAINT unique_conversation_id UNIQUE (conversation_id)
-    );
-
-    CREATE TABLE IF NOT EXISTS run_times (
-        id SERIAL PRIMARY KEY,
-        question_id SERIAL NOT NULL,
-        answer_id SERIAL NOT NULL,
-        qdrant_faq_time FLOAT8,
-        qdrant_main_time FLOAT8,
-        rerank_time FLOAT8,
-        llm_time FLOAT8
-    );
-
-    -- 3. Reporting / TBL Tables
-    CREATE TABLE IF NOT EXISTS tbl_agent_conv (
-        user_id VARCHAR(50),
-        conversation_id VARCHAR(50),
-        start_date VARCHAR(50),
-        end_date VARCHAR(50),
-        is_positive_feedback INT
-    );
-
-    CREATE TABLE IF NOT EXISTS tbl_agent_conv_detail (
-        conversation_id VARCHAR(50),
-        message_id VARCHAR(50),
-        start_date VARCHAR(50),
-        end_date VARCHAR(50),
-        question VARCHAR(50),
-        answer VARCHAR(50)
-    );
-
-    CREATE TABLE IF NOT EXISTS tbl_user_conv (
-        user_id VARCHAR(50),
-        conversation_id VARCHAR(50),
-        created_at VARCHAR(50),
-        is_helpdesk INT,
-        channel VARCHAR(50)
-    );
-
-    CREATE TABLE IF NOT EXISTS tbl_user_conv_detail (
-        conversation_id VARCHAR(50),
-        message_id VARCHAR(50),
-        start_date VARCHAR(50),
-        end_date VARCHAR(50),
-        question VARCHAR(128),
-        answer VARCHAR(128),
-        is_cannot_answer INT,
-        is_positive_feedback INT,
-        is_validated INT,
-        category VARCHAR(50),
-        sub_category VARCHAR(50)
-    );
-
-    -- ============================================================
-    -- UPDATE FOREIGN KEY CONSTRAINTS (CASCADE & SET NULL)
-    -- ============================================================
-    
-    -- 1. Roles: Drop old FK if exists, create new with ON DELETE CASCADE
-    DO $$ 
-    BEGIN
-        IF EXISTS (SELECT 1 FROM information_schema.table_constraints 
-                   WHERE constraint_name='roles_team_id_fkey' AND table_name='roles') THEN
-            ALTER TABLE roles DROP CONSTRAINT roles_team_id_fkey;
-        END IF;
-    END $$;
-    
-    ALTER TABLE roles ADD CONSTRAINT roles_team_id_fkey 
-        FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE;
-
-    -- 2. Users: Drop old FK if exists, create new with ON DELETE SET NULL
-    DO $$ 
-    BEGIN
-        IF EXISTS (SELECT 1 FROM information_schema.table_constraints 
-                   WHERE constraint_name='users_role_id_fkey' AND table_name='users') THEN
-            ALTER TABLE users DROP CONSTRAINT users_role_id_fkey;
-        END IF;
-    END $$;
-    
-    ALTER TABLE users ADD CONSTRAINT users_role_id_fkey 
-        FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE SET NULL;
-
-    -- ============================================================
-    -- INDICES
-    -- ============================================================
-    CREATE INDEX IF NOT EXISTS idx_chat_history_session_id ON chat_history(session_id);
-    CREATE INDEX IF NOT EXISTS idx_chat_history_user_id ON chat_history(user_id);
-    CREATE INDEX IF NOT EXISTS idx_conversations_platform_unique_id ON conversations(platform_unique_id);
-    CREATE INDEX IF NOT EXISTS idx_document_details_data_type ON document_details(data_type);
-    CREATE INDEX IF NOT EXISTS idx_document_details_document_id ON document_details(document_id);
-    CREATE INDEX IF NOT EXISTS idx_document_details_is_latest ON document_details(is_latest);
-    CREATE INDEX IF NOT EXISTS idx_document_details_status ON document_details(status);
-    CREATE INDEX IF NOT EXISTS idx_email_metadata_thread_key ON email_metadata(thread_key);
-
-    -- ============================================================
-    -- COLUMN ALTERATIONS (Idempotency Checks)
-    -- ============================================================
-    DO $$ 
-    BEGIN
-        -- Ensure data_type column exists and has default value
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='document_details' AND column_name='data_type') THEN
-            ALTER TABLE document_details ADD COLUMN data_type VARCHAR(10);
-            UPDATE document_details SET data_type = 'pdf' WHERE data_type IS NULL;
-            ALTER TABLE document_details ALTER COLUMN data_type SET NOT NULL;
-        END IF;
-
-        -- Updates for 'conversations'
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='conversations' AND column_name='is_positive_feedback') THEN
-            ALTER TABLE conversations ADD COLUMN is_positive_feedback BOOLEAN;
-        END IF;
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='conversations' AND column_name='is_ask_helpdesk') THEN
-            ALTER TABLE conversations ADD COLUMN is_ask_helpdesk BOOLEAN;
-        END IF;
-
-        -- Updates for 'chat_history'
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='chat_history' AND column_name='is_answered') THEN
-            ALTER TABLE chat_history ADD COLUMN is_answered BOOLEAN DEFAULT false NOT NULL;
-        END IF;
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='chat_history' AND column_name='is_validated') THEN
-            ALTER TABLE chat_history ADD COLUMN is_validated BOOLEAN;
-        END IF;
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='chat_history' AND column_name='start_timestamp') THEN
-            ALTER TABLE chat_history ADD COLUMN start_timestamp TIMESTAMP;
-        END IF;
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='chat_history' AND column_name='citation') THEN
-            ALTER TABLE chat_history ADD COLUMN citation JSONB;
-        END IF;
-
-        -- Updates for 'document_details'
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='document_details' AND column_name='ingest_status') THEN
-            ALTER TABLE document_details ADD COLUMN ingest_status TEXT;
-        END IF;
-
-        -- Updates for 'users'
-        IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-                       WHERE table_name='users' AND column_name='name') THEN
-            ALTER TABLE users ADD COLUMN name VARCHAR(255);
-            UPDATE users SET name = 'User' WHERE name IS NULL;
-            ALTER TABLE users ALTER COLUMN name SET NOT NULL;
-        END IF;
-    END $$;
-    `
+	applied, err := Up(db)
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	log.Printf("Migration completed successfully. %d migration(s) applied.", applied)
+}
+
+// RunCommand handles the `--migrate` CLI modes: up (default), down [N],
+// status and to N.
+func RunCommand(db *sqlx.DB, args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		RunMigrations(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatalf("Invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := Down(db, steps)
+		if err != nil {
+			log.Fatalf("Migration rollback failed: %v", err)
+		}
+		log.Printf("Rolled back %d migration(s).", reverted)
+	case "to":
+		if len(args) < 2 {
+			log.Fatal("Usage: --migrate to <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			log.Fatalf("Invalid migration version: %s", args[1])
+		}
+		if err := To(db, version); err != nil {
+			log.Fatalf("Migration to version %d failed: %v", version, err)
+		}
+		log.Printf("Database schema is at version %d.", version)
+	case "status":
+		statuses, err := Status(db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q (use up, down [N], status or to N)", command)
+	}
+}
+
+// Up applies all pending migrations in order.
+func Up(db *sqlx.DB) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrateTo(db, migrations, migrations[len(migrations)-1].Version)
+}
+
+// Down reverts the latest `steps` applied migrations.
+func Down(db *sqlx.DB, steps int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	target := 0
+	count := 0
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if !applied[migrations[i].Version] {
+			continue
+		}
+		if count == steps {
+			target = migrations[i].Version
+			break
+		}
+		count++
+	}
+
+	return migrateTo(db, migrations, target)
+}
+
+// To moves the schema up or down until exactly the migrations <= version are applied.
+func To(db *sqlx.DB, version int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if version > 0 && findMigration(migrations, version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	_, err = migrateTo(db, migrations, version)
+	return err
+}
+
+func Status(db *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	var rows []MigrationStatus
+	if err := db.Select(&rows, "SELECT version, name, applied_at FROM "+migrationsTable()); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	appliedAt := make(map[int]*time.Time, len(rows))
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := appliedAt[m.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+	return statuses, nil
+}
+
+func migrateTo(db *sqlx.DB, migrations []Migration, target int) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
 
+	changed := 0
+	for _, m := range migrations {
+		if m.Version > target || applied[m.Version] {
+			continue
+		}
+		log.Printf("Applying migration %04d_%s", m.Version, m.Name)
+		if err := runMigration(db, m, true); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target || !applied[m.Version] {
+			continue
+		}
+		log.Printf("Reverting migration %04d_%s", m.Version, m.Name)
+		if err := runMigration(db, m, false); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+// runMigration executes one migration and its schema_migrations bookkeeping in
+// a single transaction.
+func runMigration(db *sqlx.DB, m Migration, up bool) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if err := setSearchPath(tx); err != nil {
+		return err
+	}
+
+	// Another instance may have run it while we waited for the lock.
+	var exists bool
+	if err := tx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM "+migrationsTable()+" WHERE version = $1)", m.Version); err != nil {
+		return fmt.Errorf("failed to check migration %d: %w", m.Version, err)
+	}
+	if exists == up {
+		return nil
+	}
+
+	script := m.Up
+	if !up {
+		script = m.Down
+		if script == "" {
+			return fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+		}
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec("INSERT INTO "+migrationsTable()+" (version, name) VALUES ($1, $2)", m.Version, m.Name)
+	} else {
+		_, err = tx.Exec("DELETE FROM "+migrationsTable()+" WHERE version = $1", m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	return tx.Commit()
+}
+
+func ensureMigrationsTable(db *sqlx.DB) error {
+	if schema := config.GetDBSchema(); schema != "" {
+		if _, err := db.Exec("CREATE SCHEMA IF NOT EXISTS " + pq.QuoteIdentifier(schema)); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+	}
+
+	query := `
+		CREATE TABLE IF NOT EXISTS ` + migrationsTable() + ` (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)
+	`
 	if _, err := db.Exec(query); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(db *sqlx.DB) (map[int]bool, error) {
+	var versions []int
+	if err := db.Select(&versions, "SELECT version FROM "+migrationsTable()); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// setSearchPath pins unqualified names in migration scripts to DB_SCHEMA.
+func setSearchPath(tx *sqlx.Tx) error {
+	schema := config.GetDBSchema()
+	if schema == "" {
+		return nil
+	}
+	if _, err := tx.Exec("SET LOCAL search_path TO " + pq.QuoteIdentifier(schema)); err != nil {
+		return fmt.Errorf("failed to set search_path: %w", err)
 	}
+	return nil
+}
 
-	log.Println("Migration completed successfully.")
+func migrationsTable() string {
+	return config.QualifiedTable("schema_migrations")
+}
+
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func findMigration(migrations []Migration, version int) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+	return nil
 }
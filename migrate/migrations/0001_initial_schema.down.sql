@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS tbl_user_conv_detail CASCADE;
+DROP TABLE IF EXISTS tbl_user_conv CASCADE;
+DROP TABLE IF EXISTS tbl_agent_conv_detail CASCADE;
+DROP TABLE IF EXISTS tbl_agent_conv CASCADE;
+DROP TABLE IF EXISTS run_times CASCADE;
+DROP TABLE IF EXISTS email_metadata CASCADE;
+DROP TABLE IF EXISTS helpdesk CASCADE;
+DROP TABLE IF EXISTS chat_history_outside_oss CASCADE;
+DROP TABLE IF EXISTS chat_history CASCADE;
+DROP TABLE IF EXISTS document_details CASCADE;
+DROP TABLE IF EXISTS processed_messages CASCADE;
+DROP TABLE IF EXISTS url_format CASCADE;
+DROP TABLE IF EXISTS switch_helpdesk CASCADE;
+DROP TABLE IF EXISTS conversations CASCADE;
+DROP TABLE IF EXISTS user_query_classifications CASCADE;
+DROP TABLE IF EXISTS guides CASCADE;
+DROP TABLE IF EXISTS greetings CASCADE;
+DROP TABLE IF EXISTS documents CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+DROP TABLE IF EXISTS roles CASCADE;
+DROP TABLE IF EXISTS teams CASCADE;
+DROP TABLE IF EXISTS permissions CASCADE;
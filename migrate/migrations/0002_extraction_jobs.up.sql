@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS extraction_jobs (
+    id SERIAL PRIMARY KEY,
+    detail_id INT NOT NULL REFERENCES document_details(id) ON DELETE CASCADE,
+    external_id VARCHAR(100) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    file_path TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    last_error TEXT,
+    next_run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_extraction_jobs_status_next_run ON extraction_jobs(status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_extraction_jobs_detail_id ON extraction_jobs(detail_id);
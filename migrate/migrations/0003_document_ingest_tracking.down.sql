@@ -0,0 +1,2 @@
+ALTER TABLE document_details DROP COLUMN IF EXISTS ingest_attempts;
+ALTER TABLE document_details DROP COLUMN IF EXISTS ingest_error;
@@ -0,0 +1,2 @@
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS ingest_error TEXT;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS ingest_attempts INT NOT NULL DEFAULT 0;
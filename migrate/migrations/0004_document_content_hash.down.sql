@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_document_details_content_hash;
+ALTER TABLE document_details DROP COLUMN IF EXISTS content_hash;
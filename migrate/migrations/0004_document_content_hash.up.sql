@@ -0,0 +1,3 @@
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_document_details_content_hash ON document_details(content_hash);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_document_details_request_type;
+ALTER TABLE document_details DROP COLUMN IF EXISTS requested_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS request_type;
@@ -0,0 +1,19 @@
+-- request_type/requested_at were used by the approval queue but never created.
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS request_type VARCHAR(20);
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS requested_at TIMESTAMP;
+
+-- Backfill existing rows the same way RestoreStatus derives them.
+UPDATE document_details dd
+SET
+    request_type = CASE
+        WHEN NOT EXISTS (
+            SELECT 1 FROM document_details d2
+            WHERE d2.document_id = dd.document_id
+            AND d2.id < dd.id
+        ) THEN 'NEW'
+        ELSE 'UPDATE'
+    END,
+    requested_at = dd.created_at
+WHERE dd.request_type IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_document_details_request_type ON document_details(request_type);
@@ -0,0 +1,2 @@
+ALTER TABLE document_details DROP COLUMN IF EXISTS approved_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS approved_by;
@@ -0,0 +1,17 @@
+-- Who approved each version and when, for the version timeline.
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS approved_by BIGINT;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP;
+
+-- Recover earlier approvals from the audit log where it has them.
+UPDATE document_details dd
+SET
+    approved_by = ae.actor_user_id,
+    approved_at = ae.created_at
+FROM (
+    SELECT DISTINCT ON (entity_id) entity_id, actor_user_id, created_at
+    FROM audit_events
+    WHERE action = 'document.approve' AND entity_type = 'document_detail'
+    ORDER BY entity_id, created_at DESC
+) ae
+WHERE ae.entity_id = dd.id::text
+AND dd.approved_at IS NULL;
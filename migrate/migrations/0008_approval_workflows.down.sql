@@ -0,0 +1,5 @@
+ALTER TABLE document_details DROP COLUMN IF EXISTS current_step;
+ALTER TABLE document_details DROP COLUMN IF EXISTS workflow_id;
+DROP TABLE IF EXISTS document_approval_decisions;
+DROP TABLE IF EXISTS approval_workflow_steps;
+DROP TABLE IF EXISTS approval_workflows;
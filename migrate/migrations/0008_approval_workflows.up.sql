@@ -0,0 +1,41 @@
+-- Approval workflows: ordered review steps per category and/or team.
+-- NULL category or team matches any value.
+CREATE TABLE IF NOT EXISTS approval_workflows (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(255),
+    team VARCHAR(255),
+    auto_approve BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_workflows_scope ON approval_workflows(category, team) WHERE is_active;
+
+CREATE TABLE IF NOT EXISTS approval_workflow_steps (
+    id SERIAL PRIMARY KEY,
+    workflow_id INT NOT NULL REFERENCES approval_workflows(id) ON DELETE CASCADE,
+    step_order INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    required_permission VARCHAR(100),
+    required_role_id INT REFERENCES roles(id) ON DELETE SET NULL,
+    UNIQUE (workflow_id, step_order)
+);
+
+-- One row per approve/reject decision taken on a document version.
+CREATE TABLE IF NOT EXISTS document_approval_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    detail_id INT NOT NULL REFERENCES document_details(id) ON DELETE CASCADE,
+    workflow_id INT REFERENCES approval_workflows(id) ON DELETE SET NULL,
+    step_order INT NOT NULL,
+    step_name VARCHAR(255),
+    decision VARCHAR(20) NOT NULL,
+    actor_user_id BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_approval_decisions_detail ON document_approval_decisions(detail_id);
+
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS workflow_id INT REFERENCES approval_workflows(id) ON DELETE SET NULL;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS current_step INT;
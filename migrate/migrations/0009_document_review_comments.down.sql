@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_document_details_resubmitted_from;
+ALTER TABLE document_details DROP COLUMN IF EXISTS resubmitted_from;
+ALTER TABLE document_details DROP COLUMN IF EXISTS rejection_codes;
+ALTER TABLE document_details DROP COLUMN IF EXISTS rejection_reason;
+DROP TABLE IF EXISTS document_review_comments;
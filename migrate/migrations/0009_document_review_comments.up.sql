@@ -0,0 +1,19 @@
+-- Review discussion per document version; rejections are stored as comments
+-- of kind 'rejection' carrying their reason codes.
+CREATE TABLE IF NOT EXISTS document_review_comments (
+    id BIGSERIAL PRIMARY KEY,
+    detail_id INT NOT NULL REFERENCES document_details(id) ON DELETE CASCADE,
+    author_user_id BIGINT,
+    kind VARCHAR(20) NOT NULL DEFAULT 'comment',
+    comment TEXT NOT NULL,
+    reason_codes TEXT[],
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_review_comments_detail ON document_review_comments(detail_id, created_at);
+
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS rejection_codes TEXT[];
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS resubmitted_from INT REFERENCES document_details(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_document_details_resubmitted_from ON document_details(resubmitted_from);
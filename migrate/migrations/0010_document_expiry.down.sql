@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS document_notifications;
+DROP INDEX IF EXISTS idx_document_details_review_by;
+DROP INDEX IF EXISTS idx_document_details_expires_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS expired_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS review_notified_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS review_by;
+ALTER TABLE document_details DROP COLUMN IF EXISTS valid_from;
@@ -0,0 +1,23 @@
+-- Validity window of a version. Once expires_at passes the version is removed
+-- from the RAG index and marked Expired; owners are told ahead of review_by.
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS valid_from TIMESTAMP;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS review_by TIMESTAMP;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS review_notified_at TIMESTAMP;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS expired_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_document_details_expires_at ON document_details(expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_document_details_review_by ON document_details(review_by) WHERE review_by IS NOT NULL;
+
+-- Messages for document owners, addressed by the email stored in staff.
+CREATE TABLE IF NOT EXISTS document_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    detail_id INT NOT NULL REFERENCES document_details(id) ON DELETE CASCADE,
+    recipient VARCHAR(255) NOT NULL,
+    kind VARCHAR(30) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_notifications_recipient ON document_notifications(recipient, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_documents_tags;
+DROP INDEX IF EXISTS idx_documents_metadata;
+ALTER TABLE documents DROP COLUMN IF EXISTS tags;
+ALTER TABLE documents DROP COLUMN IF EXISTS metadata;
+DROP TABLE IF EXISTS metadata_fields;
@@ -0,0 +1,19 @@
+-- Admin-defined metadata fields. Values live on documents.metadata keyed by
+-- field key; enum and multi_select values must be one of options.
+CREATE TABLE IF NOT EXISTS metadata_fields (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(100) NOT NULL UNIQUE,
+    label VARCHAR(255) NOT NULL,
+    field_type VARCHAR(20) NOT NULL,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    is_required BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_documents_metadata ON documents USING GIN (metadata);
+CREATE INDEX IF NOT EXISTS idx_documents_tags ON documents USING GIN (tags);
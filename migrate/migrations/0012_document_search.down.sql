@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS document_text_pages;
+DROP TABLE IF EXISTS document_texts;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS dokuprime_indonesian;
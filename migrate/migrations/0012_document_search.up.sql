@@ -0,0 +1,37 @@
+-- Text search configuration for Indonesian content. The indonesian snowball
+-- stemmer ships with PostgreSQL 13+; older servers fall back to simple.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'dokuprime_indonesian') THEN
+        IF EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'indonesian') THEN
+            CREATE TEXT SEARCH CONFIGURATION dokuprime_indonesian (COPY = pg_catalog.indonesian);
+        ELSE
+            CREATE TEXT SEARCH CONFIGURATION dokuprime_indonesian (COPY = pg_catalog.simple);
+        END IF;
+    END IF;
+END
+$$;
+
+-- Indexing state of each version's extracted text.
+CREATE TABLE IF NOT EXISTS document_texts (
+    detail_id INT PRIMARY KEY REFERENCES document_details(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    page_count INT NOT NULL DEFAULT 0,
+    indexed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Extracted text per page (page_number is NULL for formats without pages),
+-- searchable with both the Indonesian and English configurations.
+CREATE TABLE IF NOT EXISTS document_text_pages (
+    id BIGSERIAL PRIMARY KEY,
+    detail_id INT NOT NULL REFERENCES document_details(id) ON DELETE CASCADE,
+    page_number INT,
+    content TEXT NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('dokuprime_indonesian', content) || to_tsvector('english', content)
+    ) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_text_pages_detail ON document_text_pages(detail_id, page_number);
+CREATE INDEX IF NOT EXISTS idx_document_text_pages_search ON document_text_pages USING GIN (search_vector);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS quarantined_files;
+ALTER TABLE document_details DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE document_details DROP COLUMN IF EXISTS scan_status;
+ALTER TABLE document_details DROP COLUMN IF EXISTS mime_type;
@@ -0,0 +1,22 @@
+-- Content type and malware scan verdict of each stored version.
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS mime_type VARCHAR(150);
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20);
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS scan_signature VARCHAR(255);
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMP;
+
+-- Uploads the scanner flagged. The file is kept under storage_key for
+-- inspection and never becomes a document.
+CREATE TABLE IF NOT EXISTS quarantined_files (
+    id BIGSERIAL PRIMARY KEY,
+    original_name VARCHAR(255) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    uploaded_by VARCHAR(255),
+    signature VARCHAR(255) NOT NULL,
+    engine VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantined_files_created_at ON quarantined_files(created_at);
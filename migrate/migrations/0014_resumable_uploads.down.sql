@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS resumable_upload_chunks;
+DROP TABLE IF EXISTS resumable_uploads;
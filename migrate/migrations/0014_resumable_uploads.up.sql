@@ -0,0 +1,34 @@
+-- tus upload sessions. Received bytes are stored as chunk objects so an
+-- upload can resume after a dropped connection or a backend restart.
+CREATE TABLE IF NOT EXISTS resumable_uploads (
+    id UUID PRIMARY KEY,
+    filename VARCHAR(255) NOT NULL,
+    data_type VARCHAR(10) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP,
+    review_by TIMESTAMP,
+    expires_at TIMESTAMP,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    staff VARCHAR(255) NOT NULL,
+    team VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'uploading',
+    error TEXT,
+    document_id INT REFERENCES documents(id) ON DELETE SET NULL,
+    detail_id INT REFERENCES document_details(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    upload_expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_resumable_uploads_expires ON resumable_uploads(upload_expires_at);
+
+CREATE TABLE IF NOT EXISTS resumable_upload_chunks (
+    upload_id UUID NOT NULL REFERENCES resumable_uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS upload_batch_items;
+DROP TABLE IF EXISTS upload_batches;
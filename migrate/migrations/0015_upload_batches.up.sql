@@ -0,0 +1,36 @@
+-- Batch uploads and the outcome of every file in them. Counters are derived
+-- from the items so they cannot drift from what actually happened.
+CREATE TABLE IF NOT EXISTS upload_batches (
+    id VARCHAR(32) PRIMARY KEY,
+    category VARCHAR(100) NOT NULL,
+    staff VARCHAR(255) NOT NULL,
+    team VARCHAR(100) NOT NULL,
+    auto_approve BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing',
+    total INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    cancelled_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_batches_staff ON upload_batches(staff, started_at DESC);
+
+CREATE TABLE IF NOT EXISTS upload_batch_items (
+    id BIGSERIAL PRIMARY KEY,
+    batch_id VARCHAR(32) NOT NULL REFERENCES upload_batches(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    scan_status VARCHAR(20),
+    scan_signature VARCHAR(255),
+    document_id INT REFERENCES documents(id) ON DELETE SET NULL,
+    detail_id INT REFERENCES document_details(id) ON DELETE SET NULL,
+    extraction_status VARCHAR(20),
+    extraction_error TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_batch_items_batch ON upload_batch_items(batch_id, position);
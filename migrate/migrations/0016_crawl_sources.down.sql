@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS crawl_run_items;
+DROP TABLE IF EXISTS crawl_runs;
+DROP TABLE IF EXISTS crawled_urls;
+DROP TABLE IF EXISTS crawl_sources;
+
+ALTER TABLE document_details DROP COLUMN IF EXISTS fetched_at;
+ALTER TABLE document_details DROP COLUMN IF EXISTS source_url;
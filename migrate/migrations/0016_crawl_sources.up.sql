@@ -0,0 +1,61 @@
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS source_url TEXT;
+ALTER TABLE document_details ADD COLUMN IF NOT EXISTS fetched_at TIMESTAMP;
+
+-- Places the backend crawls on a schedule: a single URL, a sitemap, or a
+-- listing page whose links match link_pattern.
+CREATE TABLE IF NOT EXISTS crawl_sources (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    url TEXT NOT NULL,
+    link_pattern TEXT,
+    category VARCHAR(100) NOT NULL,
+    schedule VARCHAR(100),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_sources_next_run ON crawl_sources(next_run_at) WHERE is_active = true;
+
+-- The last successful fetch of each URL, used for conditional requests and
+-- to find the document the URL feeds.
+CREATE TABLE IF NOT EXISTS crawled_urls (
+    source_id INT NOT NULL REFERENCES crawl_sources(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    etag TEXT,
+    last_modified TEXT,
+    content_hash VARCHAR(64),
+    document_id INT REFERENCES documents(id) ON DELETE SET NULL,
+    detail_id INT REFERENCES document_details(id) ON DELETE SET NULL,
+    fetched_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source_id, url)
+);
+
+CREATE TABLE IF NOT EXISTS crawl_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source_id INT NOT NULL REFERENCES crawl_sources(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    error TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_runs_source ON crawl_runs(source_id, started_at DESC);
+
+CREATE TABLE IF NOT EXISTS crawl_run_items (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES crawl_runs(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    document_id INT REFERENCES documents(id) ON DELETE SET NULL,
+    detail_id INT REFERENCES document_details(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_run_items_run ON crawl_run_items(run_id, id);
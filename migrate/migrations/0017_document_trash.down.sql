@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_documents_purge_after;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS legal_hold_at;
+ALTER TABLE documents DROP COLUMN IF EXISTS legal_hold_by;
+ALTER TABLE documents DROP COLUMN IF EXISTS legal_hold_reason;
+ALTER TABLE documents DROP COLUMN IF EXISTS legal_hold;
+ALTER TABLE documents DROP COLUMN IF EXISTS purge_after;
+ALTER TABLE documents DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE documents DROP COLUMN IF EXISTS deleted_at;
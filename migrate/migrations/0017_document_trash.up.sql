@@ -0,0 +1,13 @@
+-- Deleted documents stay in the recycle bin until purge_after, keeping their
+-- files and every version so they can be restored.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_by BIGINT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS purge_after TIMESTAMP;
+
+-- A document under legal hold cannot be deleted or purged.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_reason TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_by BIGINT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_documents_purge_after ON documents (purge_after) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,37 @@
+-- Reusable links to a document. Only the SHA-256 of the token is stored; a
+-- link without detail_id always serves the published version.
+CREATE TABLE IF NOT EXISTS document_share_links (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(8) NOT NULL,
+    document_id INT NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    detail_id INT REFERENCES document_details(id) ON DELETE CASCADE,
+    created_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    max_uses INT,
+    use_count INT NOT NULL DEFAULT 0,
+    require_login BOOLEAN NOT NULL DEFAULT false,
+    allowed_teams TEXT[] NOT NULL DEFAULT '{}',
+    revoked_at TIMESTAMP,
+    revoked_by VARCHAR(255),
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_share_links_document ON document_share_links(document_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_document_share_links_created_by ON document_share_links(created_by, created_at DESC);
+
+-- Every view of a link, including the ones that were refused.
+CREATE TABLE IF NOT EXISTS document_share_link_accesses (
+    id BIGSERIAL PRIMARY KEY,
+    link_id BIGINT NOT NULL REFERENCES document_share_links(id) ON DELETE CASCADE,
+    user_id BIGINT,
+    email VARCHAR(255),
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    granted BOOLEAN NOT NULL,
+    reason VARCHAR(50),
+    accessed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_share_link_accesses_link ON document_share_link_accesses(link_id, accessed_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chat_history_created_at;
+DROP TABLE IF EXISTS document_access_events;
@@ -0,0 +1,22 @@
+-- One row per opened view URL, download or share link view. source tells
+-- how the viewer got there: direct, citation (from a chat answer) or
+-- share_link.
+CREATE TABLE IF NOT EXISTS document_access_events (
+    id BIGSERIAL PRIMARY KEY,
+    document_id INT NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    detail_id INT REFERENCES document_details(id) ON DELETE SET NULL,
+    user_id BIGINT,
+    email VARCHAR(255),
+    channel VARCHAR(20) NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'direct',
+    share_link_id BIGINT REFERENCES document_share_links(id) ON DELETE SET NULL,
+    ip_address VARCHAR(64),
+    user_agent TEXT,
+    accessed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_access_events_accessed_at ON document_access_events(accessed_at);
+CREATE INDEX IF NOT EXISTS idx_document_access_events_document ON document_access_events(document_id, accessed_at DESC);
+
+-- Citation reports scan chat history by period.
+CREATE INDEX IF NOT EXISTS idx_chat_history_created_at ON chat_history(created_at);
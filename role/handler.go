@@ -1,6 +1,7 @@
 package role
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/util"
 	"net/http"
 	"strconv"
@@ -23,7 +24,7 @@ func (h *RoleHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Create(input); err != nil {
+	if err := h.service.Create(input, audit.ActorFromContext(c)); err != nil {
 		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -115,7 +116,7 @@ func (h *RoleHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Update(id, input); err != nil {
+	if err := h.service.Update(id, input, audit.ActorFromContext(c)); err != nil {
 		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -125,7 +126,7 @@ func (h *RoleHandler) Update(c *gin.Context) {
 
 func (h *RoleHandler) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(id, audit.ActorFromContext(c)); err != nil {
 		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 
+	"dokuprime-be/audit"
 	"dokuprime-be/middleware"
 	"dokuprime-be/permission"
 	"dokuprime-be/team"
@@ -13,18 +14,18 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
 	repoRole := NewRoleRepository(db)
 	repoTeam := team.NewTeamRepository(db)
 	repoPermission := permission.NewPermissionRepository(db)
-	service := NewRoleService(repoRole, repoTeam, repoPermission)
+	service := NewRoleService(repoRole, repoTeam, repoPermission, audit.NewAuditService(audit.NewAuditRepository(db)))
 	handler := NewRoleHandler(service)
 
 	roleGroup := r.Group("/api/roles")
 
 	roleGroup.Use(middleware.AuthMiddleware())
 	{
-		roleGroup.POST("", handler.Create)
-		roleGroup.GET("", handler.GetAll)
-		roleGroup.GET("/by-team-id/:id", handler.GetRoleByTeamID)
-		roleGroup.GET("/:id", handler.GetByID)
-		roleGroup.PUT("/:id", handler.Update)
-		roleGroup.DELETE("/:id", handler.Delete)
+		roleGroup.POST("", middleware.RequirePermission("role-management:create"), handler.Create)
+		roleGroup.GET("", middleware.RequirePermission("role-management:read", "user-management:read"), handler.GetAll)
+		roleGroup.GET("/by-team-id/:id", middleware.RequirePermission("role-management:read", "user-management:read"), handler.GetRoleByTeamID)
+		roleGroup.GET("/:id", middleware.RequirePermission("role-management:read", "user-management:read"), handler.GetByID)
+		roleGroup.PUT("/:id", middleware.RequirePermission("role-management:update"), handler.Update)
+		roleGroup.DELETE("/:id", middleware.RequirePermission("role-management:delete"), handler.Delete)
 	}
 }
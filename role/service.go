@@ -1,6 +1,8 @@
 package role
 
 import (
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
 	"dokuprime-be/permission"
 	"dokuprime-be/team"
 	"strconv"
@@ -10,18 +12,25 @@ type RoleService struct {
 	repoRole       *RoleRepository
 	repoTeam       *team.TeamRepository
 	repoPermission *permission.PermissionRepository
+	audit          *audit.AuditService
 }
 
-func NewRoleService(repoRole *RoleRepository, repoTeam *team.TeamRepository, repoPermission *permission.PermissionRepository) *RoleService {
+func NewRoleService(repoRole *RoleRepository, repoTeam *team.TeamRepository, repoPermission *permission.PermissionRepository, auditService *audit.AuditService) *RoleService {
 	return &RoleService{
 		repoRole:       repoRole,
 		repoTeam:       repoTeam,
 		repoPermission: repoPermission,
+		audit:          auditService,
 	}
 }
 
-func (s *RoleService) Create(role Role) error {
-	return s.repoRole.Create(role)
+func (s *RoleService) Create(role Role, actor audit.Actor) error {
+	if err := s.repoRole.Create(role); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionRoleCreate, audit.EntityRole, nil, nil, role)
+	return nil
 }
 
 func (s *RoleService) GetAll(limit, offset int, search string, teamID *int) ([]GetRoleDTO, int, error) {
@@ -152,10 +161,29 @@ func (s *RoleService) GetByID(id int) (*GetRoleDTO, error) {
 	return getRoleDto, nil
 }
 
-func (s *RoleService) Update(id int, role Role) error {
-	return s.repoRole.Update(id, role)
+func (s *RoleService) Update(id int, role Role, actor audit.Actor) error {
+	before, _ := s.repoRole.GetByID(id)
+
+	if err := s.repoRole.Update(id, role); err != nil {
+		return err
+	}
+
+	middleware.InvalidateRolePermissions(id)
+
+	role.ID = id
+	s.audit.Record(actor, audit.ActionRoleUpdate, audit.EntityRole, id, before, role)
+	return nil
 }
 
-func (s *RoleService) Delete(id int) error {
-	return s.repoRole.Delete(id)
+func (s *RoleService) Delete(id int, actor audit.Actor) error {
+	before, _ := s.repoRole.GetByID(id)
+
+	// role_id is set to NULL on delete, so the affected users must be resolved first.
+	middleware.InvalidateRolePermissions(id)
+	if err := s.repoRole.Delete(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionRoleDelete, audit.EntityRole, id, before, nil)
+	return nil
 }
\ No newline at end of file
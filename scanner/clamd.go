@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner streams files to a clamd daemon over TCP with the INSTREAM
+// command.
+type ClamdScanner struct {
+	address string
+	timeout time.Duration
+}
+
+func NewClamdScanner(address string, timeout time.Duration) *ClamdScanner {
+	return &ClamdScanner{address: address, timeout: timeout}
+}
+
+func (c *ClamdScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+
+	// clamd stops reading and replies early when the stream exceeds its
+	// StreamMaxLength, so a failed write still has a reply worth reading.
+	writeErr := c.stream(conn, r)
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if writeErr != nil {
+			return nil, fmt.Errorf("failed to send file to clamd: %w", writeErr)
+		}
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+func (c *ClamdScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, werr := conn.Write(size); werr != nil {
+				return werr
+			}
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	_, err := conn.Write(size)
+	return err
+}
+
+// parseClamdReply reads replies such as "stream: OK",
+// "stream: Eicar-Test-Signature FOUND" and "... ERROR".
+func parseClamdReply(reply string) (*Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case verdict == "OK":
+		return &Result{Status: StatusClean, Engine: "clamav"}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{
+			Status:    StatusInfected,
+			Signature: strings.TrimSpace(strings.TrimSuffix(verdict, " FOUND")),
+			Engine:    "clamav",
+		}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+}
@@ -0,0 +1,41 @@
+package scanner
+
+import "testing"
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		name          string
+		reply         string
+		wantStatus    string
+		wantSignature string
+		wantErr       bool
+	}{
+		{name: "clean", reply: "stream: OK\x00", wantStatus: StatusClean},
+		{name: "clean without prefix", reply: "OK\n", wantStatus: StatusClean},
+		{name: "infected", reply: "stream: Eicar-Test-Signature FOUND\x00", wantStatus: StatusInfected, wantSignature: "Eicar-Test-Signature"},
+		{name: "signature with spaces", reply: "stream: Win.Test EICAR_HDB-1 FOUND", wantStatus: StatusInfected, wantSignature: "Win.Test EICAR_HDB-1"},
+		{name: "size limit", reply: "INSTREAM size limit exceeded. ERROR\x00", wantErr: true},
+		{name: "error", reply: "stream: Can't allocate memory ERROR", wantErr: true},
+		{name: "empty", reply: "", wantErr: true},
+		{name: "garbage", reply: "PONG", wantErr: true},
+		{name: "found without signature", reply: "stream: FOUND", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseClamdReply(tt.reply)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseClamdReply(%q) = %+v, want error", tt.reply, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseClamdReply(%q) error = %v", tt.reply, err)
+			}
+			if result.Status != tt.wantStatus || result.Signature != tt.wantSignature || result.Engine != "clamav" {
+				t.Errorf("parseClamdReply(%q) = %+v, want status %q signature %q", tt.reply, result, tt.wantStatus, tt.wantSignature)
+			}
+		})
+	}
+}
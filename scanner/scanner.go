@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"dokuprime-be/config"
+)
+
+const (
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+	StatusSkipped  = "skipped"
+	StatusError    = "error"
+)
+
+// Result is the verdict on one file. Signature names the malware found when
+// Status is infected.
+type Result struct {
+	Status    string
+	Signature string
+	Engine    string
+}
+
+// Scanner checks file content for malware before it is stored.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// InitScanner builds the scanner selected by SCANNER_BACKEND.
+func InitScanner() Scanner {
+	s, err := NewScannerFromConfig(config.LoadScannerConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize malware scanner: %v", err)
+	}
+	return s
+}
+
+func NewScannerFromConfig(cfg *config.ScannerConfig) (Scanner, error) {
+	var s Scanner
+	switch cfg.Backend {
+	case "", "none":
+		return disabledScanner{}, nil
+	case "clamav":
+		s = NewClamdScanner(cfg.ClamdAddress, cfg.Timeout)
+	case "mock":
+		s = MockScanner{}
+	default:
+		return nil, fmt.Errorf("unknown SCANNER_BACKEND %q", cfg.Backend)
+	}
+
+	if cfg.FailOpen {
+		return failOpenScanner{next: s}, nil
+	}
+	return s, nil
+}
+
+// disabledScanner accepts every file and records that it was not scanned.
+type disabledScanner struct{}
+
+func (disabledScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	return &Result{Status: StatusSkipped, Engine: "none"}, nil
+}
+
+// failOpenScanner lets files through with an error verdict when the scanner
+// itself fails, instead of rejecting every upload while clamd is down.
+type failOpenScanner struct {
+	next Scanner
+}
+
+func (f failOpenScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	result, err := f.next.Scan(ctx, r)
+	if err != nil {
+		log.Printf("Warning: Malware scan failed, accepting file unscanned: %v", err)
+		return &Result{Status: StatusError}, nil
+	}
+	return result, nil
+}
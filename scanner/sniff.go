@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SniffLen is how much of the start of a file SniffContentType needs.
+const SniffLen = 1024
+
+var ErrContentMismatch = errors.New("file content does not match its extension")
+
+var (
+	pdfMagic = []byte("%PDF-")
+	zipMagic = []byte("PK\x03\x04")
+	oleMagic = []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")
+)
+
+var textExtensions = map[string]bool{
+	"txt":  true,
+	"md":   true,
+	"csv":  true,
+	"html": true,
+	"htm":  true,
+}
+
+// SniffContentType checks the first bytes of a file against what its
+// extension promises and returns the content type to store it with.
+// Extensions without a known signature are accepted as detected.
+func SniffContentType(ext string, head []byte) (string, error) {
+	ext = strings.TrimPrefix(strings.ToLower(ext), ".")
+	detected := http.DetectContentType(head)
+
+	switch {
+	case ext == "pdf":
+		if !bytes.Contains(head, pdfMagic) {
+			return "", mismatch(ext, detected)
+		}
+	case ext == "docx" || ext == "xlsx":
+		if !bytes.HasPrefix(head, zipMagic) {
+			return "", mismatch(ext, detected)
+		}
+	case ext == "doc":
+		if !bytes.HasPrefix(head, oleMagic) {
+			return "", mismatch(ext, detected)
+		}
+	case textExtensions[ext]:
+		if !isText(head, detected) {
+			return "", mismatch(ext, detected)
+		}
+	default:
+		return detected, nil
+	}
+
+	if contentType := mime.TypeByExtension("." + ext); contentType != "" {
+		return contentType, nil
+	}
+	return detected, nil
+}
+
+// isText accepts UTF-16 text by its byte order mark and anything else
+// http.DetectContentType does not consider binary.
+func isText(head []byte, detected string) bool {
+	if bytes.HasPrefix(head, []byte("\xFF\xFE")) || bytes.HasPrefix(head, []byte("\xFE\xFF")) {
+		return true
+	}
+	return strings.HasPrefix(detected, "text/")
+}
+
+func mismatch(ext, detected string) error {
+	return fmt.Errorf("%w: .%s file looks like %s", ErrContentMismatch, ext, strings.SplitN(detected, ";", 2)[0])
+}
@@ -4,20 +4,10 @@ import (
 	"log"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 func permissionSeeder(db *sqlx.DB) {
-	var count int
-	err := db.Get(&count, "SELECT COUNT(*) FROM permissions")
-	if err != nil {
-		log.Fatalf("Failed to check permissions table: %v", err)
-	}
-
-	if count > 0 {
-		log.Println("Permissions already seeded.")
-		return
-	}
-
 	permissions := []string{
 		"dashboard:read",
 		"dashboard:manager",
@@ -43,7 +33,10 @@ func permissionSeeder(db *sqlx.DB) {
 		"validation-history:delete",
 		"validation-history:manager",
 		"validation-history:master",
+		"guide:create",
 		"guide:read",
+		"guide:update",
+		"guide:delete",
 		"user-management:create",
 		"user-management:read",
 		"user-management:update",
@@ -63,6 +56,31 @@ func permissionSeeder(db *sqlx.DB) {
 		"role-management:manager",
 		"role-management:master",
 		"helpdesk:read",
+		"helpdesk:update",
+		"helpdesk:delete",
+		"audit-log:read",
+	}
+
+	var existing []string
+	if err := db.Select(&existing, "SELECT name FROM permissions"); err != nil {
+		log.Fatalf("Failed to check permissions table: %v", err)
+	}
+
+	existingMap := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		existingMap[name] = true
+	}
+
+	var missing []string
+	for _, p := range permissions {
+		if !existingMap[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	if len(missing) == 0 {
+		log.Println("Permissions already seeded.")
+		return
 	}
 
 	tx, err := db.Begin()
@@ -75,17 +93,29 @@ func permissionSeeder(db *sqlx.DB) {
 		log.Fatalf("Failed to prepare statement: %v", err)
 	}
 
-	for _, p := range permissions {
+	for _, p := range missing {
 		_, err = stmt.Exec(p)
 		if err != nil {
 			log.Fatalf("Failed to insert permission '%s': %v", p, err)
 		}
 	}
 
+	// Existing superadmin roles were seeded with the old permission list.
+	_, err = tx.Exec(`
+		UPDATE roles r
+		SET permissions = ARRAY(
+			SELECT DISTINCT unnest(r.permissions || ARRAY(SELECT id::text FROM permissions WHERE name = ANY($1)))
+		)
+		WHERE r.name = 'superadmin'
+	`, pq.Array(missing))
+	if err != nil {
+		log.Fatalf("Failed to grant new permissions to superadmin: %v", err)
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Fatalf("Failed to commit transaction: %v", err)
 	}
 
-	log.Printf("Seeded %d permissions successfully.", len(permissions))
+	log.Printf("Seeded %d permissions successfully.", len(missing))
 }
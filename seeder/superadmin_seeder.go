@@ -10,6 +10,22 @@ import (
 	"github.com/lib/pq"
 )
 
+// superadminPages are the pages of the superadmin team. Pages added here are
+// also added to the team of existing deployments on the next start.
+var superadminPages = pq.StringArray{
+	"dashboard",
+	"knowledge-base",
+	"document-management",
+	"public-service",
+	"validation-history",
+	"guide",
+	"user-management",
+	"team-management",
+	"role-management",
+	"helpdesk",
+	"audit-log",
+}
+
 func superadminSeeder(db *sqlx.DB) {
 	var userCount int
 	err := db.Get(&userCount, "SELECT COUNT(*) FROM users WHERE email = 'superadmin@superadmin.com'")
@@ -19,6 +35,7 @@ func superadminSeeder(db *sqlx.DB) {
 
 	if userCount > 0 {
 		log.Println("Superadmin user already exists.")
+		syncSuperadminPages(db)
 		return
 	}
 
@@ -28,25 +45,12 @@ func superadminSeeder(db *sqlx.DB) {
 	}
 	defer tx.Rollback()
 
-	teamPages := pq.StringArray{
-		"dashboard",
-		"knowledge-base",
-		"document-management",
-		"public-service",
-		"validation-history",
-		"guide",
-		"user-management",
-		"team-management",
-		"role-management",
-		"helpdesk",
-	}
-
 	var teamID int
 	err = tx.QueryRow(`
 		INSERT INTO teams (name, pages) 
 		VALUES ($1, $2) 
 		RETURNING id
-	`, "superadmin", teamPages).Scan(&teamID)
+	`, "superadmin", superadminPages).Scan(&teamID)
 	if err != nil {
 		log.Fatalf("Failed to create superadmin team: %v", err)
 	}
@@ -107,3 +111,21 @@ func superadminSeeder(db *sqlx.DB) {
 
 	log.Println("Superadmin seeder completed successfully.")
 }
+
+// syncSuperadminPages adds the pages missing from an existing superadmin team,
+// since its permissions only apply to the team's pages.
+func syncSuperadminPages(db *sqlx.DB) {
+	result, err := db.Exec(`
+		UPDATE teams
+		SET pages = COALESCE(pages, '{}') || ARRAY(SELECT unnest($1::text[]) EXCEPT SELECT unnest(pages))
+		WHERE name = 'superadmin'
+		AND NOT COALESCE(pages, '{}') @> $1::text[]
+	`, superadminPages)
+	if err != nil {
+		log.Fatalf("Failed to update superadmin team pages: %v", err)
+	}
+
+	if updated, err := result.RowsAffected(); err == nil && updated > 0 {
+		log.Println("Added missing pages to the superadmin team.")
+	}
+}
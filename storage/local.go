@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type LocalBackend struct {
+	root string
+}
+
+func NewLocalBackend(root string) *LocalBackend {
+	return &LocalBackend{root: root}
+}
+
+func (b *LocalBackend) Path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put writes to a temp file next to the target and renames it, so readers
+// never see a half-written file.
+func (b *LocalBackend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target, err := b.Path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to move file into place: %w", err)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	p, err := b.Path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return file, b.objectInfo(key, stat), nil
+}
+
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	p, err := b.Path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	p, err := b.Path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return b.objectInfo(key, stat), nil
+}
+
+func (b *LocalBackend) Stream(ctx context.Context, key string, w io.Writer) (*ObjectInfo, error) {
+	reader, info, err := b.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return nil, fmt.Errorf("failed to stream file: %w", err)
+	}
+	return info, nil
+}
+
+func (b *LocalBackend) objectInfo(key string, stat os.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  ContentTypeFor(key),
+		ETag:         fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+		LastModified: stat.ModTime(),
+	}
+}
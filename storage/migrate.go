@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MigrationResult struct {
+	Copied  int
+	Skipped int
+	Missing int
+	Failed  int
+}
+
+// MigrateFiles copies every file referenced by document_details and guides
+// from source to target. Files already present in target are left alone, so
+// the command can be re-run after a partial failure.
+func MigrateFiles(db *sqlx.DB, source, target Backend, deleteSource bool) (*MigrationResult, error) {
+	var keys []string
+	query := `
+		SELECT filename FROM document_details WHERE filename <> ''
+		UNION
+		SELECT filename FROM guides WHERE filename <> ''
+	`
+	if err := db.Select(&keys, query); err != nil {
+		return nil, fmt.Errorf("failed to list stored files: %w", err)
+	}
+
+	ctx := context.Background()
+	result := &MigrationResult{}
+
+	for _, key := range keys {
+		if _, err := target.Stat(ctx, key); err == nil {
+			result.Skipped++
+			continue
+		}
+
+		if err := copyObject(ctx, source, target, key); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				log.Printf("Warning: %s is referenced in the database but missing from the source storage", key)
+				result.Missing++
+				continue
+			}
+			log.Printf("Failed to migrate %s: %v", key, err)
+			result.Failed++
+			continue
+		}
+		result.Copied++
+
+		if deleteSource {
+			if err := source.Delete(ctx, key); err != nil {
+				log.Printf("Warning: Failed to delete %s from source storage: %v", key, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func copyObject(ctx context.Context, source, target Backend, key string) error {
+	reader, info, err := source.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return target.Put(ctx, key, reader, info.Size, info.ContentType)
+}
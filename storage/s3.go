@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dokuprime-be/config"
+)
+
+const (
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+)
+
+// S3Backend talks to any S3-compatible service (AWS S3, MinIO, ...) with
+// plain SigV4-signed requests.
+type S3Backend struct {
+	endpoint   *url.URL
+	cfg        *config.S3Config
+	httpClient *http.Client
+}
+
+func NewS3Backend(cfg *config.S3Config) (*S3Backend, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for the s3 storage backend")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 storage backend")
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+
+	return &S3Backend{
+		endpoint:   endpoint,
+		cfg:        cfg,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if contentType == "" {
+		contentType = ContentTypeFor(key)
+	}
+
+	// S3 does not accept chunked uploads, so an unknown size is buffered.
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read object content: %w", err)
+		}
+		r = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	req, err := b.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return b.responseError("put", key, resp)
+	}
+	return nil
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	req, err := b.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, b.responseError("get", key, resp)
+	}
+
+	return resp.Body, b.objectInfo(key, resp), nil
+}
+
+// GetRange reads length bytes of an object starting at offset.
+func (b *S3Backend) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	req, err := b.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		defer resp.Body.Close()
+		return nil, b.responseError("get range", key, resp)
+	}
+	return resp.Body, nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	req, err := b.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return b.responseError("delete", key, resp)
+	}
+	return nil
+}
+
+func (b *S3Backend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	req, err := b.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, b.responseError("stat", key, resp)
+	}
+	return b.objectInfo(key, resp), nil
+}
+
+func (b *S3Backend) Stream(ctx context.Context, key string, w io.Writer) (*ObjectInfo, error) {
+	reader, info, err := b.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return nil, fmt.Errorf("failed to stream object: %w", err)
+	}
+	return info, nil
+}
+
+func (b *S3Backend) objectKey(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if prefix := strings.Trim(b.cfg.Prefix, "/"); prefix != "" {
+		return prefix + "/" + cleaned, nil
+	}
+	return cleaned, nil
+}
+
+func (b *S3Backend) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	objectKey, err := b.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *b.endpoint
+	if b.cfg.UsePathStyle {
+		u.Path = b.endpoint.Path + "/" + b.cfg.Bucket + "/" + objectKey
+	} else {
+		u.Host = b.cfg.Bucket + "." + b.endpoint.Host
+		u.Path = b.endpoint.Path + "/" + objectKey
+	}
+	u.RawPath = s3EscapePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage request: %w", err)
+	}
+	return req, nil
+}
+
+func (b *S3Backend) do(req *http.Request) (*http.Response, error) {
+	b.sign(req, time.Now().UTC())
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	return resp, nil
+}
+
+func (b *S3Backend) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format(s3TimeFormat)
+	shortDate := now.Format(s3DateFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaderNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaderNames = append(signedHeaderNames, "content-type")
+	}
+	sort.Strings(signedHeaderNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaderNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := shortDate + "/" + b.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+b.cfg.SecretKey), shortDate)
+	signingKey = hmacSHA256(signingKey, b.cfg.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, b.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func (b *S3Backend) objectInfo(key string, resp *http.Response) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}
+	if info.Size < 0 {
+		info.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	}
+	if info.ContentType == "" {
+		info.ContentType = ContentTypeFor(key)
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = lastModified
+	}
+	return info
+}
+
+func (b *S3Backend) responseError(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage %s %s returned status %d: %s", op, key, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// s3EscapePath percent-encodes everything except RFC 3986 unreserved
+// characters and slashes, which is what SigV4 signs.
+func s3EscapePath(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
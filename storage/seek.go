@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// rangeChunkSize bounds each ranged read, so a client that stops early does
+// not leave the rest of a large object streaming from the backend.
+const rangeChunkSize = 8 << 20
+
+// rangeGetter is implemented by backends that can read part of an object
+// without downloading all of it.
+type rangeGetter interface {
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// OpenSeeker opens an object for random access, as byte range responses
+// need. Local files are seeked directly; remote objects are read with ranged
+// requests starting at the current position.
+func OpenSeeker(ctx context.Context, backend Backend, key string) (io.ReadSeekCloser, *ObjectInfo, error) {
+	if ranger, ok := backend.(rangeGetter); ok {
+		info, err := backend.Stat(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &rangeReader{ctx: ctx, ranger: ranger, key: key, size: info.Size}, info, nil
+	}
+
+	reader, info, err := backend.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if seeker, ok := reader.(io.ReadSeekCloser); ok {
+		return seeker, info, nil
+	}
+	reader.Close()
+	return nil, nil, fmt.Errorf("storage backend cannot seek in %s", key)
+}
+
+type rangeReader struct {
+	ctx    context.Context
+	ranger rangeGetter
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+	end    int64
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		length := min(int64(rangeChunkSize), r.size-r.offset)
+		body, err := r.ranger.GetRange(r.ctx, r.key, r.offset, length)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+		r.end = r.offset + length
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF || r.offset >= r.end {
+		r.body.Close()
+		r.body = nil
+		if r.offset < r.end {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.offset + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if target < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if target != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = target
+	return target, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dokuprime-be/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Backend stores uploaded files by key. Keys are the unique filenames saved in
+// document_details.filename and guides.filename.
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Stream(ctx context.Context, key string, w io.Writer) (*ObjectInfo, error)
+}
+
+// localPather is implemented by backends whose objects already live on the
+// local disk, so callers that need a real path can skip the temp copy.
+type localPather interface {
+	Path(key string) (string, error)
+}
+
+// InitBackend builds the backend selected by STORAGE_BACKEND (local or s3).
+func InitBackend() Backend {
+	backend, err := NewBackendFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage backend: %v", err)
+	}
+	return backend
+}
+
+func NewBackendFromEnv() (Backend, error) {
+	switch strings.ToLower(os.Getenv("STORAGE_BACKEND")) {
+	case "", "local":
+		return NewLocalBackend(config.GetUploadPath()), nil
+	case "s3":
+		backend, err := NewS3Backend(config.LoadS3Config())
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", os.Getenv("STORAGE_BACKEND"))
+	}
+}
+
+// LocalPath returns a path on the local disk holding the object. For remote
+// backends the object is downloaded to a temp file, removed by cleanup.
+func LocalPath(ctx context.Context, backend Backend, key string) (string, func(), error) {
+	if pather, ok := backend.(localPather); ok {
+		p, err := pather.Path(key)
+		if err != nil {
+			return "", func() {}, err
+		}
+		return p, func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "storage-*"+filepath.Ext(key))
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	if _, err := backend.Stream(ctx, key, tmp); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", func() {}, err
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// contentTypes covers the accepted upload formats, which the system MIME
+// table of a slim container often lacks.
+var contentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".txt":  "text/plain; charset=utf-8",
+	".md":   "text/markdown; charset=utf-8",
+	".csv":  "text/csv; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".htm":  "text/html; charset=utf-8",
+}
+
+func ContentTypeFor(key string) string {
+	ext := strings.ToLower(path.Ext(key))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// cleanKey keeps keys inside the bucket/root: "../x" and "/x" both become "x".
+func cleanKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(key)), "/")
+	if cleaned == "" || cleaned == "." {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return cleaned, nil
+}
@@ -17,10 +17,10 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
 
 	teamRoutes.Use(middleware.AuthMiddleware())
 	{
-		teamRoutes.POST("", handler.CreateTeam)
-		teamRoutes.GET("", handler.GetAll)
-		teamRoutes.GET("/:id", handler.GetTeamByID)
-		teamRoutes.PUT("/:id", handler.UpdateTeam)
-		teamRoutes.DELETE("/:id", handler.DeleteTeam)
+		teamRoutes.POST("", middleware.RequirePermission("team-management:create"), handler.CreateTeam)
+		teamRoutes.GET("", middleware.RequirePermission("team-management:read", "role-management:read", "user-management:read"), handler.GetAll)
+		teamRoutes.GET("/:id", middleware.RequirePermission("team-management:read", "role-management:read", "user-management:read"), handler.GetTeamByID)
+		teamRoutes.PUT("/:id", middleware.RequirePermission("team-management:update"), handler.UpdateTeam)
+		teamRoutes.DELETE("/:id", middleware.RequirePermission("team-management:delete"), handler.DeleteTeam)
 	}
 }
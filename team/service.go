@@ -2,7 +2,8 @@ package team
 
 
 import (
-	"dokuprime-be/permission" 
+	"dokuprime-be/middleware"
+	"dokuprime-be/permission"
 	"strconv"
 	"strings"
 )
@@ -59,7 +60,12 @@ func (s *TeamService) Update(team *Team) error {
 	}
 
 	
-	return s.repo.Update(team)
+	if err := s.repo.Update(team); err != nil {
+		return err
+	}
+
+	middleware.InvalidateTeamPermissions(team.ID)
+	return nil
 }
 
 
@@ -109,5 +115,6 @@ func (s *TeamService) findBannedPermissionIDs(allPerms []permission.Permission,
 	return bannedPermIDs
 }
 func (s *TeamService) Delete(id int) error {
+	middleware.InvalidateTeamPermissions(id)
 	return s.repo.Delete(id)
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package urlformat
+
+// URLFormat is the public URL template of the documents whose category is
+// Kode. The "default" kode applies to categories without a template of their
+// own.
+type URLFormat struct {
+	ID   int    `db:"id" json:"id"`
+	Kode string `db:"kode" json:"kode"`
+	URL  string `db:"url" json:"url"`
+}
+
+type URLFormatInput struct {
+	Kode string `json:"kode" binding:"required"`
+	URL  string `json:"url" binding:"required"`
+}
+
+// Values fills the placeholders of a template, keyed by placeholder name
+// without braces.
+type Values map[string]string
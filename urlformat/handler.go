@@ -0,0 +1,113 @@
+package urlformat
+
+import (
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type URLFormatHandler struct {
+	service *URLFormatService
+}
+
+func NewURLFormatHandler(service *URLFormatService) *URLFormatHandler {
+	return &URLFormatHandler{service: service}
+}
+
+func (h *URLFormatHandler) GetAll(c *gin.Context) {
+	formats, err := h.service.GetAll()
+	if err != nil {
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "URL formats fetched successfully", gin.H{
+		"formats":      formats,
+		"placeholders": Placeholders,
+	})
+}
+
+func (h *URLFormatHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid URL format ID")
+		return
+	}
+
+	format, err := h.service.GetByID(id)
+	if err != nil {
+		util.ErrorResponse(c, http.StatusNotFound, "URL format not found")
+		return
+	}
+
+	util.SuccessResponse(c, "URL format fetched successfully", format)
+}
+
+func (h *URLFormatHandler) Create(c *gin.Context) {
+	var input URLFormatInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	format, err := h.service.Create(input, audit.ActorFromContext(c))
+	if err != nil {
+		h.formatError(c, err)
+		return
+	}
+
+	util.CreatedResponse(c, "URL format created successfully", format)
+}
+
+func (h *URLFormatHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid URL format ID")
+		return
+	}
+
+	var input URLFormatInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	format, err := h.service.Update(id, input, audit.ActorFromContext(c))
+	if err != nil {
+		h.formatError(c, err)
+		return
+	}
+
+	util.SuccessResponse(c, "URL format updated successfully", format)
+}
+
+func (h *URLFormatHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid URL format ID")
+		return
+	}
+
+	if err := h.service.Delete(id, audit.ActorFromContext(c)); err != nil {
+		h.formatError(c, err)
+		return
+	}
+
+	util.SuccessResponse(c, "URL format deleted successfully", nil)
+}
+
+func (h *URLFormatHandler) formatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		util.ErrorResponse(c, http.StatusNotFound, "URL format not found")
+	case errors.Is(err, ErrInvalidURLFormat):
+		util.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
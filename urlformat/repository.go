@@ -0,0 +1,64 @@
+package urlformat
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type URLFormatRepository struct {
+	db *sqlx.DB
+}
+
+func NewURLFormatRepository(db *sqlx.DB) *URLFormatRepository {
+	return &URLFormatRepository{db: db}
+}
+
+func (r *URLFormatRepository) GetAll() ([]URLFormat, error) {
+	formats := []URLFormat{}
+	if err := r.db.Select(&formats, `SELECT id, kode, url FROM url_format ORDER BY kode`); err != nil {
+		return nil, err
+	}
+	return formats, nil
+}
+
+func (r *URLFormatRepository) GetByID(id int) (*URLFormat, error) {
+	var format URLFormat
+	if err := r.db.Get(&format, `SELECT id, kode, url FROM url_format WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &format, nil
+}
+
+func (r *URLFormatRepository) KodeTaken(kode string, excludeID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM url_format WHERE kode = $1 AND id <> $2)`, kode, excludeID)
+	return exists, err
+}
+
+func (r *URLFormatRepository) Create(format *URLFormat) error {
+	return r.db.QueryRow(`INSERT INTO url_format (kode, url) VALUES ($1, $2) RETURNING id`, format.Kode, format.URL).
+		Scan(&format.ID)
+}
+
+func (r *URLFormatRepository) Update(format *URLFormat) error {
+	result, err := r.db.Exec(`UPDATE url_format SET kode = $1, url = $2 WHERE id = $3`, format.Kode, format.URL, format.ID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *URLFormatRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM url_format WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package urlformat
+
+import (
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	permURLFormatRead   = "document-management:read"
+	permURLFormatManage = "document-management:master"
+)
+
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
+	repo := NewURLFormatRepository(db)
+	service := NewURLFormatService(repo, audit.NewAuditService(audit.NewAuditRepository(db)))
+	handler := NewURLFormatHandler(service)
+
+	formatGroup := r.Group("/api/url-formats")
+	formatGroup.Use(middleware.AuthMiddleware())
+	{
+		formatGroup.GET("", middleware.RequirePermission(permURLFormatRead), handler.GetAll)
+		formatGroup.GET("/:id", middleware.RequirePermission(permURLFormatRead), handler.GetByID)
+		formatGroup.POST("", middleware.RequirePermission(permURLFormatManage), handler.Create)
+		formatGroup.PUT("/:id", middleware.RequirePermission(permURLFormatManage), handler.Update)
+		formatGroup.DELETE("/:id", middleware.RequirePermission(permURLFormatManage), handler.Delete)
+	}
+}
@@ -0,0 +1,162 @@
+package urlformat
+
+import (
+	"dokuprime-be/audit"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultKode is the template used for categories without their own.
+const DefaultKode = "default"
+
+const maxKodeLength = 50
+
+var ErrInvalidURLFormat = errors.New("invalid url format")
+
+// Placeholders are the names a template may use as {name}.
+var Placeholders = []string{"document_id", "detail_id", "document_name", "filename", "category", "version"}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+type URLFormatService struct {
+	repo  *URLFormatRepository
+	audit *audit.AuditService
+}
+
+func NewURLFormatService(repo *URLFormatRepository, auditService *audit.AuditService) *URLFormatService {
+	return &URLFormatService{repo: repo, audit: auditService}
+}
+
+func (s *URLFormatService) GetAll() ([]URLFormat, error) {
+	return s.repo.GetAll()
+}
+
+func (s *URLFormatService) GetByID(id int) (*URLFormat, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *URLFormatService) Create(input URLFormatInput, actor audit.Actor) (*URLFormat, error) {
+	format, err := build(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkKode(format.Kode, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(format); err != nil {
+		return nil, fmt.Errorf("failed to create url format: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionURLFormatCreate, audit.EntityURLFormat, format.ID, nil, format)
+	return format, nil
+}
+
+func (s *URLFormatService) Update(id int, input URLFormatInput, actor audit.Actor) (*URLFormat, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := build(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkKode(format.Kode, id); err != nil {
+		return nil, err
+	}
+
+	format.ID = id
+	if err := s.repo.Update(format); err != nil {
+		return nil, fmt.Errorf("failed to update url format: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionURLFormatUpdate, audit.EntityURLFormat, id, existing, format)
+	return format, nil
+}
+
+func (s *URLFormatService) Delete(id int, actor audit.Actor) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionURLFormatDelete, audit.EntityURLFormat, id, existing, nil)
+	return nil
+}
+
+// Templates returns every template keyed by kode.
+func (s *URLFormatService) Templates() (map[string]string, error) {
+	formats, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]string, len(formats))
+	for _, format := range formats {
+		templates[format.Kode] = format.URL
+	}
+	return templates, nil
+}
+
+func (s *URLFormatService) checkKode(kode string, excludeID int) error {
+	taken, err := s.repo.KodeTaken(kode, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to check kode: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: a url format with kode %q already exists", ErrInvalidURLFormat, kode)
+	}
+	return nil
+}
+
+func build(input URLFormatInput) (*URLFormat, error) {
+	format := &URLFormat{
+		Kode: strings.TrimSpace(input.Kode),
+		URL:  strings.TrimSpace(input.URL),
+	}
+
+	if format.Kode == "" || len(format.Kode) > maxKodeLength {
+		return nil, fmt.Errorf("%w: kode must be 1 to %d characters", ErrInvalidURLFormat, maxKodeLength)
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(format.URL, -1) {
+		if !isPlaceholder(match[1]) {
+			return nil, fmt.Errorf("%w: unknown placeholder {%s}, use one of {%s}",
+				ErrInvalidURLFormat, match[1], strings.Join(Placeholders, "}, {"))
+		}
+	}
+
+	sample := Values{}
+	for _, name := range Placeholders {
+		sample[name] = "1"
+	}
+	parsed, err := url.Parse(Render(format.URL, sample))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidURLFormat)
+	}
+	return format, nil
+}
+
+func isPlaceholder(name string) bool {
+	for _, placeholder := range Placeholders {
+		if name == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+// Render fills the placeholders of template with values, escaped for use in
+// a URL path or query. Placeholders without a value become empty.
+func Render(template string, values Values) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		return strings.ReplaceAll(url.QueryEscape(values[match[1:len(match)-1]]), "+", "%20")
+	})
+}
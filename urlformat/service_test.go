@@ -0,0 +1,92 @@
+package urlformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		values   Values
+		want     string
+	}{
+		{name: "no placeholders", template: "https://example.com/docs", want: "https://example.com/docs"},
+		{
+			name:     "path and query",
+			template: "https://example.com/{category}/{document_id}?v={version}",
+			values:   Values{"category": "hukum", "document_id": "12", "version": "3"},
+			want:     "https://example.com/hukum/12?v=3",
+		},
+		{
+			name:     "escaped values",
+			template: "https://example.com/view/{document_name}",
+			values:   Values{"document_name": "UU 1/2023 & PP?#"},
+			want:     "https://example.com/view/UU%201%2F2023%20%26%20PP%3F%23",
+		},
+		{name: "missing value", template: "https://example.com/{filename}/x", want: "https://example.com//x"},
+		{name: "repeated placeholder", template: "{document_id}-{document_id}", values: Values{"document_id": "7"}, want: "7-7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.template, tt.values); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    URLFormatInput
+		wantKode string
+		wantURL  string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			input:    URLFormatInput{Kode: " hukum ", URL: " https://jdih.example.go.id/{document_id} "},
+			wantKode: "hukum",
+			wantURL:  "https://jdih.example.go.id/{document_id}",
+		},
+		{
+			name:     "placeholder in host",
+			input:    URLFormatInput{Kode: DefaultKode, URL: "http://{category}.example.com/{filename}"},
+			wantKode: DefaultKode,
+			wantURL:  "http://{category}.example.com/{filename}",
+		},
+		{name: "empty kode", input: URLFormatInput{Kode: "  ", URL: "https://example.com"}, wantErr: "kode"},
+		{name: "long kode", input: URLFormatInput{Kode: strings.Repeat("k", maxKodeLength+1), URL: "https://example.com"}, wantErr: "kode"},
+		{name: "unknown placeholder", input: URLFormatInput{Kode: "x", URL: "https://example.com/{id}"}, wantErr: "unknown placeholder {id}"},
+		{name: "empty placeholder", input: URLFormatInput{Kode: "x", URL: "https://example.com/{}"}, wantErr: "unknown placeholder {}"},
+		{name: "relative url", input: URLFormatInput{Kode: "x", URL: "/docs/{document_id}"}, wantErr: "absolute"},
+		{name: "other scheme", input: URLFormatInput{Kode: "x", URL: "javascript:alert({document_id})"}, wantErr: "absolute"},
+		{name: "placeholder as host", input: URLFormatInput{Kode: "x", URL: "https://{category}"}, wantKode: "x", wantURL: "https://{category}"},
+		{name: "no host", input: URLFormatInput{Kode: "x", URL: "https:///{document_id}"}, wantErr: "absolute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := build(tt.input)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("build() = %+v, want error", got)
+				}
+				if !errors.Is(err, ErrInvalidURLFormat) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("build() error = %v, want ErrInvalidURLFormat mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			if got.Kode != tt.wantKode || got.URL != tt.wantURL {
+				t.Errorf("build() = %+v, want kode %q url %q", got, tt.wantKode, tt.wantURL)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"dokuprime-be/audit"
 	"dokuprime-be/middleware"
 	"dokuprime-be/permission"
 	"dokuprime-be/role"
@@ -16,7 +17,7 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
 	repoRole := role.NewRoleRepository(db)
 	repoTeam := team.NewTeamRepository(db)
 	repoPermission := permission.NewPermissionRepository(db)
-	serviceRole := role.NewRoleService(repoRole, repoTeam, repoPermission)
+	serviceRole := role.NewRoleService(repoRole, repoTeam, repoPermission, audit.NewAuditService(audit.NewAuditRepository(db)))
 	service := NewUserService(repo, redisClient, serviceRole)
 	handler := NewUserHandler(service)
 
@@ -32,11 +33,11 @@ func RegisterRoutes(r *gin.Engine, db *sqlx.DB, redisClient *redis.Client) {
 	userGroup := r.Group("/api/users")
 	userGroup.Use(middleware.AuthMiddleware())
 	{
-		userGroup.POST("/", handler.CreateUser)
-		userGroup.GET("/", handler.GetUsers)
-		userGroup.GET("/:id", handler.GetUserByID)
-		userGroup.PUT("/:id", handler.UpdateUser)
-		userGroup.DELETE("/:id", handler.DeleteUser)
+		userGroup.POST("/", middleware.RequirePermission("user-management:create"), handler.CreateUser)
+		userGroup.GET("/", middleware.RequirePermission("user-management:read"), handler.GetUsers)
+		userGroup.GET("/:id", middleware.RequirePermission("user-management:read"), handler.GetUserByID)
+		userGroup.PUT("/:id", middleware.RequirePermission("user-management:update"), handler.UpdateUser)
+		userGroup.DELETE("/:id", middleware.RequirePermission("user-management:delete"), handler.DeleteUser)
 		userGroup.GET("/me", handler.GetCurrentUser)
 	}
 }
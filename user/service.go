@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"dokuprime-be/auth"
+	"dokuprime-be/middleware"
 	"dokuprime-be/role"
 	"dokuprime-be/util"
 
@@ -135,11 +136,23 @@ func (s *UserService) UpdateUser(id int, user *User) (*User, error) {
 		}
 		user.Password = hashedPassword
 	}
-	return s.repo.UpdateUser(id, user)
+
+	updatedUser, err := s.repo.UpdateUser(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.InvalidateUserPermissions(int64(id))
+	return updatedUser, nil
 }
 
 func (s *UserService) DeleteUser(id int) error {
-	return s.repo.DeleteUser(id)
+	if err := s.repo.DeleteUser(id); err != nil {
+		return err
+	}
+
+	middleware.InvalidateUserPermissions(int64(id))
+	return nil
 }
 
 func (s *UserService) Login(email, password string) (*LoginResponse, error) {
@@ -0,0 +1,180 @@
+package util
+
+import "strings"
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// Above this many changed lines the diff stops looking for a minimal script
+// and reports the differing middle section as replaced.
+const maxDiffEdits = 1000
+
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+type DiffStats struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// SplitLines splits text into lines, accepting both \n and \r\n endings.
+func SplitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// DiffLines returns a line diff between two texts using the Myers algorithm.
+func DiffLines(oldLines, newLines []string) []DiffLine {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	a := oldLines[prefix : len(oldLines)-suffix]
+	b := newLines[prefix : len(newLines)-suffix]
+
+	ops := make([]string, 0, len(oldLines)+len(newLines))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	ops = append(ops, myersScript(a, b)...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+
+	result := make([]DiffLine, 0, len(ops))
+	oldIdx, newIdx := 0, 0
+	for _, op := range ops {
+		switch op {
+		case DiffEqual:
+			result = append(result, DiffLine{Op: op, OldLine: oldIdx + 1, NewLine: newIdx + 1, Text: oldLines[oldIdx]})
+			oldIdx++
+			newIdx++
+		case DiffDelete:
+			result = append(result, DiffLine{Op: op, OldLine: oldIdx + 1, Text: oldLines[oldIdx]})
+			oldIdx++
+		case DiffInsert:
+			result = append(result, DiffLine{Op: op, NewLine: newIdx + 1, Text: newLines[newIdx]})
+			newIdx++
+		}
+	}
+	return result
+}
+
+func SummarizeDiff(lines []DiffLine) DiffStats {
+	var stats DiffStats
+	for _, line := range lines {
+		switch line.Op {
+		case DiffEqual:
+			stats.Unchanged++
+		case DiffInsert:
+			stats.Added++
+		case DiffDelete:
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// myersScript returns the edit operations turning a into b. Only the part of
+// the V array reachable in each round is kept for backtracking.
+func myersScript(a, b []string) []string {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	limit := min(n+m, maxDiffEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackScript(trace, n, m)
+			}
+		}
+	}
+
+	ops := make([]string, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, DiffDelete)
+	}
+	for i := 0; i < m; i++ {
+		ops = append(ops, DiffInsert)
+	}
+	return ops
+}
+
+func backtrackScript(trace [][]int, n, m int) []string {
+	var ops []string
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		window := trace[d]
+		at := func(k int) int { return window[k+d] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, DiffInsert)
+		} else {
+			ops = append(ops, DiffDelete)
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, DiffEqual)
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "only newline", text: "\n", want: nil},
+		{name: "single line", text: "a", want: []string{"a"}},
+		{name: "trailing newline", text: "a\nb\n", want: []string{"a", "b"}},
+		{name: "crlf", text: "a\r\nb\r\n", want: []string{"a", "b"}},
+		{name: "blank lines kept", text: "a\n\nb", want: []string{"a", "", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitLines(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitLines(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// diffScript renders a diff compactly: " a" for equal, "-a" for deleted and
+// "+a" for inserted lines.
+func diffScript(lines []DiffLine) string {
+	var parts []string
+	for _, line := range lines {
+		prefix := " "
+		switch line.Op {
+		case DiffInsert:
+			prefix = "+"
+		case DiffDelete:
+			prefix = "-"
+		}
+		parts = append(parts, prefix+line.Text)
+	}
+	return strings.Join(parts, ",")
+}
+
+func numbered(prefix string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return lines
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  []string
+		want      string
+		wantStats DiffStats
+	}{
+		{name: "both empty", want: "", wantStats: DiffStats{}},
+		{name: "identical", old: []string{"a", "b"}, new: []string{"a", "b"}, want: " a, b", wantStats: DiffStats{Unchanged: 2}},
+		{name: "all inserted", new: []string{"a", "b"}, want: "+a,+b", wantStats: DiffStats{Added: 2}},
+		{name: "all deleted", old: []string{"a", "b"}, want: "-a,-b", wantStats: DiffStats{Removed: 2}},
+		{name: "insert in middle", old: []string{"a", "c"}, new: []string{"a", "b", "c"}, want: " a,+b, c", wantStats: DiffStats{Added: 1, Unchanged: 2}},
+		{name: "delete in middle", old: []string{"a", "b", "c"}, new: []string{"a", "c"}, want: " a,-b, c", wantStats: DiffStats{Removed: 1, Unchanged: 2}},
+		{name: "replace", old: []string{"a", "b", "c"}, new: []string{"a", "x", "c"}, want: " a,-b,+x, c", wantStats: DiffStats{Added: 1, Removed: 1, Unchanged: 2}},
+		{
+			name:      "moved line",
+			old:       []string{"a", "b", "c", "d"},
+			new:       []string{"b", "c", "d", "a"},
+			want:      "-a, b, c, d,+a",
+			wantStats: DiffStats{Added: 1, Removed: 1, Unchanged: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := DiffLines(tt.old, tt.new)
+			if got := diffScript(lines); got != tt.want {
+				t.Errorf("DiffLines() = %q, want %q", got, tt.want)
+			}
+			if got := SummarizeDiff(lines); got != tt.wantStats {
+				t.Errorf("SummarizeDiff() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+// TestDiffLinesReconstructs checks that line numbers point into the inputs
+// and that both texts can be rebuilt from the diff, including the fallback
+// for diffs larger than maxDiffEdits.
+func TestDiffLinesReconstructs(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []string
+	}{
+		{name: "interleaved", old: []string{"a", "b", "c", "d", "e"}, new: []string{"x", "b", "y", "d", "z", "e"}},
+		{name: "shared prefix and suffix", old: []string{"h", "a", "b", "t"}, new: []string{"h", "b", "a", "t"}},
+		{name: "duplicate lines", old: []string{"a", "a", "b", "a"}, new: []string{"a", "b", "a", "a"}},
+		{name: "past edit limit", old: numbered("old", maxDiffEdits), new: numbered("new", maxDiffEdits)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var oldText, newText []string
+			for _, line := range DiffLines(tt.old, tt.new) {
+				if line.Op != DiffInsert {
+					if line.OldLine != len(oldText)+1 || tt.old[line.OldLine-1] != line.Text {
+						t.Fatalf("old line %d does not match %+v", len(oldText)+1, line)
+					}
+					oldText = append(oldText, line.Text)
+				}
+				if line.Op != DiffDelete {
+					if line.NewLine != len(newText)+1 || tt.new[line.NewLine-1] != line.Text {
+						t.Fatalf("new line %d does not match %+v", len(newText)+1, line)
+					}
+					newText = append(newText, line.Text)
+				}
+			}
+			if !reflect.DeepEqual(oldText, tt.old) || !reflect.DeepEqual(newText, tt.new) {
+				t.Errorf("diff does not rebuild the inputs")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServedFile describes a stored file for ServeFile. Name is the filename the
+// client sees; Disposition is "inline" or "attachment".
+type ServedFile struct {
+	Name         string
+	Disposition  string
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// ServeFile writes content with byte range and conditional request support
+// (Range, If-Range, If-None-Match, If-Modified-Since), answering with 206,
+// 304 or 416 where they apply.
+func ServeFile(ctx *gin.Context, content io.ReadSeeker, file ServedFile) {
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", file.ContentType)
+	header.Set("Content-Disposition", ContentDisposition(file.Disposition, file.Name))
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("X-Content-Type-Options", "nosniff")
+	if strings.HasPrefix(file.ContentType, "text/html") {
+		// Uploaded HTML must not run scripts on the API origin.
+		header.Set("Content-Security-Policy", "sandbox")
+	}
+	if file.ETag != "" {
+		header.Set("ETag", file.ETag)
+	}
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", file.LastModified, content)
+}
+
+// ContentDisposition builds an RFC 6266 header value. Names that are not
+// plain ASCII get an ASCII fallback in filename and the exact name in
+// filename* (RFC 8187).
+func ContentDisposition(disposition, name string) string {
+	fallback := asciiFilename(name)
+	value := fmt.Sprintf(`%s; filename="%s"`, disposition, fallback)
+	if fallback != name {
+		value += "; filename*=UTF-8''" + encodeExtValue(name)
+	}
+	return value
+}
+
+func asciiFilename(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r < 0x20 || r >= 0x7f:
+			sb.WriteByte('_')
+		case r == '"' || r == '\\' || r == '/':
+			sb.WriteByte('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 {
+		return "download"
+	}
+	return sb.String()
+}
+
+// encodeExtValue percent-encodes everything outside the RFC 8187 attr-char
+// set.
+func encodeExtValue(s string) string {
+	const attrChars = "!#$&+-.^_`|~"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || strings.IndexByte(attrChars, c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
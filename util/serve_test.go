@@ -0,0 +1,151 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		filename    string
+		want        string
+	}{
+		{name: "ascii", disposition: "inline", filename: "report.pdf", want: `inline; filename="report.pdf"`},
+		{name: "spaces kept", disposition: "attachment", filename: "annual report.pdf", want: `attachment; filename="annual report.pdf"`},
+		{
+			name:        "quotes and slashes",
+			disposition: "attachment",
+			filename:    `a"b\c/d.pdf`,
+			want:        `attachment; filename="a_b_c_d.pdf"; filename*=UTF-8''a%22b%5Cc%2Fd.pdf`,
+		},
+		{
+			name:        "non-ascii",
+			disposition: "inline",
+			filename:    "Peraturan Ü.pdf",
+			want:        `inline; filename="Peraturan _.pdf"; filename*=UTF-8''Peraturan%20%C3%9C.pdf`,
+		},
+		{
+			name:        "header injection",
+			disposition: "inline",
+			filename:    "a\r\nSet-Cookie: x.pdf",
+			want:        `inline; filename="a__Set-Cookie: x.pdf"; filename*=UTF-8''a%0D%0ASet-Cookie%3A%20x.pdf`,
+		},
+		{name: "empty", disposition: "attachment", filename: "", want: `attachment; filename="download"; filename*=UTF-8''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentDisposition(tt.disposition, tt.filename); got != tt.want {
+				t.Errorf("ContentDisposition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	file := ServedFile{
+		Name:         "doc.txt",
+		Disposition:  "inline",
+		ContentType:  "text/plain",
+		ETag:         `"abc"`,
+		LastModified: modified,
+	}
+
+	tests := []struct {
+		name        string
+		file        ServedFile
+		headers     map[string]string
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "full content",
+			file:       file,
+			wantStatus: http.StatusOK,
+			wantBody:   "0123456789",
+			wantHeaders: map[string]string{
+				"Content-Disposition":     `inline; filename="doc.txt"`,
+				"ETag":                    `"abc"`,
+				"X-Content-Type-Options":  "nosniff",
+				"Content-Security-Policy": "",
+			},
+		},
+		{
+			name:        "byte range",
+			file:        file,
+			headers:     map[string]string{"Range": "bytes=2-4"},
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    "234",
+			wantHeaders: map[string]string{"Content-Range": "bytes 2-4/10"},
+		},
+		{
+			name:       "unsatisfiable range",
+			file:       file,
+			headers:    map[string]string{"Range": "bytes=20-30"},
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:       "if-range with stale etag",
+			file:       file,
+			headers:    map[string]string{"Range": "bytes=2-4", "If-Range": `"old"`},
+			wantStatus: http.StatusOK,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "matching etag",
+			file:       file,
+			headers:    map[string]string{"If-None-Match": `"abc"`},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "not modified since",
+			file:       ServedFile{Name: "doc.txt", Disposition: "inline", ContentType: "text/plain", LastModified: modified},
+			headers:    map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:        "html is sandboxed",
+			file:        ServedFile{Name: "page.html", Disposition: "inline", ContentType: "text/html; charset=utf-8"},
+			wantStatus:  http.StatusOK,
+			wantBody:    "0123456789",
+			wantHeaders: map[string]string{"Content-Security-Policy": "sandbox"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/file", nil)
+			for key, value := range tt.headers {
+				ctx.Request.Header.Set(key, value)
+			}
+
+			ServeFile(ctx, strings.NewReader("0123456789"), tt.file)
+			// gin writes a bodiless status once the handlers return.
+			ctx.Writer.WriteHeaderNow()
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && recorder.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), tt.wantBody)
+			}
+			for key, want := range tt.wantHeaders {
+				if got := recorder.Header().Get(key); got != want {
+					t.Errorf("header %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package util
+
+import (
+	"os"
+	"strings"
+)
+
+// PublicBaseURL is the externally visible origin of the API, taken from
+// PUBLIC_BASE_URL without a trailing slash. Links built on it never trust the
+// request's Host header; when it is unset they stay relative.
+func PublicBaseURL() string {
+	return strings.TrimRight(strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")), "/")
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import "time"
+
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+)
+
+// Workflow is an ordered list of review steps applied to documents of a
+// category and/or team. A nil Category or Team matches any value.
+type Workflow struct {
+	ID          int       `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Category    *string   `db:"category" json:"category"`
+	Team        *string   `db:"team" json:"team"`
+	AutoApprove bool      `db:"auto_approve" json:"auto_approve"`
+	IsActive    bool      `db:"is_active" json:"is_active"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	Steps       []Step    `db:"-" json:"steps"`
+}
+
+// Step is one review stage. The reviewer needs RequiredPermission and/or
+// RequiredRoleID when they are set.
+type Step struct {
+	ID                 int     `db:"id" json:"id"`
+	WorkflowID         int     `db:"workflow_id" json:"workflow_id"`
+	StepOrder          int     `db:"step_order" json:"step_order"`
+	Name               string  `db:"name" json:"name"`
+	RequiredPermission *string `db:"required_permission" json:"required_permission"`
+	RequiredRoleID     *int    `db:"required_role_id" json:"required_role_id"`
+}
+
+type Decision struct {
+	ID          int64     `db:"id" json:"id"`
+	DetailID    int       `db:"detail_id" json:"detail_id"`
+	WorkflowID  *int      `db:"workflow_id" json:"workflow_id"`
+	StepOrder   int       `db:"step_order" json:"step_order"`
+	StepName    *string   `db:"step_name" json:"step_name"`
+	Decision    string    `db:"decision" json:"decision"`
+	ActorUserID *int64    `db:"actor_user_id" json:"actor_user_id"`
+	ActorName   *string   `db:"actor_name" json:"actor_name"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+type WorkflowInput struct {
+	Name        string      `json:"name" binding:"required"`
+	Category    *string     `json:"category"`
+	Team        *string     `json:"team"`
+	AutoApprove bool        `json:"auto_approve"`
+	IsActive    *bool       `json:"is_active"`
+	Steps       []StepInput `json:"steps"`
+}
+
+type StepInput struct {
+	Name               string  `json:"name" binding:"required"`
+	RequiredPermission *string `json:"required_permission"`
+	RequiredRoleID     *int    `json:"required_role_id"`
+}
+
+// Progress describes where a document version is in its workflow.
+type Progress struct {
+	DetailID    int        `json:"detail_id"`
+	Workflow    *Workflow  `json:"workflow"`
+	CurrentStep *int       `json:"current_step"`
+	Decisions   []Decision `json:"decisions"`
+}
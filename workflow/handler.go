@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/util"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkflowHandler struct {
+	service *WorkflowService
+}
+
+func NewWorkflowHandler(service *WorkflowService) *WorkflowHandler {
+	return &WorkflowHandler{service: service}
+}
+
+func (h *WorkflowHandler) GetAll(c *gin.Context) {
+	workflows, err := h.service.GetAll()
+	if err != nil {
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Approval workflows fetched successfully", workflows)
+}
+
+func (h *WorkflowHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	workflow, err := h.service.GetByID(id)
+	if err != nil {
+		util.ErrorResponse(c, http.StatusNotFound, "Approval workflow not found")
+		return
+	}
+
+	util.SuccessResponse(c, "Approval workflow fetched successfully", workflow)
+}
+
+func (h *WorkflowHandler) Create(c *gin.Context) {
+	var input WorkflowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	workflow, err := h.service.Create(input, audit.ActorFromContext(c))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.CreatedResponse(c, "Approval workflow created successfully", workflow)
+}
+
+func (h *WorkflowHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	var input WorkflowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	workflow, err := h.service.Update(id, input, audit.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(c, http.StatusNotFound, "Approval workflow not found")
+			return
+		}
+		util.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Approval workflow updated successfully", workflow)
+}
+
+func (h *WorkflowHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.ErrorResponse(c, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	if err := h.service.Delete(id, audit.ActorFromContext(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.ErrorResponse(c, http.StatusNotFound, "Approval workflow not found")
+			return
+		}
+		util.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, "Approval workflow deleted successfully", nil)
+}
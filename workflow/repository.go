@@ -0,0 +1,229 @@
+package workflow
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type WorkflowRepository struct {
+	db *sqlx.DB
+}
+
+func NewWorkflowRepository(db *sqlx.DB) *WorkflowRepository {
+	return &WorkflowRepository{db: db}
+}
+
+const workflowColumns = `id, name, category, team, auto_approve, is_active, created_at, updated_at`
+
+func (r *WorkflowRepository) GetAll() ([]Workflow, error) {
+	workflows := []Workflow{}
+	if err := r.db.Select(&workflows, `SELECT `+workflowColumns+` FROM approval_workflows ORDER BY id`); err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	if err := r.db.Select(&steps, `SELECT * FROM approval_workflow_steps ORDER BY workflow_id, step_order`); err != nil {
+		return nil, err
+	}
+
+	byWorkflow := make(map[int][]Step)
+	for _, step := range steps {
+		byWorkflow[step.WorkflowID] = append(byWorkflow[step.WorkflowID], step)
+	}
+	for i := range workflows {
+		workflows[i].Steps = byWorkflow[workflows[i].ID]
+		if workflows[i].Steps == nil {
+			workflows[i].Steps = []Step{}
+		}
+	}
+	return workflows, nil
+}
+
+func (r *WorkflowRepository) GetByID(id int) (*Workflow, error) {
+	var workflow Workflow
+	if err := r.db.Get(&workflow, `SELECT `+workflowColumns+` FROM approval_workflows WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	steps, err := r.GetSteps(id)
+	if err != nil {
+		return nil, err
+	}
+	workflow.Steps = steps
+	return &workflow, nil
+}
+
+func (r *WorkflowRepository) GetSteps(workflowID int) ([]Step, error) {
+	steps := []Step{}
+	query := `SELECT * FROM approval_workflow_steps WHERE workflow_id = $1 ORDER BY step_order`
+	if err := r.db.Select(&steps, query, workflowID); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// FindForScope returns the active workflow that best matches a category and
+// team: category matches win over team matches, which win over catch-alls.
+func (r *WorkflowRepository) FindForScope(category, team string) (*Workflow, error) {
+	var workflow Workflow
+	query := `
+		SELECT ` + workflowColumns + `
+		FROM approval_workflows
+		WHERE is_active = true
+		AND (category IS NULL OR category = $1)
+		AND (team IS NULL OR team = $2)
+		ORDER BY (category IS NOT NULL) DESC, (team IS NOT NULL) DESC, id
+		LIMIT 1
+	`
+	if err := r.db.Get(&workflow, query, category, team); err != nil {
+		return nil, err
+	}
+
+	steps, err := r.GetSteps(workflow.ID)
+	if err != nil {
+		return nil, err
+	}
+	workflow.Steps = steps
+	return &workflow, nil
+}
+
+// ScopeTaken reports whether another active workflow already covers exactly
+// this category and team.
+func (r *WorkflowRepository) ScopeTaken(category, team *string, excludeID int) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM approval_workflows
+			WHERE is_active = true
+			AND category IS NOT DISTINCT FROM $1
+			AND team IS NOT DISTINCT FROM $2
+			AND id <> $3
+		)
+	`
+	err := r.db.Get(&exists, query, category, team, excludeID)
+	return exists, err
+}
+
+func (r *WorkflowRepository) Create(workflow *Workflow) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO approval_workflows (name, category, team, auto_approve, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, workflow.Name, workflow.Category, workflow.Team, workflow.AutoApprove, workflow.IsActive).
+		Scan(&workflow.ID, &workflow.CreatedAt, &workflow.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertSteps(tx, workflow); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *WorkflowRepository) Update(workflow *Workflow) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE approval_workflows
+		SET name = $1, category = $2, team = $3, auto_approve = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(query, workflow.Name, workflow.Category, workflow.Team, workflow.AutoApprove, workflow.IsActive, workflow.ID).
+		Scan(&workflow.CreatedAt, &workflow.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM approval_workflow_steps WHERE workflow_id = $1`, workflow.ID); err != nil {
+		return err
+	}
+	if err := insertSteps(tx, workflow); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSteps(tx *sqlx.Tx, workflow *Workflow) error {
+	query := `
+		INSERT INTO approval_workflow_steps (workflow_id, step_order, name, required_permission, required_role_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	for i := range workflow.Steps {
+		step := &workflow.Steps[i]
+		step.WorkflowID = workflow.ID
+		step.StepOrder = i + 1
+		if err := tx.QueryRow(query, step.WorkflowID, step.StepOrder, step.Name, step.RequiredPermission, step.RequiredRoleID).Scan(&step.ID); err != nil {
+			return fmt.Errorf("failed to save step %d: %w", step.StepOrder, err)
+		}
+	}
+	return nil
+}
+
+func (r *WorkflowRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM approval_workflows WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *WorkflowRepository) RecordDecision(decision *Decision) error {
+	query := `
+		INSERT INTO document_approval_decisions (detail_id, workflow_id, step_order, step_name, decision, actor_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		decision.DetailID,
+		decision.WorkflowID,
+		decision.StepOrder,
+		decision.StepName,
+		decision.Decision,
+		decision.ActorUserID,
+	).Scan(&decision.ID, &decision.CreatedAt)
+}
+
+func (r *WorkflowRepository) GetDecisions(detailID int) ([]Decision, error) {
+	decisions := []Decision{}
+	query := `
+		SELECT
+			d.id, d.detail_id, d.workflow_id, d.step_order, d.step_name, d.decision,
+			d.actor_user_id, u.name AS actor_name, d.created_at
+		FROM document_approval_decisions d
+		LEFT JOIN users u ON u.id = d.actor_user_id
+		WHERE d.detail_id = $1
+		ORDER BY d.created_at, d.id
+	`
+	if err := r.db.Select(&decisions, query, detailID); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+func (r *WorkflowRepository) GetUserRoleID(userID int64) (*int, error) {
+	var roleID *int
+	if err := r.db.Get(&roleID, `SELECT role_id FROM users WHERE id = $1`, userID); err != nil {
+		return nil, err
+	}
+	return roleID, nil
+}
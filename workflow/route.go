@@ -0,0 +1,30 @@
+package workflow
+
+import (
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	permWorkflowRead   = "document-management:read"
+	permWorkflowManage = "document-management:master"
+)
+
+func RegisterRoutes(r *gin.Engine, db *sqlx.DB) {
+	repo := NewWorkflowRepository(db)
+	service := NewWorkflowService(repo, audit.NewAuditService(audit.NewAuditRepository(db)))
+	handler := NewWorkflowHandler(service)
+
+	workflowGroup := r.Group("/api/approval-workflows")
+	workflowGroup.Use(middleware.AuthMiddleware())
+	{
+		workflowGroup.GET("", middleware.RequirePermission(permWorkflowRead), handler.GetAll)
+		workflowGroup.GET("/:id", middleware.RequirePermission(permWorkflowRead), handler.GetByID)
+		workflowGroup.POST("", middleware.RequirePermission(permWorkflowManage), handler.Create)
+		workflowGroup.PUT("/:id", middleware.RequirePermission(permWorkflowManage), handler.Update)
+		workflowGroup.DELETE("/:id", middleware.RequirePermission(permWorkflowManage), handler.Delete)
+	}
+}
@@ -0,0 +1,246 @@
+package workflow
+
+import (
+	"database/sql"
+	"dokuprime-be/audit"
+	"dokuprime-be/middleware"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNotReviewer = errors.New("you are not allowed to review this step")
+
+type WorkflowService struct {
+	repo  *WorkflowRepository
+	audit *audit.AuditService
+}
+
+func NewWorkflowService(repo *WorkflowRepository, auditService *audit.AuditService) *WorkflowService {
+	return &WorkflowService{repo: repo, audit: auditService}
+}
+
+func (s *WorkflowService) GetAll() ([]Workflow, error) {
+	return s.repo.GetAll()
+}
+
+func (s *WorkflowService) GetByID(id int) (*Workflow, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *WorkflowService) Create(input WorkflowInput, actor audit.Actor) (*Workflow, error) {
+	workflow, err := s.build(input, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(workflow); err != nil {
+		return nil, fmt.Errorf("failed to create workflow: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionWorkflowCreate, audit.EntityWorkflow, workflow.ID, nil, workflow)
+	return workflow, nil
+}
+
+func (s *WorkflowService) Update(id int, input WorkflowInput, actor audit.Actor) (*Workflow, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow, err := s.build(input, id)
+	if err != nil {
+		return nil, err
+	}
+	workflow.ID = id
+	if err := s.repo.Update(workflow); err != nil {
+		return nil, fmt.Errorf("failed to update workflow: %w", err)
+	}
+
+	s.audit.Record(actor, audit.ActionWorkflowUpdate, audit.EntityWorkflow, id, existing, workflow)
+	return workflow, nil
+}
+
+// Delete removes a workflow. Documents still in review under it fall back to
+// the single-step approval.
+func (s *WorkflowService) Delete(id int, actor audit.Actor) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, audit.ActionWorkflowDelete, audit.EntityWorkflow, id, existing, nil)
+	return nil
+}
+
+func (s *WorkflowService) build(input WorkflowInput, id int) (*Workflow, error) {
+	workflow := &Workflow{
+		Name:        strings.TrimSpace(input.Name),
+		Category:    normalizeScope(input.Category),
+		Team:        normalizeScope(input.Team),
+		AutoApprove: input.AutoApprove,
+		IsActive:    input.IsActive == nil || *input.IsActive,
+		Steps:       []Step{},
+	}
+	if workflow.Name == "" {
+		return nil, fmt.Errorf("workflow name is required")
+	}
+	if !workflow.AutoApprove && len(input.Steps) == 0 {
+		return nil, fmt.Errorf("a workflow without auto-approve needs at least one step")
+	}
+
+	for i, in := range input.Steps {
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			return nil, fmt.Errorf("step %d needs a name", i+1)
+		}
+		permission := normalizeScope(in.RequiredPermission)
+		if permission != nil && !strings.Contains(*permission, ":") {
+			return nil, fmt.Errorf("step %d: permission must look like module:action", i+1)
+		}
+		workflow.Steps = append(workflow.Steps, Step{
+			Name:               name,
+			RequiredPermission: permission,
+			RequiredRoleID:     in.RequiredRoleID,
+		})
+	}
+
+	if workflow.IsActive {
+		taken, err := s.repo.ScopeTaken(workflow.Category, workflow.Team, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check workflow scope: %w", err)
+		}
+		if taken {
+			return nil, fmt.Errorf("another active workflow already covers this category and team")
+		}
+	}
+	return workflow, nil
+}
+
+func normalizeScope(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// Resolve returns the workflow that applies to a document, or nil when none
+// is configured and the single-step approval applies.
+func (s *WorkflowService) Resolve(category, team string) (*Workflow, error) {
+	workflow, err := s.repo.FindForScope(category, team)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve approval workflow: %w", err)
+	}
+	return workflow, nil
+}
+
+// Step returns the workflow and the step at `order`. A missing workflow
+// (deleted while the document was in review) returns nil without error.
+func (s *WorkflowService) Step(workflowID, order int) (*Workflow, *Step, error) {
+	workflow, err := s.repo.GetByID(workflowID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get approval workflow: %w", err)
+	}
+
+	for i := range workflow.Steps {
+		if workflow.Steps[i].StepOrder == order {
+			return workflow, &workflow.Steps[i], nil
+		}
+	}
+	return workflow, nil, nil
+}
+
+// CheckReviewer verifies the actor may decide on a step. A reviewer who
+// approved an earlier step of the same review round cannot approve again.
+func (s *WorkflowService) CheckReviewer(step *Step, detailID int, actor audit.Actor) error {
+	if actor.UserID == nil {
+		return ErrNotReviewer
+	}
+
+	if step.RequiredPermission != nil {
+		ok, err := middleware.UserHasPermission(*actor.UserID, *step.RequiredPermission)
+		if err != nil {
+			return fmt.Errorf("failed to check reviewer permissions: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q requires %s", ErrNotReviewer, step.Name, *step.RequiredPermission)
+		}
+	}
+
+	if step.RequiredRoleID != nil {
+		roleID, err := s.repo.GetUserRoleID(*actor.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to check reviewer role: %w", err)
+		}
+		if roleID == nil || *roleID != *step.RequiredRoleID {
+			return fmt.Errorf("%w: %q requires a different role", ErrNotReviewer, step.Name)
+		}
+	}
+
+	decisions, err := s.repo.GetDecisions(detailID)
+	if err != nil {
+		return fmt.Errorf("failed to get approval history: %w", err)
+	}
+	for _, d := range currentRound(decisions) {
+		if d.Decision == DecisionApproved && d.ActorUserID != nil && *d.ActorUserID == *actor.UserID && d.StepOrder < step.StepOrder {
+			return fmt.Errorf("%w: you already approved an earlier step of this document", ErrNotReviewer)
+		}
+	}
+	return nil
+}
+
+// currentRound drops the decisions made before the latest rejection.
+func currentRound(decisions []Decision) []Decision {
+	for i := len(decisions) - 1; i >= 0; i-- {
+		if decisions[i].Decision == DecisionRejected {
+			return decisions[i+1:]
+		}
+	}
+	return decisions
+}
+
+func (s *WorkflowService) RecordDecision(detailID int, workflowID *int, step *Step, decision string, actor audit.Actor) error {
+	record := &Decision{
+		DetailID:    detailID,
+		WorkflowID:  workflowID,
+		StepOrder:   1,
+		Decision:    decision,
+		ActorUserID: actor.UserID,
+	}
+	if step != nil {
+		record.StepOrder = step.StepOrder
+		record.StepName = &step.Name
+	}
+	return s.repo.RecordDecision(record)
+}
+
+func (s *WorkflowService) Progress(detailID int, workflowID, currentStep *int) (*Progress, error) {
+	progress := &Progress{DetailID: detailID, CurrentStep: currentStep}
+
+	if workflowID != nil {
+		workflow, err := s.repo.GetByID(*workflowID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		progress.Workflow = workflow
+	}
+
+	decisions, err := s.repo.GetDecisions(detailID)
+	if err != nil {
+		return nil, err
+	}
+	progress.Decisions = decisions
+	return progress, nil
+}